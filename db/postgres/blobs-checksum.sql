@@ -0,0 +1,11 @@
+--
+-- add checksum support to existing blobs and blob_versions tables
+--
+
+-- the SHA-256 of the payload, blank for blobs stored before checksums were introduced
+ALTER TABLE blobs ADD COLUMN IF NOT EXISTS checksum VARCHAR( 64 ) NOT NULL DEFAULT '';
+ALTER TABLE blob_versions ADD COLUMN IF NOT EXISTS checksum VARCHAR( 64 ) NOT NULL DEFAULT '';
+
+--
+-- end of file
+--
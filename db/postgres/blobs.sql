@@ -13,6 +13,7 @@ CREATE TABLE blobs (
    name       VARCHAR( 256 ) NOT NULL DEFAULT '',
    mimetype   VARCHAR( 32 ) NOT NULL DEFAULT '',
    payload    BYTEA,
+   checksum   VARCHAR( 64 ) NOT NULL DEFAULT '',
 
    created_at timestamp DEFAULT NOW(),
    updated_at timestamp DEFAULT NOW()
@@ -20,8 +20,8 @@ CREATE TABLE fields (
 -- create the namespace/oid key index
 CREATE INDEX fields_key_idx ON fields(namespace, oid);
 
--- create the distinct index
-CREATE UNIQUE INDEX fields_distinct_idx ON fields(namespace, oid, name);
+-- create the name index, a field can have several values (one row each)
+CREATE INDEX fields_name_idx ON fields(namespace, oid, name);
 
 -- auto vacuum parameters
 -- see: https://aws.amazon.com/blogs/database/understanding-autovacuum-in-amazon-rds-for-postgresql-environments/
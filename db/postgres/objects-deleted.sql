@@ -0,0 +1,13 @@
+--
+-- add soft delete support to an existing objects table
+--
+
+-- the time the object was deleted, NULL for live objects
+ALTER TABLE objects ADD COLUMN IF NOT EXISTS deleted_at timestamp DEFAULT NULL;
+
+-- create the index used to find deleted objects (the trash)
+CREATE INDEX IF NOT EXISTS objects_deleted_idx ON objects(deleted_at);
+
+--
+-- end of file
+--
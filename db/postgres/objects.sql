@@ -13,12 +13,20 @@ CREATE TABLE objects (
    vtag       VARCHAR( 32 ) UNIQUE NOT NULL,
 
    created_at timestamp DEFAULT NOW(),
-   updated_at timestamp DEFAULT NOW()
+   updated_at timestamp DEFAULT NOW(),
+   deleted_at timestamp DEFAULT NULL
 );
 
 -- create the namespace/oid index
 CREATE UNIQUE INDEX objects_key_idx ON objects(namespace, oid);
 
+-- create the indexes used when paging by created and updated time
+CREATE INDEX objects_created_idx ON objects(created_at, namespace, oid);
+CREATE INDEX objects_updated_idx ON objects(updated_at, namespace, oid);
+
+-- create the index used to find deleted objects (the trash)
+CREATE INDEX objects_deleted_idx ON objects(deleted_at);
+
 -- auto vacuum parameters
 -- see: https://aws.amazon.com/blogs/database/understanding-autovacuum-in-amazon-rds-for-postgresql-environments/
 ALTER TABLE objects SET (autovacuum_vacuum_scale_factor = 0.2);  -- 20%
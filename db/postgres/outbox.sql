@@ -0,0 +1,38 @@
+--
+--
+--
+
+-- drop the table if it exists
+DROP TABLE IF EXISTS outbox;
+
+-- and create the new one
+CREATE TABLE outbox (
+   id           serial PRIMARY KEY,
+   eid          VARCHAR( 32 ) NOT NULL,
+   name         VARCHAR( 64 ) NOT NULL,
+   namespace    VARCHAR( 32 ) NOT NULL,
+   oid          VARCHAR( 64 ) NOT NULL,
+   detail       TEXT NOT NULL DEFAULT '',
+   attempts     INTEGER NOT NULL DEFAULT 0,
+
+   created_at   timestamp DEFAULT NOW(),
+   delivered_at timestamp DEFAULT NULL
+);
+
+-- create the event identifier index
+CREATE UNIQUE INDEX outbox_eid_idx ON outbox(eid);
+
+-- create the indexes used to find pending events and to replay events by time
+CREATE INDEX outbox_pending_idx ON outbox(delivered_at, created_at);
+CREATE INDEX outbox_created_idx ON outbox(created_at);
+
+-- auto vacuum parameters
+-- see: https://aws.amazon.com/blogs/database/understanding-autovacuum-in-amazon-rds-for-postgresql-environments/
+ALTER TABLE outbox SET (autovacuum_vacuum_scale_factor = 0.2);  -- 20%
+ALTER TABLE outbox SET (autovacuum_vacuum_threshold = 1000);
+ALTER TABLE outbox SET (autovacuum_analyze_scale_factor = 0.1); -- 10%
+ALTER TABLE outbox SET (autovacuum_analyze_threshold = 1000);
+
+--
+-- end of file
+--
@@ -0,0 +1,13 @@
+--
+-- add multi-valued field support to an existing fields table
+--
+
+-- a field can have several values (one row each) so names are no longer distinct
+DROP INDEX IF EXISTS fields_distinct_idx;
+
+-- create the name index
+CREATE INDEX IF NOT EXISTS fields_name_idx ON fields(namespace, oid, name);
+
+--
+-- end of file
+--
@@ -0,0 +1,11 @@
+--
+-- add the paging indexes to an existing objects table
+--
+
+-- create the indexes used when paging by created and updated time
+CREATE INDEX IF NOT EXISTS objects_created_idx ON objects(created_at, namespace, oid);
+CREATE INDEX IF NOT EXISTS objects_updated_idx ON objects(updated_at, namespace, oid);
+
+--
+-- end of file
+--
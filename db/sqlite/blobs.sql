@@ -13,9 +13,10 @@ CREATE TABLE blobs (
    name       VARCHAR( 256 ) NOT NULL DEFAULT '',
    mimetype   VARCHAR( 32 ) NOT NULL DEFAULT '',
    payload    BLOB,
+   checksum   VARCHAR( 64 ) NOT NULL DEFAULT '',
 
-   created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-   updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+   created_at TIMESTAMP NOT NULL DEFAULT (STRFTIME('%Y-%m-%d %H:%M:%f000', 'NOW')),
+   updated_at TIMESTAMP NOT NULL DEFAULT (STRFTIME('%Y-%m-%d %H:%M:%f000', 'NOW'))
 );
 
 -- create the namespace/oid index
@@ -13,15 +13,15 @@ CREATE TABLE fields (
    name       VARCHAR( 32 ) NOT NULL DEFAULT '',
    value      TEXT NOT NULL DEFAULT '',
 
-   created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-   updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+   created_at TIMESTAMP NOT NULL DEFAULT (STRFTIME('%Y-%m-%d %H:%M:%f000', 'NOW')),
+   updated_at TIMESTAMP NOT NULL DEFAULT (STRFTIME('%Y-%m-%d %H:%M:%f000', 'NOW'))
 );
 
 -- create the namespace/oid index
 CREATE INDEX fields_key_idx ON fields(namespace, oid);
 
--- create the distinct index
-CREATE UNIQUE INDEX fields_distinct_idx ON fields(namespace, oid, name);
+-- create the name index, a field can have several values (one row each)
+CREATE INDEX fields_name_idx ON fields(namespace, oid, name);
 
 --
 -- end of file
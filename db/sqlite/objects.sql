@@ -12,13 +12,21 @@ CREATE TABLE objects (
    oid        VARCHAR( 64 ) NOT NULL DEFAULT '' ,
    vtag       VARCHAR( 32 ) NOT NULL DEFAULT '',
 
-   created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-   updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+   created_at TIMESTAMP NOT NULL DEFAULT (STRFTIME('%Y-%m-%d %H:%M:%f000', 'NOW')),
+   updated_at TIMESTAMP NOT NULL DEFAULT (STRFTIME('%Y-%m-%d %H:%M:%f000', 'NOW')),
+   deleted_at TIMESTAMP DEFAULT NULL
 );
 
 -- create the namespace/oid index
 CREATE UNIQUE INDEX objects_key_idx ON objects(namespace, oid);
 
+-- create the indexes used when paging by created and updated time
+CREATE INDEX objects_created_idx ON objects(created_at, namespace, oid);
+CREATE INDEX objects_updated_idx ON objects(updated_at, namespace, oid);
+
+-- create the index used to find deleted objects (the trash)
+CREATE INDEX objects_deleted_idx ON objects(deleted_at);
+
 --
 -- end of file
 --
\ No newline at end of file
@@ -0,0 +1,31 @@
+--
+--
+--
+
+-- drop the table if it exists
+DROP TABLE IF EXISTS outbox;
+
+-- and create the new one
+CREATE TABLE outbox (
+   id           INTEGER PRIMARY KEY,
+   eid          VARCHAR( 32 ) NOT NULL DEFAULT '',
+   name         VARCHAR( 64 ) NOT NULL DEFAULT '',
+   namespace    VARCHAR( 32 ) NOT NULL DEFAULT '',
+   oid          VARCHAR( 64 ) NOT NULL DEFAULT '',
+   detail       TEXT NOT NULL DEFAULT '',
+   attempts     INTEGER NOT NULL DEFAULT 0,
+
+   created_at   TIMESTAMP NOT NULL DEFAULT (STRFTIME('%Y-%m-%d %H:%M:%f000', 'NOW')),
+   delivered_at TIMESTAMP DEFAULT NULL
+);
+
+-- create the event identifier index
+CREATE UNIQUE INDEX outbox_eid_idx ON outbox(eid);
+
+-- create the indexes used to find pending events and to replay events by time
+CREATE INDEX outbox_pending_idx ON outbox(delivered_at, created_at);
+CREATE INDEX outbox_created_idx ON outbox(created_at);
+
+--
+-- end of file
+--
@@ -0,0 +1,51 @@
+--
+-- prior object versions, each is a copy of the object, fields and blobs it replaced
+--
+
+-- drop the tables if they exist
+DROP TABLE IF EXISTS object_versions;
+DROP TABLE IF EXISTS field_versions;
+DROP TABLE IF EXISTS blob_versions;
+
+-- and create the new ones
+CREATE TABLE object_versions (
+   id         INTEGER PRIMARY KEY,
+   namespace  VARCHAR( 32 ) NOT NULL DEFAULT '' ,
+   oid        VARCHAR( 64 ) NOT NULL DEFAULT '' ,
+   vtag       VARCHAR( 32 ) NOT NULL DEFAULT '',
+
+   created_at TIMESTAMP,
+   updated_at TIMESTAMP
+);
+
+CREATE TABLE field_versions (
+   id         INTEGER PRIMARY KEY,
+   namespace  VARCHAR( 32 ) NOT NULL DEFAULT '' ,
+   oid        VARCHAR( 64 ) NOT NULL DEFAULT '' ,
+   vtag       VARCHAR( 32 ) NOT NULL DEFAULT '',
+   name       VARCHAR( 32 ) NOT NULL DEFAULT '' ,
+   value      TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE blob_versions (
+   id         INTEGER PRIMARY KEY,
+   namespace  VARCHAR( 32 ) NOT NULL DEFAULT '' ,
+   oid        VARCHAR( 64 ) NOT NULL DEFAULT '' ,
+   vtag       VARCHAR( 32 ) NOT NULL DEFAULT '',
+   name       VARCHAR( 256 ) NOT NULL DEFAULT '' ,
+   mimetype   VARCHAR( 32 ) NOT NULL DEFAULT '' ,
+   payload    BLOB,
+   checksum   VARCHAR( 64 ) NOT NULL DEFAULT '',
+
+   created_at TIMESTAMP,
+   updated_at TIMESTAMP
+);
+
+-- create the version indexes
+CREATE UNIQUE INDEX object_versions_key_idx ON object_versions(namespace, oid, vtag);
+CREATE INDEX field_versions_key_idx ON field_versions(namespace, oid, vtag);
+CREATE INDEX blob_versions_key_idx ON blob_versions(namespace, oid, vtag);
+
+--
+-- end of file
+--
@@ -5,6 +5,7 @@ import (
 	"flag"
 	"fmt"
 	"github.com/uvalib/easystore/uvaeasystore"
+	"io"
 	"log"
 	"net/http"
 	"os"
@@ -24,7 +25,7 @@ func main() {
 	var debug bool
 	var logger *log.Logger
 
-	flag.StringVar(&mode, "mode", "postgres", "Mode, sqlite, postgres, s3, proxy")
+	flag.StringVar(&mode, "mode", "postgres", "Mode, sqlite, postgres, s3, filesystem, proxy")
 	flag.StringVar(&id, "identifier", "", "Object to change, ns/oid")
 	flag.StringVar(&cmd, "cmd", "", "Command, add, del, rename, show, update")
 	flag.StringVar(&name, "name", "", "Name (add, del, rename only)")
@@ -91,12 +92,12 @@ func main() {
 	var err error
 
 	switch mode {
-	//case "sqlite":
-	//	implConfig = uvaeasystore.DatastoreSqliteConfig{
-	//		DataSource: os.Getenv("SQLITEFILE"),
-	//		Log:        logger,
-	//	}
-	//	es, err = uvaeasystore.NewEasyStore(implConfig)
+	case "sqlite":
+		implConfig = uvaeasystore.DatastoreSqliteConfig{
+			DataSource: os.Getenv("SQLITEFILE"),
+			Log:        logger,
+		}
+		es, err = uvaeasystore.NewEasyStore(implConfig)
 
 	//case "postgres":
 	//	implConfig = uvaeasystore.DatastorePostgresConfig{
@@ -126,9 +127,17 @@ func main() {
 		}
 		es, err = uvaeasystore.NewEasyStore(implConfig)
 
+	case "filesystem":
+		implConfig = uvaeasystore.DatastoreFilesystemConfig{
+			RootDir: os.Getenv("FSROOT"),
+			Log:     logger,
+		}
+		es, err = uvaeasystore.NewEasyStore(implConfig)
+
 	case "proxy":
 		proxyConfig = uvaeasystore.ProxyConfigImpl{
 			ServiceEndpoint: os.Getenv("ESENDPOINT"),
+			ServiceToken:    os.Getenv("ESTOKEN"),
 			Log:             logger,
 		}
 		es, err = uvaeasystore.NewEasyStoreProxy(proxyConfig)
@@ -185,18 +194,22 @@ func main() {
 
 func addBlob(es uvaeasystore.EasyStore, eso uvaeasystore.EasyStoreObject, name string, fname string) error {
 
-	// read the file
-	buf, err := os.ReadFile(fname)
+	// open the file
+	file, err := os.Open(fname)
 	if err != nil {
 		log.Printf("INFO: %s not found or not readable\n", fname)
 		return nil
 	}
 
 	// attempt to determine the content type
-	mt := http.DetectContentType(buf)
+	mt, err := detectContentType(file)
+	if err != nil {
+		file.Close()
+		return err
+	}
 
-	// make the new blob
-	bl := uvaeasystore.NewEasyStoreBlob(name, mt, buf)
+	// make the new blob, the file is streamed (and closed) when it is stored
+	bl := uvaeasystore.NewEasyStoreBlobFromReader(name, mt, file)
 	// and add it
 	err = es.FileCreate(eso.Namespace(), eso.Id(), bl)
 
@@ -243,18 +256,22 @@ func show(eso uvaeasystore.EasyStoreObject) error {
 
 func updateBlob(es uvaeasystore.EasyStore, eso uvaeasystore.EasyStoreObject, name string, fname string) error {
 
-	// read the file
-	buf, err := os.ReadFile(fname)
+	// open the file
+	file, err := os.Open(fname)
 	if err != nil {
 		log.Printf("INFO: %s not found or not readable\n", fname)
 		return nil
 	}
 
 	// attempt to determine the content type
-	mt := http.DetectContentType(buf)
+	mt, err := detectContentType(file)
+	if err != nil {
+		file.Close()
+		return err
+	}
 
-	// make the new blob
-	bl := uvaeasystore.NewEasyStoreBlob(name, mt, buf)
+	// make the new blob, the file is streamed (and closed) when it is stored
+	bl := uvaeasystore.NewEasyStoreBlobFromReader(name, mt, file)
 	// and update it
 	err = es.FileUpdate(eso.Namespace(), eso.Id(), bl)
 
@@ -267,6 +284,20 @@ func updateBlob(es uvaeasystore.EasyStore, eso uvaeasystore.EasyStoreObject, nam
 	return err
 }
 
+// determine the content type from the start of the file then rewind it
+func detectContentType(file *os.File) (string, error) {
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(file, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		return "", err
+	}
+	return http.DetectContentType(buf[:n]), nil
+}
+
 func asIntWithDefault(str string, def int) int {
 	if len(str) == 0 {
 		return def
@@ -7,6 +7,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // main entry point
@@ -15,16 +16,24 @@ func main() {
 	var mode string
 	var single string
 	var bulk string
+	var restore bool
+	var purge bool
+	var expire time.Duration
+	var namespace string
 	var debug bool
 	var logger *log.Logger
 
-	flag.StringVar(&mode, "mode", "postgres", "Mode, sqlite, postgres, s3, proxy")
+	flag.StringVar(&mode, "mode", "postgres", "Mode, sqlite, postgres, s3, filesystem, proxy")
 	flag.StringVar(&single, "single", "", "Object to delete, ns/oid")
 	flag.StringVar(&bulk, "bulk", "", "File containing list of objects to delete, ns/oid")
+	flag.BoolVar(&restore, "restore", false, "Restore the deleted object(s) from the trash")
+	flag.BoolVar(&purge, "purge", false, "Permanently remove the deleted object(s) from the trash")
+	flag.DurationVar(&expire, "expire", 0, "Permanently remove objects deleted longer ago than this (e.g. 720h) from the trash")
+	flag.StringVar(&namespace, "namespace", "", "Namespace to expire deleted objects from, blank for all")
 	flag.BoolVar(&debug, "debug", false, "Log debug information")
 	flag.Parse()
 
-	if len(single) == 0 && len(bulk) == 0 {
+	if len(single) == 0 && len(bulk) == 0 && expire == 0 {
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
@@ -41,13 +50,13 @@ func main() {
 	var err error
 
 	switch mode {
-	//	case "sqlite":
-	//		implConfig = uvaeasystore.DatastoreSqliteConfig{
-	//			DataSource: os.Getenv("SQLITEFILE"),
-	//			Log:        logger,
-	//		}
-	//		es, err = uvaeasystore.NewEasyStore(implConfig)
-	//
+	case "sqlite":
+		implConfig = uvaeasystore.DatastoreSqliteConfig{
+			DataSource: os.Getenv("SQLITEFILE"),
+			Log:        logger,
+		}
+		es, err = uvaeasystore.NewEasyStore(implConfig)
+
 	//	case "postgres":
 	//		implConfig = uvaeasystore.DatastorePostgresConfig{
 	//			DbHost:     os.Getenv("DBHOST"),
@@ -76,9 +85,17 @@ func main() {
 		}
 		es, err = uvaeasystore.NewEasyStore(implConfig)
 
+	case "filesystem":
+		implConfig = uvaeasystore.DatastoreFilesystemConfig{
+			RootDir: os.Getenv("FSROOT"),
+			Log:     logger,
+		}
+		es, err = uvaeasystore.NewEasyStore(implConfig)
+
 	case "proxy":
 		proxyConfig = uvaeasystore.ProxyConfigImpl{
 			ServiceEndpoint: os.Getenv("ESENDPOINT"),
+			ServiceToken:    os.Getenv("ESTOKEN"),
 			Log:             logger,
 		}
 		es, err = uvaeasystore.NewEasyStoreProxy(proxyConfig)
@@ -94,6 +111,16 @@ func main() {
 	// important, cleanup properly
 	defer es.Close()
 
+	// expire the trash, this is intended to be run periodically
+	if expire != 0 {
+		count, err := es.ObjectPurgeTrash(namespace, time.Now().Add(-expire))
+		if err != nil {
+			log.Fatalf("ERROR: terminate with '%s', purged %d object(s)", err.Error(), count)
+		}
+		log.Printf("INFO: terminate normally, purged %d object(s)", count)
+		return
+	}
+
 	lines := make([]string, 0)
 	if len(bulk) != 0 {
 		buf, err := os.ReadFile(bulk)
@@ -113,6 +140,30 @@ func main() {
 		if len(l) != 0 {
 			parts := strings.Split(l, "/")
 			if len(parts) == 2 {
+				// deleted objects are restored or purged by key
+				if restore == true {
+					_, err = es.ObjectRestore(parts[0], parts[1])
+					if err != nil {
+						log.Printf("WARNING: restore %s/%s returns error (%s), continuing", parts[0], parts[1], err.Error())
+						errors++
+						continue
+					}
+					log.Printf("INFO: restore %s/%s success...", parts[0], parts[1])
+					success++
+					continue
+				}
+				if purge == true {
+					err = es.ObjectPurge(parts[0], parts[1])
+					if err != nil {
+						log.Printf("WARNING: purge %s/%s returns error (%s), continuing", parts[0], parts[1], err.Error())
+						errors++
+						continue
+					}
+					log.Printf("INFO: purge %s/%s success...", parts[0], parts[1])
+					success++
+					continue
+				}
+
 				o, err = es.ObjectGetByKey(parts[0], parts[1], uvaeasystore.BaseComponent)
 				if err != nil {
 					log.Printf("WARNING: get %s/%s returns error (%s), continuing", parts[0], parts[1], err.Error())
@@ -133,7 +184,7 @@ func main() {
 	}
 
 	if err == nil {
-		log.Printf("INFO: terminate normally, deleted %d object(s), %d error(s)", success, errors)
+		log.Printf("INFO: terminate normally, processed %d object(s), %d error(s)", success, errors)
 	} else {
 		log.Printf("ERROR: terminate with '%s'", err.Error())
 	}
@@ -1,14 +1,11 @@
 package main
 
 import (
-	"bufio"
-	"bytes"
 	"flag"
 	"fmt"
 	"github.com/uvalib/easystore/uvaeasystore"
 	"io"
 	"log"
-	"net/http"
 	"os"
 	"strconv"
 	"strings"
@@ -26,7 +23,7 @@ func main() {
 	var debug bool
 	var logger *log.Logger
 
-	flag.StringVar(&mode, "mode", "postgres", "Mode, sqlite, postgres, s3, proxy")
+	flag.StringVar(&mode, "mode", "postgres", "Mode, sqlite, postgres, s3, filesystem, proxy")
 	flag.StringVar(&namespace, "namespace", "", "namespace to export")
 	flag.StringVar(&whatCmd, "what", "id", "What to export, can be 1 or more of id,fields,metadata,files")
 	flag.StringVar(&whereCmd, "where", "", "Query by field (fields:name=value)")
@@ -46,13 +43,13 @@ func main() {
 	var err error
 
 	switch mode {
-	//	case "sqlite":
-	//		implConfig = uvaeasystore.DatastoreSqliteConfig{
-	//			DataSource: os.Getenv("SQLITEFILE"),
-	//			Log:        logger,
-	//		}
-	//		esro, err = uvaeasystore.NewEasyStoreReadonly(implConfig)
-	//
+	case "sqlite":
+		implConfig = uvaeasystore.DatastoreSqliteConfig{
+			DataSource: os.Getenv("SQLITEFILE"),
+			Log:        logger,
+		}
+		esro, err = uvaeasystore.NewEasyStoreReadonly(implConfig)
+
 	//	case "postgres":
 	//		implConfig = uvaeasystore.DatastorePostgresConfig{
 	//			DbHost:     os.Getenv("DBHOST"),
@@ -81,9 +78,17 @@ func main() {
 		}
 		esro, err = uvaeasystore.NewEasyStoreReadonly(implConfig)
 
+	case "filesystem":
+		implConfig = uvaeasystore.DatastoreFilesystemConfig{
+			RootDir: os.Getenv("FSROOT"),
+			Log:     logger,
+		}
+		esro, err = uvaeasystore.NewEasyStoreReadonly(implConfig)
+
 	case "proxy":
 		proxyConfig = uvaeasystore.ProxyConfigImpl{
 			ServiceEndpoint: os.Getenv("ESENDPOINT"),
+			ServiceToken:    os.Getenv("ESTOKEN"),
 			Log:             logger,
 		}
 		esro, err = uvaeasystore.NewEasyStoreProxyReadonly(proxyConfig)
@@ -169,7 +174,7 @@ func exportObject(obj uvaeasystore.EasyStoreObject, serializer uvaeasystore.Easy
 	}
 
 	// export fields if they exist
-	i = serializer.FieldsSerialize(obj.Fields())
+	i = serializer.MultiFieldsSerialize(obj.MultiFields())
 	fname = fmt.Sprintf("%s/fields.json", outdir)
 	err = outputFile(fname, i.([]byte))
 	if err != nil {
@@ -191,8 +196,8 @@ func exportObject(obj uvaeasystore.EasyStoreObject, serializer uvaeasystore.Easy
 	// export files of they exist
 	for ix, f := range obj.Files() {
 
-		// serialize the blob object
-		i = serializer.BlobSerialize(f)
+		// serialize the blob object, the payload is exported separately
+		i = serializer.BlobSerialize(blobDescriptor{f})
 		fname = fmt.Sprintf("%s/blob-%03d.json", outdir, ix+1)
 		err = outputFile(fname, i.([]byte))
 		if err != nil {
@@ -200,50 +205,56 @@ func exportObject(obj uvaeasystore.EasyStoreObject, serializer uvaeasystore.Easy
 			return err
 		}
 
-		// and stream the file locally if appropriate
-		if len(f.Url()) != 0 {
-			fname = fmt.Sprintf("%s/%s", outdir, f.Name())
-			err = streamFile(fname, f.Url())
-			if err != nil {
-				log.Printf("ERROR: streaming/writing %s (%s)", fname, err.Error())
-				return err
-			}
+		// and stream the file locally
+		fname = fmt.Sprintf("%s/%s", outdir, f.Name())
+		err = streamFile(fname, f)
+		if err != nil {
+			log.Printf("ERROR: streaming/writing %s (%s)", fname, err.Error())
+			return err
 		}
 	}
 
 	return nil
 }
 
+// a blob that serializes without its payload
+type blobDescriptor struct {
+	uvaeasystore.EasyStoreBlob
+}
+
+func (b blobDescriptor) Payload() ([]byte, error) {
+	return nil, nil
+}
+
 func outputFile(name string, contents []byte) error {
 	err := os.WriteFile(name, contents, 0644)
 	return err
 }
 
-func streamFile(name string, url string) error {
+func streamFile(name string, blob uvaeasystore.EasyStoreBlob) error {
 
 	start := time.Now()
 
-	resp, err := http.Get(url)
+	reader, err := blob.Open()
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
+	defer reader.Close()
 
-	// Check response
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("bad status: %s", resp.Status)
+	file, err := os.Create(name)
+	if err != nil {
+		return err
 	}
 
 	// stream
-	var b bytes.Buffer
-	writer := bufio.NewWriter(&b)
-	_, err = io.Copy(writer, resp.Body)
+	_, err = io.Copy(file, reader)
 	if err != nil {
+		file.Close()
 		return err
 	}
 
-	// and write
-	err = outputFile(name, b.Bytes())
+	// and close
+	err = file.Close()
 	if err != nil {
 		return err
 	}
@@ -21,7 +21,7 @@ func main() {
 	var debug bool
 	var logger *log.Logger
 
-	flag.StringVar(&mode, "mode", "postgres", "Mode, sqlite, postgres, s3, proxy")
+	flag.StringVar(&mode, "mode", "postgres", "Mode, sqlite, postgres, s3, filesystem, proxy")
 	flag.StringVar(&id, "identifier", "", "Object to change, ns/oid")
 	flag.StringVar(&oper, "operation", "add", "Tag operation, add|del")
 	flag.StringVar(&name, "name", "", "Field name")
@@ -64,13 +64,13 @@ func main() {
 	var err error
 
 	switch mode {
-	//	case "sqlite":
-	//		implConfig = uvaeasystore.DatastoreSqliteConfig{
-	//			DataSource: os.Getenv("SQLITEFILE"),
-	//			Log:        logger,
-	//		}
-	//		es, err = uvaeasystore.NewEasyStore(implConfig)
-	//
+	case "sqlite":
+		implConfig = uvaeasystore.DatastoreSqliteConfig{
+			DataSource: os.Getenv("SQLITEFILE"),
+			Log:        logger,
+		}
+		es, err = uvaeasystore.NewEasyStore(implConfig)
+
 	//	case "postgres":
 	//		implConfig = uvaeasystore.DatastorePostgresConfig{
 	//			DbHost:     os.Getenv("DBHOST"),
@@ -99,9 +99,17 @@ func main() {
 		}
 		es, err = uvaeasystore.NewEasyStore(implConfig)
 
+	case "filesystem":
+		implConfig = uvaeasystore.DatastoreFilesystemConfig{
+			RootDir: os.Getenv("FSROOT"),
+			Log:     logger,
+		}
+		es, err = uvaeasystore.NewEasyStore(implConfig)
+
 	case "proxy":
 		proxyConfig = uvaeasystore.ProxyConfigImpl{
 			ServiceEndpoint: os.Getenv("ESENDPOINT"),
+			ServiceToken:    os.Getenv("ESTOKEN"),
 			Log:             logger,
 		}
 		es, err = uvaeasystore.NewEasyStoreProxy(proxyConfig)
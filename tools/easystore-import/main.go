@@ -20,7 +20,7 @@ func main() {
 	var debug bool
 	var logger *log.Logger
 
-	flag.StringVar(&mode, "mode", "postgres", "Mode, sqlite, postgres, s3, proxy")
+	flag.StringVar(&mode, "mode", "postgres", "Mode, sqlite, postgres, s3, filesystem, proxy")
 	flag.StringVar(&namespace, "namespace", "", "namespace to import")
 	flag.StringVar(&inDir, "importdir", "", "Import directory")
 	flag.BoolVar(&debug, "debug", false, "Log debug information")
@@ -38,13 +38,13 @@ func main() {
 	var err error
 
 	switch mode {
-	//	case "sqlite":
-	//		implConfig = uvaeasystore.DatastoreSqliteConfig{
-	//			DataSource: os.Getenv("SQLITEFILE"),
-	//			Log:        logger,
-	//		}
-	//		es, err = uvaeasystore.NewEasyStore(implConfig)
-	//
+	case "sqlite":
+		implConfig = uvaeasystore.DatastoreSqliteConfig{
+			DataSource: os.Getenv("SQLITEFILE"),
+			Log:        logger,
+		}
+		es, err = uvaeasystore.NewEasyStore(implConfig)
+
 	//	case "postgres":
 	//		implConfig = uvaeasystore.DatastorePostgresConfig{
 	//			DbHost:     os.Getenv("DBHOST"),
@@ -73,9 +73,17 @@ func main() {
 		}
 		es, err = uvaeasystore.NewEasyStore(implConfig)
 
+	case "filesystem":
+		implConfig = uvaeasystore.DatastoreFilesystemConfig{
+			RootDir: os.Getenv("FSROOT"),
+			Log:     logger,
+		}
+		es, err = uvaeasystore.NewEasyStore(implConfig)
+
 	case "proxy":
 		proxyConfig = uvaeasystore.ProxyConfigImpl{
 			ServiceEndpoint: os.Getenv("ESENDPOINT"),
+			ServiceToken:    os.Getenv("ESTOKEN"),
 			Log:             logger,
 		}
 		es, err = uvaeasystore.NewEasyStoreProxy(proxyConfig)
@@ -145,9 +153,9 @@ func makeObject(serializer uvaeasystore.EasyStoreSerializer, indir string, names
 	// import fields if they exist
 	buf, err = os.ReadFile(fmt.Sprintf("%s/fields.json", indir))
 	if err == nil {
-		fields, err := serializer.FieldsDeserialize(buf)
+		fields, err := serializer.MultiFieldsDeserialize(buf)
 		if err == nil {
-			obj.SetFields(fields)
+			obj.SetMultiFields(fields)
 			log.Printf("DEBUG: ==> imported fields for [%s]", obj.Id())
 		} else {
 			log.Fatalf("ERROR: deserializing fields (%s)", err.Error())
@@ -195,6 +203,16 @@ func makeObject(serializer uvaeasystore.EasyStoreSerializer, indir string, names
 				log.Fatalf("ERROR: deserializing blob (%s)", err.Error())
 			}
 
+			// the payload is exported separately, stream it from the file
+			pl, _ := blob.Payload()
+			if len(pl) == 0 {
+				file, err := os.Open(fmt.Sprintf("%s/%s", indir, blob.Name()))
+				if err != nil {
+					log.Fatalf("ERROR: opening blob payload (%s)", err.Error())
+				}
+				blob = uvaeasystore.NewEasyStoreBlobFromReader(blob.Name(), blob.MimeType(), file)
+			}
+
 			blobs = append(blobs, blob)
 			ix++
 			buf, err = os.ReadFile(fmt.Sprintf("%s/blob-%03d.json", indir, ix+1))
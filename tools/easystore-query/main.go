@@ -1,14 +1,11 @@
 package main
 
 import (
-	"bufio"
-	"bytes"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"log"
-	"net/http"
 	"os"
 	"sort"
 	"strconv"
@@ -29,16 +26,24 @@ func main() {
 	var debug bool
 	var quiet bool
 	var limit int
+	var order string
+	var token string
+	var versions bool
+	var vtag string
 	var logger *log.Logger
 
-	flag.StringVar(&mode, "mode", "postgres", "Mode, sqlite, postgres, s3, proxy")
+	flag.StringVar(&mode, "mode", "postgres", "Mode, sqlite, postgres, s3, filesystem, proxy")
 	flag.StringVar(&namespace, "namespace", "", "namespace to query")
 	flag.StringVar(&whatCmd, "what", "id", "What to query for, can be 1 or more of id,fields,metadata,files")
-	flag.StringVar(&whereCmd, "where", "", "How to specify, either by object id (oid=nnnnn) or by field (fields:name=value)")
+	flag.StringVar(&whereCmd, "where", "", "How to specify, by object id (oid=nnnnn), by field (fields:name=value) or by query (e.g. name=value AND NOT EXISTS other)")
 	flag.StringVar(&dumpDir, "dumpdir", "", "Directory to dump files and/or metadata")
 	flag.BoolVar(&debug, "debug", false, "Log debug information")
 	flag.BoolVar(&quiet, "quiet", false, "Quiet mode")
-	flag.IntVar(&limit, "limit", 0, "Query count limit, 0 is no limit")
+	flag.IntVar(&limit, "limit", 0, "Query page size, 0 is no limit")
+	flag.StringVar(&order, "order", "id", "Query result order, one of id, created, modified")
+	flag.StringVar(&token, "token", "", "Continuation token from a previous query, gets the next page")
+	flag.BoolVar(&versions, "versions", false, "List the versions of the object (requires oid=nnnnn)")
+	flag.StringVar(&vtag, "vtag", "", "Get the object as of this version (requires oid=nnnnn)")
 	flag.Parse()
 
 	if debug == true {
@@ -53,12 +58,12 @@ func main() {
 	var err error
 
 	switch mode {
-	//case "sqlite":
-	//	implConfig = uvaeasystore.DatastoreSqliteConfig{
-	//		DataSource: os.Getenv("SQLITEFILE"),
-	//		Log:        logger,
-	//	}
-	//	esro, err = uvaeasystore.NewEasyStoreReadonly(implConfig)
+	case "sqlite":
+		implConfig = uvaeasystore.DatastoreSqliteConfig{
+			DataSource: os.Getenv("SQLITEFILE"),
+			Log:        logger,
+		}
+		esro, err = uvaeasystore.NewEasyStoreReadonly(implConfig)
 
 	//case "postgres":
 	//	implConfig = uvaeasystore.DatastorePostgresConfig{
@@ -88,9 +93,17 @@ func main() {
 		}
 		esro, err = uvaeasystore.NewEasyStoreReadonly(implConfig)
 
+	case "filesystem":
+		implConfig = uvaeasystore.DatastoreFilesystemConfig{
+			RootDir: os.Getenv("FSROOT"),
+			Log:     logger,
+		}
+		esro, err = uvaeasystore.NewEasyStoreReadonly(implConfig)
+
 	case "proxy":
 		proxyConfig = uvaeasystore.ProxyConfigImpl{
 			ServiceEndpoint: os.Getenv("ESENDPOINT"),
+			ServiceToken:    os.Getenv("ESTOKEN"),
 			Log:             logger,
 		}
 		esro, err = uvaeasystore.NewEasyStoreProxyReadonly(proxyConfig)
@@ -118,9 +131,34 @@ func main() {
 		what += uvaeasystore.Files
 	}
 
+	// the page we are requesting
+	page := uvaeasystore.EasyStorePage{Limit: uint(limit), Token: token}
+	switch order {
+	case "id":
+		page.Order = uvaeasystore.OrderById
+	case "created":
+		page.Order = uvaeasystore.OrderByCreated
+	case "modified":
+		page.Order = uvaeasystore.OrderByModified
+	default:
+		log.Fatalf("ERROR: unsupported order (%s)", order)
+	}
+
+	// version queries are for a single object
+	if versions == true || len(vtag) != 0 {
+		if strings.HasPrefix(whereCmd, "oid=") == false {
+			log.Fatalf("ERROR: version queries require oid=nnnnn")
+		}
+		err = queryVersions(namespace, esro, what, whereCmd[4:], vtag, dumpDir)
+		if err != nil {
+			log.Fatalf("ERROR: querying easystore (%s)", err.Error())
+		}
+		return
+	}
+
 	// issue the query
 	start := time.Now()
-	results, err := queryEasyStore(namespace, esro, what, whereCmd)
+	results, err := queryEasyStore(namespace, esro, what, whereCmd, page)
 	if err != nil {
 		log.Fatalf("ERROR: querying easystore (%s)", err.Error())
 	}
@@ -148,24 +186,23 @@ func main() {
 
 			obj, err = results.Next()
 			current++
-			if limit > 0 && current > limit {
-				log.Printf("INFO: terminating at %d object(s)...", limit)
-				break
-			}
 		}
 		totalDuration := time.Since(start)
 
 		log.Printf("INFO: query time %0.2f seconds", queryDuration.Seconds())
 		log.Printf("INFO: %d results in %0.2f seconds", results.Count(), totalDuration.Seconds())
+		if len(results.Continuation()) != 0 {
+			log.Printf("INFO: more results available, use -token %s", results.Continuation())
+		}
 	} else {
 		log.Printf("INFO: no objects found, terminating")
 	}
 }
 
-func queryEasyStore(namespace string, esro uvaeasystore.EasyStoreReadonly, what uvaeasystore.EasyStoreComponents, whereCmd string) (uvaeasystore.EasyStoreObjectSet, error) {
+func queryEasyStore(namespace string, esro uvaeasystore.EasyStoreReadonly, what uvaeasystore.EasyStoreComponents, whereCmd string, page uvaeasystore.EasyStorePage) (uvaeasystore.EasyStoreObjectSet, error) {
 
 	// query by id
-	if strings.Contains(whereCmd, "oid=") {
+	if strings.HasPrefix(whereCmd, "oid=") {
 		oid := whereCmd[4:]
 		fmt.Printf("Querying by OID: %s\n", oid)
 		oids := []string{oid}
@@ -173,8 +210,8 @@ func queryEasyStore(namespace string, esro uvaeasystore.EasyStoreReadonly, what
 	}
 
 	// query by fields
-	fields := uvaeasystore.DefaultEasyStoreFields()
-	if strings.Contains(whereCmd, "fields:") {
+	if strings.HasPrefix(whereCmd, "fields:") {
+		fields := uvaeasystore.DefaultEasyStoreFields()
 		split := strings.Split(whereCmd[7:], ",")
 		for _, s := range split {
 			name := strings.Split(s, "=")[0]
@@ -182,10 +219,55 @@ func queryEasyStore(namespace string, esro uvaeasystore.EasyStoreReadonly, what
 			fields[name] = value
 			fmt.Printf("INFO: querying by field: %s=%s\n", name, value)
 		}
+		return esro.ObjectGetByFieldsPage(namespace, fields, what, page)
+	}
+
+	// query by query, the entire namespace if there is none
+	query := uvaeasystore.QueryAnd()
+	if len(whereCmd) != 0 {
+		var err error
+		query, err = uvaeasystore.ParseEasyStoreQuery(whereCmd)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("INFO: querying by query: %s\n", whereCmd)
+	}
+
+	// the limit is applied by the store
+	return esro.ObjectGetByQuery(namespace, query, what, page)
+}
+
+func queryVersions(namespace string, esro uvaeasystore.EasyStoreReadonly, what uvaeasystore.EasyStoreComponents, oid string, vtag string, dumpDir string) error {
+
+	// list the versions
+	if len(vtag) == 0 {
+		versions, err := esro.ObjectVersions(namespace, oid)
+		if err != nil {
+			return err
+		}
+		log.Printf("INFO: located %d version(s)...", len(versions))
+		for ix, v := range versions {
+			current := ""
+			if v.Current == true {
+				current = " (current)"
+			}
+			fmt.Printf("  ===> version %d: %s, %s%s\n", ix+1, v.VTag, v.Modified, current)
+		}
+		return nil
 	}
 
-	// return query by fields
-	return esro.ObjectGetByFields(namespace, fields, what)
+	// get the object as of the version
+	fmt.Printf("Querying by OID: %s, vtag %s\n", oid, vtag)
+	obj, err := esro.ObjectGetByVersion(namespace, oid, vtag, what)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("  ===> ns/id: %s/%s\n", obj.Namespace(), obj.Id())
+	err = outputObject(obj, what)
+	if err != nil {
+		return err
+	}
+	return dumpObject(obj, dumpDir)
 }
 
 func outputObject(obj uvaeasystore.EasyStoreObject, what uvaeasystore.EasyStoreComponents) error {
@@ -195,15 +277,17 @@ func outputObject(obj uvaeasystore.EasyStoreObject, what uvaeasystore.EasyStoreC
 	fmt.Printf("       updated: %s\n", obj.Modified())
 
 	if what&uvaeasystore.Fields == uvaeasystore.Fields {
-		if len(obj.Fields()) != 0 {
-			// output our fields in sorted order
-			keys := make([]string, 0, len(obj.Fields()))
-			for k := range obj.Fields() {
+		if len(obj.MultiFields()) != 0 {
+			// output our fields in sorted order, each value of those with several
+			keys := make([]string, 0, len(obj.MultiFields()))
+			for k := range obj.MultiFields() {
 				keys = append(keys, k)
 			}
 			sort.Strings(keys)
 			for _, k := range keys {
-				fmt.Printf("       field: %s=%s\n", k, obj.Fields()[k])
+				for _, v := range obj.MultiFields()[k] {
+					fmt.Printf("       field: %s=%s\n", k, v)
+				}
 			}
 		} else {
 			fmt.Printf("       no fields\n")
@@ -267,42 +351,9 @@ func dumpObject(obj uvaeasystore.EasyStoreObject, outdir string) error {
 	// dump files if they exist
 	if obj.Files() != nil {
 		for _, f := range obj.Files() {
-			var buf []byte
-			var err error
-
-			// check for a streaming URL
-			if len(f.Url()) != 0 {
-				
-				fmt.Printf("       ==> streaming %s...\n", f.Url())
-
-				resp, err := http.Get(f.Url())
-				if err != nil {
-					return err
-				}
-				defer resp.Body.Close()
-
-				// Check response
-				if resp.StatusCode != http.StatusOK {
-					return fmt.Errorf("bad status: %s", resp.Status)
-				}
-
-				// Write
-				var b bytes.Buffer
-				writer := bufio.NewWriter(&b)
-				_, err = io.Copy(writer, resp.Body)
-				if err != nil {
-					return err
-				}
-				buf = b.Bytes()
-			} else {
-				buf, err = f.Payload()
-				if err != nil {
-					return err
-				}
-			}
 			fname := fmt.Sprintf("%s/%s-%s-%s", outdir, obj.Namespace(), obj.Id(), f.Name())
 			fmt.Printf("       ==> writing %s...\n", fname)
-			err = os.WriteFile(fname, buf, 0644)
+			err := dumpFile(fname, f)
 			if err != nil {
 				return err
 			}
@@ -327,6 +378,28 @@ func dumpObject(obj uvaeasystore.EasyStoreObject, outdir string) error {
 	return nil
 }
 
+// stream the file payload (from the payload or the url) to the named file
+func dumpFile(name string, blob uvaeasystore.EasyStoreBlob) error {
+
+	reader, err := blob.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(file, reader)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
 func asIntWithDefault(str string, def int) int {
 	if len(str) == 0 {
 		return def
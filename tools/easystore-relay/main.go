@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"github.com/uvalib/easystore/uvaeasystore"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// main entry point
+func main() {
+
+	var mode string
+	var interval time.Duration
+	var once bool
+	var replay bool
+	var after time.Duration
+	var before time.Duration
+	var debug bool
+	var logger *log.Logger
+
+	flag.StringVar(&mode, "mode", "postgres", "Mode, sqlite, postgres, s3, filesystem")
+	flag.DurationVar(&interval, "interval", 30*time.Second, "How often to publish the pending events")
+	flag.BoolVar(&once, "once", false, "Publish the pending events once and exit")
+	flag.BoolVar(&replay, "replay", false, "Publish the events created in the time range again and exit")
+	flag.DurationVar(&after, "after", 24*time.Hour, "Replay the events created after this long ago (e.g. 24h)")
+	flag.DurationVar(&before, "before", 0, "Replay the events created before this long ago (e.g. 1h)")
+	flag.BoolVar(&debug, "debug", false, "Log debug information")
+	flag.Parse()
+
+	if debug == true {
+		logger = log.Default()
+	}
+
+	// a local sink instead of the message bus (if configured)
+	var sink uvaeasystore.EasyStoreEventSink
+	var err error
+	if len(os.Getenv("WEBHOOK")) != 0 {
+		sink = uvaeasystore.NewWebhookEventSink(os.Getenv("WEBHOOK"), os.Getenv("WEBHOOK_SECRET"), asIntWithDefault(os.Getenv("WEBHOOK_TIMEOUT"), 30))
+	} else if len(os.Getenv("EVENTFILE")) != 0 {
+		sink, err = uvaeasystore.NewFileEventSink(os.Getenv("EVENTFILE"))
+		if err != nil {
+			log.Fatalf("ERROR: opening events file (%s)", err.Error())
+		}
+	}
+	if sink != nil {
+		defer sink.Close()
+	}
+
+	var implConfig uvaeasystore.EasyStoreImplConfig
+
+	switch mode {
+	case "sqlite":
+		implConfig = uvaeasystore.DatastoreSqliteConfig{
+			DataSource: os.Getenv("SQLITEFILE"),
+			BusName:    os.Getenv("EVENTBUS"),
+			SourceName: os.Getenv("EVENTSRC"),
+			Sink:       sink,
+			Log:        logger,
+		}
+
+	case "postgres":
+		implConfig = uvaeasystore.DatastorePostgresConfig{
+			DbHost:     os.Getenv("DBHOST"),
+			DbPort:     asIntWithDefault(os.Getenv("DBPORT"), 0),
+			DbName:     os.Getenv("DBNAME"),
+			DbUser:     os.Getenv("DBUSER"),
+			DbPassword: os.Getenv("DBPASS"),
+			DbTimeout:  asIntWithDefault(os.Getenv("DBTIMEOUT"), 0),
+			BusName:    os.Getenv("EVENTBUS"),
+			SourceName: os.Getenv("EVENTSRC"),
+			Sink:       sink,
+			Log:        logger,
+		}
+
+	case "s3":
+		implConfig = uvaeasystore.DatastoreS3Config{
+			Bucket:              os.Getenv("BUCKET"),
+			SignerAccessKey:     os.Getenv("SIGNER_ACCESS_KEY"),
+			SignerSecretKey:     os.Getenv("SIGNER_SECRET_KEY"),
+			SignerExpireMinutes: asIntWithDefault(os.Getenv("SIGNEXPIRE"), 60),
+			DbHost:              os.Getenv("DBHOST"),
+			DbPort:              asIntWithDefault(os.Getenv("DBPORT"), 0),
+			DbName:              os.Getenv("DBNAME"),
+			DbUser:              os.Getenv("DBUSER"),
+			DbPassword:          os.Getenv("DBPASS"),
+			DbTimeout:           asIntWithDefault(os.Getenv("DBTIMEOUT"), 0),
+			BusName:             os.Getenv("EVENTBUS"),
+			SourceName:          os.Getenv("EVENTSRC"),
+			Sink:                sink,
+			Log:                 logger,
+		}
+
+	case "filesystem":
+		implConfig = uvaeasystore.DatastoreFilesystemConfig{
+			RootDir:    os.Getenv("FSROOT"),
+			BusName:    os.Getenv("EVENTBUS"),
+			SourceName: os.Getenv("EVENTSRC"),
+			Sink:       sink,
+			Log:        logger,
+		}
+
+	default:
+		log.Fatalf("ERROR: unsupported mode (%s)", mode)
+	}
+
+	relay, err := uvaeasystore.NewEasyStoreRelay(implConfig)
+	if err != nil {
+		log.Fatalf("ERROR: creating relay (%s)", err.Error())
+	}
+
+	// important, cleanup properly
+	defer relay.Close()
+
+	// publish the events in the time range again, consumers must tolerate duplicates
+	if replay == true {
+		now := time.Now()
+		count, err := relay.Replay(context.Background(), now.Add(-after), now.Add(-before))
+		if err != nil {
+			log.Fatalf("ERROR: terminate with '%s', replayed %d event(s)", err.Error(), count)
+		}
+		log.Printf("INFO: terminate normally, replayed %d event(s)", count)
+		return
+	}
+
+	// publish the pending events, this is intended to be run periodically
+	if once == true {
+		count, err := relay.Drain(context.Background())
+		if err != nil {
+			log.Fatalf("ERROR: terminate with '%s', published %d event(s)", err.Error(), count)
+		}
+		log.Printf("INFO: terminate normally, published %d event(s)", count)
+		return
+	}
+
+	// otherwise run until we are told to stop
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	log.Printf("INFO: publishing pending events every %s", interval)
+	err = relay.Run(ctx, interval)
+	if err != nil {
+		log.Fatalf("ERROR: terminate with '%s'", err.Error())
+	}
+	log.Printf("INFO: terminate normally")
+}
+
+func asIntWithDefault(str string, def int) int {
+	if len(str) == 0 {
+		return def
+	}
+	i, err := strconv.Atoi(str)
+	if err != nil {
+		return def
+	}
+	return i
+}
+
+//
+// end of file
+//
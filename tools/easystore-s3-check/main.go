@@ -7,6 +7,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/uvalib/easystore/uvaeasystore"
+	"io"
 	"log"
 	"os"
 	"strconv"
@@ -19,6 +20,7 @@ func main() {
 	var namespace string
 	var debug bool
 	var verifyCache bool
+	var verifyFixity bool
 	var limit int
 	var logger *log.Logger
 
@@ -26,6 +28,7 @@ func main() {
 	flag.BoolVar(&debug, "debug", false, "Log debug information")
 	flag.IntVar(&limit, "limit", 0, "Check count limit, 0 is no limit")
 	flag.BoolVar(&verifyCache, "verify", false, "Verify against cache")
+	flag.BoolVar(&verifyFixity, "fixity", false, "Verify file contents against their checksums (downloads every file)")
 	flag.Parse()
 
 	if debug == true {
@@ -36,6 +39,10 @@ func main() {
 		log.Printf("INFO: enabled cache verify\n")
 	}
 
+	if verifyFixity == true {
+		log.Printf("INFO: enabled fixity audit\n")
+	}
+
 	// create the S3 store configuration
 	s3Config := uvaeasystore.DatastoreS3Config{
 		Bucket:              os.Getenv("BUCKET"),
@@ -88,7 +95,7 @@ func main() {
 		key := uvaeasystore.DataStoreKey{Namespace: namespace, ObjectId: id}
 
 		// get the object
-		eso, err := s3ds.GetObjectByKey(key, uvaeasystore.NOCACHE)
+		eso, err := s3ds.GetObjectByKey(context.TODO(), key, uvaeasystore.NOCACHE)
 		if err != nil {
 			log.Printf("ERROR: getting object from S3 datastore (%s), continuing\n", err.Error())
 			errorCount++
@@ -96,8 +103,8 @@ func main() {
 		}
 
 		// get the fields
-		var fields *uvaeasystore.EasyStoreObjectFields
-		fields, err = s3ds.GetFieldsByKey(key, uvaeasystore.NOCACHE)
+		var fields *uvaeasystore.EasyStoreObjectMultiFields
+		fields, err = s3ds.GetFieldsByKey(context.TODO(), key, uvaeasystore.NOCACHE)
 		if err != nil {
 			if errors.Is(err, uvaeasystore.ErrNotFound) == true {
 				//log.Printf("INFO: no fields located for this object\n")
@@ -111,7 +118,7 @@ func main() {
 		}
 
 		// get the metadata
-		md, err := s3ds.GetMetadataByKey(key, uvaeasystore.NOCACHE)
+		md, err := s3ds.GetMetadataByKey(context.TODO(), key, uvaeasystore.NOCACHE)
 		if err != nil {
 			if errors.Is(err, uvaeasystore.ErrNotFound) == true {
 				//log.Printf("INFO: no metadata located for this object\n")
@@ -130,7 +137,7 @@ func main() {
 		}
 
 		// get the blobs
-		_, err = s3ds.GetBlobsByKey(key, uvaeasystore.NOCACHE)
+		blobs, err := s3ds.GetBlobsByKey(context.TODO(), key, uvaeasystore.NOCACHE)
 		if err != nil {
 			if errors.Is(err, uvaeasystore.ErrNotFound) == true {
 				//log.Printf("INFO: no blobs located for this object\n")
@@ -143,13 +150,22 @@ func main() {
 			//log.Printf("INFO: %d blobs located for this object\n", len(blobs))
 		}
 
+		// do we verify the file contents
+		if verifyFixity == true && len(blobs) != 0 {
+			if checkFixity(blobs) == false {
+				log.Printf("ERROR: file contents do not match their checksums, continuing\n")
+				errorCount++
+				continue
+			}
+		}
+
 		// do we verify the cache
 		if verifyCache == true {
 
 			//log.Printf("INFO: checking cache for this object\n")
 
 			var esoCache uvaeasystore.EasyStoreObject
-			esoCache, err = s3ds.GetObjectByKey(key, uvaeasystore.FROMCACHE)
+			esoCache, err = s3ds.GetObjectByKey(context.TODO(), key, uvaeasystore.FROMCACHE)
 			if err != nil {
 				log.Printf("ERROR: getting cached object from S3 datastore (%s), continuing\n", err.Error())
 				errorCount++
@@ -164,8 +180,8 @@ func main() {
 
 			// get the fields
 			if fields != nil {
-				var fieldsCache *uvaeasystore.EasyStoreObjectFields
-				fieldsCache, err = s3ds.GetFieldsByKey(key, uvaeasystore.FROMCACHE)
+				var fieldsCache *uvaeasystore.EasyStoreObjectMultiFields
+				fieldsCache, err = s3ds.GetFieldsByKey(context.TODO(), key, uvaeasystore.FROMCACHE)
 				if err != nil {
 					if errors.Is(err, uvaeasystore.ErrNotFound) == true {
 						//log.Printf("INFO: no fields located for this object\n")
@@ -235,6 +251,39 @@ func getIds(namespace string, s3Store *uvaeasystore.S3Storage) ([]string, error)
 	return result, nil
 }
 
+// download each file and compare its contents with the checksum recorded when it was stored
+func checkFixity(blobs []uvaeasystore.EasyStoreBlob) bool {
+
+	same := true
+	for _, b := range blobs {
+
+		// files stored before checksums were introduced cannot be verified
+		if len(b.Checksum()) == 0 {
+			log.Printf("WARNING: no checksum for [%s], not verified\n", b.Name())
+			continue
+		}
+
+		// the contents are checked as they are read
+		reader, err := b.Open()
+		if err != nil {
+			log.Printf("ERROR: opening [%s] (%s)\n", b.Name(), err.Error())
+			same = false
+			continue
+		}
+		_, err = io.Copy(io.Discard, reader)
+		reader.Close()
+		if err != nil {
+			if errors.Is(err, uvaeasystore.ErrChecksum) == true {
+				log.Printf("ERROR: checksum mismatch (%s)\n", err.Error())
+			} else {
+				log.Printf("ERROR: reading [%s] (%s)\n", b.Name(), err.Error())
+			}
+			same = false
+		}
+	}
+	return same
+}
+
 func verifyObject(eso1 uvaeasystore.EasyStoreObject, eso2 uvaeasystore.EasyStoreObject) bool {
 
 	same := true
@@ -267,14 +316,20 @@ func verifyObject(eso1 uvaeasystore.EasyStoreObject, eso2 uvaeasystore.EasyStore
 	return same
 }
 
-func verifyFields(fs1 uvaeasystore.EasyStoreObjectFields, fs2 uvaeasystore.EasyStoreObjectFields) bool {
+func verifyFields(fs1 uvaeasystore.EasyStoreObjectMultiFields, fs2 uvaeasystore.EasyStoreObjectMultiFields) bool {
 	if len(fs1) != len(fs2) {
 		return false
 	}
-	for key, value := range fs1 {
-		if val, ok := fs2[key]; !ok || val != value {
+	for key, values := range fs1 {
+		vals, ok := fs2[key]
+		if !ok || len(vals) != len(values) {
 			return false
 		}
+		for i, value := range values {
+			if vals[i] != value {
+				return false
+			}
+		}
 	}
 	return true
 }
@@ -95,14 +95,14 @@ func main() {
 	for ix, id := range ids {
 		log.Printf("INFO: processing ns/oid [%s/%s] (%d of %d)\n", namespace, id, ix+1, count)
 		key := uvaeasystore.DataStoreKey{Namespace: namespace, ObjectId: id}
-		obj, err := s3ds.GetObjectByKey(key, uvaeasystore.NOCACHE)
+		obj, err := s3ds.GetObjectByKey(context.TODO(), key, uvaeasystore.NOCACHE)
 		if err != nil {
 			log.Printf("ERROR: getting object from S3 datastore, continuing\n")
 			errorCount++
 			continue
 		}
 
-		fields, err := s3ds.GetFieldsByKey(key, uvaeasystore.NOCACHE)
+		fields, err := s3ds.GetFieldsByKey(context.TODO(), key, uvaeasystore.NOCACHE)
 		if err != nil {
 			if errors.Is(err, uvaeasystore.ErrNotFound) == true {
 				log.Printf("INFO: no fields located for this object\n")
@@ -118,12 +118,12 @@ func main() {
 		if dryRun == false {
 			if delBefore == true {
 				log.Printf("INFO: deleting object and fields before adding\n")
-				_ = pgds.DeleteFieldsByKey(key)
-				_ = pgds.DeleteObjectByKey(key)
+				_ = pgds.DeleteFieldsByKey(context.TODO(), key)
+				_ = pgds.DeleteObjectByKey(context.TODO(), key)
 			}
 
 			log.Printf("INFO: adding object to DB datastore...\n")
-			err = pgds.AddObject(obj)
+			err = pgds.AddObject(context.TODO(), obj)
 			if err != nil {
 				log.Printf("ERROR: adding object to DB datastore, continuing\n")
 				errorCount++
@@ -133,7 +133,7 @@ func main() {
 			// do we have fields to regenerate?
 			if fields != nil && len(*fields) != 0 {
 				log.Printf("INFO: adding fields to DB datastore...\n")
-				err = pgds.AddFields(key, *fields)
+				err = pgds.AddFields(context.TODO(), key, *fields)
 				if err != nil {
 					log.Printf("ERROR: adding fields to DB datastore, continuing\n")
 					errorCount++
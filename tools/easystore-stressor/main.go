@@ -47,6 +47,7 @@ func main() {
 
 	proxyConfig := uvaeasystore.ProxyConfigImpl{
 		ServiceEndpoint: os.Getenv("ESENDPOINT"),
+		ServiceToken:    os.Getenv("ESTOKEN"),
 		Log:             logger,
 	}
 	es, err := uvaeasystore.NewEasyStoreProxy(proxyConfig)
@@ -0,0 +1,171 @@
+//
+// searching and paging of object keys in memory, shared by the datastores without a database
+//
+
+// only include this file for service builds
+
+//go:build service
+// +build service
+
+package uvaeasystore
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// select a page of the matching keys along with the token for the next page, ordered and continued in
+// the same way as the datastores with a database
+func pageOfKeys(page EasyStorePage, matches []pageKey, log *log.Logger) ([]DataStoreKey, string, error) {
+
+	switch page.Order {
+	case OrderById, OrderByCreated, OrderByModified:
+	default:
+		return nil, "", fmt.Errorf("%q: %w", fmt.Sprintf("unknown order [%d]", page.Order), ErrBadParameter)
+	}
+
+	// continue after the last key of the previous page
+	keys := matches
+	if len(page.Token) != 0 {
+		token, err := decodePageToken(page.Token)
+		if err != nil {
+			return nil, "", err
+		}
+		if token.Order != page.Order {
+			return nil, "", fmt.Errorf("%q: %w", "continuation token order differs from the requested order", ErrBadParameter)
+		}
+		after := pageKey{key: DataStoreKey{token.Namespace, token.ObjectId}, created: token.When, modified: token.When}
+		keys = make([]pageKey, 0, len(matches))
+		for _, k := range matches {
+			if comparePageKeys(page.Order, k, after) > 0 {
+				keys = append(keys, k)
+			}
+		}
+	}
+
+	// check for not found
+	if len(keys) == 0 {
+		return nil, "", fmt.Errorf("%q: %w", "key(s) not found", ErrNotFound)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return comparePageKeys(page.Order, keys[i], keys[j]) < 0
+	})
+
+	next := ""
+	if page.Limit != 0 && uint(len(keys)) > page.Limit {
+		keys = keys[:page.Limit]
+		next = encodePageToken(page.Order, keys[len(keys)-1])
+	}
+
+	logDebug(log, fmt.Sprintf("found %d key(s)", len(keys)))
+	results := make([]DataStoreKey, 0, len(keys))
+	for _, k := range keys {
+		results = append(results, k.key)
+	}
+	return results, next, nil
+}
+
+// compare keys by the sort value for the order then by namespace and identifier
+func comparePageKeys(order EasyStoreOrder, a pageKey, b pageKey) int {
+
+	var aWhen, bWhen time.Time
+	switch order {
+	case OrderByCreated:
+		aWhen, bWhen = a.created, b.created
+	case OrderByModified:
+		aWhen, bWhen = a.modified, b.modified
+	}
+	if aWhen.Equal(bWhen) == false {
+		if aWhen.Before(bWhen) == true {
+			return -1
+		}
+		return 1
+	}
+	if a.key.Namespace != b.key.Namespace {
+		return strings.Compare(a.key.Namespace, b.key.Namespace)
+	}
+	return strings.Compare(a.key.ObjectId, b.key.ObjectId)
+}
+
+// evaluate the query against the fields of an object, a field with several values matches when any of them do
+func matchQuery(query EasyStoreQuery, fields EasyStoreObjectMultiFields) (bool, error) {
+
+	switch query.Op {
+	case QueryOpAnd:
+		for _, t := range query.Terms {
+			match, err := matchQuery(t, fields)
+			if err != nil || match == false {
+				return false, err
+			}
+		}
+		return true, nil
+
+	case QueryOpOr:
+		for _, t := range query.Terms {
+			match, err := matchQuery(t, fields)
+			if err != nil || match == true {
+				return match, err
+			}
+		}
+		return false, nil
+
+	case QueryOpNot:
+		if len(query.Terms) != 1 {
+			return false, fmt.Errorf("%q: %w", "not requires a single term", ErrBadParameter)
+		}
+		match, err := matchQuery(query.Terms[0], fields)
+		return match == false, err
+
+	case QueryOpExists:
+		return len(fields[query.Field]) != 0, nil
+	}
+
+	for _, value := range fields[query.Field] {
+		match, err := matchValue(query, value)
+		if err != nil || match == true {
+			return match, err
+		}
+	}
+
+	// validate the operator even when there are no values to compare
+	_, err := matchValue(query, "")
+	return false, err
+}
+
+// evaluate a value comparison
+func matchValue(query EasyStoreQuery, value string) (bool, error) {
+
+	switch query.Op {
+	case QueryOpEquals:
+		return value == query.Value, nil
+	case QueryOpPrefix:
+		return strings.HasPrefix(value, query.Value), nil
+	case QueryOpWildcard:
+		return wildcardPattern(query.Value).MatchString(value), nil
+	case QueryOpLess:
+		return value < query.Value, nil
+	case QueryOpLessEqual:
+		return value <= query.Value, nil
+	case QueryOpGreater:
+		return value > query.Value, nil
+	case QueryOpGreaterEqual:
+		return value >= query.Value, nil
+	}
+
+	return false, fmt.Errorf("%q: %w", fmt.Sprintf("unknown query operator [%s]", query.Op), ErrBadParameter)
+}
+
+// convert a wildcard pattern (* and ?) into an anchored regular expression
+func wildcardPattern(pattern string) *regexp.Regexp {
+	re := strings.NewReplacer("\\*", ".*", "\\?", ".").Replace(regexp.QuoteMeta(pattern))
+	return regexp.MustCompile(fmt.Sprintf("(?s)^%s$", re))
+}
+
+//
+// end of file
+//
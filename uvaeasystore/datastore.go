@@ -9,9 +9,10 @@
 
 package uvaeasystore
 
-//import (
-//	_ "github.com/mattn/go-sqlite3"
-//)
+import (
+	"context"
+	"time"
+)
 
 type DataStoreKey struct {
 	Namespace string
@@ -24,62 +25,117 @@ const (
 	NOCACHE   = false
 )
 
-// our dbStorage interface
+// our dbStorage interface. All methods take a context so request deadlines and
+// cancellation reach the underlying database and/or S3 calls
 type DataStore interface {
-	Check() error
+	Check(ctx context.Context) error
 
 	// update methods
-	UpdateBlob(key DataStoreKey, blob EasyStoreBlob) error
-	UpdateFields(key DataStoreKey, fields EasyStoreObjectFields) error
-	UpdateMetadata(key DataStoreKey, md EasyStoreMetadata) error
-	UpdateObject(key DataStoreKey) error
+	UpdateBlob(ctx context.Context, key DataStoreKey, blob EasyStoreBlob) error
+	UpdateFields(ctx context.Context, key DataStoreKey, fields EasyStoreObjectMultiFields) error
+	UpdateMetadata(ctx context.Context, key DataStoreKey, md EasyStoreMetadata) error
+	UpdateObject(ctx context.Context, key DataStoreKey) error
+
+	// lock method, within a transaction the object cannot be changed by anyone else until the transaction
+	// completes. Returns ErrStaleObject if the object is no longer at the vtag
+	LockObjectByKey(ctx context.Context, key DataStoreKey, vtag string) error
 
 	// add methods
-	AddBlob(key DataStoreKey, blob EasyStoreBlob) error
-	AddFields(key DataStoreKey, fields EasyStoreObjectFields) error
-	AddMetadata(key DataStoreKey, md EasyStoreMetadata) error
-	AddObject(obj EasyStoreObject) error
+	AddBlob(ctx context.Context, key DataStoreKey, blob EasyStoreBlob) error
+	AddFields(ctx context.Context, key DataStoreKey, fields EasyStoreObjectMultiFields) error
+	AddMetadata(ctx context.Context, key DataStoreKey, md EasyStoreMetadata) error
+	AddObject(ctx context.Context, obj EasyStoreObject) error
 
 	// get multiples methods
-	GetBlobsByKey(key DataStoreKey, useCache bool) ([]EasyStoreBlob, error)
-	GetFieldsByKey(key DataStoreKey, useCache bool) (*EasyStoreObjectFields, error)
-	GetMetadataByKey(key DataStoreKey, useCache bool) (EasyStoreMetadata, error)
-	GetObjectsByKey(keys []DataStoreKey, useCache bool) ([]EasyStoreObject, error)
+	GetBlobsByKey(ctx context.Context, key DataStoreKey, useCache bool) ([]EasyStoreBlob, error)
+	GetFieldsByKey(ctx context.Context, key DataStoreKey, useCache bool) (*EasyStoreObjectMultiFields, error)
+	GetMetadataByKey(ctx context.Context, key DataStoreKey, useCache bool) (EasyStoreMetadata, error)
+	GetObjectsByKey(ctx context.Context, keys []DataStoreKey, useCache bool) ([]EasyStoreObject, error)
 
 	// get single methods
-	//GetBlobByKey(key DataStoreKey, curName string, useCache bool) ([]EasyStoreBlob, error)
-	GetObjectByKey(key DataStoreKey, useCache bool) (EasyStoreObject, error)
+	GetBlobByKey(ctx context.Context, key DataStoreKey, curName string, useCache bool) (EasyStoreBlob, error)
+	GetObjectByKey(ctx context.Context, key DataStoreKey, useCache bool) (EasyStoreObject, error)
 
 	// rename method
-	RenameBlobByKey(key DataStoreKey, curName string, newName string) error
+	RenameBlobByKey(ctx context.Context, key DataStoreKey, curName string, newName string) error
+
+	// copy method, copies the object, fields, metadata and files (but not the prior versions) to the new key.
+	// The timestamps are preserved, the copy has a new vtag and the new key must not be in use
+	CopyObjectByKey(ctx context.Context, from DataStoreKey, to DataStoreKey) error
 
 	// delete multiple methods
-	DeleteBlobsByKey(key DataStoreKey) error
+	DeleteBlobsByKey(ctx context.Context, key DataStoreKey) error
 
 	// delete single methods
-	DeleteBlobByKey(key DataStoreKey, curName string) error
-	DeleteFieldsByKey(key DataStoreKey) error
-	DeleteMetadataByKey(key DataStoreKey) error
-	DeleteObjectByKey(key DataStoreKey) error
-
-	// search method
-	GetKeysByFields(namespace string, fields EasyStoreObjectFields) ([]DataStoreKey, error)
+	DeleteBlobByKey(ctx context.Context, key DataStoreKey, curName string) error
+	DeleteFieldsByKey(ctx context.Context, key DataStoreKey) error
+	DeleteMetadataByKey(ctx context.Context, key DataStoreKey) error
+	DeleteObjectByKey(ctx context.Context, key DataStoreKey) error
+
+	// search method, returns a page of keys of objects matching the query and the continuation token
+	// for the next page (blank if there are no more)
+	GetKeysByFields(ctx context.Context, namespace string, query EasyStoreQuery, page EasyStorePage) ([]DataStoreKey, string, error)
+
+	// version methods, versions are preserved before an object is changed and are never changed themselves
+	AddVersion(ctx context.Context, key DataStoreKey) error
+	GetVersionsByKey(ctx context.Context, key DataStoreKey) ([]EasyStoreVersion, error)
+	GetVersionByKey(ctx context.Context, key DataStoreKey, vtag string) (EasyStoreObject, error)
+
+	// trash methods, deleted objects are tombstoned and hidden from the get and search methods until they
+	// are restored or purged. Purging removes the object and its versions, the components are deleted separately
+	TombstoneObjectByKey(ctx context.Context, key DataStoreKey) error
+	RestoreObjectByKey(ctx context.Context, key DataStoreKey) error
+	PurgeObjectByKey(ctx context.Context, key DataStoreKey) error
+	GetTombstones(ctx context.Context, namespace string, before time.Time) ([]EasyStoreTombstone, error)
+
+	// outbox methods, events are added in the same transaction as the change they describe and updated as
+	// they are delivered. Delivered events are kept so they can be replayed, both get methods are oldest first.
+	// Events attempted the maximum number of times are parked, they are no longer pending but can be replayed
+	AddEvent(ctx context.Context, event EasyStoreEvent) error
+	GetPendingEvents(ctx context.Context, maxAttempts uint, limit uint) ([]EasyStoreEvent, error)
+	GetEventsByTime(ctx context.Context, after time.Time, before time.Time, offset uint, limit uint) ([]EasyStoreEvent, error)
+	UpdateEvent(ctx context.Context, event EasyStoreEvent) error
+
+	// begin a transaction, all changes made through the returned DataStoreTx are
+	// applied when it is committed and discarded when it is rolled back
+	Begin(ctx context.Context) (DataStoreTx, error)
 
 	// close connections
 	Close() error
 }
 
+// DataStoreTx -- a unit of work against a DataStore. Closing a transaction that has not
+// been committed rolls it back. Nested transactions are not supported
+type DataStoreTx interface {
+	DataStore
+
+	Commit() error
+	Rollback() error
+}
+
 // our factory
 func NewDatastore(config EasyStoreImplConfig) (DataStore, error) {
 
 	// check for a sqlite configuration
-	//_, ok := config.(DatastoreSqliteConfig)
-	//if ok == true {
-	//	return newSqliteStore(config)
-	//}
+	_, ok := config.(DatastoreSqliteConfig)
+	if ok == true {
+		return newSqliteStore(config)
+	}
+
+	// check for in-memory configuration
+	_, ok = config.(DatastoreMemoryConfig)
+	if ok == true {
+		return newMemoryStore(config)
+	}
+
+	// check for file system configuration
+	_, ok = config.(DatastoreFilesystemConfig)
+	if ok == true {
+		return newFilesystemStore(config)
+	}
 
 	// check for postgres configuration
-	_, ok := config.(DatastorePostgresConfig)
+	_, ok = config.(DatastorePostgresConfig)
 	if ok == true {
 		return newPostgresStore(config)
 	}
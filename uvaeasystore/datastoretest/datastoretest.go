@@ -0,0 +1,799 @@
+//
+// conformance tests for implementations of the datastore interface
+//
+
+// only include this file for service builds
+
+//go:build service
+// +build service
+
+package datastoretest
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/uvalib/easystore/uvaeasystore"
+)
+
+// Factory -- makes the datastore under test. It is called for each test, the datastores it makes may
+// share their contents but must not share transactions. Run closes each one when the test is complete
+type Factory func(t *testing.T) uvaeasystore.DataStore
+
+// Run -- check the datastore honors the DataStore contract. Every object is created in a new namespace
+// so a persistent datastore does not need to be empty
+func Run(t *testing.T, factory Factory) {
+
+	namespace := fmt.Sprintf("dst-%s", xid.New().String())
+
+	tests := []struct {
+		name string
+		test func(*testing.T, fixture)
+	}{
+		{"Check", testCheck},
+		{"Objects", testObjects},
+		{"Fields", testFields},
+		{"Metadata", testMetadata},
+		{"Blobs", testBlobs},
+		{"RenameBlob", testRenameBlob},
+		{"CopyObject", testCopyObject},
+		{"Search", testSearch},
+		{"Versions", testVersions},
+		{"Trash", testTrash},
+		{"Transactions", testTransactions},
+		{"Locking", testLocking},
+		{"Outbox", testOutbox},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ds := factory(t)
+			defer ds.Close()
+			tc.test(t, fixture{ctx: context.Background(), ds: ds, namespace: namespace})
+		})
+	}
+}
+
+// what each test works with
+type fixture struct {
+	ctx       context.Context
+	ds        uvaeasystore.DataStore
+	namespace string
+}
+
+// both ways of getting things, the results must be the same
+var cacheModes = []bool{uvaeasystore.NOCACHE, uvaeasystore.FROMCACHE}
+
+func testCheck(t *testing.T, f fixture) {
+	expectOK(t, f.ds.Check(f.ctx))
+}
+
+func testObjects(t *testing.T, f fixture) {
+
+	obj, key := f.newObject(t)
+
+	// the object is available both ways
+	for _, useCache := range cacheModes {
+		after, err := f.ds.GetObjectByKey(f.ctx, key, useCache)
+		expectOK(t, err)
+		expectEqual(t, obj.Namespace(), after.Namespace())
+		expectEqual(t, obj.Id(), after.Id())
+		expectEqual(t, obj.VTag(), after.VTag())
+	}
+
+	// the identifier cannot be reused
+	err := f.ds.AddObject(f.ctx, uvaeasystore.NewEasyStoreObject(f.namespace, obj.Id()))
+	expectError(t, err, uvaeasystore.ErrAlreadyExists)
+
+	// unknown objects are not found
+	missing := f.missingKey()
+	for _, useCache := range cacheModes {
+		_, err = f.ds.GetObjectByKey(f.ctx, missing, useCache)
+		expectError(t, err, uvaeasystore.ErrNotFound)
+	}
+
+	// updating makes a new vtag
+	before, err := f.ds.GetObjectByKey(f.ctx, key, uvaeasystore.NOCACHE)
+	expectOK(t, err)
+	expectOK(t, f.ds.UpdateObject(f.ctx, key))
+	for _, useCache := range cacheModes {
+		after, err := f.ds.GetObjectByKey(f.ctx, key, useCache)
+		expectOK(t, err)
+		if after.VTag() == before.VTag() {
+			t.Fatalf("expected a new vtag but got '%s'\n", after.VTag())
+		}
+		if after.Modified().Before(before.Modified()) == true {
+			t.Fatalf("modified time went backwards\n")
+		}
+	}
+
+	// multiple objects, the missing ones are ignored
+	_, key2 := f.newObject(t)
+	for _, useCache := range cacheModes {
+		objs, err := f.ds.GetObjectsByKey(f.ctx, []uvaeasystore.DataStoreKey{key, missing, key2}, useCache)
+		expectOK(t, err)
+		expectEqual(t, 2, len(objs))
+		_, err = f.ds.GetObjectsByKey(f.ctx, []uvaeasystore.DataStoreKey{missing}, useCache)
+		expectError(t, err, uvaeasystore.ErrNotFound)
+	}
+
+	// deleted objects are gone
+	expectOK(t, f.ds.DeleteObjectByKey(f.ctx, key2))
+	for _, useCache := range cacheModes {
+		_, err = f.ds.GetObjectByKey(f.ctx, key2, useCache)
+		expectError(t, err, uvaeasystore.ErrNotFound)
+	}
+}
+
+func testFields(t *testing.T, f fixture) {
+
+	_, key := f.newObject(t)
+
+	// no fields yet
+	for _, useCache := range cacheModes {
+		_, err := f.ds.GetFieldsByKey(f.ctx, key, useCache)
+		expectError(t, err, uvaeasystore.ErrNotFound)
+	}
+
+	fields := uvaeasystore.EasyStoreObjectMultiFields{"field1": {"value1"}, "field2": {"value2"}}
+	expectOK(t, f.ds.AddFields(f.ctx, key, fields))
+	for _, useCache := range cacheModes {
+		after, err := f.ds.GetFieldsByKey(f.ctx, key, useCache)
+		expectOK(t, err)
+		expectFields(t, fields, *after)
+	}
+
+	// updating replaces the whole set
+	fields = uvaeasystore.EasyStoreObjectMultiFields{"field1": {"value3"}}
+	expectOK(t, f.ds.UpdateFields(f.ctx, key, fields))
+	for _, useCache := range cacheModes {
+		after, err := f.ds.GetFieldsByKey(f.ctx, key, useCache)
+		expectOK(t, err)
+		expectFields(t, fields, *after)
+	}
+
+	// a field can have several values, they keep their order
+	fields = uvaeasystore.EasyStoreObjectMultiFields{"field1": {"value6", "value4", "value5"}, "field2": {"value7"}}
+	expectOK(t, f.ds.UpdateFields(f.ctx, key, fields))
+	for _, useCache := range cacheModes {
+		after, err := f.ds.GetFieldsByKey(f.ctx, key, useCache)
+		expectOK(t, err)
+		expectFields(t, fields, *after)
+	}
+
+	expectOK(t, f.ds.DeleteFieldsByKey(f.ctx, key))
+	for _, useCache := range cacheModes {
+		_, err := f.ds.GetFieldsByKey(f.ctx, key, useCache)
+		expectError(t, err, uvaeasystore.ErrNotFound)
+	}
+
+	// deleting what is not there is not an error
+	expectOK(t, f.ds.DeleteFieldsByKey(f.ctx, key))
+}
+
+func testMetadata(t *testing.T, f fixture) {
+
+	_, key := f.newObject(t)
+
+	// no metadata yet
+	for _, useCache := range cacheModes {
+		_, err := f.ds.GetMetadataByKey(f.ctx, key, useCache)
+		expectError(t, err, uvaeasystore.ErrNotFound)
+	}
+
+	md := uvaeasystore.NewEasyStoreMetadata("application/json", []byte("{\"id\":1}"))
+	expectOK(t, f.ds.AddMetadata(f.ctx, key, md))
+	for _, useCache := range cacheModes {
+		after, err := f.ds.GetMetadataByKey(f.ctx, key, useCache)
+		expectOK(t, err)
+		expectMetadata(t, md, after)
+	}
+
+	md = uvaeasystore.NewEasyStoreMetadata("text/plain", []byte("updated"))
+	expectOK(t, f.ds.UpdateMetadata(f.ctx, key, md))
+	for _, useCache := range cacheModes {
+		after, err := f.ds.GetMetadataByKey(f.ctx, key, useCache)
+		expectOK(t, err)
+		expectMetadata(t, md, after)
+	}
+
+	expectOK(t, f.ds.DeleteMetadataByKey(f.ctx, key))
+	for _, useCache := range cacheModes {
+		_, err := f.ds.GetMetadataByKey(f.ctx, key, useCache)
+		expectError(t, err, uvaeasystore.ErrNotFound)
+	}
+
+	// deleting what is not there is not an error
+	expectOK(t, f.ds.DeleteMetadataByKey(f.ctx, key))
+}
+
+func testBlobs(t *testing.T, f fixture) {
+
+	_, key := f.newObject(t)
+
+	// no blobs yet
+	for _, useCache := range cacheModes {
+		_, err := f.ds.GetBlobsByKey(f.ctx, key, useCache)
+		expectError(t, err, uvaeasystore.ErrNotFound)
+	}
+
+	b1 := uvaeasystore.NewEasyStoreBlob("file1.txt", "text/plain", []byte("file one"))
+	b2 := uvaeasystore.NewEasyStoreBlob("file2.bin", "application/octet-stream", []byte{0, 1, 2, 3})
+	expectOK(t, f.ds.AddBlob(f.ctx, key, b1))
+	expectOK(t, f.ds.AddBlob(f.ctx, key, b2))
+
+	// the name cannot be reused
+	err := f.ds.AddBlob(f.ctx, key, b1)
+	expectError(t, err, uvaeasystore.ErrAlreadyExists)
+
+	for _, useCache := range cacheModes {
+		blobs, err := f.ds.GetBlobsByKey(f.ctx, key, useCache)
+		expectOK(t, err)
+		expectEqual(t, 2, len(blobs))
+		for _, b := range blobs {
+			if b.Name() == b1.Name() {
+				expectBlob(t, b1, b)
+			} else {
+				expectBlob(t, b2, b)
+			}
+		}
+
+		b, err := f.ds.GetBlobByKey(f.ctx, key, b2.Name(), useCache)
+		expectOK(t, err)
+		expectBlob(t, b2, b)
+
+		_, err = f.ds.GetBlobByKey(f.ctx, key, "missing.txt", useCache)
+		expectError(t, err, uvaeasystore.ErrFileNotFound)
+	}
+
+	// update the contents, the blob keeps its created time
+	before, err := f.ds.GetBlobByKey(f.ctx, key, b1.Name(), uvaeasystore.NOCACHE)
+	expectOK(t, err)
+	b1 = uvaeasystore.NewEasyStoreBlob(b1.Name(), "text/plain", []byte("file one, updated"))
+	expectOK(t, f.ds.UpdateBlob(f.ctx, key, b1))
+	for _, useCache := range cacheModes {
+		b, err := f.ds.GetBlobByKey(f.ctx, key, b1.Name(), useCache)
+		expectOK(t, err)
+		expectBlob(t, b1, b)
+		if b.Created().Equal(before.Created()) == false {
+			t.Fatalf("expected created '%s' but got '%s'\n", before.Created(), b.Created())
+		}
+	}
+	err = f.ds.UpdateBlob(f.ctx, key, uvaeasystore.NewEasyStoreBlob("missing.txt", "text/plain", []byte("missing")))
+	expectError(t, err, uvaeasystore.ErrNotFound)
+
+	// delete them one at a time then all together
+	expectOK(t, f.ds.DeleteBlobByKey(f.ctx, key, b1.Name()))
+	for _, useCache := range cacheModes {
+		_, err = f.ds.GetBlobByKey(f.ctx, key, b1.Name(), useCache)
+		expectError(t, err, uvaeasystore.ErrFileNotFound)
+		blobs, err := f.ds.GetBlobsByKey(f.ctx, key, useCache)
+		expectOK(t, err)
+		expectEqual(t, 1, len(blobs))
+	}
+	expectOK(t, f.ds.DeleteBlobsByKey(f.ctx, key))
+	for _, useCache := range cacheModes {
+		_, err = f.ds.GetBlobsByKey(f.ctx, key, useCache)
+		expectError(t, err, uvaeasystore.ErrNotFound)
+	}
+}
+
+func testRenameBlob(t *testing.T, f fixture) {
+
+	_, key := f.newObject(t)
+
+	b1 := uvaeasystore.NewEasyStoreBlob("file1.txt", "text/plain", []byte("file one"))
+	b2 := uvaeasystore.NewEasyStoreBlob("file2.txt", "text/plain", []byte("file two"))
+	expectOK(t, f.ds.AddBlob(f.ctx, key, b1))
+	expectOK(t, f.ds.AddBlob(f.ctx, key, b2))
+
+	err := f.ds.RenameBlobByKey(f.ctx, key, "missing.txt", "file3.txt")
+	expectError(t, err, uvaeasystore.ErrFileNotFound)
+
+	err = f.ds.RenameBlobByKey(f.ctx, key, b1.Name(), b2.Name())
+	expectError(t, err, uvaeasystore.ErrAlreadyExists)
+
+	expectOK(t, f.ds.RenameBlobByKey(f.ctx, key, b1.Name(), "file3.txt"))
+	for _, useCache := range cacheModes {
+		_, err = f.ds.GetBlobByKey(f.ctx, key, b1.Name(), useCache)
+		expectError(t, err, uvaeasystore.ErrFileNotFound)
+		b, err := f.ds.GetBlobByKey(f.ctx, key, "file3.txt", useCache)
+		expectOK(t, err)
+		expectBlob(t, uvaeasystore.NewEasyStoreBlob("file3.txt", b1.MimeType(), []byte("file one")), b)
+		blobs, err := f.ds.GetBlobsByKey(f.ctx, key, useCache)
+		expectOK(t, err)
+		expectEqual(t, 2, len(blobs))
+	}
+}
+
+func testCopyObject(t *testing.T, f fixture) {
+
+	obj, from := f.newObject(t)
+	fields := uvaeasystore.EasyStoreObjectMultiFields{"copy": {obj.Id(), "c", "a", "b"}}
+	md := uvaeasystore.NewEasyStoreMetadata("application/json", []byte(`{"copy":true}`))
+	b1 := uvaeasystore.NewEasyStoreBlob("file1.txt", "text/plain", []byte("file one"))
+	expectOK(t, f.ds.AddFields(f.ctx, from, fields))
+	expectOK(t, f.ds.AddMetadata(f.ctx, from, md))
+	expectOK(t, f.ds.AddBlob(f.ctx, from, b1))
+	before, err := f.ds.GetObjectByKey(f.ctx, from, uvaeasystore.NOCACHE)
+	expectOK(t, err)
+
+	// to another namespace with the same identifier
+	to := uvaeasystore.DataStoreKey{Namespace: fmt.Sprintf("%s-copy", f.namespace), ObjectId: obj.Id()}
+	expectError(t, f.ds.CopyObjectByKey(f.ctx, f.missingKey(), to), uvaeasystore.ErrNotFound)
+	expectOK(t, f.ds.CopyObjectByKey(f.ctx, from, to))
+	expectError(t, f.ds.CopyObjectByKey(f.ctx, from, to), uvaeasystore.ErrAlreadyExists)
+
+	// the copy has the same components and timestamps but is a new version
+	for _, useCache := range cacheModes {
+		after, err := f.ds.GetObjectByKey(f.ctx, to, useCache)
+		expectOK(t, err)
+		expectEqual(t, to.Namespace, after.Namespace())
+		expectEqual(t, to.ObjectId, after.Id())
+		expectEqual(t, before.Created().Unix(), after.Created().Unix())
+		if after.VTag() == before.VTag() {
+			t.Fatalf("object vtags are equal but should not be\n")
+		}
+		f2, err := f.ds.GetFieldsByKey(f.ctx, to, useCache)
+		expectOK(t, err)
+		expectFields(t, fields, *f2)
+		md2, err := f.ds.GetMetadataByKey(f.ctx, to, useCache)
+		expectOK(t, err)
+		expectMetadata(t, md, md2)
+		b, err := f.ds.GetBlobByKey(f.ctx, to, b1.Name(), useCache)
+		expectOK(t, err)
+		expectBlob(t, uvaeasystore.NewEasyStoreBlob(b1.Name(), b1.MimeType(), []byte("file one")), b)
+	}
+	found, _, err := f.ds.GetKeysByFields(f.ctx, to.Namespace, uvaeasystore.QueryEquals("copy", obj.Id()), uvaeasystore.EasyStorePage{})
+	expectOK(t, err)
+	expectEqual(t, 1, len(found))
+
+	// but the prior versions are not copied
+	_, err = f.ds.GetVersionsByKey(f.ctx, to)
+	expectError(t, err, uvaeasystore.ErrNotFound)
+
+	// objects in the trash cannot be copied
+	expectOK(t, f.ds.TombstoneObjectByKey(f.ctx, from))
+	err = f.ds.CopyObjectByKey(f.ctx, from, uvaeasystore.DataStoreKey{Namespace: f.namespace, ObjectId: xid.New().String()})
+	expectError(t, err, uvaeasystore.ErrNotFound)
+}
+
+func testSearch(t *testing.T, f fixture) {
+
+	// a value no other test uses
+	search := xid.New().String()
+	keys := make(map[uvaeasystore.DataStoreKey]bool)
+	for _, n := range []string{"1", "2", "3"} {
+		_, key := f.newObject(t)
+		expectOK(t, f.ds.AddFields(f.ctx, key, uvaeasystore.EasyStoreObjectMultiFields{"search": {search}, "n": {n}}))
+		keys[key] = true
+	}
+
+	// and one with several values for a field
+	_, multi := f.newObject(t)
+	expectOK(t, f.ds.AddFields(f.ctx, multi, uvaeasystore.EasyStoreObjectMultiFields{"search": {search}, "n": {"5", "6"}}))
+	keys[multi] = true
+
+	// any of the values match
+	for _, n := range []string{"5", "6"} {
+		query := uvaeasystore.QueryAnd(uvaeasystore.QueryEquals("search", search), uvaeasystore.QueryEquals("n", n))
+		found, _, err := f.ds.GetKeysByFields(f.ctx, f.namespace, query, uvaeasystore.EasyStorePage{})
+		expectOK(t, err)
+		expectEqual(t, 1, len(found))
+		expectEqual(t, multi, found[0])
+	}
+
+	// all fields must match
+	query := uvaeasystore.QueryAnd(uvaeasystore.QueryEquals("search", search), uvaeasystore.QueryEquals("n", "2"))
+	found, next, err := f.ds.GetKeysByFields(f.ctx, f.namespace, query, uvaeasystore.EasyStorePage{})
+	expectOK(t, err)
+	expectEqual(t, 1, len(found))
+	expectEqual(t, "", next)
+
+	query = uvaeasystore.QueryAnd(uvaeasystore.QueryEquals("search", search), uvaeasystore.QueryEquals("n", "4"))
+	_, _, err = f.ds.GetKeysByFields(f.ctx, f.namespace, query, uvaeasystore.EasyStorePage{})
+	expectError(t, err, uvaeasystore.ErrNotFound)
+
+	// page through them in each order, every key appears once
+	query = uvaeasystore.QueryEquals("search", search)
+	for _, order := range []uvaeasystore.EasyStoreOrder{uvaeasystore.OrderById, uvaeasystore.OrderByCreated, uvaeasystore.OrderByModified} {
+		page := uvaeasystore.EasyStorePage{Limit: 2, Order: order}
+		seen := make(map[uvaeasystore.DataStoreKey]bool)
+		for {
+			found, next, err := f.ds.GetKeysByFields(f.ctx, f.namespace, query, page)
+			expectOK(t, err)
+			for _, k := range found {
+				if keys[k] == false || seen[k] == true {
+					t.Fatalf("unexpected key %s/%s\n", k.Namespace, k.ObjectId)
+				}
+				seen[k] = true
+			}
+			if len(next) == 0 {
+				break
+			}
+			page.Token = next
+		}
+		expectEqual(t, len(keys), len(seen))
+	}
+}
+
+func testVersions(t *testing.T, f fixture) {
+
+	// values are preserved in order
+	obj, key := f.newObject(t)
+	fields := uvaeasystore.EasyStoreObjectMultiFields{"version": {"1", "c", "a", "b"}}
+	expectOK(t, f.ds.AddFields(f.ctx, key, fields))
+
+	// no versions yet
+	_, err := f.ds.GetVersionsByKey(f.ctx, key)
+	expectError(t, err, uvaeasystore.ErrNotFound)
+
+	// preserve the current version then change it
+	expectOK(t, f.ds.AddVersion(f.ctx, key))
+	expectOK(t, f.ds.UpdateFields(f.ctx, key, uvaeasystore.EasyStoreObjectMultiFields{"version": {"2"}}))
+	expectOK(t, f.ds.UpdateObject(f.ctx, key))
+
+	versions, err := f.ds.GetVersionsByKey(f.ctx, key)
+	expectOK(t, err)
+	expectEqual(t, 1, len(versions))
+	expectEqual(t, obj.VTag(), versions[0].VTag)
+
+	// the version is unchanged
+	version, err := f.ds.GetVersionByKey(f.ctx, key, obj.VTag())
+	expectOK(t, err)
+	expectEqual(t, obj.VTag(), version.VTag())
+	expectFields(t, fields, version.MultiFields())
+
+	_, err = f.ds.GetVersionByKey(f.ctx, key, "vtag-missing")
+	expectError(t, err, uvaeasystore.ErrNotFound)
+
+	// only live objects are versioned
+	err = f.ds.AddVersion(f.ctx, f.missingKey())
+	expectError(t, err, uvaeasystore.ErrNotFound)
+}
+
+func testTrash(t *testing.T, f fixture) {
+
+	search := xid.New().String()
+	obj, key := f.newObject(t)
+	expectOK(t, f.ds.AddFields(f.ctx, key, uvaeasystore.EasyStoreObjectMultiFields{"trash": {search}}))
+
+	// live objects cannot be restored or purged
+	expectError(t, f.ds.RestoreObjectByKey(f.ctx, key), uvaeasystore.ErrNotFound)
+	expectError(t, f.ds.PurgeObjectByKey(f.ctx, key), uvaeasystore.ErrNotFound)
+
+	// objects in the trash are hidden
+	expectOK(t, f.ds.TombstoneObjectByKey(f.ctx, key))
+	expectError(t, f.ds.TombstoneObjectByKey(f.ctx, key), uvaeasystore.ErrNotFound)
+	for _, useCache := range cacheModes {
+		_, err := f.ds.GetObjectByKey(f.ctx, key, useCache)
+		expectError(t, err, uvaeasystore.ErrNotFound)
+	}
+	_, _, err := f.ds.GetKeysByFields(f.ctx, f.namespace, uvaeasystore.QueryEquals("trash", search), uvaeasystore.EasyStorePage{})
+	expectError(t, err, uvaeasystore.ErrNotFound)
+
+	// but their identifiers are still in use
+	err = f.ds.AddObject(f.ctx, uvaeasystore.NewEasyStoreObject(f.namespace, obj.Id()))
+	expectError(t, err, uvaeasystore.ErrAlreadyExists)
+
+	// and they are in the trash, it is only what was deleted before the specified time
+	expectTombstone(t, f, key, time.Now().Add(time.Minute), true)
+	expectTombstone(t, f, key, time.Now().Add(-time.Hour), false)
+
+	// restored objects are back as they were
+	expectOK(t, f.ds.RestoreObjectByKey(f.ctx, key))
+	expectError(t, f.ds.RestoreObjectByKey(f.ctx, key), uvaeasystore.ErrNotFound)
+	for _, useCache := range cacheModes {
+		after, err := f.ds.GetObjectByKey(f.ctx, key, useCache)
+		expectOK(t, err)
+		expectEqual(t, obj.VTag(), after.VTag())
+	}
+	found, _, err := f.ds.GetKeysByFields(f.ctx, f.namespace, uvaeasystore.QueryEquals("trash", search), uvaeasystore.EasyStorePage{})
+	expectOK(t, err)
+	expectEqual(t, 1, len(found))
+
+	// purged objects are gone for good and the identifier can be reused
+	expectOK(t, f.ds.TombstoneObjectByKey(f.ctx, key))
+	expectOK(t, f.ds.PurgeObjectByKey(f.ctx, key))
+	expectError(t, f.ds.PurgeObjectByKey(f.ctx, key), uvaeasystore.ErrNotFound)
+	expectError(t, f.ds.RestoreObjectByKey(f.ctx, key), uvaeasystore.ErrNotFound)
+	expectTombstone(t, f, key, time.Now().Add(time.Minute), false)
+	expectOK(t, f.ds.AddObject(f.ctx, uvaeasystore.NewEasyStoreObject(f.namespace, obj.Id())))
+}
+
+func testTransactions(t *testing.T, f fixture) {
+
+	// rolled back changes are discarded
+	obj1 := uvaeasystore.NewEasyStoreObject(f.namespace, "")
+	key1 := uvaeasystore.DataStoreKey{Namespace: obj1.Namespace(), ObjectId: obj1.Id()}
+	tx, err := f.ds.Begin(f.ctx)
+	expectOK(t, err)
+	expectOK(t, tx.AddObject(f.ctx, obj1))
+	_, err = tx.GetObjectByKey(f.ctx, key1, uvaeasystore.NOCACHE)
+	expectOK(t, err)
+
+	// nested transactions are not supported
+	_, err = tx.Begin(f.ctx)
+	expectError(t, err, uvaeasystore.ErrNotImplemented)
+
+	expectOK(t, tx.Rollback())
+	for _, useCache := range cacheModes {
+		_, err = f.ds.GetObjectByKey(f.ctx, key1, useCache)
+		expectError(t, err, uvaeasystore.ErrNotFound)
+	}
+
+	// committed changes are kept
+	obj2 := uvaeasystore.NewEasyStoreObject(f.namespace, "")
+	key2 := uvaeasystore.DataStoreKey{Namespace: obj2.Namespace(), ObjectId: obj2.Id()}
+	tx, err = f.ds.Begin(f.ctx)
+	expectOK(t, err)
+	expectOK(t, tx.AddObject(f.ctx, obj2))
+	expectOK(t, tx.AddFields(f.ctx, key2, uvaeasystore.EasyStoreObjectMultiFields{"field1": {"value1"}}))
+	expectOK(t, tx.Commit())
+
+	// rolling back a completed transaction does nothing
+	expectOK(t, tx.Rollback())
+	expectOK(t, tx.Close())
+	for _, useCache := range cacheModes {
+		_, err = f.ds.GetObjectByKey(f.ctx, key2, useCache)
+		expectOK(t, err)
+		fields, err := f.ds.GetFieldsByKey(f.ctx, key2, useCache)
+		expectOK(t, err)
+		expectFields(t, uvaeasystore.EasyStoreObjectMultiFields{"field1": {"value1"}}, *fields)
+	}
+}
+
+func testLocking(t *testing.T, f fixture) {
+
+	_, key := f.newObject(t)
+	obj, err := f.ds.GetObjectByKey(f.ctx, key, uvaeasystore.NOCACHE)
+	expectOK(t, err)
+	vtag := obj.VTag()
+
+	// the current vtag locks, any other is stale and a missing object is not found
+	tx, err := f.ds.Begin(f.ctx)
+	expectOK(t, err)
+	defer tx.Rollback()
+	expectError(t, tx.LockObjectByKey(f.ctx, key, "vtag-stale"), uvaeasystore.ErrStaleObject)
+	expectError(t, tx.LockObjectByKey(f.ctx, f.missingKey(), vtag), uvaeasystore.ErrNotFound)
+	expectOK(t, tx.LockObjectByKey(f.ctx, key, vtag))
+
+	// a second writer with the same vtag waits for the first and then finds the object has changed
+	done := make(chan error)
+	go func() {
+		tx2, err := f.ds.Begin(f.ctx)
+		if err != nil {
+			done <- err
+			return
+		}
+		defer tx2.Rollback()
+		done <- tx2.LockObjectByKey(f.ctx, key, vtag)
+	}()
+	expectOK(t, tx.UpdateObject(f.ctx, key))
+	expectOK(t, tx.Commit())
+	expectError(t, <-done, uvaeasystore.ErrStaleObject)
+}
+
+func testOutbox(t *testing.T, f fixture) {
+
+	start := time.Now().Add(-time.Second)
+	ev1 := f.newEvent("event-one")
+	ev2 := f.newEvent("event-two")
+	ev2.Created = ev1.Created.Add(time.Millisecond)
+	expectOK(t, f.ds.AddEvent(f.ctx, ev1))
+	expectOK(t, f.ds.AddEvent(f.ctx, ev2))
+	expectError(t, f.ds.AddEvent(f.ctx, ev1), uvaeasystore.ErrAlreadyExists)
+
+	// both are pending, oldest first
+	pending, err := f.ds.GetPendingEvents(f.ctx, 0, 0)
+	expectOK(t, err)
+	found := f.selectEvents(pending)
+	expectEqual(t, 2, len(found))
+	expectEqual(t, ev1.Id, found[0].Id)
+	expectEqual(t, ev2.Id, found[1].Id)
+	expectEqual(t, ev1.Name, found[0].Name)
+	expectEqual(t, ev1.ObjectId, found[0].ObjectId)
+	expectEqual(t, string(ev1.Detail), string(found[0].Detail))
+	if found[0].Delivered != nil {
+		t.Fatalf("event is delivered but should not be\n")
+	}
+	pending, err = f.ds.GetPendingEvents(f.ctx, 0, 1)
+	expectOK(t, err)
+	expectEqual(t, 1, len(pending))
+
+	// delivered events are no longer pending
+	delivered := time.Now()
+	ev1.Attempts, ev1.Delivered = 2, &delivered
+	expectOK(t, f.ds.UpdateEvent(f.ctx, ev1))
+	expectError(t, f.ds.UpdateEvent(f.ctx, f.newEvent("event-missing")), uvaeasystore.ErrNotFound)
+	pending, err = f.ds.GetPendingEvents(f.ctx, 0, 0)
+	expectOK(t, err)
+	found = f.selectEvents(pending)
+	expectEqual(t, 1, len(found))
+	expectEqual(t, ev2.Id, found[0].Id)
+
+	// neither are events attempted the maximum number of times
+	ev2.Attempts = 3
+	expectOK(t, f.ds.UpdateEvent(f.ctx, ev2))
+	pending, err = f.ds.GetPendingEvents(f.ctx, 3, 0)
+	if err != nil {
+		expectError(t, err, uvaeasystore.ErrNotFound)
+	}
+	expectEqual(t, 0, len(f.selectEvents(pending)))
+	pending, err = f.ds.GetPendingEvents(f.ctx, 4, 0)
+	expectOK(t, err)
+	expectEqual(t, 1, len(f.selectEvents(pending)))
+
+	// but they can still be found by time
+	events, err := f.ds.GetEventsByTime(f.ctx, start, time.Now().Add(time.Second), 0, 0)
+	expectOK(t, err)
+	found = f.selectEvents(events)
+	expectEqual(t, 2, len(found))
+	expectEqual(t, ev1.Id, found[0].Id)
+	expectEqual(t, uint(2), found[0].Attempts)
+	if found[0].Delivered == nil {
+		t.Fatalf("event is not delivered but should be\n")
+	}
+	_, err = f.ds.GetEventsByTime(f.ctx, start.Add(-time.Hour), start.Add(-time.Minute), 0, 0)
+	expectError(t, err, uvaeasystore.ErrNotFound)
+
+	// a page at a time
+	after, before := ev1.Created.Add(-time.Microsecond), ev2.Created.Add(time.Microsecond)
+	events, err = f.ds.GetEventsByTime(f.ctx, after, before, 0, 1)
+	expectOK(t, err)
+	expectEqual(t, 1, len(events))
+	expectEqual(t, ev1.Id, events[0].Id)
+	events, err = f.ds.GetEventsByTime(f.ctx, after, before, 1, 1)
+	expectOK(t, err)
+	expectEqual(t, 1, len(events))
+	expectEqual(t, ev2.Id, events[0].Id)
+	events, err = f.ds.GetEventsByTime(f.ctx, after, before, 1, 0)
+	expectOK(t, err)
+	expectEqual(t, 1, len(events))
+	expectEqual(t, ev2.Id, events[0].Id)
+	_, err = f.ds.GetEventsByTime(f.ctx, after, before, 2, 1)
+	expectError(t, err, uvaeasystore.ErrNotFound)
+
+	// events are added with the change they describe
+	ev3 := f.newEvent("event-three")
+	tx, err := f.ds.Begin(f.ctx)
+	expectOK(t, err)
+	expectOK(t, tx.AddEvent(f.ctx, ev3))
+	expectOK(t, tx.Rollback())
+	events, err = f.ds.GetEventsByTime(f.ctx, start, time.Now().Add(time.Second), 0, 0)
+	expectOK(t, err)
+	expectEqual(t, 2, len(f.selectEvents(events)))
+}
+
+//
+// private methods
+//
+
+// add a new object and return it along with its key
+func (f fixture) newObject(t *testing.T) (uvaeasystore.EasyStoreObject, uvaeasystore.DataStoreKey) {
+	t.Helper()
+	obj := uvaeasystore.NewEasyStoreObject(f.namespace, "")
+	expectOK(t, f.ds.AddObject(f.ctx, obj))
+	return obj, uvaeasystore.DataStoreKey{Namespace: obj.Namespace(), ObjectId: obj.Id()}
+}
+
+// the key of an object that does not exist
+func (f fixture) missingKey() uvaeasystore.DataStoreKey {
+	return uvaeasystore.DataStoreKey{Namespace: f.namespace, ObjectId: fmt.Sprintf("oid-%s", xid.New().String())}
+}
+
+// a new event for the test namespace
+func (f fixture) newEvent(name string) uvaeasystore.EasyStoreEvent {
+	return uvaeasystore.EasyStoreEvent{
+		Id:        fmt.Sprintf("evt-%s", xid.New().String()),
+		Name:      name,
+		Namespace: f.namespace,
+		ObjectId:  fmt.Sprintf("oid-%s", xid.New().String()),
+		Detail:    []byte(`{"vtag":"vtag-outbox"}`),
+		Created:   time.Now(),
+	}
+}
+
+// the events from the test namespace, the datastore may have others
+func (f fixture) selectEvents(events []uvaeasystore.EasyStoreEvent) []uvaeasystore.EasyStoreEvent {
+	selected := make([]uvaeasystore.EasyStoreEvent, 0)
+	for _, ev := range events {
+		if ev.Namespace == f.namespace {
+			selected = append(selected, ev)
+		}
+	}
+	return selected
+}
+
+func expectOK(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+}
+
+func expectError(t *testing.T, err error, expected error) {
+	t.Helper()
+	if errors.Is(err, expected) == false {
+		t.Fatalf("expected '%s' but got '%v'\n", expected, err)
+	}
+}
+
+func expectEqual[T comparable](t *testing.T, expected T, actual T) {
+	t.Helper()
+	if expected != actual {
+		t.Fatalf("expected '%v' but got '%v'\n", expected, actual)
+	}
+}
+
+func expectFields(t *testing.T, expected uvaeasystore.EasyStoreObjectMultiFields, actual uvaeasystore.EasyStoreObjectMultiFields) {
+	t.Helper()
+	expectEqual(t, len(expected), len(actual))
+	for n, values := range expected {
+		expectEqual(t, len(values), len(actual[n]))
+		for i, v := range values {
+			expectEqual(t, v, actual[n][i])
+		}
+	}
+}
+
+func expectMetadata(t *testing.T, expected uvaeasystore.EasyStoreMetadata, actual uvaeasystore.EasyStoreMetadata) {
+	t.Helper()
+	expectEqual(t, expected.MimeType(), actual.MimeType())
+	buf1, _ := expected.Payload()
+	buf2, err := actual.Payload()
+	expectOK(t, err)
+	if bytes.Equal(buf1, buf2) == false {
+		t.Fatalf("metadata payloads are unequal but should be\n")
+	}
+}
+
+// blobs may carry their contents or a url to them
+func expectBlob(t *testing.T, expected uvaeasystore.EasyStoreBlob, actual uvaeasystore.EasyStoreBlob) {
+	t.Helper()
+	expectEqual(t, expected.Name(), actual.Name())
+	expectEqual(t, expected.MimeType(), actual.MimeType())
+	buf1, _ := expected.Payload()
+	reader, err := actual.Open()
+	expectOK(t, err)
+	defer reader.Close()
+	buf2, err := io.ReadAll(reader)
+	expectOK(t, err)
+	if bytes.Equal(buf1, buf2) == false {
+		t.Fatalf("file payloads are unequal but should be\n")
+	}
+
+	// stored blobs know the checksum and size of their contents
+	sum := sha256.Sum256(buf1)
+	expectEqual(t, hex.EncodeToString(sum[:]), actual.Checksum())
+	expectEqual(t, int64(len(buf1)), actual.Size())
+}
+
+func expectTombstone(t *testing.T, f fixture, key uvaeasystore.DataStoreKey, before time.Time, expected bool) {
+	t.Helper()
+	tombstones, err := f.ds.GetTombstones(f.ctx, key.Namespace, before)
+	if err != nil && errors.Is(err, uvaeasystore.ErrNotFound) == false {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	found := false
+	for _, ts := range tombstones {
+		if ts.Namespace == key.Namespace && ts.Id == key.ObjectId {
+			found = true
+		}
+	}
+	if found != expected {
+		t.Fatalf("expected the object in the trash to be %t but got %t\n", expected, found)
+	}
+}
+
+//
+// end of file
+//
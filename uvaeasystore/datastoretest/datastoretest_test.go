@@ -0,0 +1,82 @@
+//
+//
+//
+
+package datastoretest
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/uvalib/easystore/uvaeasystore"
+)
+
+func TestMemoryDatastore(t *testing.T) {
+	Run(t, func(t *testing.T) uvaeasystore.DataStore {
+		return newDatastore(t, uvaeasystore.DatastoreMemoryConfig{Name: "datastoretest"})
+	})
+}
+
+func TestFilesystemDatastore(t *testing.T) {
+	root := t.TempDir()
+	Run(t, func(t *testing.T) uvaeasystore.DataStore {
+		return newDatastore(t, uvaeasystore.DatastoreFilesystemConfig{RootDir: root})
+	})
+}
+
+func TestSqliteDatastore(t *testing.T) {
+	dataSource := newSqliteDatabase(t)
+	Run(t, func(t *testing.T) uvaeasystore.DataStore {
+		return newDatastore(t, uvaeasystore.DatastoreSqliteConfig{DataSource: dataSource})
+	})
+}
+
+//
+// private methods
+//
+
+func newDatastore(t *testing.T, config uvaeasystore.EasyStoreImplConfig) uvaeasystore.DataStore {
+	ds, err := uvaeasystore.NewDatastore(config)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	return ds
+}
+
+// create an empty database using the sqlite schema
+func newSqliteDatabase(t *testing.T) string {
+
+	schemas, err := filepath.Glob("../../db/sqlite/*.sql")
+	if err != nil || len(schemas) == 0 {
+		t.Skipf("no sqlite schema available\n")
+	}
+
+	dataSource := filepath.Join(t.TempDir(), "easystore.db")
+	db, err := sql.Open("sqlite3", dataSource)
+	if err == nil {
+		err = db.Ping()
+	}
+	if err != nil {
+		// sqlite needs cgo
+		t.Skipf("sqlite is not available: %s\n", err)
+	}
+	defer db.Close()
+
+	for _, schema := range schemas {
+		buf, err := os.ReadFile(schema)
+		if err != nil {
+			t.Fatalf("expected 'OK' but got '%s'\n", err)
+		}
+		_, err = db.Exec(string(buf))
+		if err != nil {
+			t.Fatalf("%s: expected 'OK' but got '%s'\n", schema, err)
+		}
+	}
+	return dataSource
+}
+
+//
+// end of file
+//
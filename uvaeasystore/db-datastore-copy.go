@@ -0,0 +1,60 @@
+//
+// db implementation of the datastore copy method
+//
+
+// only include this file for service builds
+
+//go:build service
+// +build service
+
+package uvaeasystore
+
+import (
+	"context"
+	"fmt"
+)
+
+// CopyObjectByKey -- copy the object, fields, metadata and files to the new key
+func (s *dbStorage) CopyObjectByKey(ctx context.Context, from DataStoreKey, to DataStoreKey) error {
+
+	// the object first, it tells us if there is anything to copy. The distinct index rejects
+	// a new key that is already in use
+	stmt, err := s.conn().PrepareContext(ctx, "INSERT INTO objects( namespace, oid, vtag, created_at, updated_at ) SELECT CAST( $1 AS VARCHAR ), CAST( $2 AS VARCHAR ), CAST( $3 AS VARCHAR ), created_at, updated_at FROM objects WHERE namespace = $4 AND oid = $5 AND deleted_at IS NULL")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, to.Namespace, to.ObjectId, newVtag(), from.Namespace, from.ObjectId)
+	if err != nil {
+		return errorMapper(err)
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s", from.Namespace, from.ObjectId), ErrNotFound)
+	}
+
+	// then the components, the metadata is one of the blobs
+	for _, query := range []string{
+		"INSERT INTO fields( namespace, oid, name, value, created_at, updated_at ) SELECT CAST( $1 AS VARCHAR ), CAST( $2 AS VARCHAR ), name, value, created_at, updated_at FROM fields WHERE namespace = $3 AND oid = $4 ORDER BY id",
+		"INSERT INTO blobs( namespace, oid, name, mimetype, payload, checksum, created_at, updated_at ) SELECT CAST( $1 AS VARCHAR ), CAST( $2 AS VARCHAR ), name, mimetype, payload, checksum, created_at, updated_at FROM blobs WHERE namespace = $3 AND oid = $4",
+	} {
+		stmt, err := s.conn().PrepareContext(ctx, query)
+		if err != nil {
+			return err
+		}
+		err = execPrepared(ctx, stmt, to.Namespace, to.ObjectId, from.Namespace, from.ObjectId)
+		stmt.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//
+// end of file
+//
@@ -10,17 +10,58 @@
 package uvaeasystore
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"strings"
 )
 
-func execPrepared(stmt *sql.Stmt, values ...any) error {
-	_, err := stmt.Exec(values...)
+// dbHandle -- the statement methods common to a database connection and a transaction
+type dbHandle interface {
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func execPrepared(ctx context.Context, stmt *sql.Stmt, values ...any) error {
+	_, err := stmt.ExecContext(ctx, values...)
 	return errorMapper(err)
 }
 
+// lock the object row, the update changes nothing but concurrent writers wait for it until the
+// transaction completes. Stale if the vtag has changed, not found if there is no object
+func lockObject(ctx context.Context, db dbHandle, key DataStoreKey, vtag string) error {
+
+	stmt, err := db.PrepareContext(ctx, "UPDATE objects SET vtag = vtag WHERE namespace = $1 AND oid = $2 AND vtag = $3 AND deleted_at IS NULL")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, key.Namespace, key.ObjectId, vtag)
+	if err != nil {
+		return errorMapper(err)
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count != 0 {
+		return nil
+	}
+
+	// nothing locked, is the object gone or has it changed
+	rows, err := db.QueryContext(ctx, "SELECT vtag FROM objects WHERE namespace = $1 AND oid = $2 AND deleted_at IS NULL", key.Namespace, key.ObjectId)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if rows.Next() == false {
+		return fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s", key.Namespace, key.ObjectId), ErrNotFound)
+	}
+	return fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s (vtag %s)", key.Namespace, key.ObjectId, vtag), ErrStaleObject)
+}
+
 func objectQueryResults(rows *sql.Rows, log *log.Logger) (EasyStoreObject, error) {
 	results := easyStoreObjectImpl{}
 	count := 0
@@ -71,9 +112,9 @@ func objectsQueryResults(rows *sql.Rows, log *log.Logger) ([]EasyStoreObject, er
 	return results, nil
 }
 
-func fieldQueryResults(rows *sql.Rows, log *log.Logger) (*EasyStoreObjectFields, error) {
+func fieldQueryResults(rows *sql.Rows, log *log.Logger) (*EasyStoreObjectMultiFields, error) {
 
-	results := EasyStoreObjectFields{}
+	results := EasyStoreObjectMultiFields{}
 	count := 0
 
 	for rows.Next() {
@@ -83,7 +124,7 @@ func fieldQueryResults(rows *sql.Rows, log *log.Logger) (*EasyStoreObjectFields,
 			return nil, err
 		}
 
-		results[name] = value
+		results[name] = append(results[name], value)
 		count++
 	}
 	if err := rows.Err(); err != nil {
@@ -105,10 +146,11 @@ func blobQueryResults(rows *sql.Rows, log *log.Logger) ([]EasyStoreBlob, error)
 
 	for rows.Next() {
 		blob := easyStoreBlobImpl{}
-		err := rows.Scan(&blob.Name_, &blob.MimeType_, &blob.Payload_, &blob.Created_, &blob.Modified_)
+		err := rows.Scan(&blob.Name_, &blob.MimeType_, &blob.Payload_, &blob.Checksum_, &blob.Created_, &blob.Modified_)
 		if err != nil {
 			return nil, err
 		}
+		blob.Size_ = int64(len(blob.Payload_))
 
 		results = append(results, blob)
 		count++
@@ -126,35 +168,6 @@ func blobQueryResults(rows *sql.Rows, log *log.Logger) ([]EasyStoreBlob, error)
 	return results, nil
 }
 
-func keyQueryResults(rows *sql.Rows, log *log.Logger) ([]DataStoreKey, error) {
-	results := make([]DataStoreKey, 0)
-	count := 0
-
-	for rows.Next() {
-		var namespace string
-		var oid string
-		var ct int
-		err := rows.Scan(&namespace, &oid, &ct)
-		if err != nil {
-			return nil, err
-		}
-
-		results = append(results, DataStoreKey{namespace, oid})
-		count++
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-
-	// check for not found
-	if count == 0 {
-		return nil, fmt.Errorf("%q: %w", "key(s) not found", ErrNotFound)
-	}
-
-	logDebug(log, fmt.Sprintf("found %d key(s)", count))
-	return results, nil
-}
-
 // handles unwrapping certain classes of errors
 func errorMapper(err error) error {
 	if err != nil {
@@ -164,12 +177,9 @@ func errorMapper(err error) error {
 		}
 
 		// try sqlite errors
-		//var sqliteErr sqlite3.Error
-		//if errors.As(err, &sqliteErr) {
-		//	if errors.Is(sqliteErr.Code, sqlite3.ErrConstraint) {
-		//		return fmt.Errorf("%q: %w", sqliteErr.Error(), ErrAlreadyExists)
-		//	}
-		//}
+		if sqliteUniqueViolation(err) == true {
+			return fmt.Errorf("%q: %w", err.Error(), ErrAlreadyExists)
+		}
 
 	}
 	return err
@@ -10,11 +10,12 @@
 package uvaeasystore
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
-
-	"golang.org/x/exp/maps"
+	"time"
 )
 
 // we store opaque metadata as a blob so need to distinguish it as special
@@ -23,47 +24,89 @@ var blobMetadataName = "metadata.secret.hidden"
 // this is our DB implementation
 type dbStorage struct {
 	dbCurrentTimeFn string      // implementations use a different function name for the current time
+	sqlite          bool        // sqlite needs its placeholders and time values rewritten
 	log             *log.Logger // logger
+	tx              *sql.Tx     // the current transaction (if any)
 	*sql.DB                     // database connection
 }
 
 // Check -- check our database health
-func (s *dbStorage) Check() error {
-	return s.Ping()
+func (s *dbStorage) Check(ctx context.Context) error {
+	return s.PingContext(ctx)
 }
 
 // UpdateBlob -- update the contents of an existing blob
-func (s *dbStorage) UpdateBlob(key DataStoreKey, blob EasyStoreBlob) error {
-	return ErrNotImplemented
+func (s *dbStorage) UpdateBlob(ctx context.Context, key DataStoreKey, blob EasyStoreBlob) error {
+
+	stmt, err := s.conn().PrepareContext(ctx, fmt.Sprintf("UPDATE blobs SET mimetype = $1, payload = $2, checksum = $3, updated_at = %s WHERE namespace = $4 AND oid = $5 AND name = $6", s.dbCurrentTimeFn))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	// errors here are serialization errors
+	buf, err := blob.Payload()
+	if err != nil {
+		return err
+	}
+
+	res, err := stmt.ExecContext(ctx, blob.MimeType(), buf, payloadChecksum(buf), key.Namespace, key.ObjectId, blob.Name())
+	if err != nil {
+		return errorMapper(err)
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s/%s", key.Namespace, key.ObjectId, blob.Name()), ErrNotFound)
+	}
+	return nil
 }
 
 // UpdateFields -- update the contents of an existing field set
-func (s *dbStorage) UpdateFields(key DataStoreKey, fields EasyStoreObjectFields) error {
-	return ErrNotImplemented
+func (s *dbStorage) UpdateFields(ctx context.Context, key DataStoreKey, fields EasyStoreObjectMultiFields) error {
+
+	// replace the whole set, fields not in the new set are removed
+	err := s.DeleteFieldsByKey(ctx, key)
+	if err != nil {
+		return err
+	}
+	return s.AddFields(ctx, key, fields)
 }
 
 // UpdateMetadata -- update the contents of existing metadata
-func (s *dbStorage) UpdateMetadata(key DataStoreKey, md EasyStoreMetadata) error {
-	return ErrNotImplemented
+func (s *dbStorage) UpdateMetadata(ctx context.Context, key DataStoreKey, md EasyStoreMetadata) error {
+
+	err := s.DeleteMetadataByKey(ctx, key)
+	if err != nil {
+		return err
+	}
+	return s.AddMetadata(ctx, key, md)
 }
 
 // UpdateObject -- update a couple of object fields
-func (s *dbStorage) UpdateObject(key DataStoreKey) error {
+func (s *dbStorage) UpdateObject(ctx context.Context, key DataStoreKey) error {
 
-	stmt, err := s.Prepare("UPDATE objects set vtag = $1, updated_at = $2 WHERE namespace = $3 AND oid = $4")
+	stmt, err := s.conn().PrepareContext(ctx, fmt.Sprintf("UPDATE objects set vtag = $1, updated_at = %s WHERE namespace = $2 AND oid = $3 AND deleted_at IS NULL", s.dbCurrentTimeFn))
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
 	newVTag := newVtag()
-	return execPrepared(stmt, newVTag, s.dbCurrentTimeFn, key.Namespace, key.ObjectId)
+	return execPrepared(ctx, stmt, newVTag, key.Namespace, key.ObjectId)
+}
+
+// LockObjectByKey -- lock the object row for the rest of the transaction and check the vtag
+func (s *dbStorage) LockObjectByKey(ctx context.Context, key DataStoreKey, vtag string) error {
+	return lockObject(ctx, s.conn(), key, vtag)
 }
 
 // AddBlob -- add a new blob object
-func (s *dbStorage) AddBlob(key DataStoreKey, blob EasyStoreBlob) error {
+func (s *dbStorage) AddBlob(ctx context.Context, key DataStoreKey, blob EasyStoreBlob) error {
 
-	stmt, err := s.Prepare("INSERT INTO blobs( namespace, oid, name, mimetype, payload ) VALUES( $1,$2,$3,$4,$5 )")
+	stmt, err := s.conn().PrepareContext(ctx, "INSERT INTO blobs( namespace, oid, name, mimetype, payload, checksum ) VALUES( $1,$2,$3,$4,$5,$6 )")
 	if err != nil {
 		return err
 	}
@@ -75,32 +118,35 @@ func (s *dbStorage) AddBlob(key DataStoreKey, blob EasyStoreBlob) error {
 		return err
 	}
 
-	_, err = stmt.Exec(key.Namespace, key.ObjectId, blob.Name(), blob.MimeType(), buf)
+	_, err = stmt.ExecContext(ctx, key.Namespace, key.ObjectId, blob.Name(), blob.MimeType(), buf, payloadChecksum(buf))
 	return errorMapper(err)
 }
 
 // AddFields -- add a new fields object
-func (s *dbStorage) AddFields(key DataStoreKey, fields EasyStoreObjectFields) error {
+func (s *dbStorage) AddFields(ctx context.Context, key DataStoreKey, fields EasyStoreObjectMultiFields) error {
 
-	stmt, err := s.Prepare("INSERT INTO fields( namespace, oid, name, value ) VALUES( $1,$2,$3,$4 )")
+	stmt, err := s.conn().PrepareContext(ctx, "INSERT INTO fields( namespace, oid, name, value ) VALUES( $1,$2,$3,$4 )")
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
-	for n, v := range fields {
-		_, err = stmt.Exec(key.Namespace, key.ObjectId, n, v)
-		if err != nil {
-			return errorMapper(err)
+	// each value is a separate row
+	for n, values := range fields {
+		for _, v := range values {
+			_, err = stmt.ExecContext(ctx, key.Namespace, key.ObjectId, n, v)
+			if err != nil {
+				return errorMapper(err)
+			}
 		}
 	}
 	return nil
 }
 
 // AddMetadata -- add a new metadata object
-func (s *dbStorage) AddMetadata(key DataStoreKey, obj EasyStoreMetadata) error {
+func (s *dbStorage) AddMetadata(ctx context.Context, key DataStoreKey, obj EasyStoreMetadata) error {
 
-	stmt, err := s.Prepare("INSERT INTO blobs( namespace, oid, name, mimetype, payload ) VALUES( $1,$2,$3,$4,$5 )")
+	stmt, err := s.conn().PrepareContext(ctx, "INSERT INTO blobs( namespace, oid, name, mimetype, payload ) VALUES( $1,$2,$3,$4,$5 )")
 	if err != nil {
 		return err
 	}
@@ -112,28 +158,28 @@ func (s *dbStorage) AddMetadata(key DataStoreKey, obj EasyStoreMetadata) error {
 		return err
 	}
 
-	_, err = stmt.Exec(key.Namespace, key.ObjectId, blobMetadataName, obj.MimeType(), buf)
+	_, err = stmt.ExecContext(ctx, key.Namespace, key.ObjectId, blobMetadataName, obj.MimeType(), buf)
 	return errorMapper(err)
 }
 
 // AddObject -- add a new object
-func (s *dbStorage) AddObject(obj EasyStoreObject) error {
+func (s *dbStorage) AddObject(ctx context.Context, obj EasyStoreObject) error {
 
-	stmt, err := s.Prepare("INSERT INTO objects( namespace, oid, vtag ) VALUES( $1,$2,$3 )")
+	stmt, err := s.conn().PrepareContext(ctx, "INSERT INTO objects( namespace, oid, vtag ) VALUES( $1,$2,$3 )")
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
-	return execPrepared(stmt, obj.Namespace(), obj.Id(), obj.VTag())
+	return execPrepared(ctx, stmt, obj.Namespace(), obj.Id(), obj.VTag())
 }
 
 // GetBlobsByKey -- get all blob data associated with the specified object
-func (s *dbStorage) GetBlobsByKey(key DataStoreKey, useCache bool) ([]EasyStoreBlob, error) {
+func (s *dbStorage) GetBlobsByKey(ctx context.Context, key DataStoreKey, useCache bool) ([]EasyStoreBlob, error) {
 
 	// this implementation does not use a cache so useCache is ignored
 
-	rows, err := s.Query("SELECT name, mimetype, payload, created_at, updated_at FROM blobs WHERE namespace = $1 AND oid = $2 and name != $3 ORDER BY updated_at", key.Namespace, key.ObjectId, blobMetadataName)
+	rows, err := s.conn().QueryContext(ctx, "SELECT name, mimetype, payload, checksum, created_at, updated_at FROM blobs WHERE namespace = $1 AND oid = $2 and name != $3 ORDER BY updated_at", key.Namespace, key.ObjectId, blobMetadataName)
 	if err != nil {
 		return nil, err
 	}
@@ -143,11 +189,11 @@ func (s *dbStorage) GetBlobsByKey(key DataStoreKey, useCache bool) ([]EasyStoreB
 }
 
 // GetFieldsByKey -- get all field data associated with the specified object
-func (s *dbStorage) GetFieldsByKey(key DataStoreKey, useCache bool) (*EasyStoreObjectFields, error) {
+func (s *dbStorage) GetFieldsByKey(ctx context.Context, key DataStoreKey, useCache bool) (*EasyStoreObjectMultiFields, error) {
 
 	// this implementation does not use a cache so useCache is ignored
 
-	rows, err := s.Query("SELECT name, value FROM fields WHERE namespace = $1 AND oid = $2 ORDER BY updated_at", key.Namespace, key.ObjectId)
+	rows, err := s.conn().QueryContext(ctx, "SELECT name, value FROM fields WHERE namespace = $1 AND oid = $2 ORDER BY id", key.Namespace, key.ObjectId)
 	if err != nil {
 		return nil, err
 	}
@@ -157,11 +203,11 @@ func (s *dbStorage) GetFieldsByKey(key DataStoreKey, useCache bool) (*EasyStoreO
 }
 
 // GetMetadataByKey -- get all field data associated with the specified object
-func (s *dbStorage) GetMetadataByKey(key DataStoreKey, useCache bool) (EasyStoreMetadata, error) {
+func (s *dbStorage) GetMetadataByKey(ctx context.Context, key DataStoreKey, useCache bool) (EasyStoreMetadata, error) {
 
 	// this implementation does not use a cache so useCache is ignored
 
-	rows, err := s.Query("SELECT name, mimetype, payload, created_at, updated_at FROM blobs WHERE namespace = $1 AND oid = $2 and name = $3 LIMIT 1", key.Namespace, key.ObjectId, blobMetadataName)
+	rows, err := s.conn().QueryContext(ctx, "SELECT name, mimetype, payload, checksum, created_at, updated_at FROM blobs WHERE namespace = $1 AND oid = $2 and name = $3 LIMIT 1", key.Namespace, key.ObjectId, blobMetadataName)
 	if err != nil {
 		return nil, err
 	}
@@ -182,12 +228,38 @@ func (s *dbStorage) GetMetadataByKey(key DataStoreKey, useCache bool) (EasyStore
 	return md, nil
 }
 
+// GetBlobByKey -- get the named blob associated with the specified object
+func (s *dbStorage) GetBlobByKey(ctx context.Context, key DataStoreKey, curName string, useCache bool) (EasyStoreBlob, error) {
+
+	// this implementation does not use a cache so useCache is ignored
+
+	// the metadata is stored as a blob but is not a file
+	if curName == blobMetadataName {
+		return nil, fmt.Errorf("%q: %w", curName, ErrFileNotFound)
+	}
+
+	rows, err := s.conn().QueryContext(ctx, "SELECT name, mimetype, payload, checksum, created_at, updated_at FROM blobs WHERE namespace = $1 AND oid = $2 and name = $3 LIMIT 1", key.Namespace, key.ObjectId, curName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	br, err := blobQueryResults(rows, s.log)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) == true {
+			return nil, fmt.Errorf("%q: %w", curName, ErrFileNotFound)
+		}
+		return nil, err
+	}
+	return br[0], nil
+}
+
 // GetObjectByKey -- get all field data associated with the specified object
-func (s *dbStorage) GetObjectByKey(key DataStoreKey, useCache bool) (EasyStoreObject, error) {
+func (s *dbStorage) GetObjectByKey(ctx context.Context, key DataStoreKey, useCache bool) (EasyStoreObject, error) {
 
 	// this implementation does not use a cache so useCache is ignored
 
-	rows, err := s.Query("SELECT namespace, oid, vtag, created_at, updated_at FROM objects WHERE namespace = $1 AND oid = $2 LIMIT 1", key.Namespace, key.ObjectId)
+	rows, err := s.conn().QueryContext(ctx, "SELECT namespace, oid, vtag, created_at, updated_at FROM objects WHERE namespace = $1 AND oid = $2 AND deleted_at IS NULL LIMIT 1", key.Namespace, key.ObjectId)
 	if err != nil {
 		return nil, err
 	}
@@ -197,12 +269,12 @@ func (s *dbStorage) GetObjectByKey(key DataStoreKey, useCache bool) (EasyStoreOb
 }
 
 // GetObjectsByKey -- get all field data associated with the specified object
-func (s *dbStorage) GetObjectsByKey(keys []DataStoreKey, useCache bool) ([]EasyStoreObject, error) {
+func (s *dbStorage) GetObjectsByKey(ctx context.Context, keys []DataStoreKey, useCache bool) ([]EasyStoreObject, error) {
 
 	// this implementation does not use a cache so useCache is ignored
 
 	args := make([]any, 0)
-	query := "SELECT namespace, oid, vtag, created_at, updated_at FROM objects WHERE "
+	query := "SELECT namespace, oid, vtag, created_at, updated_at FROM objects WHERE deleted_at IS NULL AND ("
 
 	variableNum := 1
 	for _, k := range keys {
@@ -214,11 +286,11 @@ func (s *dbStorage) GetObjectsByKey(keys []DataStoreKey, useCache bool) ([]EasyS
 		args = append(args, k.Namespace, k.ObjectId)
 	}
 
-	query += " ORDER BY updated_at"
+	query += ") ORDER BY updated_at"
 
 	//fmt.Printf("QUERY [%s]\n", query)
 
-	rows, err := s.Query(query, args...)
+	rows, err := s.conn().QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -228,120 +300,138 @@ func (s *dbStorage) GetObjectsByKey(keys []DataStoreKey, useCache bool) ([]EasyS
 }
 
 // RenameBlobByKey -- rename the named blob to the new name
-func (s *dbStorage) RenameBlobByKey(key DataStoreKey, curName string, newName string) error {
-	return ErrNotImplemented
+func (s *dbStorage) RenameBlobByKey(ctx context.Context, key DataStoreKey, curName string, newName string) error {
+
+	// the metadata is stored as a blob but is not a file
+	if curName == blobMetadataName {
+		return fmt.Errorf("%q: %w", curName, ErrFileNotFound)
+	}
+	if newName == blobMetadataName {
+		return fmt.Errorf("%q: %w", fmt.Sprintf("%s is a reserved name", newName), ErrBadParameter)
+	}
+
+	stmt, err := s.conn().PrepareContext(ctx, fmt.Sprintf("UPDATE blobs SET name = $1, updated_at = %s WHERE namespace = $2 AND oid = $3 AND name = $4", s.dbCurrentTimeFn))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	// the distinct index rejects a new name that already exists
+	res, err := stmt.ExecContext(ctx, newName, key.Namespace, key.ObjectId, curName)
+	if err != nil {
+		return errorMapper(err)
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s/%s", key.Namespace, key.ObjectId, curName), ErrFileNotFound)
+	}
+	return nil
 }
 
 // DeleteBlobByKey -- delete a single blob associated with the specified object
-func (s *dbStorage) DeleteBlobByKey(key DataStoreKey, curName string) error {
+func (s *dbStorage) DeleteBlobByKey(ctx context.Context, key DataStoreKey, curName string) error {
 
-	stmt, err := s.Prepare("DELETE FROM blobs WHERE namespace = $1 AND oid = $2 and name = $3")
+	stmt, err := s.conn().PrepareContext(ctx, "DELETE FROM blobs WHERE namespace = $1 AND oid = $2 and name = $3")
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
-	return execPrepared(stmt, key.Namespace, key.ObjectId, curName)
+	return execPrepared(ctx, stmt, key.Namespace, key.ObjectId, curName)
 }
 
 // DeleteBlobsByKey -- delete all blob data associated with the specified object
-func (s *dbStorage) DeleteBlobsByKey(key DataStoreKey) error {
+func (s *dbStorage) DeleteBlobsByKey(ctx context.Context, key DataStoreKey) error {
 
-	stmt, err := s.Prepare("DELETE FROM blobs WHERE namespace = $1 AND oid = $2 and name != $3")
+	stmt, err := s.conn().PrepareContext(ctx, "DELETE FROM blobs WHERE namespace = $1 AND oid = $2 and name != $3")
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
-	return execPrepared(stmt, key.Namespace, key.ObjectId, blobMetadataName)
+	return execPrepared(ctx, stmt, key.Namespace, key.ObjectId, blobMetadataName)
 }
 
 // DeleteFieldsByKey -- delete all field data associated with the specified object
-func (s *dbStorage) DeleteFieldsByKey(key DataStoreKey) error {
+func (s *dbStorage) DeleteFieldsByKey(ctx context.Context, key DataStoreKey) error {
 
-	stmt, err := s.Prepare("DELETE FROM fields WHERE namespace = $1 AND oid = $2")
+	stmt, err := s.conn().PrepareContext(ctx, "DELETE FROM fields WHERE namespace = $1 AND oid = $2")
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
-	return execPrepared(stmt, key.Namespace, key.ObjectId)
+	return execPrepared(ctx, stmt, key.Namespace, key.ObjectId)
 }
 
 // DeleteMetadataByKey -- delete all field data associated with the specified object
-func (s *dbStorage) DeleteMetadataByKey(key DataStoreKey) error {
+func (s *dbStorage) DeleteMetadataByKey(ctx context.Context, key DataStoreKey) error {
 
-	stmt, err := s.Prepare("DELETE FROM blobs WHERE namespace = $1 AND oid = $2 AND name = $3")
+	stmt, err := s.conn().PrepareContext(ctx, "DELETE FROM blobs WHERE namespace = $1 AND oid = $2 AND name = $3")
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
-	return execPrepared(stmt, key.Namespace, key.ObjectId, blobMetadataName)
+	return execPrepared(ctx, stmt, key.Namespace, key.ObjectId, blobMetadataName)
 }
 
 // DeleteObjectByKey -- delete all field data associated with the specified object
-func (s *dbStorage) DeleteObjectByKey(key DataStoreKey) error {
+func (s *dbStorage) DeleteObjectByKey(ctx context.Context, key DataStoreKey) error {
 
-	stmt, err := s.Prepare("DELETE FROM objects WHERE namespace = $1 AND oid = $2")
+	stmt, err := s.conn().PrepareContext(ctx, "DELETE FROM objects WHERE namespace = $1 AND oid = $2")
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
-	return execPrepared(stmt, key.Namespace, key.ObjectId)
+	return execPrepared(ctx, stmt, key.Namespace, key.ObjectId)
 }
 
-// GetKeysByFields -- get a list of keys that have the supplied fields/values
-func (s *dbStorage) GetKeysByFields(namespace string, fields EasyStoreObjectFields) ([]DataStoreKey, error) {
-
-	var err error
-	var rows *sql.Rows
-	var query string
-	//
-	// support the following cases:
-	// empty namespace (all namespaces) or specified namespace
-	// no fields (all objects) or variable set of fields
-	//
-	if len(fields) == 0 {
-		if len(namespace) == 0 {
-			query = "SELECT namespace, oid, 0 FROM objects ORDER BY namespace, oid"
-			rows, err = s.Query(query)
-		} else {
-			query = "SELECT namespace, oid, 0 FROM objects where namespace = $1 ORDER BY namespace, oid"
-			rows, err = s.Query(query, namespace)
-		}
-	} else {
-		// dynamically build the query because we have a variable number of fields
-		args := make([]any, 0)
-		query = "SELECT namespace, oid, count(*) FROM fields WHERE "
-		variableIx := 1
-		if len(namespace) != 0 {
-			query += fmt.Sprintf("namespace = $%d AND ", variableIx)
-			args = append(args, namespace)
-			variableIx++
-		}
+// GetKeysByFields -- get a page of keys of objects matching the query and the token for the next page
+func (s *dbStorage) GetKeysByFields(ctx context.Context, namespace string, query EasyStoreQuery, page EasyStorePage) ([]DataStoreKey, string, error) {
+	return keysByQueryPage(ctx, s.conn(), namespace, query, page, s.log)
+}
 
-		for ix, k := range maps.Keys(fields) {
-			query += fmt.Sprintf("(name = $%d AND value = $%d) ", variableIx, variableIx+1)
-			variableIx += 2
-			args = append(args, k, fields[k])
-			if ix != (len(fields) - 1) {
-				query += "OR "
-			}
-		}
+// Begin -- begin a new transaction
+func (s *dbStorage) Begin(ctx context.Context) (DataStoreTx, error) {
 
-		query += fmt.Sprintf("GROUP BY namespace, oid HAVING count(*) = %d", len(fields))
-		rows, err = s.Query(query, args...)
+	if s.tx != nil {
+		return nil, fmt.Errorf("%q: %w", "nested transactions are not supported", ErrNotImplemented)
 	}
 
+	tx, err := s.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	return keyQueryResults(rows, s.log)
+	ts := *s
+	ts.tx = tx
+	return &dbStorageTx{&ts}, nil
 }
 
 //
 // private implementation methods
 //
 
+// time values as the database expects them
+func (s *dbStorage) timeValue(t time.Time) any {
+	if s.sqlite == true {
+		return t.UTC().Format(sqliteTimeFormat)
+	}
+	return t
+}
+
+// statements go to the current transaction when we have one
+func (s *dbStorage) conn() dbHandle {
+	var h dbHandle = s.DB
+	if s.tx != nil {
+		h = s.tx
+	}
+	if s.sqlite == true {
+		return sqliteHandle{h}
+	}
+	return h
+}
+
 //
 // end of file
 //
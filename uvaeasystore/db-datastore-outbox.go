@@ -0,0 +1,161 @@
+//
+// db implementation of the datastore outbox methods
+//
+
+// only include this file for service builds
+
+//go:build service
+// +build service
+
+package uvaeasystore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// AddEvent -- add an event to the outbox
+func (s *dbStorage) AddEvent(ctx context.Context, event EasyStoreEvent) error {
+	return addOutboxEvent(ctx, s.conn(), event, s.timeValue(event.Created))
+}
+
+// GetPendingEvents -- get the events that have not been delivered and are not parked, oldest first (a maximum
+// or limit of 0 is no maximum or limit)
+func (s *dbStorage) GetPendingEvents(ctx context.Context, maxAttempts uint, limit uint) ([]EasyStoreEvent, error) {
+	return pendingOutboxEvents(ctx, s.conn(), maxAttempts, limit, s.log)
+}
+
+// GetEventsByTime -- get the events created at or after the first time and before the second, oldest first,
+// skipping the first offset events (limit of 0 is no limit)
+func (s *dbStorage) GetEventsByTime(ctx context.Context, after time.Time, before time.Time, offset uint, limit uint) ([]EasyStoreEvent, error) {
+	return outboxEventsByTime(ctx, s.conn(), after, before, offset, limit, s.log)
+}
+
+// UpdateEvent -- update the delivery state of an event
+func (s *dbStorage) UpdateEvent(ctx context.Context, event EasyStoreEvent) error {
+	var delivered any
+	if event.Delivered != nil {
+		delivered = s.timeValue(*event.Delivered)
+	}
+	return updateOutboxEvent(ctx, s.conn(), event, delivered)
+}
+
+//
+// private methods, shared by the datastores with a database
+//
+
+var outboxColumns = "eid, name, namespace, oid, detail, attempts, created_at, delivered_at"
+
+func addOutboxEvent(ctx context.Context, db dbHandle, event EasyStoreEvent, created any) error {
+
+	stmt, err := db.PrepareContext(ctx, fmt.Sprintf("INSERT INTO outbox( %s ) VALUES( $1,$2,$3,$4,$5,$6,$7,NULL )", outboxColumns))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	return execPrepared(ctx, stmt, event.Id, event.Name, event.Namespace, event.ObjectId, string(event.Detail), event.Attempts, created)
+}
+
+func updateOutboxEvent(ctx context.Context, db dbHandle, event EasyStoreEvent, delivered any) error {
+
+	stmt, err := db.PrepareContext(ctx, "UPDATE outbox SET attempts = $1, delivered_at = $2 WHERE eid = $3")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, event.Attempts, delivered, event.Id)
+	if err != nil {
+		return errorMapper(err)
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("%q: %w", fmt.Sprintf("event %s", event.Id), ErrNotFound)
+	}
+	return nil
+}
+
+func pendingOutboxEvents(ctx context.Context, db dbHandle, maxAttempts uint, limit uint, log *log.Logger) ([]EasyStoreEvent, error) {
+
+	query := fmt.Sprintf("SELECT %s FROM outbox WHERE delivered_at IS NULL", outboxColumns)
+	if maxAttempts != 0 {
+		query += fmt.Sprintf(" AND attempts < %d", maxAttempts)
+	}
+	query += " ORDER BY created_at, id"
+	if limit != 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return outboxQueryResults(rows, log)
+}
+
+func outboxEventsByTime(ctx context.Context, db dbHandle, after time.Time, before time.Time, offset uint, limit uint, log *log.Logger) ([]EasyStoreEvent, error) {
+
+	// the dialects only agree on an offset that comes with a limit
+	query := fmt.Sprintf("SELECT %s FROM outbox WHERE created_at >= $1 AND created_at < $2 ORDER BY created_at, id", outboxColumns)
+	if limit != 0 {
+		query += fmt.Sprintf(" LIMIT %d OFFSET %d", limit, offset)
+		offset = 0
+	}
+	rows, err := db.QueryContext(ctx, query, after, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results, err := outboxQueryResults(rows, log)
+	if err != nil {
+		return nil, err
+	}
+	results = pageEvents(results, offset, 0)
+	if len(results) == 0 {
+		return nil, fmt.Errorf("%q: %w", "event(s) not found", ErrNotFound)
+	}
+	return results, nil
+}
+
+func outboxQueryResults(rows *sql.Rows, log *log.Logger) ([]EasyStoreEvent, error) {
+
+	results := make([]EasyStoreEvent, 0)
+	for rows.Next() {
+		var e EasyStoreEvent
+		var detail string
+		var delivered sql.NullTime
+		err := rows.Scan(&e.Id, &e.Name, &e.Namespace, &e.ObjectId, &detail, &e.Attempts, &e.Created, &delivered)
+		if err != nil {
+			return nil, err
+		}
+		e.Detail = []byte(detail)
+		if delivered.Valid == true {
+			e.Delivered = &delivered.Time
+		}
+		results = append(results, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// check for not found
+	if len(results) == 0 {
+		return nil, fmt.Errorf("%q: %w", "event(s) not found", ErrNotFound)
+	}
+
+	logDebug(log, fmt.Sprintf("found %d event(s)", len(results)))
+	return results, nil
+}
+
+//
+// end of file
+//
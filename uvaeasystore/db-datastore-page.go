@@ -0,0 +1,183 @@
+//
+// keyset pagination of object keys, shared by the datastores with a database
+//
+
+// only include this file for service builds
+
+//go:build service
+// +build service
+
+package uvaeasystore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// the continuation token contents, the last key of the previous page and its sort value
+type pageToken struct {
+	Order     EasyStoreOrder `json:"o"`
+	Namespace string         `json:"n"`
+	ObjectId  string         `json:"i"`
+	When      time.Time      `json:"t,omitempty"`
+}
+
+// a key with the values it can be sorted by
+type pageKey struct {
+	key      DataStoreKey
+	created  time.Time
+	modified time.Time
+}
+
+// get a page of the keys of objects matching the query along with the token for the next page. Pages
+// are selected using the sort column and the key rather than an offset so each page costs the same
+// regardless of how far into the result set it is
+func keysByQueryPage(ctx context.Context, db dbHandle, namespace string, query EasyStoreQuery, page EasyStorePage, log *log.Logger) ([]DataStoreKey, string, error) {
+
+	var sortColumn string
+	switch page.Order {
+	case OrderById:
+	case OrderByCreated:
+		sortColumn = "o.created_at"
+	case OrderByModified:
+		sortColumn = "o.updated_at"
+	default:
+		return nil, "", fmt.Errorf("%q: %w", fmt.Sprintf("unknown order [%d]", page.Order), ErrBadParameter)
+	}
+
+	// deleted objects are never found
+	args := make([]any, 0)
+	conditions := []string{"o.deleted_at IS NULL"}
+	sqlQuery := "SELECT o.namespace, o.oid, o.created_at, o.updated_at FROM objects o"
+
+	if len(namespace) != 0 {
+		args = append(args, namespace)
+		conditions = append(conditions, fmt.Sprintf("o.namespace = $%d", len(args)))
+	}
+
+	match, err := compileQuery(query, &args)
+	if err != nil {
+		return nil, "", err
+	}
+	conditions = append(conditions, match)
+
+	// continue after the last key of the previous page
+	if len(page.Token) != 0 {
+		token, err := decodePageToken(page.Token)
+		if err != nil {
+			return nil, "", err
+		}
+		if token.Order != page.Order {
+			return nil, "", fmt.Errorf("%q: %w", "continuation token order differs from the requested order", ErrBadParameter)
+		}
+		if len(sortColumn) != 0 {
+			args = append(args, token.When, token.Namespace, token.ObjectId)
+			conditions = append(conditions, fmt.Sprintf("(%s, o.namespace, o.oid) > ($%d, $%d, $%d)", sortColumn, len(args)-2, len(args)-1, len(args)))
+		} else {
+			args = append(args, token.Namespace, token.ObjectId)
+			conditions = append(conditions, fmt.Sprintf("(o.namespace, o.oid) > ($%d, $%d)", len(args)-1, len(args)))
+		}
+	}
+
+	sqlQuery += " WHERE " + strings.Join(conditions, " AND ")
+
+	if len(sortColumn) != 0 {
+		sqlQuery += fmt.Sprintf(" ORDER BY %s, o.namespace, o.oid", sortColumn)
+	} else {
+		sqlQuery += " ORDER BY o.namespace, o.oid"
+	}
+
+	// get one more than we need so we know if there is another page
+	if page.Limit != 0 {
+		sqlQuery += fmt.Sprintf(" LIMIT %d", page.Limit+1)
+	}
+
+	//fmt.Printf("QUERY [%s]\n", sqlQuery)
+
+	rows, err := db.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	keys, err := pageKeyQueryResults(rows, log)
+	if err != nil {
+		return nil, "", err
+	}
+
+	next := ""
+	if page.Limit != 0 && uint(len(keys)) > page.Limit {
+		keys = keys[:page.Limit]
+		next = encodePageToken(page.Order, keys[len(keys)-1])
+	}
+
+	results := make([]DataStoreKey, 0, len(keys))
+	for _, k := range keys {
+		results = append(results, k.key)
+	}
+	return results, next, nil
+}
+
+func pageKeyQueryResults(rows *sql.Rows, log *log.Logger) ([]pageKey, error) {
+	results := make([]pageKey, 0)
+	count := 0
+
+	for rows.Next() {
+		var k pageKey
+		err := rows.Scan(&k.key.Namespace, &k.key.ObjectId, &k.created, &k.modified)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, k)
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// check for not found
+	if count == 0 {
+		return nil, fmt.Errorf("%q: %w", "key(s) not found", ErrNotFound)
+	}
+
+	logDebug(log, fmt.Sprintf("found %d key(s)", count))
+	return results, nil
+}
+
+func encodePageToken(order EasyStoreOrder, last pageKey) string {
+	token := pageToken{Order: order, Namespace: last.key.Namespace, ObjectId: last.key.ObjectId}
+	switch order {
+	case OrderByCreated:
+		token.When = last.created
+	case OrderByModified:
+		token.When = last.modified
+	}
+
+	// cannot fail, the token is all simple types
+	buf, _ := json.Marshal(token)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+func decodePageToken(str string) (pageToken, error) {
+	var token pageToken
+	buf, err := base64.RawURLEncoding.DecodeString(str)
+	if err != nil {
+		return token, fmt.Errorf("%q: %w", "malformed continuation token", ErrBadParameter)
+	}
+	err = json.Unmarshal(buf, &token)
+	if err != nil {
+		return token, fmt.Errorf("%q: %w", "malformed continuation token", ErrBadParameter)
+	}
+	return token, nil
+}
+
+//
+// end of file
+//
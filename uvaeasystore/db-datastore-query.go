@@ -0,0 +1,95 @@
+//
+// compiles an easystore query into parameterized SQL, shared by the datastores with a database
+//
+
+// only include this file for service builds
+
+//go:build service
+// +build service
+
+package uvaeasystore
+
+import (
+	"fmt"
+	"strings"
+)
+
+// compile the query into a condition on the objects table (aliased as o), the values are appended
+// to the argument list and referenced as numbered parameters
+func compileQuery(query EasyStoreQuery, args *[]any) (string, error) {
+
+	switch query.Op {
+	case QueryOpAnd, QueryOpOr:
+		// the identities for an empty list
+		if len(query.Terms) == 0 {
+			if query.Op == QueryOpAnd {
+				return "1 = 1", nil
+			}
+			return "1 = 0", nil
+		}
+		terms := make([]string, 0, len(query.Terms))
+		for _, t := range query.Terms {
+			term, err := compileQuery(t, args)
+			if err != nil {
+				return "", err
+			}
+			terms = append(terms, term)
+		}
+		return fmt.Sprintf("(%s)", strings.Join(terms, fmt.Sprintf(" %s ", strings.ToUpper(string(query.Op))))), nil
+
+	case QueryOpNot:
+		if len(query.Terms) != 1 {
+			return "", fmt.Errorf("%q: %w", "not requires a single term", ErrBadParameter)
+		}
+		term, err := compileQuery(query.Terms[0], args)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("NOT %s", term), nil
+
+	case QueryOpExists:
+		return fieldCondition(query.Field, "", "", args), nil
+	case QueryOpEquals:
+		return fieldCondition(query.Field, "f.value = $%d", query.Value, args), nil
+	case QueryOpPrefix:
+		return fieldCondition(query.Field, "f.value LIKE $%d ESCAPE '\\'", likeEscape(query.Value)+"%", args), nil
+	case QueryOpWildcard:
+		return fieldCondition(query.Field, "f.value LIKE $%d ESCAPE '\\'", wildcardToLike(query.Value), args), nil
+	case QueryOpLess:
+		return fieldCondition(query.Field, "f.value < $%d", query.Value, args), nil
+	case QueryOpLessEqual:
+		return fieldCondition(query.Field, "f.value <= $%d", query.Value, args), nil
+	case QueryOpGreater:
+		return fieldCondition(query.Field, "f.value > $%d", query.Value, args), nil
+	case QueryOpGreaterEqual:
+		return fieldCondition(query.Field, "f.value >= $%d", query.Value, args), nil
+	}
+
+	return "", fmt.Errorf("%q: %w", fmt.Sprintf("unknown query operator [%s]", query.Op), ErrBadParameter)
+}
+
+// the object has the named field and its value satisfies the (optional) value condition
+func fieldCondition(name string, valueCondition string, value string, args *[]any) string {
+
+	*args = append(*args, name)
+	condition := fmt.Sprintf("EXISTS (SELECT 1 FROM fields f WHERE f.namespace = o.namespace AND f.oid = o.oid AND f.name = $%d", len(*args))
+	if len(valueCondition) != 0 {
+		*args = append(*args, value)
+		condition += " AND " + fmt.Sprintf(valueCondition, len(*args))
+	}
+	return condition + ")"
+}
+
+// escape the LIKE special characters so they match literally
+func likeEscape(str string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(str)
+}
+
+// convert a wildcard pattern (* and ?) into a LIKE pattern
+func wildcardToLike(pattern string) string {
+	return strings.NewReplacer("*", "%", "?", "_").Replace(likeEscape(pattern))
+}
+
+//
+// end of file
+//
@@ -0,0 +1,128 @@
+//
+// db implementation of the datastore trash methods
+//
+
+// only include this file for service builds
+
+//go:build service
+// +build service
+
+package uvaeasystore
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// TombstoneObjectByKey -- move the object to the trash
+func (s *dbStorage) TombstoneObjectByKey(ctx context.Context, key DataStoreKey) error {
+	return updateTombstone(ctx, s.conn(), key, "UPDATE objects SET deleted_at = $1 WHERE namespace = $2 AND oid = $3 AND deleted_at IS NULL", s.timeValue(time.Now()))
+}
+
+// RestoreObjectByKey -- restore the object from the trash
+func (s *dbStorage) RestoreObjectByKey(ctx context.Context, key DataStoreKey) error {
+	return updateTombstone(ctx, s.conn(), key, "UPDATE objects SET deleted_at = NULL WHERE namespace = $1 AND oid = $2 AND deleted_at IS NOT NULL")
+}
+
+// PurgeObjectByKey -- remove the object from the trash along with its versions
+func (s *dbStorage) PurgeObjectByKey(ctx context.Context, key DataStoreKey) error {
+
+	// the object first, it must be in the trash
+	err := updateTombstone(ctx, s.conn(), key, "DELETE FROM objects WHERE namespace = $1 AND oid = $2 AND deleted_at IS NOT NULL")
+	if err != nil {
+		return err
+	}
+
+	for _, query := range []string{
+		"DELETE FROM object_versions WHERE namespace = $1 AND oid = $2",
+		"DELETE FROM field_versions WHERE namespace = $1 AND oid = $2",
+		"DELETE FROM blob_versions WHERE namespace = $1 AND oid = $2",
+	} {
+		stmt, err := s.conn().PrepareContext(ctx, query)
+		if err != nil {
+			return err
+		}
+		err = execPrepared(ctx, stmt, key.Namespace, key.ObjectId)
+		stmt.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetTombstones -- get the objects in the trash that were deleted before the specified time
+func (s *dbStorage) GetTombstones(ctx context.Context, namespace string, before time.Time) ([]EasyStoreTombstone, error) {
+	return tombstonesBefore(ctx, s.conn(), namespace, before, s.log)
+}
+
+//
+// private methods, shared by the datastores with a database
+//
+
+// update or delete a single object, not found if the statement affects nothing
+func updateTombstone(ctx context.Context, db dbHandle, key DataStoreKey, query string, values ...any) error {
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, append(values, key.Namespace, key.ObjectId)...)
+	if err != nil {
+		return errorMapper(err)
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s", key.Namespace, key.ObjectId), ErrNotFound)
+	}
+	return nil
+}
+
+func tombstonesBefore(ctx context.Context, db dbHandle, namespace string, before time.Time, log *log.Logger) ([]EasyStoreTombstone, error) {
+
+	args := []any{before}
+	query := "SELECT namespace, oid, deleted_at FROM objects WHERE deleted_at IS NOT NULL AND deleted_at < $1"
+	if len(namespace) != 0 {
+		args = append(args, namespace)
+		query += " AND namespace = $2"
+	}
+	query += " ORDER BY deleted_at"
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]EasyStoreTombstone, 0)
+	for rows.Next() {
+		var t EasyStoreTombstone
+		err := rows.Scan(&t.Namespace, &t.Id, &t.Deleted)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// check for not found
+	if len(results) == 0 {
+		return nil, fmt.Errorf("%q: %w", "deleted object(s) not found", ErrNotFound)
+	}
+
+	logDebug(log, fmt.Sprintf("found %d deleted object(s)", len(results)))
+	return results, nil
+}
+
+//
+// end of file
+//
@@ -0,0 +1,43 @@
+//
+// db implementation of the datastore transaction interface
+//
+
+// only include this file for service builds
+
+//go:build service
+// +build service
+
+package uvaeasystore
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// this is our DB transaction implementation
+type dbStorageTx struct {
+	*dbStorage // the storage, bound to the transaction
+}
+
+// Commit -- commit the transaction
+func (t *dbStorageTx) Commit() error {
+	return t.tx.Commit()
+}
+
+// Rollback -- roll back the transaction, a no-op if it is already complete
+func (t *dbStorageTx) Rollback() error {
+	err := t.tx.Rollback()
+	if errors.Is(err, sql.ErrTxDone) == true {
+		return nil
+	}
+	return err
+}
+
+// Close -- roll back anything not committed, the underlying connection remains open
+func (t *dbStorageTx) Close() error {
+	return t.Rollback()
+}
+
+//
+// end of file
+//
@@ -0,0 +1,149 @@
+//
+// db implementation of the datastore version methods
+//
+
+// only include this file for service builds
+
+//go:build service
+// +build service
+
+package uvaeasystore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// AddVersion -- preserve the current object, fields, metadata and files as a prior version
+func (s *dbStorage) AddVersion(ctx context.Context, key DataStoreKey) error {
+
+	// the object first, it tells us if there is anything to preserve
+	stmt, err := s.conn().PrepareContext(ctx, "INSERT INTO object_versions( namespace, oid, vtag, created_at, updated_at ) SELECT namespace, oid, vtag, created_at, updated_at FROM objects WHERE namespace = $1 AND oid = $2 AND deleted_at IS NULL")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, key.Namespace, key.ObjectId)
+	if err != nil {
+		return errorMapper(err)
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s", key.Namespace, key.ObjectId), ErrNotFound)
+	}
+
+	// then the components, tagged with the version they belong to
+	for _, query := range []string{
+		"INSERT INTO field_versions( namespace, oid, vtag, name, value ) SELECT f.namespace, f.oid, o.vtag, f.name, f.value FROM fields f JOIN objects o ON o.namespace = f.namespace AND o.oid = f.oid WHERE f.namespace = $1 AND f.oid = $2 ORDER BY f.id",
+		"INSERT INTO blob_versions( namespace, oid, vtag, name, mimetype, payload, checksum, created_at, updated_at ) SELECT b.namespace, b.oid, o.vtag, b.name, b.mimetype, b.payload, b.checksum, b.created_at, b.updated_at FROM blobs b JOIN objects o ON o.namespace = b.namespace AND o.oid = b.oid WHERE b.namespace = $1 AND b.oid = $2",
+	} {
+		stmt, err := s.conn().PrepareContext(ctx, query)
+		if err != nil {
+			return err
+		}
+		err = execPrepared(ctx, stmt, key.Namespace, key.ObjectId)
+		stmt.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetVersionsByKey -- get the prior versions of the specified object, oldest first
+func (s *dbStorage) GetVersionsByKey(ctx context.Context, key DataStoreKey) ([]EasyStoreVersion, error) {
+
+	rows, err := s.conn().QueryContext(ctx, "SELECT vtag, created_at, updated_at FROM object_versions WHERE namespace = $1 AND oid = $2 ORDER BY updated_at, id", key.Namespace, key.ObjectId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]EasyStoreVersion, 0)
+	for rows.Next() {
+		var v EasyStoreVersion
+		err := rows.Scan(&v.VTag, &v.Created, &v.Modified)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// check for not found
+	if len(results) == 0 {
+		return nil, fmt.Errorf("%q: %w", "version(s) not found", ErrNotFound)
+	}
+
+	logDebug(s.log, fmt.Sprintf("found %d version(s)", len(results)))
+	return results, nil
+}
+
+// GetVersionByKey -- get the specified prior version of an object with all of its components
+func (s *dbStorage) GetVersionByKey(ctx context.Context, key DataStoreKey, vtag string) (EasyStoreObject, error) {
+
+	rows, err := s.conn().QueryContext(ctx, "SELECT namespace, oid, vtag, created_at, updated_at FROM object_versions WHERE namespace = $1 AND oid = $2 AND vtag = $3 LIMIT 1", key.Namespace, key.ObjectId, vtag)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := objectQueryResults(rows, s.log)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	// fields
+	rows, err = s.conn().QueryContext(ctx, "SELECT name, value FROM field_versions WHERE namespace = $1 AND oid = $2 AND vtag = $3 ORDER BY id", key.Namespace, key.ObjectId, vtag)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := fieldQueryResults(rows, s.log)
+	rows.Close()
+	if err != nil && errors.Is(err, ErrNotFound) == false {
+		return nil, err
+	}
+	if fields != nil {
+		obj.SetMultiFields(*fields)
+	}
+
+	// files and metadata
+	rows, err = s.conn().QueryContext(ctx, "SELECT name, mimetype, payload, checksum, created_at, updated_at FROM blob_versions WHERE namespace = $1 AND oid = $2 AND vtag = $3 ORDER BY updated_at", key.Namespace, key.ObjectId, vtag)
+	if err != nil {
+		return nil, err
+	}
+	blobs, err := blobQueryResults(rows, s.log)
+	rows.Close()
+	if err != nil && errors.Is(err, ErrNotFound) == false {
+		return nil, err
+	}
+
+	files := make([]EasyStoreBlob, 0)
+	for _, blob := range blobs {
+		b, _ := blob.(easyStoreBlobImpl)
+		if b.Name_ == blobMetadataName {
+			obj.SetMetadata(&easyStoreMetadataImpl{
+				MimeType_: b.MimeType_,
+				Payload_:  b.Payload_,
+				Created_:  b.Created_,
+				Modified_: b.Modified_})
+			continue
+		}
+		files = append(files, blob)
+	}
+	if len(files) != 0 {
+		obj.SetFiles(files)
+	}
+
+	return obj, nil
+}
+
+//
+// end of file
+//
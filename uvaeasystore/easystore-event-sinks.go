@@ -0,0 +1,138 @@
+//
+// the local event sinks, for tests and installations without the message bus
+//
+
+// only include this file for service builds
+
+//go:build service
+// +build service
+
+package uvaeasystore
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// the webhook headers
+var WebhookEventHeader = "X-Easystore-Event"
+var WebhookSignatureHeader = "X-Easystore-Signature"
+
+// NewCallbackEventSink -- an event sink that calls the function for each event. An error from the
+// function leaves the event in the outbox
+func NewCallbackEventSink(callback func(EasyStoreEvent) error) EasyStoreEventSink {
+	return callbackEventSink{callback: callback}
+}
+
+// NewChannelEventSink -- an event sink that sends each event to the channel, the send blocks until
+// the event is received
+func NewChannelEventSink(events chan<- EasyStoreEvent) EasyStoreEventSink {
+	return NewCallbackEventSink(func(event EasyStoreEvent) error {
+		events <- event
+		return nil
+	})
+}
+
+// NewFileEventSink -- an event sink that appends each event to the file as a line of JSON
+func NewFileEventSink(filename string) (EasyStoreEventSink, error) {
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &fileEventSink{file: file, lock: &sync.Mutex{}}, nil
+}
+
+// NewWebhookEventSink -- an event sink that POSTs each event as JSON to the url. When there is a secret
+// the body is signed (see SignEventPayload) and the signature is in the X-Easystore-Signature header
+func NewWebhookEventSink(url string, secret string, timeout int) EasyStoreEventSink {
+	return webhookEventSink{url: url, secret: secret, client: newHTTPClient(timeout)}
+}
+
+// SignEventPayload -- the signature of a webhook body, the hex encoded HMAC-SHA256 prefixed with "sha256="
+func SignEventPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return fmt.Sprintf("sha256=%s", hex.EncodeToString(mac.Sum(nil)))
+}
+
+// VerifyEventPayload -- check the signature of a webhook body, for receivers
+func VerifyEventPayload(secret string, payload []byte, signature string) bool {
+	return hmac.Equal([]byte(SignEventPayload(secret, payload)), []byte(signature))
+}
+
+//
+// private implementation methods
+//
+
+type callbackEventSink struct {
+	callback func(EasyStoreEvent) error
+}
+
+func (s callbackEventSink) PublishEvent(event EasyStoreEvent) error {
+	return s.callback(event)
+}
+
+func (s callbackEventSink) Close() error {
+	return nil
+}
+
+type fileEventSink struct {
+	file *os.File    // the events file
+	lock *sync.Mutex // each event is written in one piece
+}
+
+func (s *fileEventSink) PublishEvent(event EasyStoreEvent) error {
+	buf, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("%q: %w", err.Error(), ErrSerialize)
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	_, err = s.file.Write(append(buf, '\n'))
+	return err
+}
+
+func (s *fileEventSink) Close() error {
+	return s.file.Close()
+}
+
+type webhookEventSink struct {
+	url    string       // where to send the events
+	secret string       // the signing secret (blank if not signed)
+	client *http.Client // the http client
+}
+
+func (s webhookEventSink) PublishEvent(event EasyStoreEvent) error {
+	buf, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("%q: %w", err.Error(), ErrSerialize)
+	}
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, s.url, bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	req.Header.Add("content-type", jsonContentType)
+	req.Header.Add(WebhookEventHeader, event.Name)
+	if len(s.secret) != 0 {
+		req.Header.Add(WebhookSignatureHeader, SignEventPayload(s.secret, buf))
+	}
+	_, err = httpSend(s.client, req)
+	return err
+}
+
+func (s webhookEventSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+//
+// end of file
+//
@@ -14,8 +14,27 @@ import (
 	"fmt"
 	"github.com/uvalib/librabus-sdk/uvalibrabus"
 	"log"
+	"time"
 )
 
+// NewEventSink -- the event sink for the configuration, the configured sink if there is one
+// otherwise the message bus. Returns nil if neither is configured
+func NewEventSink(config EasyStoreImplConfig) (EasyStoreEventSink, error) {
+	if sinkConfig, ok := config.(EasyStoreEventSinkConfig); ok == true && sinkConfig.EventSink() != nil {
+		return sinkConfig.EventSink(), nil
+	}
+	return NewBusEventSink(config.EventSource(), config.MessageBus(), config.Logger())
+}
+
+// NewBusEventSink -- an event sink that publishes to the message bus
+func NewBusEventSink(eventSource string, eventBus string, logger *log.Logger) (EasyStoreEventSink, error) {
+	bus, err := NewEventBus(eventSource, eventBus, logger)
+	if err != nil || bus == nil {
+		return nil, err
+	}
+	return busEventSink{bus: bus}, nil
+}
+
 func NewEventBus(eventSource string, eventBus string, logger *log.Logger) (uvalibrabus.UvaBus, error) {
 	// we will accept bad config and return nil quietly
 	if len(eventBus) == 0 {
@@ -28,128 +47,99 @@ func NewEventBus(eventSource string, eventBus string, logger *log.Logger) (uvali
 	return uvalibrabus.NewUvaBus(cfg)
 }
 
-func pubObjectCreate(bus uvalibrabus.UvaBus, obj EasyStoreObject) error {
-	if bus == nil {
-		return ErrBusNotConfigured
-	}
-	detail, err := eventPayload(obj)
-	if err != nil {
-		return fmt.Errorf("%q: %w", err, ErrSerialize)
-	}
-	ev := uvalibrabus.UvaBusEvent{
-		EventName:  uvalibrabus.EventObjectCreate,
-		Namespace:  obj.Namespace(),
-		Identifier: obj.Id(),
-		Detail:     detail,
-	}
-	return bus.PublishEvent(&ev)
+// an event to add to the outbox, its name and the detail specific to it
+type eventSpec struct {
+	name   string
+	detail EasyStoreEventDetail
 }
 
-func pubObjectUpdate(bus uvalibrabus.UvaBus, obj EasyStoreObject) error {
-	if bus == nil {
-		return ErrBusNotConfigured
-	}
-	detail, err := eventPayload(obj)
+// create a new event for the specified object, it is added to the outbox and published later
+func newEvent(name string, obj EasyStoreObject, detail EasyStoreEventDetail) (EasyStoreEvent, error) {
+	detail.VTag = obj.VTag()
+	buf, err := json.Marshal(detail)
 	if err != nil {
-		return fmt.Errorf("%q: %w", err, ErrSerialize)
-	}
-	ev := uvalibrabus.UvaBusEvent{
-		EventName:  uvalibrabus.EventObjectUpdate,
-		Namespace:  obj.Namespace(),
-		Identifier: obj.Id(),
-		Detail:     detail,
-	}
-	return bus.PublishEvent(&ev)
+		return EasyStoreEvent{}, fmt.Errorf("%q: %w", err, ErrSerialize)
+	}
+	return EasyStoreEvent{
+		Id:        newEventId(),
+		Name:      name,
+		Namespace: obj.Namespace(),
+		ObjectId:  obj.Id(),
+		Detail:    buf,
+		Created:   time.Now(),
+	}, nil
 }
 
-func pubObjectDelete(bus uvalibrabus.UvaBus, obj EasyStoreObject) error {
-	if bus == nil {
+// publish an event from the outbox
+func publishEvent(sink EasyStoreEventSink, event EasyStoreEvent) error {
+	if sink == nil {
 		return ErrBusNotConfigured
 	}
-	detail, err := eventPayload(obj)
-	if err != nil {
-		return fmt.Errorf("%q: %w", err, ErrSerialize)
-	}
-	ev := uvalibrabus.UvaBusEvent{
-		EventName:  uvalibrabus.EventObjectDelete,
-		Namespace:  obj.Namespace(),
-		Identifier: obj.Id(),
-		Detail:     detail,
-	}
-	return bus.PublishEvent(&ev)
+	return sink.PublishEvent(event)
 }
 
-func pubMetadataUpdate(bus uvalibrabus.UvaBus, obj EasyStoreObject) error {
-	if bus == nil {
-		return ErrBusNotConfigured
-	}
-	detail, err := eventPayload(obj)
-	if err != nil {
-		return fmt.Errorf("%q: %w", err, ErrSerialize)
-	}
-	ev := uvalibrabus.UvaBusEvent{
-		EventName:  uvalibrabus.EventMetadataUpdate,
-		Namespace:  obj.Namespace(),
-		Identifier: obj.Id(),
-		Detail:     detail,
-	}
-	return bus.PublishEvent(&ev)
+// an event that has been attempted the maximum number of times is parked, it is left for a replay
+func eventParked(event EasyStoreEvent, maxAttempts uint) bool {
+	return maxAttempts != 0 && event.Attempts >= maxAttempts
 }
 
-func pubFileCreate(bus uvalibrabus.UvaBus, obj EasyStoreObject) error {
-	if bus == nil {
-		return ErrBusNotConfigured
+// the page of events after skipping the first offset (limit of 0 is no limit)
+func pageEvents(events []EasyStoreEvent, offset uint, limit uint) []EasyStoreEvent {
+	if offset >= uint(len(events)) {
+		return events[:0]
 	}
-	detail, err := eventPayload(obj)
-	if err != nil {
-		return fmt.Errorf("%q: %w", err, ErrSerialize)
-	}
-	ev := uvalibrabus.UvaBusEvent{
-		EventName:  uvalibrabus.EventFileCreate,
-		Namespace:  obj.Namespace(),
-		Identifier: obj.Id(),
-		Detail:     detail,
+	events = events[offset:]
+	if limit != 0 && uint(len(events)) > limit {
+		events = events[:limit]
 	}
-	return bus.PublishEvent(&ev)
+	return events
 }
 
-func pubFileDelete(bus uvalibrabus.UvaBus, obj EasyStoreObject) error {
-	if bus == nil {
-		return ErrBusNotConfigured
+// the components an object has
+func objectComponents(obj EasyStoreObject) EasyStoreComponents {
+	which := BaseComponent
+	if len(obj.Fields()) != 0 {
+		which |= Fields
 	}
-	detail, err := eventPayload(obj)
-	if err != nil {
-		return fmt.Errorf("%q: %w", err, ErrSerialize)
+	if len(obj.Files()) != 0 {
+		which |= Files
 	}
-	ev := uvalibrabus.UvaBusEvent{
-		EventName:  uvalibrabus.EventFileCreate,
-		Namespace:  obj.Namespace(),
-		Identifier: obj.Id(),
-		Detail:     detail,
+	if obj.Metadata() != nil {
+		which |= Metadata
 	}
-	return bus.PublishEvent(&ev)
+	return which
 }
 
-func pubFileUpdate(bus uvalibrabus.UvaBus, obj EasyStoreObject) error {
-	if bus == nil {
-		return ErrBusNotConfigured
-	}
-	detail, err := eventPayload(obj)
-	if err != nil {
-		return fmt.Errorf("%q: %w", err, ErrSerialize)
+func blobNames(blobs []EasyStoreBlob) []string {
+	names := make([]string, 0, len(blobs))
+	for _, b := range blobs {
+		names = append(names, b.Name())
 	}
+	return names
+}
+
+func keyName(key DataStoreKey) string {
+	return fmt.Sprintf("%s/%s", key.Namespace, key.ObjectId)
+}
+
+// the message bus sink
+type busEventSink struct {
+	bus uvalibrabus.UvaBus
+}
+
+func (s busEventSink) PublishEvent(event EasyStoreEvent) error {
 	ev := uvalibrabus.UvaBusEvent{
-		EventName:  uvalibrabus.EventFileUpdate,
-		Namespace:  obj.Namespace(),
-		Identifier: obj.Id(),
-		Detail:     detail,
+		EventName:  event.Name,
+		Namespace:  event.Namespace,
+		Identifier: event.ObjectId,
+		EventTime:  event.Created.UTC().Format(time.RFC3339),
+		Detail:     event.Detail,
 	}
-	return bus.PublishEvent(&ev)
+	return s.bus.PublishEvent(&ev)
 }
 
-func eventPayload(obj EasyStoreObject) (json.RawMessage, error) {
-	pl := uvalibrabus.UvaStorageEvent{VTag: obj.VTag()}
-	return pl.Serialize()
+func (s busEventSink) Close() error {
+	return nil
 }
 
 //
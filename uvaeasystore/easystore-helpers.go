@@ -55,6 +55,25 @@ func newObjectId() string {
 	return fmt.Sprintf("oid-%s", xid.New().String())
 }
 
+func newEventId() string {
+	return fmt.Sprintf("evt-%s", xid.New().String())
+}
+
+// the names of the components, as used in the event detail and the access policy
+func componentNames(which EasyStoreComponents) []string {
+	names := make([]string, 0)
+	if (which & Fields) == Fields {
+		names = append(names, "fields")
+	}
+	if (which & Files) == Files {
+		names = append(names, "files")
+	}
+	if (which & Metadata) == Metadata {
+		names = append(names, "metadata")
+	}
+	return names
+}
+
 //
 // end of file
 //
@@ -0,0 +1,234 @@
+//
+// the event outbox, events are added in the same transaction as the change they describe and
+// published to the event sink once it is committed. Events that cannot be published are left for the relay
+//
+
+// only include this file for service builds
+
+//go:build service
+// +build service
+
+package uvaeasystore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// the number of pending events read from the outbox at a time
+var outboxPageSize = uint(100)
+
+// the longest the relay will wait between attempts, as a multiple of the interval
+var relayMaxBackoff = 16
+
+// events that fail this many times are parked, the relay moves on and they can only be replayed
+var relayMaxAttempts = uint(10)
+
+// EasyStoreRelay - publishes the events left in the outbox to the event sink
+type EasyStoreRelay interface {
+	Drain(ctx context.Context) (uint, error)                                     // publish all pending events, oldest first, parking those that keep failing
+	Replay(ctx context.Context, after time.Time, before time.Time) (uint, error) // publish the events created in the time range again
+	Run(ctx context.Context, interval time.Duration) error                       // drain the outbox at the interval until the context is done
+	Close() error                                                                // close the relay
+}
+
+// our relay implementation
+type easyStoreRelayImpl struct {
+	config    EasyStoreImplConfig // configuration info
+	store     DataStore           // storage/persistence implementation
+	eventSink EasyStoreEventSink  // where events are published
+}
+
+// NewEasyStoreRelay -- factory for the relay
+func NewEasyStoreRelay(config EasyStoreImplConfig) (EasyStoreRelay, error) {
+
+	// create the data store
+	store, err := NewDatastore(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// create the event sink, the relay is no use without one
+	sink, err := NewEventSink(config)
+	if err != nil {
+		_ = store.Close()
+		return nil, err
+	}
+	if sink == nil {
+		_ = store.Close()
+		return nil, ErrBusNotConfigured
+	}
+
+	logInfo(config.Logger(), fmt.Sprintf("new easystore relay"))
+	return easyStoreRelayImpl{config: config, store: store, eventSink: sink}, nil
+}
+
+func (impl easyStoreRelayImpl) Drain(ctx context.Context) (uint, error) {
+
+	var count uint
+	for {
+		events, err := impl.store.GetPendingEvents(ctx, relayMaxAttempts, outboxPageSize)
+		if err != nil {
+			// known error
+			if errors.Is(err, ErrNotFound) {
+				return count, nil
+			}
+			return count, err
+		}
+
+		// stop at the first failure so the events are delivered in order, unless the event is
+		// parked by the failure. Then it is the event rather than the sink at fault so move on
+		for _, ev := range events {
+			err = deliverEvent(ctx, impl.store, impl.eventSink, ev)
+			if err != nil {
+				// this attempt counts
+				ev.Attempts++
+				if eventParked(ev, relayMaxAttempts) == true {
+					logError(impl.config.Logger(), fmt.Sprintf("parking event %s after %d attempts (%s)", ev.Id, ev.Attempts, err.Error()))
+					continue
+				}
+				logError(impl.config.Logger(), fmt.Sprintf("publishing event %s (%s)", ev.Id, err.Error()))
+				return count, err
+			}
+			count++
+		}
+	}
+}
+
+func (impl easyStoreRelayImpl) Replay(ctx context.Context, after time.Time, before time.Time) (uint, error) {
+
+	// preflight validation
+	if after.Before(before) == false {
+		logError(impl.config.Logger(), "preflight failure")
+		return 0, ErrBadParameter
+	}
+
+	// a page at a time, replaying does not change the order so the offset is stable
+	var count uint
+	for {
+		events, err := impl.store.GetEventsByTime(ctx, after, before, count, outboxPageSize)
+		if err != nil {
+			// known error
+			if errors.Is(err, ErrNotFound) {
+				if count == 0 {
+					logInfo(impl.config.Logger(), fmt.Sprintf("no events to replay"))
+				}
+				return count, nil
+			}
+			return count, err
+		}
+
+		for _, ev := range events {
+			err = deliverEvent(ctx, impl.store, impl.eventSink, ev)
+			if err != nil {
+				logError(impl.config.Logger(), fmt.Sprintf("publishing event %s (%s)", ev.Id, err.Error()))
+				return count, err
+			}
+			count++
+		}
+		if uint(len(events)) < outboxPageSize {
+			return count, nil
+		}
+	}
+}
+
+func (impl easyStoreRelayImpl) Run(ctx context.Context, interval time.Duration) error {
+
+	// preflight validation
+	if interval <= 0 {
+		logError(impl.config.Logger(), "preflight failure")
+		return ErrBadParameter
+	}
+
+	// back off while the sink is failing
+	backoff := 1
+	for {
+		count, err := impl.Drain(ctx)
+		if err != nil {
+			if backoff < relayMaxBackoff {
+				backoff *= 2
+			}
+		} else {
+			backoff = 1
+		}
+		if count != 0 {
+			logInfo(impl.config.Logger(), fmt.Sprintf("published %d event(s)", count))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval * time.Duration(backoff)):
+		}
+	}
+}
+
+func (impl easyStoreRelayImpl) Close() error {
+	return impl.store.Close()
+}
+
+//
+// private implementation methods
+//
+
+// add the events to the outbox within the transaction, nothing is added when there is no sink to publish them to.
+// The object is as it is after the change and the prior vtag is blank when there was no prior version
+func (impl easyStoreImpl) queueEvents(ctx context.Context, tx DataStoreTx, obj EasyStoreObject, prior string, specs ...eventSpec) ([]EasyStoreEvent, error) {
+
+	if impl.eventSink == nil {
+		return nil, nil
+	}
+
+	events := make([]EasyStoreEvent, 0, len(specs))
+	for _, spec := range specs {
+		detail := spec.detail
+		detail.PriorVTag = prior
+		detail.Actor = ActorFromContext(ctx)
+		ev, err := newEvent(spec.name, obj, detail)
+		if err != nil {
+			return nil, err
+		}
+		err = tx.AddEvent(ctx, ev)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, ev)
+	}
+	return events, nil
+}
+
+// publish the events once the transaction is committed, errors are not too important because
+// any events not published are left in the outbox for the relay
+func (impl easyStoreImpl) deliverEvents(ctx context.Context, events []EasyStoreEvent) {
+	for _, ev := range events {
+		err := deliverEvent(ctx, impl.store, impl.eventSink, ev)
+		if err != nil {
+			logError(impl.config.Logger(), fmt.Sprintf("publishing event %s (%s)", ev.Id, err.Error()))
+			// leave the rest so they are delivered in order
+			return
+		}
+	}
+}
+
+// publish an event and record the outcome in the outbox
+func deliverEvent(ctx context.Context, store DataStore, sink EasyStoreEventSink, event EasyStoreEvent) error {
+
+	event.Attempts++
+	perr := publishEvent(sink, event)
+	if perr == nil {
+		now := time.Now()
+		event.Delivered = &now
+	}
+
+	err := store.UpdateEvent(ctx, event)
+	if perr != nil {
+		return perr
+	}
+	return err
+}
+
+//
+// end of file
+//
@@ -21,6 +21,19 @@ type GetObjectsResponse struct {
 
 type SearchObjectsResponse struct {
 	Results []easyStoreObjectImpl `json:"results"`
+	Next    string                `json:"next,omitempty"` // continuation token for the next page
+}
+
+type GetVersionsResponse struct {
+	Results []EasyStoreVersion `json:"results"`
+}
+
+type GetTrashResponse struct {
+	Results []EasyStoreTombstone `json:"results"`
+}
+
+type PurgeTrashResponse struct {
+	Count uint `json:"count"` // the number of objects purged
 }
 
 // we need a custom unmarshaler because the implementation specifies some fields as interfaces
@@ -38,30 +51,45 @@ func (obj *easyStoreObjectImpl) UnmarshalJSON(data []byte) error {
 	// then unmarshal each field we are interested in
 
 	// namespace (we always have this)
+	if objmap["namespace"] == nil {
+		return fmt.Errorf("%q: %w", "missing namespace", ErrDeserialize)
+	}
 	err = json.Unmarshal(*objmap["namespace"], &obj.Namespace_)
 	if err != nil {
 		return err
 	}
 
 	// id (we always have this)
+	if objmap["id"] == nil {
+		return fmt.Errorf("%q: %w", "missing id", ErrDeserialize)
+	}
 	err = json.Unmarshal(*objmap["id"], &obj.Id_)
 	if err != nil {
 		return err
 	}
 
 	// vtag (we always have this)
+	if objmap["vtag"] == nil {
+		return fmt.Errorf("%q: %w", "missing vtag", ErrDeserialize)
+	}
 	err = json.Unmarshal(*objmap["vtag"], &obj.Vtag_)
 	if err != nil {
 		return err
 	}
 
 	// created (we always have this)
+	if objmap["created"] == nil {
+		return fmt.Errorf("%q: %w", "missing created", ErrDeserialize)
+	}
 	err = json.Unmarshal(*objmap["created"], &obj.Created_)
 	if err != nil {
 		return err
 	}
 
 	// modified (we always have this)
+	if objmap["modified"] == nil {
+		return fmt.Errorf("%q: %w", "missing modified", ErrDeserialize)
+	}
 	err = json.Unmarshal(*objmap["modified"], &obj.Modified_)
 	if err != nil {
 		return err
@@ -105,6 +133,9 @@ func (obj *easyStoreObjectImpl) UnmarshalJSON(data []byte) error {
 // maps http reponse payload into an easystore error (if possible)
 func mapResponseToError(strErr string) error {
 
+	if strings.Contains(strErr, ErrNotAuthorized.Error()) {
+		return ErrNotAuthorized
+	}
 	if strings.Contains(strErr, ErrNotImplemented.Error()) {
 		return ErrNotImplemented
 	}
@@ -117,18 +148,22 @@ func mapResponseToError(strErr string) error {
 	if strings.Contains(strErr, ErrNotFound.Error()) {
 		return ErrNotFound
 	}
+	if strings.Contains(strErr, ErrChecksum.Error()) {
+		return ErrChecksum
+	}
 	if strings.Contains(strErr, ErrStaleObject.Error()) {
 		return ErrStaleObject
 	}
 	if strings.Contains(strErr, ErrAlreadyExists.Error()) {
 		return ErrAlreadyExists
 	}
-	if strings.Contains(strErr, ErrSerialize.Error()) {
-		return ErrSerialize
-	}
+	// must come before serialize, the serialize error is a substring
 	if strings.Contains(strErr, ErrDeserialize.Error()) {
 		return ErrDeserialize
 	}
+	if strings.Contains(strErr, ErrSerialize.Error()) {
+		return ErrSerialize
+	}
 	if strings.Contains(strErr, ErrBusNotConfigured.Error()) {
 		return ErrBusNotConfigured
 	}
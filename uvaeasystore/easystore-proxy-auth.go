@@ -0,0 +1,76 @@
+//
+// authenticators used by the easystore service to identify the principal making a request
+//
+
+package uvaeasystore
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// bearer tokens, each identifies a principal
+func newTokenAuthenticator(tokens map[string]string) EasyStoreAuthenticator {
+	return func(r *http.Request) (string, error) {
+		auth := r.Header.Get("Authorization")
+		if strings.HasPrefix(auth, "Bearer ") == false {
+			return "", nil
+		}
+		token := strings.TrimPrefix(auth, "Bearer ")
+		for t, principal := range tokens {
+			if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+				return principal, nil
+			}
+		}
+		return "", fmt.Errorf("%q: %w", "unknown bearer token", ErrNotAuthorized)
+	}
+}
+
+// basic auth users and their passwords, the user is the principal
+func newBasicAuthenticator(users map[string]string) EasyStoreAuthenticator {
+	return func(r *http.Request) (string, error) {
+		user, password, ok := r.BasicAuth()
+		if ok == false {
+			return "", nil
+		}
+		expected, found := users[user]
+		if found == false || subtle.ConstantTimeCompare([]byte(expected), []byte(password)) != 1 {
+			return "", fmt.Errorf("%q: %w", fmt.Sprintf("bad credentials for [%s]", user), ErrNotAuthorized)
+		}
+		return user, nil
+	}
+}
+
+// client certificates verified by the TLS server, the subject common name is the principal
+func newCertificateAuthenticator() EasyStoreAuthenticator {
+	return func(r *http.Request) (string, error) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+			return "", nil
+		}
+		principal := r.TLS.VerifiedChains[0][0].Subject.CommonName
+		if len(principal) == 0 {
+			return "", fmt.Errorf("%q: %w", "client certificate has no common name", ErrNotAuthorized)
+		}
+		return principal, nil
+	}
+}
+
+// the principal identified by the first authenticator that recognizes the request credentials
+func authenticate(r *http.Request, authenticators []EasyStoreAuthenticator) (string, error) {
+	for _, authenticator := range authenticators {
+		principal, err := authenticator(r)
+		if err != nil {
+			return "", err
+		}
+		if len(principal) != 0 {
+			return principal, nil
+		}
+	}
+	return "", fmt.Errorf("%q: %w", "no credentials", ErrNotAuthorized)
+}
+
+//
+// end of file
+//
@@ -1,12 +1,17 @@
 package uvaeasystore
 
 import (
-	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	neturl "net/url"
+	"os"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -14,6 +19,17 @@ var maxHttpRetries = 3
 var retrySleepTime = 100 * time.Millisecond
 var jsonContentType = "application/json"
 
+// the actor the changes are made on behalf of (see WithActor)
+var actorHeader = "X-Easystore-Actor"
+
+// the principal the requests are made by (see WithPrincipal), the service checks it against the credentials
+var principalHeader = "X-Easystore-Principal"
+
+// the parts of a multipart object request, and the header naming the file in a file part
+var objectPartName = "object"
+var filePartName = "file"
+var fileNameHeader = "X-Easystore-File"
+
 func newHTTPClient(timeout int) *http.Client {
 	defaultTransport := &http.Transport{
 		Dial: (&net.Dialer{
@@ -30,34 +46,93 @@ func newHTTPClient(timeout int) *http.Client {
 	}
 }
 
-func httpGet(client *http.Client, url string) ([]byte, error) {
+// the http client for a proxy, with the configured TLS and credentials
+func newProxyHTTPClient(config EasyStoreProxyConfig) (*http.Client, error) {
+
+	client := newHTTPClient(config.Timeout())
+
+	// authentication settings are optional
+	auth, ok := config.(EasyStoreProxyAuthConfig)
+	if ok == false {
+		return client, nil
+	}
+
+	// client certificates and/or our own CA bundle
+	certFile, keyFile := auth.ClientCertificate()
+	if len(certFile) != 0 || len(auth.CACertificate()) != 0 {
+		tlsConfig, err := newClientTLSConfig(certFile, keyFile, auth.CACertificate())
+		if err != nil {
+			return nil, err
+		}
+		client.Transport.(*http.Transport).TLSClientConfig = tlsConfig
+	}
+
+	// credentials are added to each request
+	user, password := auth.BasicAuth()
+	if len(auth.Token()) != 0 || auth.TokenSource() != nil || len(user) != 0 {
+		client.Transport = &authTransport{
+			base:     client.Transport,
+			token:    auth.Token(),
+			source:   auth.TokenSource(),
+			user:     user,
+			password: password,
+		}
+	}
+	return client, nil
+}
+
+func newClientTLSConfig(certFile string, keyFile string, caFile string) (*tls.Config, error) {
 
-	req, err := http.NewRequest("GET", url, nil)
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if len(certFile) != 0 {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", fmt.Sprintf("loading client certificate (%s)", err.Error()), ErrBadParameter)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if len(caFile) != 0 {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", fmt.Sprintf("loading CA bundle (%s)", err.Error()), ErrBadParameter)
+		}
+		pool := x509.NewCertPool()
+		if pool.AppendCertsFromPEM(pem) == false {
+			return nil, fmt.Errorf("%q: %w", fmt.Sprintf("no certificates in CA bundle [%s]", caFile), ErrBadParameter)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return tlsConfig, nil
+}
+
+func httpGet(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		fmt.Printf("ERROR: GET %s failed with error (%s)\n", url, err)
+		fmt.Printf("ERROR: GET %s failed with error (%s)\n", redactURL(url), err)
 		return nil, err
 	}
 
 	return httpSend(client, req)
 }
 
-func httpDelete(client *http.Client, url string) ([]byte, error) {
+func httpDelete(ctx context.Context, client *http.Client, url string) ([]byte, error) {
 
-	req, err := http.NewRequest("DELETE", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
 	if err != nil {
-		fmt.Printf("ERROR: DELETE %s failed with error (%s)\n", url, err)
+		fmt.Printf("ERROR: DELETE %s failed with error (%s)\n", redactURL(url), err)
 		return nil, err
 	}
 
 	return httpSend(client, req)
 }
 
-func httpPost(client *http.Client, url string, payload []byte, contentType string) ([]byte, error) {
+func httpPost(ctx context.Context, client *http.Client, url string, payload io.Reader, contentType string) ([]byte, error) {
 
-	reader := bytes.NewReader(payload)
-	req, err := http.NewRequest("POST", url, reader)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, payload)
 	if err != nil {
-		fmt.Printf("ERROR: POST %s failed with error (%s)\n", url, err)
+		fmt.Printf("ERROR: POST %s failed with error (%s)\n", redactURL(url), err)
 		return nil, err
 	}
 
@@ -69,12 +144,11 @@ func httpPost(client *http.Client, url string, payload []byte, contentType strin
 	return httpSend(client, req)
 }
 
-func httpPut(client *http.Client, url string, payload []byte, contentType string) ([]byte, error) {
+func httpPut(ctx context.Context, client *http.Client, url string, payload io.Reader, contentType string) ([]byte, error) {
 
-	reader := bytes.NewReader(payload)
-	req, err := http.NewRequest("PUT", url, reader)
+	req, err := http.NewRequestWithContext(ctx, "PUT", url, payload)
 	if err != nil {
-		fmt.Printf("ERROR: PUT %s failed with error (%s)\n", url, err)
+		fmt.Printf("ERROR: PUT %s failed with error (%s)\n", redactURL(url), err)
 		return nil, err
 	}
 
@@ -90,7 +164,17 @@ func httpSend(client *http.Client, req *http.Request) ([]byte, error) {
 
 	var response *http.Response
 	var err error
-	url := req.URL.String()
+	url := req.URL.Redacted()
+
+	// the service records the actor in the events
+	if actor := ActorFromContext(req.Context()); len(actor) != 0 {
+		req.Header.Set(actorHeader, actor)
+	}
+
+	// and checks the principal against its access policy
+	if principal := PrincipalFromContext(req.Context()); len(principal) != 0 {
+		req.Header.Set(principalHeader, principal)
+	}
 	count := 0
 	for {
 		//start := time.Now()
@@ -110,10 +194,28 @@ func httpSend(client *http.Client, req *http.Request) ([]byte, error) {
 				return nil, err
 			}
 
+			// a streamed payload cannot be sent again
+			if req.Body != nil && req.GetBody == nil {
+				fmt.Printf("ERROR: %s %s failed with error, cannot retry (%s)\n", req.Method, url, err)
+				return nil, err
+			}
+
 			fmt.Printf("ERROR: %s %s failed with error, retrying (%s)\n", req.Method, url, err)
 
-			// sleep for a bit before retrying
-			time.Sleep(retrySleepTime)
+			// sleep for a bit before retrying, give up if the context is done
+			select {
+			case <-req.Context().Done():
+				return nil, req.Context().Err()
+			case <-time.After(retrySleepTime):
+			}
+
+			// rewind the payload
+			if req.GetBody != nil {
+				req.Body, err = req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+			}
 		} else {
 
 			defer response.Body.Close()
@@ -171,6 +273,105 @@ func canRetry(err error) bool {
 	return false
 }
 
+// the url with any password removed, urls are logged but credentials must never be
+func redactURL(url string) string {
+	u, err := neturl.Parse(url)
+	if err != nil {
+		return "(unparsable url)"
+	}
+	return u.Redacted()
+}
+
+// adds the credentials to each request, a bearer token from a token source is refreshed when the
+// service rejects it and the request is sent again
+type authTransport struct {
+	base     http.RoundTripper    // the underlying transport
+	lock     sync.Mutex           // protects the token
+	token    string               // the current bearer token (blank if there is none yet)
+	source   EasyStoreTokenSource // the token source (nil for a static token)
+	user     string               // basic auth user
+	password string               // basic auth password
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	token, err := t.currentToken(req.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := t.base.RoundTrip(t.authorize(req, token))
+	if err != nil || response.StatusCode != http.StatusUnauthorized || t.source == nil {
+		return response, err
+	}
+
+	// the token has probably expired, get another
+	token, err = t.refreshToken(req.Context(), token)
+	if err != nil {
+		response.Body.Close()
+		return nil, err
+	}
+
+	// a streamed payload cannot be sent again, the next request will use the new token
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return response, nil
+	}
+	response.Body.Close()
+
+	retry := t.authorize(req, token)
+	if req.GetBody != nil {
+		retry.Body, err = req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return t.base.RoundTrip(retry)
+}
+
+func (t *authTransport) CloseIdleConnections() {
+	if c, ok := t.base.(interface{ CloseIdleConnections() }); ok == true {
+		c.CloseIdleConnections()
+	}
+}
+
+// a copy of the request with the credentials, the original request is not changed
+func (t *authTransport) authorize(req *http.Request, token string) *http.Request {
+	r := req.Clone(req.Context())
+	if len(token) != 0 {
+		r.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	} else if len(t.user) != 0 {
+		r.SetBasicAuth(t.user, t.password)
+	}
+	return r
+}
+
+func (t *authTransport) currentToken(ctx context.Context) (string, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if len(t.token) == 0 && t.source != nil {
+		token, err := t.source(ctx)
+		if err != nil {
+			return "", err
+		}
+		t.token = token
+	}
+	return t.token, nil
+}
+
+// replace the rejected token, unless another request already has
+func (t *authTransport) refreshToken(ctx context.Context, rejected string) (string, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.token == rejected {
+		token, err := t.source(ctx)
+		if err != nil {
+			return "", err
+		}
+		t.token = token
+	}
+	return t.token, nil
+}
+
 //
 // end of file
 //
@@ -0,0 +1,719 @@
+//
+// HTTP service implementation of the REST contract spoken by the easystore proxy
+//
+
+package uvaeasystore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// this is our service implementation
+type easyStoreServiceImpl struct {
+	store          EasyStore                // the easystore we are serving
+	authenticators []EasyStoreAuthenticator // identify the request principal
+	log            *log.Logger              // logger
+}
+
+// factory for our service handler
+func newEasyStoreService(store EasyStore, logger *log.Logger, authenticators []EasyStoreAuthenticator) http.Handler {
+	logInfo(logger, fmt.Sprintf("new easystore service (%d authenticators)", len(authenticators)))
+	return &easyStoreServiceImpl{store: store, authenticators: authenticators, log: logger}
+}
+
+// ServeHTTP -- route the request to the appropriate handler. Routes are as follows:
+//
+//	GET    /healthcheck                         health check
+//	POST   /{ns}                                create object (multipart when files are streamed)
+//	PUT    /{ns}                                get objects by ids
+//	PUT    /{ns}/search?limit=&token=&order=    get objects by fields
+//	PUT    /{ns}/query?limit=&token=&order=     get objects by query
+//	GET    /{ns}/trash                          get deleted objects
+//	DELETE /{ns}/trash?before=                  purge objects deleted before the time (RFC3339)
+//	GET    /{ns}/{id}?attribs=                  get object
+//	PUT    /{ns}/{id}?attribs=                  update object (multipart when files are streamed)
+//	DELETE /{ns}/{id}?vtag=&attribs=            delete object
+//	POST   /{ns}/{id}/file?name=                create file (raw payload when name is specified)
+//	PUT    /{ns}/{id}/file?name=                update file (raw payload when name is specified)
+//	GET    /{ns}/{id}/file/{name}               get file
+//	DELETE /{ns}/{id}/file/{name}               delete file
+//	POST   /{ns}/{id}/file/{name}?new=          rename file
+//	GET    /{ns}/{id}/versions                  list versions
+//	GET    /{ns}/{id}/versions/{vtag}?attribs=  get object version
+//	POST   /{ns}/{id}/versions/{vtag}           restore object version
+//	POST   /{ns}/{id}/restore                   restore deleted object
+//	DELETE /{ns}/{id}/purge                     purge deleted object
+//	POST   /{ns}/{id}/copy?ns=&id=              copy object (blank id for a new identifier)
+//	POST   /{ns}/{id}/move?ns=&id=              move object (blank id for a new identifier)
+func (impl *easyStoreServiceImpl) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	// changes are made on behalf of the client's actor
+	if actor := r.Header.Get(actorHeader); len(actor) != 0 {
+		r = r.WithContext(WithActor(r.Context(), actor))
+	}
+
+	// namespaces can be blank for searches so we cannot use a standard mux (it cleans the path)
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+
+	// and requests are made by the principal the credentials identify, the principal the client claims
+	// is only a cross check because anyone can send it
+	if len(impl.authenticators) != 0 && (len(parts) != 1 || parts[0] != "healthcheck") {
+		principal, err := authenticate(r, impl.authenticators)
+		if err != nil {
+			logWarning(impl.log, fmt.Sprintf("%s %s: %s", r.Method, r.URL.Path, err.Error()))
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if claimed := r.Header.Get(principalHeader); len(claimed) != 0 && claimed != principal {
+			err = fmt.Errorf("%q: %w", fmt.Sprintf("principal [%s] does not match the credentials", claimed), ErrNotAuthorized)
+			logWarning(impl.log, fmt.Sprintf("%s %s: %s", r.Method, r.URL.Path, err.Error()))
+			impl.errorResponse(w, err)
+			return
+		}
+		r = r.WithContext(WithPrincipal(r.Context(), principal))
+	}
+
+	switch {
+	case len(parts) == 1 && parts[0] == "healthcheck" && r.Method == http.MethodGet:
+		impl.healthCheck(w, r)
+
+	case len(parts) == 1 && r.Method == http.MethodPost:
+		impl.objectCreate(w, r, parts[0])
+
+	case len(parts) == 1 && r.Method == http.MethodPut:
+		impl.objectGetByKeys(w, r, parts[0])
+
+	case len(parts) == 2 && parts[1] == "search" && r.Method == http.MethodPut:
+		impl.objectGetByFields(w, r, parts[0])
+
+	case len(parts) == 2 && parts[1] == "query" && r.Method == http.MethodPut:
+		impl.objectGetByQuery(w, r, parts[0])
+
+	case len(parts) == 2 && parts[1] == "trash" && r.Method == http.MethodGet:
+		impl.objectTrash(w, r, parts[0])
+
+	case len(parts) == 2 && parts[1] == "trash" && r.Method == http.MethodDelete:
+		impl.objectPurgeTrash(w, r, parts[0])
+
+	case len(parts) == 2 && r.Method == http.MethodGet:
+		impl.objectGetByKey(w, r, parts[0], parts[1])
+
+	case len(parts) == 2 && r.Method == http.MethodPut:
+		impl.objectUpdate(w, r, parts[0], parts[1])
+
+	case len(parts) == 2 && r.Method == http.MethodDelete:
+		impl.objectDelete(w, r, parts[0], parts[1])
+
+	case len(parts) == 3 && parts[2] == "file" && r.Method == http.MethodPost:
+		impl.fileCreate(w, r, parts[0], parts[1])
+
+	case len(parts) == 3 && parts[2] == "file" && r.Method == http.MethodPut:
+		impl.fileUpdate(w, r, parts[0], parts[1])
+
+	case len(parts) > 3 && parts[2] == "file" && r.Method == http.MethodGet:
+		impl.fileGetByKey(w, r, parts[0], parts[1], strings.Join(parts[3:], "/"))
+
+	case len(parts) > 3 && parts[2] == "file" && r.Method == http.MethodDelete:
+		impl.fileDelete(w, r, parts[0], parts[1], strings.Join(parts[3:], "/"))
+
+	case len(parts) > 3 && parts[2] == "file" && r.Method == http.MethodPost:
+		impl.fileRename(w, r, parts[0], parts[1], strings.Join(parts[3:], "/"))
+
+	case len(parts) == 3 && parts[2] == "versions" && r.Method == http.MethodGet:
+		impl.objectVersions(w, r, parts[0], parts[1])
+
+	case len(parts) == 4 && parts[2] == "versions" && r.Method == http.MethodGet:
+		impl.objectGetByVersion(w, r, parts[0], parts[1], parts[3])
+
+	case len(parts) == 4 && parts[2] == "versions" && r.Method == http.MethodPost:
+		impl.objectVersionRestore(w, r, parts[0], parts[1], parts[3])
+
+	case len(parts) == 3 && parts[2] == "restore" && r.Method == http.MethodPost:
+		impl.objectRestore(w, r, parts[0], parts[1])
+
+	case len(parts) == 3 && parts[2] == "purge" && r.Method == http.MethodDelete:
+		impl.objectPurge(w, r, parts[0], parts[1])
+
+	case len(parts) == 3 && parts[2] == "copy" && r.Method == http.MethodPost:
+		impl.objectCopy(w, r, parts[0], parts[1])
+
+	case len(parts) == 3 && parts[2] == "move" && r.Method == http.MethodPost:
+		impl.objectMove(w, r, parts[0], parts[1])
+
+	default:
+		logWarning(impl.log, fmt.Sprintf("unsupported request %s %s", r.Method, r.URL.Path))
+		http.Error(w, fmt.Sprintf("%s %s: %s", r.Method, r.URL.Path, ErrNotImplemented.Error()), http.StatusNotFound)
+	}
+}
+
+//
+// route handlers
+//
+
+func (impl *easyStoreServiceImpl) healthCheck(w http.ResponseWriter, r *http.Request) {
+	err := impl.store.CheckCtx(r.Context())
+	if err != nil {
+		impl.errorResponse(w, err)
+		return
+	}
+	impl.jsonResponse(w, http.StatusOK, struct{}{})
+}
+
+func (impl *easyStoreServiceImpl) objectCreate(w http.ResponseWriter, r *http.Request, namespace string) {
+
+	req, cleanup, ok := impl.decodeObject(w, r)
+	if ok == false {
+		return
+	}
+	defer cleanup()
+
+	// the url namespace is authoritative
+	req.SetNamespace(namespace)
+
+	obj, err := impl.store.ObjectCreateCtx(r.Context(), req)
+	if err != nil {
+		impl.errorResponse(w, err)
+		return
+	}
+	impl.jsonResponse(w, http.StatusOK, obj)
+}
+
+func (impl *easyStoreServiceImpl) objectGetByKeys(w http.ResponseWriter, r *http.Request, namespace string) {
+
+	var req GetObjectsRequest
+	if impl.decodeRequest(w, r, &req) == false {
+		return
+	}
+
+	// the proxy gets the remaining components lazily
+	set, err := impl.store.ObjectGetByKeysCtx(r.Context(), namespace, req.Ids, BaseComponent)
+	if err != nil {
+		impl.errorResponse(w, err)
+		return
+	}
+
+	results, err := serviceObjectList(set)
+	if err != nil {
+		impl.errorResponse(w, err)
+		return
+	}
+	impl.jsonResponse(w, http.StatusOK, GetObjectsResponse{Results: results})
+}
+
+func (impl *easyStoreServiceImpl) objectGetByFields(w http.ResponseWriter, r *http.Request, namespace string) {
+
+	page, err := servicePage(r.URL.Query())
+	if err != nil {
+		impl.errorResponse(w, err)
+		return
+	}
+
+	req := DefaultEasyStoreFields()
+	if impl.decodeRequest(w, r, &req) == false {
+		return
+	}
+
+	// the proxy gets the remaining components lazily
+	set, err := impl.store.ObjectGetByFieldsPageCtx(r.Context(), namespace, req, BaseComponent, page)
+	if err != nil {
+		impl.errorResponse(w, err)
+		return
+	}
+
+	results, err := serviceObjectList(set)
+	if err != nil {
+		impl.errorResponse(w, err)
+		return
+	}
+	impl.jsonResponse(w, http.StatusOK, SearchObjectsResponse{Results: results, Next: set.Continuation()})
+}
+
+func (impl *easyStoreServiceImpl) objectGetByQuery(w http.ResponseWriter, r *http.Request, namespace string) {
+
+	page, err := servicePage(r.URL.Query())
+	if err != nil {
+		impl.errorResponse(w, err)
+		return
+	}
+
+	var req EasyStoreQuery
+	if impl.decodeRequest(w, r, &req) == false {
+		return
+	}
+
+	// the proxy gets the remaining components lazily
+	set, err := impl.store.ObjectGetByQueryCtx(r.Context(), namespace, req, BaseComponent, page)
+	if err != nil {
+		impl.errorResponse(w, err)
+		return
+	}
+
+	results, err := serviceObjectList(set)
+	if err != nil {
+		impl.errorResponse(w, err)
+		return
+	}
+	impl.jsonResponse(w, http.StatusOK, SearchObjectsResponse{Results: results, Next: set.Continuation()})
+}
+
+func (impl *easyStoreServiceImpl) objectGetByKey(w http.ResponseWriter, r *http.Request, namespace string, id string) {
+
+	which, err := serviceComponents(r.URL.Query().Get("attribs"))
+	if err != nil {
+		impl.errorResponse(w, err)
+		return
+	}
+
+	obj, err := impl.store.ObjectGetByKeyCtx(r.Context(), namespace, id, which)
+	if err != nil {
+		impl.errorResponse(w, err)
+		return
+	}
+	impl.jsonResponse(w, http.StatusOK, obj)
+}
+
+func (impl *easyStoreServiceImpl) objectUpdate(w http.ResponseWriter, r *http.Request, namespace string, id string) {
+
+	which, err := serviceComponents(r.URL.Query().Get("attribs"))
+	if err != nil {
+		impl.errorResponse(w, err)
+		return
+	}
+
+	req, cleanup, ok := impl.decodeObject(w, r)
+	if ok == false {
+		return
+	}
+	defer cleanup()
+
+	// the url namespace and identifier are authoritative
+	req.Namespace_, req.Id_ = namespace, id
+
+	obj, err := impl.store.ObjectUpdateCtx(r.Context(), req, which)
+	if err != nil {
+		impl.errorResponse(w, err)
+		return
+	}
+	impl.jsonResponse(w, http.StatusOK, obj)
+}
+
+func (impl *easyStoreServiceImpl) objectDelete(w http.ResponseWriter, r *http.Request, namespace string, id string) {
+
+	which, err := serviceComponents(r.URL.Query().Get("attribs"))
+	if err != nil {
+		impl.errorResponse(w, err)
+		return
+	}
+
+	obj, err := impl.store.ObjectDeleteCtx(r.Context(), ProxyEasyStoreObject(namespace, id, r.URL.Query().Get("vtag")), which)
+	if err != nil {
+		impl.errorResponse(w, err)
+		return
+	}
+	impl.jsonResponse(w, http.StatusOK, obj)
+}
+
+func (impl *easyStoreServiceImpl) fileCreate(w http.ResponseWriter, r *http.Request, namespace string, id string) {
+
+	file, ok := impl.decodeFile(w, r)
+	if ok == false {
+		return
+	}
+
+	err := impl.store.FileCreateCtx(r.Context(), namespace, id, file)
+	if err != nil {
+		impl.errorResponse(w, err)
+		return
+	}
+	impl.jsonResponse(w, http.StatusOK, struct{}{})
+}
+
+func (impl *easyStoreServiceImpl) fileUpdate(w http.ResponseWriter, r *http.Request, namespace string, id string) {
+
+	file, ok := impl.decodeFile(w, r)
+	if ok == false {
+		return
+	}
+
+	err := impl.store.FileUpdateCtx(r.Context(), namespace, id, file)
+	if err != nil {
+		impl.errorResponse(w, err)
+		return
+	}
+	impl.jsonResponse(w, http.StatusOK, struct{}{})
+}
+
+func (impl *easyStoreServiceImpl) fileGetByKey(w http.ResponseWriter, r *http.Request, namespace string, id string, name string) {
+
+	file, err := impl.store.FileGetByKeyCtx(r.Context(), namespace, id, name)
+	if err != nil {
+		impl.errorResponse(w, err)
+		return
+	}
+	impl.jsonResponse(w, http.StatusOK, file)
+}
+
+func (impl *easyStoreServiceImpl) fileDelete(w http.ResponseWriter, r *http.Request, namespace string, id string, name string) {
+
+	err := impl.store.FileDeleteCtx(r.Context(), namespace, id, name)
+	if err != nil {
+		impl.errorResponse(w, err)
+		return
+	}
+	impl.jsonResponse(w, http.StatusOK, struct{}{})
+}
+
+func (impl *easyStoreServiceImpl) fileRename(w http.ResponseWriter, r *http.Request, namespace string, id string, name string) {
+
+	err := impl.store.FileRenameCtx(r.Context(), namespace, id, name, r.URL.Query().Get("new"))
+	if err != nil {
+		impl.errorResponse(w, err)
+		return
+	}
+	impl.jsonResponse(w, http.StatusOK, struct{}{})
+}
+
+func (impl *easyStoreServiceImpl) objectVersions(w http.ResponseWriter, r *http.Request, namespace string, id string) {
+
+	versions, err := impl.store.ObjectVersionsCtx(r.Context(), namespace, id)
+	if err != nil {
+		impl.errorResponse(w, err)
+		return
+	}
+	impl.jsonResponse(w, http.StatusOK, GetVersionsResponse{Results: versions})
+}
+
+func (impl *easyStoreServiceImpl) objectGetByVersion(w http.ResponseWriter, r *http.Request, namespace string, id string, vtag string) {
+
+	which, err := serviceComponents(r.URL.Query().Get("attribs"))
+	if err != nil {
+		impl.errorResponse(w, err)
+		return
+	}
+
+	obj, err := impl.store.ObjectGetByVersionCtx(r.Context(), namespace, id, vtag, which)
+	if err != nil {
+		impl.errorResponse(w, err)
+		return
+	}
+	impl.jsonResponse(w, http.StatusOK, obj)
+}
+
+func (impl *easyStoreServiceImpl) objectVersionRestore(w http.ResponseWriter, r *http.Request, namespace string, id string, vtag string) {
+
+	obj, err := impl.store.ObjectVersionRestoreCtx(r.Context(), namespace, id, vtag)
+	if err != nil {
+		impl.errorResponse(w, err)
+		return
+	}
+	impl.jsonResponse(w, http.StatusOK, obj)
+}
+
+func (impl *easyStoreServiceImpl) objectTrash(w http.ResponseWriter, r *http.Request, namespace string) {
+
+	tombstones, err := impl.store.ObjectTrashCtx(r.Context(), namespace)
+	if err != nil {
+		impl.errorResponse(w, err)
+		return
+	}
+	impl.jsonResponse(w, http.StatusOK, GetTrashResponse{Results: tombstones})
+}
+
+func (impl *easyStoreServiceImpl) objectPurgeTrash(w http.ResponseWriter, r *http.Request, namespace string) {
+
+	before, err := time.Parse(time.RFC3339, r.URL.Query().Get("before"))
+	if err != nil {
+		impl.errorResponse(w, fmt.Errorf("%q: %w", fmt.Sprintf("bad time [%s]", r.URL.Query().Get("before")), ErrBadParameter))
+		return
+	}
+
+	count, err := impl.store.ObjectPurgeTrashCtx(r.Context(), namespace, before)
+	if err != nil {
+		impl.errorResponse(w, err)
+		return
+	}
+	impl.jsonResponse(w, http.StatusOK, PurgeTrashResponse{Count: count})
+}
+
+func (impl *easyStoreServiceImpl) objectRestore(w http.ResponseWriter, r *http.Request, namespace string, id string) {
+
+	obj, err := impl.store.ObjectRestoreCtx(r.Context(), namespace, id)
+	if err != nil {
+		impl.errorResponse(w, err)
+		return
+	}
+	impl.jsonResponse(w, http.StatusOK, obj)
+}
+
+func (impl *easyStoreServiceImpl) objectPurge(w http.ResponseWriter, r *http.Request, namespace string, id string) {
+
+	err := impl.store.ObjectPurgeCtx(r.Context(), namespace, id)
+	if err != nil {
+		impl.errorResponse(w, err)
+		return
+	}
+	impl.jsonResponse(w, http.StatusOK, struct{}{})
+}
+
+func (impl *easyStoreServiceImpl) objectCopy(w http.ResponseWriter, r *http.Request, namespace string, id string) {
+
+	obj, err := impl.store.ObjectCopyCtx(r.Context(), namespace, id, r.URL.Query().Get("ns"), r.URL.Query().Get("id"))
+	if err != nil {
+		impl.errorResponse(w, err)
+		return
+	}
+	impl.jsonResponse(w, http.StatusOK, obj)
+}
+
+func (impl *easyStoreServiceImpl) objectMove(w http.ResponseWriter, r *http.Request, namespace string, id string) {
+
+	obj, err := impl.store.ObjectMoveCtx(r.Context(), namespace, id, r.URL.Query().Get("ns"), r.URL.Query().Get("id"))
+	if err != nil {
+		impl.errorResponse(w, err)
+		return
+	}
+	impl.jsonResponse(w, http.StatusOK, obj)
+}
+
+//
+// private methods
+//
+
+func (impl *easyStoreServiceImpl) decodeRequest(w http.ResponseWriter, r *http.Request, req any) bool {
+
+	buf, err := io.ReadAll(r.Body)
+	if err != nil {
+		logError(impl.log, fmt.Sprintf("reading request payload (%s)", err.Error()))
+		impl.errorResponse(w, fmt.Errorf("%q: %w", err.Error(), ErrDeserialize))
+		return false
+	}
+
+	err = json.Unmarshal(buf, req)
+	if err != nil {
+		logError(impl.log, fmt.Sprintf("unable to unmarshal request (%s)", err.Error()))
+		impl.errorResponse(w, fmt.Errorf("%q: %w", err.Error(), ErrDeserialize))
+		return false
+	}
+	return true
+}
+
+// objects are either sent as json or, when they have streamed files, as multipart (the object then a part
+// for each file). The files are spooled to temporary files rather than held in memory, cleanup removes them
+func (impl *easyStoreServiceImpl) decodeObject(w http.ResponseWriter, r *http.Request) (*easyStoreObjectImpl, func(), bool) {
+
+	var req easyStoreObjectImpl
+	spooled := make([]*os.File, 0)
+	cleanup := func() {
+		for _, f := range spooled {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+		}
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("content-type"))
+	if mediaType != "multipart/form-data" {
+		return &req, cleanup, impl.decodeRequest(w, r, &req)
+	}
+
+	fail := func(err error) (*easyStoreObjectImpl, func(), bool) {
+		cleanup()
+		logError(impl.log, fmt.Sprintf("unable to decode multipart request (%s)", err.Error()))
+		impl.errorResponse(w, fmt.Errorf("%q: %w", err.Error(), ErrDeserialize))
+		return nil, nil, false
+	}
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return fail(err)
+	}
+
+	// the object comes first
+	part, err := mr.NextPart()
+	if err != nil {
+		return fail(err)
+	}
+	if part.FormName() != objectPartName {
+		return fail(fmt.Errorf("expected the %s part but got [%s]", objectPartName, part.FormName()))
+	}
+	buf, err := io.ReadAll(part)
+	if err != nil {
+		return fail(err)
+	}
+	if err = json.Unmarshal(buf, &req); err != nil {
+		return fail(err)
+	}
+
+	// then the files
+	files := req.Files()
+	for {
+		part, err = mr.NextPart()
+		if errors.Is(err, io.EOF) == true {
+			break
+		}
+		if err != nil {
+			return fail(err)
+		}
+		name, err := url.QueryUnescape(part.Header.Get(fileNameHeader))
+		if err != nil || part.FormName() != filePartName || len(name) == 0 {
+			return fail(fmt.Errorf("bad %s part [%s]", filePartName, part.FormName()))
+		}
+
+		f, err := os.CreateTemp("", "easystore-upload-*")
+		if err != nil {
+			return fail(err)
+		}
+		spooled = append(spooled, f)
+		if _, err = io.Copy(f, part); err != nil {
+			return fail(err)
+		}
+		if _, err = f.Seek(0, io.SeekStart); err != nil {
+			return fail(err)
+		}
+		files = append(files, newEasyStoreBlobFromReader(name, part.Header.Get("Content-Type"), f))
+	}
+	req.SetFiles(files)
+	return &req, cleanup, true
+}
+
+// files are either streamed as the request body (named by the name parameter) or sent as a json blob
+func (impl *easyStoreServiceImpl) decodeFile(w http.ResponseWriter, r *http.Request) (EasyStoreBlob, bool) {
+
+	name := r.URL.Query().Get("name")
+	if len(name) != 0 {
+		return newEasyStoreBlobFromReader(name, r.Header.Get("content-type"), r.Body), true
+	}
+
+	var req easyStoreBlobImpl
+	if impl.decodeRequest(w, r, &req) == false {
+		return nil, false
+	}
+	return &req, true
+}
+
+func (impl *easyStoreServiceImpl) jsonResponse(w http.ResponseWriter, status int, resp any) {
+
+	buf, err := json.Marshal(resp)
+	if err != nil {
+		logError(impl.log, fmt.Sprintf("unable to marshal response (%s)", err.Error()))
+		impl.errorResponse(w, fmt.Errorf("%q: %w", err.Error(), ErrSerialize))
+		return
+	}
+
+	w.Header().Set("content-type", jsonContentType)
+	w.WriteHeader(status)
+	_, _ = w.Write(buf)
+}
+
+func (impl *easyStoreServiceImpl) errorResponse(w http.ResponseWriter, err error) {
+	status := mapErrorToStatus(err)
+	if status >= http.StatusInternalServerError {
+		logError(impl.log, fmt.Sprintf("request failed (%s)", err.Error()))
+	}
+	// the proxy maps the response payload back into an easystore error
+	http.Error(w, err.Error(), status)
+}
+
+// maps an easystore error into the appropriate http status
+func mapErrorToStatus(err error) int {
+
+	switch {
+	case errors.Is(err, ErrBadParameter), errors.Is(err, ErrDeserialize):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrFileNotFound), errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrStaleObject), errors.Is(err, ErrAlreadyExists):
+		return http.StatusConflict
+	case errors.Is(err, ErrNotAuthorized):
+		return http.StatusForbidden
+	case errors.Is(err, ErrNotImplemented):
+		return http.StatusNotImplemented
+	}
+	return http.StatusInternalServerError
+}
+
+// parses the attribs query parameter into a set of components (the inverse of the proxy componentHelper)
+func serviceComponents(attribs string) (EasyStoreComponents, error) {
+
+	which := BaseComponent
+	if len(attribs) == 0 {
+		return which, nil
+	}
+
+	for _, a := range strings.Split(attribs, ",") {
+		switch a {
+		case "all":
+			which |= AllComponents
+		case "fields":
+			which |= Fields
+		case "files":
+			which |= Files
+		case "metadata":
+			which |= Metadata
+		default:
+			return BaseComponent, fmt.Errorf("%q: %w", fmt.Sprintf("unknown attribute [%s]", a), ErrBadParameter)
+		}
+	}
+	return which, nil
+}
+
+// enumerate an object set into the list used in the proxy responses
+func serviceObjectList(set EasyStoreObjectSet) ([]easyStoreObjectImpl, error) {
+
+	results := make([]easyStoreObjectImpl, 0, set.Count())
+	obj, err := set.Next()
+	for err == nil {
+		results = append(results, easyStoreObjectImpl{
+			Namespace_: obj.Namespace(),
+			Id_:        obj.Id(),
+			Vtag_:      obj.VTag(),
+			Created_:   obj.Created(),
+			Modified_:  obj.Modified(),
+		})
+		obj, err = set.Next()
+	}
+	if errors.Is(err, io.EOF) == false {
+		return nil, err
+	}
+	return results, nil
+}
+
+// parses the paging query parameters (the inverse of the proxy pageHelper)
+func servicePage(params url.Values) (EasyStorePage, error) {
+
+	page := EasyStorePage{Token: params.Get("token")}
+
+	if limit := params.Get("limit"); len(limit) != 0 {
+		l, err := strconv.ParseUint(limit, 10, 32)
+		if err != nil {
+			return page, fmt.Errorf("%q: %w", fmt.Sprintf("bad limit [%s]", limit), ErrBadParameter)
+		}
+		page.Limit = uint(l)
+	}
+
+	switch params.Get("order") {
+	case "", "id":
+		page.Order = OrderById
+	case "created":
+		page.Order = OrderByCreated
+	case "modified":
+		page.Order = OrderByModified
+	default:
+		return page, fmt.Errorf("%q: %w", fmt.Sprintf("unknown order [%s]", params.Get("order")), ErrBadParameter)
+	}
+	return page, nil
+}
+
+//
+// end of file
+//
@@ -0,0 +1,294 @@
+//
+//
+//
+
+package uvaeasystore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServiceComponents(t *testing.T) {
+	proxy := easyStoreProxyReadonlyImpl{}
+
+	// every component combination should survive the trip through the attribs parameter
+	for _, which := range []EasyStoreComponents{BaseComponent, Fields, Files, Metadata, Fields | Files, Fields | Metadata, Files | Metadata, AllComponents} {
+		attribs := proxy.componentHelper(which)
+		if len(attribs) != 0 {
+			attribs = attribs[len("attribs="):]
+		}
+		after, err := serviceComponents(attribs)
+		if err != nil {
+			t.Fatalf("expected 'OK' but got '%s'\n", err)
+		}
+		if after != which {
+			t.Fatalf("expected '%d' but got '%d'\n", which, after)
+		}
+	}
+
+	// bad attributes
+	expected := ErrBadParameter
+	_, err := serviceComponents("fields,blablabla")
+	if errors.Is(err, expected) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", expected, err)
+	}
+}
+
+func TestServicePage(t *testing.T) {
+	proxy := easyStoreProxyReadonlyImpl{}
+
+	// every page should survive the trip through the query parameters
+	for _, page := range []EasyStorePage{{}, {Limit: 10}, {Limit: 10, Token: "abc-_123", Order: OrderByCreated}, {Order: OrderByModified}} {
+		params, err := url.ParseQuery(proxy.pageHelper(page))
+		if err != nil {
+			t.Fatalf("expected 'OK' but got '%s'\n", err)
+		}
+		after, err := servicePage(params)
+		if err != nil {
+			t.Fatalf("expected 'OK' but got '%s'\n", err)
+		}
+		if after != page {
+			t.Fatalf("expected '%v' but got '%v'\n", page, after)
+		}
+	}
+
+	// bad parameters
+	expected := ErrBadParameter
+	for _, query := range []string{"limit=-1", "limit=blablabla", "order=blablabla"} {
+		params, _ := url.ParseQuery(query)
+		_, err := servicePage(params)
+		if errors.Is(err, expected) == false {
+			t.Fatalf("expected '%s' but got '%s'\n", expected, err)
+		}
+	}
+}
+
+func TestServiceErrors(t *testing.T) {
+
+	// the proxy must be able to map every error we return back into the original
+	for _, expected := range []error{ErrNotImplemented, ErrBadParameter, ErrFileNotFound, ErrNotFound, ErrStaleObject, ErrAlreadyExists, ErrDeserialize, ErrNotAuthorized} {
+		err := fmt.Errorf("%q: %w", "wrapped", expected)
+		if mapErrorToStatus(err) < http.StatusBadRequest {
+			t.Fatalf("expected an error status for '%s'\n", expected)
+		}
+		after := mapResponseToError(err.Error())
+		if errors.Is(after, expected) == false {
+			t.Fatalf("expected '%s' but got '%s'\n", expected, after)
+		}
+	}
+
+	// and some specific status codes
+	testEqual(t, fmt.Sprintf("%d", http.StatusNotFound), fmt.Sprintf("%d", mapErrorToStatus(ErrNotFound)))
+	testEqual(t, fmt.Sprintf("%d", http.StatusConflict), fmt.Sprintf("%d", mapErrorToStatus(ErrStaleObject)))
+	testEqual(t, fmt.Sprintf("%d", http.StatusInternalServerError), fmt.Sprintf("%d", mapErrorToStatus(fmt.Errorf("blablabla"))))
+}
+
+func TestProxyContextCancel(t *testing.T) {
+
+	// a service that never responds in a reasonable time
+	svc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer svc.Close()
+
+	proxy := easyStoreProxyReadonlyImpl{config: ProxyConfigImpl{ServiceEndpoint: svc.URL}, HTTPClient: newHTTPClient(30)}
+
+	// the cancellation must reach the in-flight request
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	expected := context.DeadlineExceeded
+	_, err := proxy.ObjectGetByKeyCtx(ctx, "ns", "oid", BaseComponent)
+	if errors.Is(err, expected) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", expected, err)
+	}
+}
+
+func TestProxyActor(t *testing.T) {
+	es, _, sink := testOutboxSetup(t)
+	defer es.Close()
+	svc := httptest.NewServer(NewEasyStoreService(es, nil))
+	defer svc.Close()
+
+	proxy, err := NewEasyStoreProxy(ProxyConfigImpl{ServiceEndpoint: svc.URL})
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	defer proxy.Close()
+
+	// the actor is recorded in the events made by the service
+	_, err = proxy.ObjectCreateCtx(WithActor(context.Background(), testActor), NewEasyStoreObject(goodNamespace, ""))
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	if len(sink.events) != 1 {
+		t.Fatalf("expected 1 event but got %d\n", len(sink.events))
+	}
+	detail, err := EventDetail(sink.events[0])
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	testEqual(t, testActor, detail.Actor)
+}
+
+func TestProxyMultiFields(t *testing.T) {
+	es, _, _ := testOutboxSetup(t)
+	defer es.Close()
+	svc := httptest.NewServer(NewEasyStoreService(es, nil))
+	defer svc.Close()
+
+	proxy, err := NewEasyStoreProxy(ProxyConfigImpl{ServiceEndpoint: svc.URL})
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	defer proxy.Close()
+
+	// fields with several values survive the trip through the service
+	fields := EasyStoreObjectMultiFields{"author": {"smith", "jones"}, "title": {"a title"}}
+	o := NewEasyStoreObject(goodNamespace, "")
+	o.SetMultiFields(fields)
+	_, err = proxy.ObjectCreate(o)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	after, err := proxy.ObjectGetByKey(goodNamespace, o.Id(), Fields)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	if reflect.DeepEqual(fields, after.MultiFields()) == false {
+		t.Fatalf("expected '%v' but got '%v'\n", fields, after.MultiFields())
+	}
+}
+
+func TestProxyFileRetry(t *testing.T) {
+	es, _, _ := testOutboxSetup(t)
+	defer es.Close()
+	handler := NewEasyStoreService(es, nil)
+
+	// the first file request times out, the retry succeeds
+	requests := 0
+	svc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/file") == true {
+			requests++
+			if requests == 1 {
+				_, _ = io.ReadAll(r.Body)
+				select {
+				case <-r.Context().Done():
+				case <-time.After(time.Second):
+				}
+				return
+			}
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer svc.Close()
+
+	proxy := easyStoreProxyImpl{easyStoreProxyReadonlyImpl{config: ProxyConfigImpl{ServiceEndpoint: svc.URL}, HTTPClient: &http.Client{Timeout: 200 * time.Millisecond}}}
+	o, err := proxy.ObjectCreate(NewEasyStoreObject(goodNamespace, ""))
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	// an in-memory payload is sent again
+	payload := []byte("file1 payload")
+	err = proxy.FileCreate(goodNamespace, o.Id(), NewEasyStoreBlob("file1.txt", "text/plain", payload))
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	testEqual(t, "2", fmt.Sprintf("%d", requests))
+	blob, err := es.FileGetByKey(goodNamespace, o.Id(), "file1.txt")
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	buf, _ := blob.Payload()
+	testEqual(t, string(payload), string(buf))
+}
+
+func TestProxyStreamedObject(t *testing.T) {
+	es, _, _ := testOutboxSetup(t)
+	defer es.Close()
+	handler := NewEasyStoreService(es, nil)
+
+	// remember how the objects are sent
+	mediaTypes := make([]string, 0)
+	svc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost || r.Method == http.MethodPut {
+			mediaType, _, _ := mime.ParseMediaType(r.Header.Get("content-type"))
+			mediaTypes = append(mediaTypes, mediaType)
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer svc.Close()
+
+	proxy, err := NewEasyStoreProxy(ProxyConfigImpl{ServiceEndpoint: svc.URL})
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	defer proxy.Close()
+
+	// streamed files are sent as parts of their own, in-memory ones in the object
+	streamed := []byte("a streamed payload")
+	o := NewEasyStoreObject(goodNamespace, "")
+	o.SetFields(EasyStoreObjectFields{"title": "a title"})
+	o.SetFiles([]EasyStoreBlob{
+		NewEasyStoreBlob("file1.txt", "text/plain", []byte("file1")),
+		NewEasyStoreBlobFromReader("dir/file2.bin", "application/octet-stream", io.MultiReader(bytes.NewReader(streamed))),
+	})
+	o, err = proxy.ObjectCreate(o)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	testEqual(t, "multipart/form-data", mediaTypes[0])
+	testEqual(t, "a title", o.Fields()["title"])
+
+	blob, err := es.FileGetByKey(goodNamespace, o.Id(), "dir/file2.bin")
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	buf, _ := blob.Payload()
+	testEqual(t, string(streamed), string(buf))
+	testEqual(t, "application/octet-stream", blob.MimeType())
+	blob, err = es.FileGetByKey(goodNamespace, o.Id(), "file1.txt")
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	// and the same for updates
+	updated := []byte("an updated streamed payload")
+	o.SetFiles([]EasyStoreBlob{NewEasyStoreBlobFromReader("dir/file2.bin", "application/octet-stream", io.MultiReader(bytes.NewReader(updated)))})
+	o, err = proxy.ObjectUpdate(o, Files)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	testEqual(t, "multipart/form-data", mediaTypes[len(mediaTypes)-1])
+	testEqual(t, "1", fmt.Sprintf("%d", len(o.Files())))
+	blob, err = es.FileGetByKey(goodNamespace, o.Id(), "dir/file2.bin")
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	buf, _ = blob.Payload()
+	testEqual(t, string(updated), string(buf))
+
+	// objects without streamed files are still json
+	o.SetFiles([]EasyStoreBlob{NewEasyStoreBlob("file1.txt", "text/plain", []byte("file1"))})
+	_, err = proxy.ObjectUpdate(o, Files)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	testEqual(t, jsonContentType, mediaTypes[len(mediaTypes)-1])
+}
+
+//
+// end of file
+//
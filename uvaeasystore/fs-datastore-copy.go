@@ -0,0 +1,77 @@
+//
+// file system implementation of the datastore copy method
+//
+
+// only include this file for service builds
+
+//go:build service
+// +build service
+
+package uvaeasystore
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+)
+
+// CopyObjectByKey -- copy the object, fields, metadata and files to the new key
+func (s *fsStorage) CopyObjectByKey(ctx context.Context, from DataStoreKey, to DataStoreKey) error {
+
+	// the source must exist and not be in the trash
+	obj, err := s.GetObjectByKey(ctx, from, NOCACHE)
+	if err != nil {
+		return err
+	}
+
+	// the identifier is in use until the object is purged, even when it is in the trash
+	if s.checkAssetExists(to.Namespace, to.ObjectId, S3ObjectFileName) == true {
+		return fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s", to.Namespace, to.ObjectId), ErrAlreadyExists)
+	}
+
+	// everything other than the object file is independent of the key so is copied as is
+	names, err := s.listFiles(filepath.Join(s.root, from.Namespace, from.ObjectId))
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if name == S3ObjectFileName || name == S3TombstoneFileName {
+			continue
+		}
+		err = s.copyFile(ctx, s.assetPath(from.Namespace, from.ObjectId, name), s.assetPath(to.Namespace, to.ObjectId, name))
+		if err != nil {
+			return err
+		}
+	}
+
+	var fields EasyStoreObjectMultiFields
+	if s.checkAssetExists(from.Namespace, from.ObjectId, S3FieldsFileName) == true {
+		f, err := s.getFields(ctx, from.Namespace, from.ObjectId)
+		if err != nil {
+			return err
+		}
+		fields = *f
+	}
+
+	// the object file last, it keeps the timestamps of the source
+	impl, ok := obj.(*easyStoreObjectImpl)
+	if ok == false {
+		return fmt.Errorf("%q: %w", "cast failed, not an easyStoreObjectImpl", ErrBadParameter)
+	}
+	impl.Namespace_, impl.Id_, impl.Vtag_ = to.Namespace, to.ObjectId, newVtag()
+	b := s.serialize.ObjectSerialize(impl).([]byte)
+	err = s.writeBuffer(ctx, s.assetPath(to.Namespace, to.ObjectId, S3ObjectFileName), b)
+	if err != nil {
+		return err
+	}
+
+	// update the index
+	return s.updateIndex(ctx, to.Namespace, func(idx fsIndex) error {
+		idx[to.ObjectId] = &fsIndexEntry{Created: impl.Created_, Modified: impl.Modified_, Fields: fields}
+		return nil
+	})
+}
+
+//
+// end of file
+//
@@ -0,0 +1,744 @@
+//
+// file system implementation of the datastore interface
+//
+
+// only include this file for service builds
+
+//go:build service
+// +build service
+
+package uvaeasystore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// objects use the same asset layout as the S3 implementation:
+// root-dir/namespace/object-identifier/asset-name
+
+// this is our file system implementation
+type fsStorage struct {
+	root      string              // the root directory
+	serialize EasyStoreSerializer // standard serializer
+	log       *log.Logger         // logger
+	lock      *sync.Mutex         // serializes changes, held for the life of a transaction
+	journal   *fsJournal          // changes made in the current transaction (if any)
+}
+
+// Check -- check the root directory is available
+func (s *fsStorage) Check(ctx context.Context) error {
+	fi, err := os.Stat(s.root)
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() == false {
+		return fmt.Errorf("%q: %w", fmt.Sprintf("%s is not a directory", s.root), ErrBadParameter)
+	}
+	return nil
+}
+
+// UpdateBlob -- update the contents of an existing blob
+func (s *fsStorage) UpdateBlob(ctx context.Context, key DataStoreKey, blob EasyStoreBlob) error {
+
+	// check the blob already exists
+	if s.checkAssetExists(key.Namespace, key.ObjectId, fmt.Sprintf("%s%s", blob.Name(), S3BlobFileNameSuffix)) == false {
+		return fmt.Errorf("%q: %w", blob.Name(), ErrNotFound)
+	}
+
+	// the blob keeps its original created time
+	current, err := s.readBlobDescriptor(ctx, s.assetPath(key.Namespace, key.ObjectId, fmt.Sprintf("%s%s", blob.Name(), S3BlobFileNameSuffix)))
+	if err != nil {
+		return err
+	}
+	return s.addBlob(ctx, key.Namespace, key.ObjectId, blob, current.Created_)
+}
+
+// UpdateFields -- update the contents of an existing field set
+func (s *fsStorage) UpdateFields(ctx context.Context, key DataStoreKey, fields EasyStoreObjectMultiFields) error {
+	return s.AddFields(ctx, key, fields)
+}
+
+// UpdateMetadata -- update the contents of existing metadata
+func (s *fsStorage) UpdateMetadata(ctx context.Context, key DataStoreKey, md EasyStoreMetadata) error {
+	return s.AddMetadata(ctx, key, md)
+}
+
+// UpdateObject -- update a couple of object fields
+func (s *fsStorage) UpdateObject(ctx context.Context, key DataStoreKey) error {
+	obj, err := s.GetObjectByKey(ctx, key, NOCACHE)
+	if err != nil {
+		return err
+	}
+
+	impl, ok := obj.(*easyStoreObjectImpl)
+	if ok == false {
+		return fmt.Errorf("%q: %w", "cast failed, not an easyStoreObjectImpl", ErrBadParameter)
+	}
+
+	impl.Vtag_ = newVtag()
+	impl.Modified_ = time.Now()
+
+	b := s.serialize.ObjectSerialize(impl).([]byte)
+	err = s.writeBuffer(ctx, s.assetPath(key.Namespace, key.ObjectId, S3ObjectFileName), b)
+	if err != nil {
+		return err
+	}
+
+	// update the index
+	return s.updateIndex(ctx, key.Namespace, func(idx fsIndex) error {
+		e, err := idx.entry(key)
+		if err != nil {
+			return err
+		}
+		e.Modified = impl.Modified_
+		return nil
+	})
+}
+
+// LockObjectByKey -- check the vtag, a transaction already holds the storage lock until it completes
+func (s *fsStorage) LockObjectByKey(ctx context.Context, key DataStoreKey, vtag string) error {
+	obj, err := s.GetObjectByKey(ctx, key, NOCACHE)
+	if err != nil {
+		return err
+	}
+	if obj.VTag() != vtag {
+		return fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s (vtag %s)", key.Namespace, key.ObjectId, vtag), ErrStaleObject)
+	}
+	return nil
+}
+
+// AddBlob -- add a new blob object
+func (s *fsStorage) AddBlob(ctx context.Context, key DataStoreKey, blob EasyStoreBlob) error {
+
+	// check the blob does not already exist
+	if s.checkAssetExists(key.Namespace, key.ObjectId, fmt.Sprintf("%s%s", blob.Name(), S3BlobFileNameSuffix)) == true {
+		return fmt.Errorf("%q: %w", blob.Name(), ErrAlreadyExists)
+	}
+	return s.addBlob(ctx, key.Namespace, key.ObjectId, blob, time.Now())
+}
+
+// AddFields -- add a new fields object
+func (s *fsStorage) AddFields(ctx context.Context, key DataStoreKey, fields EasyStoreObjectMultiFields) error {
+
+	b := s.serialize.MultiFieldsSerialize(fields).([]byte)
+	err := s.writeBuffer(ctx, s.assetPath(key.Namespace, key.ObjectId, S3FieldsFileName), b)
+	if err != nil {
+		return err
+	}
+
+	// update the index
+	return s.updateIndex(ctx, key.Namespace, func(idx fsIndex) error {
+		e, err := idx.entry(key)
+		if err != nil {
+			return err
+		}
+		e.Fields = fields
+		return nil
+	})
+}
+
+// AddMetadata -- add a new metadata object
+func (s *fsStorage) AddMetadata(ctx context.Context, key DataStoreKey, metadata EasyStoreMetadata) error {
+
+	// for setting the timestamps
+	impl, ok := metadata.(*easyStoreMetadataImpl)
+	if ok == false {
+		return fmt.Errorf("%q: %w", "cast failed, not an easyStoreMetadataImpl", ErrBadParameter)
+	}
+	impl.Created_, impl.Modified_ = time.Now(), time.Now()
+
+	b := s.serialize.MetadataSerialize(impl).([]byte)
+	return s.writeBuffer(ctx, s.assetPath(key.Namespace, key.ObjectId, S3MetadataFileName), b)
+}
+
+// AddObject -- add a new object
+func (s *fsStorage) AddObject(ctx context.Context, obj EasyStoreObject) error {
+
+	// the identifier is in use until the object is purged, even when it is in the trash
+	if s.checkAssetExists(obj.Namespace(), obj.Id(), S3ObjectFileName) == true {
+		return fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s", obj.Namespace(), obj.Id()), ErrAlreadyExists)
+	}
+
+	// for setting the timestamps
+	impl, ok := obj.(*easyStoreObjectImpl)
+	if ok == false {
+		return fmt.Errorf("%q: %w", "cast failed, not an easyStoreObjectImpl", ErrBadParameter)
+	}
+	impl.Created_, impl.Modified_ = time.Now(), time.Now()
+
+	b := s.serialize.ObjectSerialize(impl).([]byte)
+	err := s.writeBuffer(ctx, s.assetPath(obj.Namespace(), obj.Id(), S3ObjectFileName), b)
+	if err != nil {
+		return err
+	}
+
+	// update the index
+	return s.updateIndex(ctx, obj.Namespace(), func(idx fsIndex) error {
+		idx[obj.Id()] = &fsIndexEntry{Created: impl.Created_, Modified: impl.Modified_}
+		return nil
+	})
+}
+
+// GetBlobsByKey -- get all blob data associated with the specified object
+func (s *fsStorage) GetBlobsByKey(ctx context.Context, key DataStoreKey, useCache bool) ([]EasyStoreBlob, error) {
+
+	// this implementation does not use a cache so useCache is ignored
+
+	blobs, err := s.getBlobs(ctx, key.Namespace, key.ObjectId)
+	if err != nil {
+		return nil, err
+	}
+
+	// no blobs
+	if len(blobs) == 0 {
+		return nil, ErrNotFound
+	}
+	return blobs, nil
+}
+
+// GetFieldsByKey -- get all field data associated with the specified object
+func (s *fsStorage) GetFieldsByKey(ctx context.Context, key DataStoreKey, useCache bool) (*EasyStoreObjectMultiFields, error) {
+
+	// this implementation does not use a cache so useCache is ignored
+
+	if s.checkAssetExists(key.Namespace, key.ObjectId, S3FieldsFileName) == false {
+		return nil, fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s/%s", key.Namespace, key.ObjectId, S3FieldsFileName), ErrNotFound)
+	}
+	return s.getFields(ctx, key.Namespace, key.ObjectId)
+}
+
+// GetMetadataByKey -- get all field data associated with the specified object
+func (s *fsStorage) GetMetadataByKey(ctx context.Context, key DataStoreKey, useCache bool) (EasyStoreMetadata, error) {
+
+	// this implementation does not use a cache so useCache is ignored
+
+	if s.checkAssetExists(key.Namespace, key.ObjectId, S3MetadataFileName) == false {
+		return nil, fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s/%s", key.Namespace, key.ObjectId, S3MetadataFileName), ErrNotFound)
+	}
+	return s.getMetadata(ctx, key.Namespace, key.ObjectId)
+}
+
+// GetBlobByKey -- get the named blob associated with the specified object
+func (s *fsStorage) GetBlobByKey(ctx context.Context, key DataStoreKey, curName string, useCache bool) (EasyStoreBlob, error) {
+
+	// this implementation does not use a cache so useCache is ignored
+
+	blobName := fmt.Sprintf("%s%s", curName, S3BlobFileNameSuffix)
+	if s.checkAssetExists(key.Namespace, key.ObjectId, blobName) == false {
+		return nil, fmt.Errorf("%q: %w", curName, ErrFileNotFound)
+	}
+	return s.getBlob(ctx, key.Namespace, key.ObjectId, blobName)
+}
+
+// GetObjectByKey -- get all field data associated with the specified object
+func (s *fsStorage) GetObjectByKey(ctx context.Context, key DataStoreKey, useCache bool) (EasyStoreObject, error) {
+
+	// this implementation does not use a cache so useCache is ignored
+
+	if s.checkAssetExists(key.Namespace, key.ObjectId, S3ObjectFileName) == false {
+		return nil, fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s/%s", key.Namespace, key.ObjectId, S3ObjectFileName), ErrNotFound)
+	}
+	// deleted objects are in the trash
+	if s.checkAssetExists(key.Namespace, key.ObjectId, S3TombstoneFileName) == true {
+		return nil, fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s/%s", key.Namespace, key.ObjectId, S3TombstoneFileName), ErrNotFound)
+	}
+	return s.getObject(ctx, key.Namespace, key.ObjectId)
+}
+
+// GetObjectsByKey -- get all field data associated with the specified object
+func (s *fsStorage) GetObjectsByKey(ctx context.Context, keys []DataStoreKey, useCache bool) ([]EasyStoreObject, error) {
+
+	results := make([]EasyStoreObject, 0, len(keys))
+	for _, key := range keys {
+		obj, err := s.GetObjectByKey(ctx, key, useCache)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) == false {
+				// a real error
+				return nil, err
+			}
+		} else {
+			results = append(results, obj)
+		}
+	}
+	if len(results) == 0 {
+		return nil, ErrNotFound
+	}
+	return results, nil
+}
+
+// RenameBlobByKey -- rename the named blob to the new name
+func (s *fsStorage) RenameBlobByKey(ctx context.Context, key DataStoreKey, curName string, newName string) error {
+
+	if err := checkAssetName(newName); err != nil {
+		return err
+	}
+
+	// names of the blob description files
+	curBlobName := fmt.Sprintf("%s%s", curName, S3BlobFileNameSuffix)
+	newBlobName := fmt.Sprintf("%s%s", newName, S3BlobFileNameSuffix)
+
+	// check currently named asset exists
+	if s.checkAssetExists(key.Namespace, key.ObjectId, curBlobName) == false {
+		return fmt.Errorf("%q: %w", curName, ErrFileNotFound)
+	}
+
+	// check new asset name does not already exist
+	if s.checkAssetExists(key.Namespace, key.ObjectId, newBlobName) == true {
+		return fmt.Errorf("%q: %w", newName, ErrAlreadyExists)
+	}
+
+	blob, err := s.readBlobDescriptor(ctx, s.assetPath(key.Namespace, key.ObjectId, curBlobName))
+	if err != nil {
+		return err
+	}
+
+	// update the attributes
+	blob.Name_ = newName
+	blob.Modified_ = time.Now()
+
+	// write the new blob descriptor and remove the old one
+	b := s.serialize.BlobSerialize(blob).([]byte)
+	err = s.writeBuffer(ctx, s.assetPath(key.Namespace, key.ObjectId, newBlobName), b)
+	if err != nil {
+		return err
+	}
+	err = s.removeFile(ctx, s.assetPath(key.Namespace, key.ObjectId, curBlobName))
+	if err != nil {
+		return err
+	}
+
+	// rename the actual asset file
+	return s.renameFile(ctx, s.assetPath(key.Namespace, key.ObjectId, curName), s.assetPath(key.Namespace, key.ObjectId, newName))
+}
+
+// DeleteBlobByKey -- delete a single blob associated with the specified object
+func (s *fsStorage) DeleteBlobByKey(ctx context.Context, key DataStoreKey, curName string) error {
+
+	// the blob descriptor and the file contents
+	err := s.removeFile(ctx, s.assetPath(key.Namespace, key.ObjectId, fmt.Sprintf("%s%s", curName, S3BlobFileNameSuffix)))
+	if err != nil {
+		return err
+	}
+	return s.removeFile(ctx, s.assetPath(key.Namespace, key.ObjectId, curName))
+}
+
+// DeleteBlobsByKey -- delete all blob data associated with the specified object
+func (s *fsStorage) DeleteBlobsByKey(ctx context.Context, key DataStoreKey) error {
+
+	names, err := s.listFiles(filepath.Join(s.root, key.Namespace, key.ObjectId))
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if s.isBlobName(name) == true {
+			err = s.DeleteBlobByKey(ctx, key, strings.TrimSuffix(name, S3BlobFileNameSuffix))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// DeleteFieldsByKey -- delete all field data associated with the specified object
+func (s *fsStorage) DeleteFieldsByKey(ctx context.Context, key DataStoreKey) error {
+
+	err := s.removeFile(ctx, s.assetPath(key.Namespace, key.ObjectId, S3FieldsFileName))
+	if err != nil {
+		return err
+	}
+
+	// update the index, the object may already be gone
+	return s.updateIndex(ctx, key.Namespace, func(idx fsIndex) error {
+		if e, found := idx[key.ObjectId]; found == true {
+			e.Fields = nil
+		}
+		return nil
+	})
+}
+
+// DeleteMetadataByKey -- delete all field data associated with the specified object
+func (s *fsStorage) DeleteMetadataByKey(ctx context.Context, key DataStoreKey) error {
+	return s.removeFile(ctx, s.assetPath(key.Namespace, key.ObjectId, S3MetadataFileName))
+}
+
+// DeleteObjectByKey -- delete all field data associated with the specified object
+func (s *fsStorage) DeleteObjectByKey(ctx context.Context, key DataStoreKey) error {
+
+	for _, name := range []string{S3ObjectFileName, S3TombstoneFileName} {
+		err := s.removeFile(ctx, s.assetPath(key.Namespace, key.ObjectId, name))
+		if err != nil {
+			return err
+		}
+	}
+
+	// update the index
+	return s.updateIndex(ctx, key.Namespace, func(idx fsIndex) error {
+		delete(idx, key.ObjectId)
+		return nil
+	})
+}
+
+// GetKeysByFields -- get a page of keys of objects matching the query and the token for the next page
+func (s *fsStorage) GetKeysByFields(ctx context.Context, namespace string, query EasyStoreQuery, page EasyStorePage) ([]DataStoreKey, string, error) {
+	return s.keysByQueryPage(ctx, namespace, query, page)
+}
+
+// Begin -- begin a new transaction. Changes are journaled so they can be undone on rollback and
+// other changes wait until the transaction is complete
+func (s *fsStorage) Begin(ctx context.Context) (DataStoreTx, error) {
+
+	if s.journal != nil {
+		return nil, fmt.Errorf("%q: %w", "nested transactions are not supported", ErrNotImplemented)
+	}
+
+	s.lock.Lock()
+	ts := *s
+	ts.journal = newFsJournal()
+	return &fsStorageTx{&ts}, nil
+}
+
+// Close -- nothing to close
+func (s *fsStorage) Close() error {
+	return nil
+}
+
+//
+// private implementation methods
+//
+
+// assetPath -- assets are named as follows:
+// root-dir/namespace/object-identifier/asset-name
+func (s *fsStorage) assetPath(namespace string, identifier string, assetName string) string {
+	return filepath.Join(s.root, namespace, identifier, assetName)
+}
+
+func (s *fsStorage) checkAssetExists(namespace string, identifier string, assetName string) bool {
+	_, err := os.Stat(s.assetPath(namespace, identifier, assetName))
+	return err == nil
+}
+
+func (s *fsStorage) isBlobName(name string) bool {
+	return strings.HasSuffix(name, S3BlobFileNameSuffix)
+}
+
+// file names become paths so must name a single file in the object directory
+func checkAssetName(name string) error {
+	if name != filepath.Base(name) || name == "." || name == ".." {
+		return fmt.Errorf("%q: %w", fmt.Sprintf("%s is not a valid file name", name), ErrBadParameter)
+	}
+	return nil
+}
+
+func (s *fsStorage) addBlob(ctx context.Context, namespace string, identifier string, blob EasyStoreBlob, created time.Time) error {
+
+	if err := checkAssetName(blob.Name()); err != nil {
+		return err
+	}
+
+	// for setting the timestamps
+	impl, ok := blob.(*easyStoreBlobImpl)
+	if ok == false {
+		return fmt.Errorf("%q: %w", "cast failed, not an easyStoreBlobImpl", ErrBadParameter)
+	}
+	impl.Created_, impl.Modified_ = created, time.Now()
+
+	// we store the original file alongside the blob descriptor
+	reader, err := impl.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	// the checksum is computed as the file is written
+	checksum := newChecksumReader(reader)
+	err = s.writeFile(ctx, s.assetPath(namespace, identifier, blob.Name()), checksum)
+	if err != nil {
+		return err
+	}
+	impl.Checksum_, impl.Size_ = checksum.Checksum(), checksum.Size()
+
+	// dont want to serialize the payload
+	implClone := *impl
+	implClone.Payload_ = nil
+	implClone.stream = nil
+	b := s.serialize.BlobSerialize(implClone).([]byte)
+	return s.writeBuffer(ctx, s.assetPath(namespace, identifier, fmt.Sprintf("%s%s", blob.Name(), S3BlobFileNameSuffix)), b)
+}
+
+func (s *fsStorage) getObject(ctx context.Context, namespace string, identifier string) (EasyStoreObject, error) {
+	b, err := s.readFile(ctx, s.assetPath(namespace, identifier, S3ObjectFileName))
+	if err != nil {
+		return nil, err
+	}
+	return s.serialize.ObjectDeserialize(b)
+}
+
+func (s *fsStorage) getFields(ctx context.Context, namespace string, identifier string) (*EasyStoreObjectMultiFields, error) {
+	b, err := s.readFile(ctx, s.assetPath(namespace, identifier, S3FieldsFileName))
+	if err != nil {
+		return nil, err
+	}
+	fields, err := s.serialize.MultiFieldsDeserialize(b)
+	if err != nil {
+		return nil, err
+	}
+	return &fields, nil
+}
+
+func (s *fsStorage) getMetadata(ctx context.Context, namespace string, identifier string) (EasyStoreMetadata, error) {
+	b, err := s.readFile(ctx, s.assetPath(namespace, identifier, S3MetadataFileName))
+	if err != nil {
+		return nil, err
+	}
+	return s.serialize.MetadataDeserialize(b)
+}
+
+// all the blobs of an object, oldest first
+func (s *fsStorage) getBlobs(ctx context.Context, namespace string, identifier string) ([]EasyStoreBlob, error) {
+
+	names, err := s.listFiles(filepath.Join(s.root, namespace, identifier))
+	if err != nil {
+		return nil, err
+	}
+
+	blobs := make([]EasyStoreBlob, 0)
+	for _, name := range names {
+		if s.isBlobName(name) == true {
+			blob, err := s.getBlob(ctx, namespace, identifier, name)
+			if err != nil {
+				return nil, err
+			}
+			blobs = append(blobs, blob)
+		}
+	}
+
+	sort.SliceStable(blobs, func(i, j int) bool {
+		return blobs[i].Modified().Before(blobs[j].Modified())
+	})
+	return blobs, nil
+}
+
+// the blob descriptor, the original file contents are referenced by url
+func (s *fsStorage) getBlob(ctx context.Context, namespace string, identifier string, blobName string) (EasyStoreBlob, error) {
+
+	blob, err := s.readBlobDescriptor(ctx, s.assetPath(namespace, identifier, blobName))
+	if err != nil {
+		return nil, err
+	}
+
+	// the contents are available by url, like the signed urls of the S3 implementation
+	path := s.assetPath(namespace, identifier, strings.TrimSuffix(blobName, S3BlobFileNameSuffix))
+	blob.Url_ = fmt.Sprintf("file://%s", path)
+
+	// blobs stored before sizes were recorded get theirs from the file
+	if blob.Size_ == 0 {
+		if info, err := os.Stat(path); err == nil {
+			blob.Size_ = info.Size()
+		}
+	}
+	return blob, nil
+}
+
+func (s *fsStorage) readBlobDescriptor(ctx context.Context, path string) (*easyStoreBlobImpl, error) {
+	b, err := s.readFile(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	blob, err := s.serialize.BlobDeserialize(b)
+	if err != nil {
+		return nil, err
+	}
+	impl, ok := blob.(*easyStoreBlobImpl)
+	if ok == false {
+		return nil, fmt.Errorf("%q: %w", "cast failed, not an easyStoreBlobImpl", ErrBadParameter)
+	}
+	return impl, nil
+}
+
+//
+// file helpers
+//
+
+func (s *fsStorage) readFile(ctx context.Context, path string) ([]byte, error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	logDebug(s.log, fmt.Sprintf("reading [%s]", path))
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) == true {
+			return nil, fmt.Errorf("%q: %w", path, ErrNotFound)
+		}
+		logError(s.log, fmt.Sprintf("read [%s] failed (%s)", path, err.Error()))
+		return nil, err
+	}
+	return b, nil
+}
+
+func (s *fsStorage) writeBuffer(ctx context.Context, path string, buf []byte) error {
+	return s.writeFile(ctx, path, bytes.NewReader(buf))
+}
+
+// files are written to a temporary file that replaces the original so readers never see partial
+// contents and the original is unchanged (which is what the transaction backups rely on)
+func (s *fsStorage) writeFile(ctx context.Context, path string, reader io.Reader) error {
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := s.journalPath(path); err != nil {
+		return err
+	}
+
+	logDebug(s.log, fmt.Sprintf("writing [%s]", path))
+	start := time.Now()
+
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), FsTempPrefix)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, reader)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		logError(s.log, fmt.Sprintf("write [%s] failed (%s)", path, err.Error()))
+		return err
+	}
+
+	duration := time.Since(start)
+	logDebug(s.log, fmt.Sprintf("write [%s] complete in %0.2f seconds", path, duration.Seconds()))
+	return nil
+}
+
+// remove a file, it is not an error if it does not exist
+func (s *fsStorage) removeFile(ctx context.Context, path string) error {
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := s.journalPath(path); err != nil {
+		return err
+	}
+
+	logDebug(s.log, fmt.Sprintf("removing [%s]", path))
+	err := os.Remove(path)
+	if err != nil && errors.Is(err, fs.ErrNotExist) == false {
+		logError(s.log, fmt.Sprintf("remove [%s] failed (%s)", path, err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (s *fsStorage) renameFile(ctx context.Context, oldPath string, newPath string) error {
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := s.journalPath(oldPath); err != nil {
+		return err
+	}
+	if err := s.journalPath(newPath); err != nil {
+		return err
+	}
+
+	logDebug(s.log, fmt.Sprintf("renaming [%s]->[%s]", oldPath, newPath))
+	err := os.Rename(oldPath, newPath)
+	if err != nil {
+		logError(s.log, fmt.Sprintf("rename [%s]->[%s] failed (%s)", oldPath, newPath, err.Error()))
+	}
+	return err
+}
+
+// copy a file to a new location, files are never changed in place so a link will do when we can
+func (s *fsStorage) copyFile(ctx context.Context, srcPath string, dstPath string) error {
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := s.journalPath(dstPath); err != nil {
+		return err
+	}
+
+	logDebug(s.log, fmt.Sprintf("copying [%s]->[%s]", srcPath, dstPath))
+	err := linkOrCopy(srcPath, dstPath)
+	if err != nil {
+		logError(s.log, fmt.Sprintf("copy [%s]->[%s] failed (%s)", srcPath, dstPath, err.Error()))
+	}
+	return err
+}
+
+// the names of the files in a directory, a missing directory has none
+func (s *fsStorage) listFiles(dir string) ([]string, error) {
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) == true {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() == false && strings.HasPrefix(e.Name(), FsTempPrefix) == false {
+			names = append(names, e.Name())
+		}
+	}
+	return names, nil
+}
+
+func linkOrCopy(srcPath string, dstPath string) error {
+
+	err := os.MkdirAll(filepath.Dir(dstPath), 0755)
+	if err != nil {
+		return err
+	}
+	_ = os.Remove(dstPath)
+	if os.Link(srcPath, dstPath) == nil {
+		return nil
+	}
+
+	// not all file systems support links
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.Create(dstPath)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+//
+// end of file
+//
@@ -0,0 +1,186 @@
+//
+// the file system datastore index, used for field lookups and the trash
+//
+
+// only include this file for service builds
+
+//go:build service
+// +build service
+
+package uvaeasystore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// each namespace has an index of its objects, their timestamps and fields, as follows:
+// root-dir/.index/namespace.json
+var FsIndexDir = ".index"
+
+// temporary files are written alongside the files they replace and are named as follows
+var FsTempPrefix = ".tmp-"
+
+// an indexed object
+type fsIndexEntry struct {
+	Created  time.Time                  `json:"created"`
+	Modified time.Time                  `json:"modified"`
+	Deleted  *time.Time                 `json:"deleted,omitempty"` // when it was moved to the trash (if it was)
+	Fields   EasyStoreObjectMultiFields `json:"fields,omitempty"`
+}
+
+// the index of a namespace, keyed by object identifier
+type fsIndex map[string]*fsIndexEntry
+
+// the entry for the specified object, it must exist
+func (idx fsIndex) entry(key DataStoreKey) (*fsIndexEntry, error) {
+	e, found := idx[key.ObjectId]
+	if found == false {
+		return nil, fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s not indexed", key.Namespace, key.ObjectId), ErrNotFound)
+	}
+	return e, nil
+}
+
+func (s *fsStorage) indexPath(namespace string) string {
+	return filepath.Join(s.root, FsIndexDir, fmt.Sprintf("%s.json", namespace))
+}
+
+// the index of the specified namespace, a namespace without one is empty
+func (s *fsStorage) readIndex(ctx context.Context, namespace string) (fsIndex, error) {
+
+	idx := make(fsIndex)
+	b, err := s.readFile(ctx, s.indexPath(namespace))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) == true {
+			return idx, nil
+		}
+		return nil, err
+	}
+	err = json.Unmarshal(b, &idx)
+	if err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// change the index of the specified namespace. Outside of a transaction changes must still be made one
+// at a time so they are not lost
+func (s *fsStorage) updateIndex(ctx context.Context, namespace string, update func(fsIndex) error) error {
+
+	if s.journal == nil {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+	}
+
+	idx, err := s.readIndex(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	err = update(idx)
+	if err != nil {
+		return err
+	}
+
+	// cannot fail, the index is all simple types
+	b, _ := json.Marshal(idx)
+	return s.writeBuffer(ctx, s.indexPath(namespace), b)
+}
+
+// the indexed namespaces
+func (s *fsStorage) indexNamespaces() ([]string, error) {
+	names, err := s.listFiles(filepath.Join(s.root, FsIndexDir))
+	if err != nil {
+		return nil, err
+	}
+	namespaces := make([]string, 0, len(names))
+	for _, name := range names {
+		if strings.HasSuffix(name, ".json") == true {
+			namespaces = append(namespaces, strings.TrimSuffix(name, ".json"))
+		}
+	}
+	return namespaces, nil
+}
+
+// the namespace if there is one, otherwise all of them
+func (s *fsStorage) searchNamespaces(namespace string) ([]string, error) {
+	if len(namespace) != 0 {
+		return []string{namespace}, nil
+	}
+	return s.indexNamespaces()
+}
+
+// get a page of the keys of objects matching the query along with the token for the next page, ordered
+// and continued in the same way as the datastores with a database
+func (s *fsStorage) keysByQueryPage(ctx context.Context, namespace string, query EasyStoreQuery, page EasyStorePage) ([]DataStoreKey, string, error) {
+
+	namespaces, err := s.searchNamespaces(namespace)
+	if err != nil {
+		return nil, "", err
+	}
+
+	keys := make([]pageKey, 0)
+	for _, ns := range namespaces {
+		idx, err := s.readIndex(ctx, ns)
+		if err != nil {
+			return nil, "", err
+		}
+		for oid, e := range idx {
+			// deleted objects are never found
+			if e.Deleted != nil {
+				continue
+			}
+			match, err := matchQuery(query, e.Fields)
+			if err != nil {
+				return nil, "", err
+			}
+			if match == true {
+				keys = append(keys, pageKey{key: DataStoreKey{ns, oid}, created: e.Created, modified: e.Modified})
+			}
+		}
+	}
+	return pageOfKeys(page, keys, s.log)
+}
+
+// the objects in the trash that were deleted before the specified time, oldest first
+func (s *fsStorage) tombstonesBefore(ctx context.Context, namespace string, before time.Time) ([]EasyStoreTombstone, error) {
+
+	namespaces, err := s.searchNamespaces(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]EasyStoreTombstone, 0)
+	for _, ns := range namespaces {
+		idx, err := s.readIndex(ctx, ns)
+		if err != nil {
+			return nil, err
+		}
+		for oid, e := range idx {
+			if e.Deleted != nil && e.Deleted.Before(before) == true {
+				results = append(results, EasyStoreTombstone{Namespace: ns, Id: oid, Deleted: *e.Deleted})
+			}
+		}
+	}
+
+	// check for not found
+	if len(results) == 0 {
+		return nil, fmt.Errorf("%q: %w", "deleted object(s) not found", ErrNotFound)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Deleted.Before(results[j].Deleted)
+	})
+
+	logDebug(s.log, fmt.Sprintf("found %d deleted object(s)", len(results)))
+	return results, nil
+}
+
+//
+// end of file
+//
@@ -0,0 +1,165 @@
+//
+// file system implementation of the datastore outbox methods
+//
+
+// only include this file for service builds
+
+//go:build service
+// +build service
+
+package uvaeasystore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// each event in the outbox is a file as follows:
+// root-dir/.outbox/event-identifier.json
+var FsOutboxDir = ".outbox"
+
+// AddEvent -- add an event to the outbox
+func (s *fsStorage) AddEvent(ctx context.Context, event EasyStoreEvent) error {
+
+	if err := checkAssetName(event.Id); err != nil {
+		return err
+	}
+	return s.updateEvent(ctx, event.Id, func(cur *EasyStoreEvent) (*EasyStoreEvent, error) {
+		if cur != nil {
+			return nil, fmt.Errorf("%q: %w", fmt.Sprintf("event %s", event.Id), ErrAlreadyExists)
+		}
+		return &event, nil
+	})
+}
+
+// GetPendingEvents -- get the events that have not been delivered and are not parked, oldest first (a maximum
+// or limit of 0 is no maximum or limit)
+func (s *fsStorage) GetPendingEvents(ctx context.Context, maxAttempts uint, limit uint) ([]EasyStoreEvent, error) {
+	return s.selectEvents(ctx, 0, limit, func(ev *EasyStoreEvent) bool {
+		return ev.Delivered == nil && eventParked(*ev, maxAttempts) == false
+	})
+}
+
+// GetEventsByTime -- get the events created at or after the first time and before the second, oldest first,
+// skipping the first offset events (limit of 0 is no limit)
+func (s *fsStorage) GetEventsByTime(ctx context.Context, after time.Time, before time.Time, offset uint, limit uint) ([]EasyStoreEvent, error) {
+	return s.selectEvents(ctx, offset, limit, func(ev *EasyStoreEvent) bool {
+		return ev.Created.Before(after) == false && ev.Created.Before(before) == true
+	})
+}
+
+// UpdateEvent -- update the delivery state of an event
+func (s *fsStorage) UpdateEvent(ctx context.Context, event EasyStoreEvent) error {
+
+	if err := checkAssetName(event.Id); err != nil {
+		return err
+	}
+	return s.updateEvent(ctx, event.Id, func(cur *EasyStoreEvent) (*EasyStoreEvent, error) {
+		if cur == nil {
+			return nil, fmt.Errorf("%q: %w", fmt.Sprintf("event %s", event.Id), ErrNotFound)
+		}
+		cur.Attempts, cur.Delivered = event.Attempts, event.Delivered
+		return cur, nil
+	})
+}
+
+//
+// private implementation methods
+//
+
+func (s *fsStorage) eventPath(id string) string {
+	return filepath.Join(s.root, FsOutboxDir, fmt.Sprintf("%s.json", id))
+}
+
+// change the specified event (nil if there is none). Outside of a transaction changes must still be made
+// one at a time so they are not lost
+func (s *fsStorage) updateEvent(ctx context.Context, id string, update func(*EasyStoreEvent) (*EasyStoreEvent, error)) error {
+
+	if s.journal == nil {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+	}
+
+	var cur *EasyStoreEvent
+	if s.checkEventExists(id) == true {
+		ev, err := s.readEvent(ctx, s.eventPath(id))
+		if err != nil {
+			return err
+		}
+		cur = ev
+	}
+	ev, err := update(cur)
+	if err != nil {
+		return err
+	}
+
+	// cannot fail, the event is all simple types
+	b, _ := json.Marshal(ev)
+	return s.writeBuffer(ctx, s.eventPath(id), b)
+}
+
+func (s *fsStorage) checkEventExists(id string) bool {
+	return s.checkAssetExists(FsOutboxDir, "", fmt.Sprintf("%s.json", id))
+}
+
+func (s *fsStorage) readEvent(ctx context.Context, path string) (*EasyStoreEvent, error) {
+	b, err := s.readFile(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	var ev EasyStoreEvent
+	err = json.Unmarshal(b, &ev)
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w", err.Error(), ErrDeserialize)
+	}
+	return &ev, nil
+}
+
+// the selected events, oldest first
+func (s *fsStorage) selectEvents(ctx context.Context, offset uint, limit uint, selected func(*EasyStoreEvent) bool) ([]EasyStoreEvent, error) {
+
+	dir := filepath.Join(s.root, FsOutboxDir)
+	names, err := s.listFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]EasyStoreEvent, 0)
+	for _, name := range names {
+		if strings.HasSuffix(name, ".json") == false {
+			continue
+		}
+		ev, err := s.readEvent(ctx, filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		if selected(ev) == true {
+			results = append(results, *ev)
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Created.Equal(results[j].Created) == true {
+			return results[i].Id < results[j].Id
+		}
+		return results[i].Created.Before(results[j].Created)
+	})
+	results = pageEvents(results, offset, limit)
+
+	// check for not found
+	if len(results) == 0 {
+		return nil, fmt.Errorf("%q: %w", "event(s) not found", ErrNotFound)
+	}
+
+	logDebug(s.log, fmt.Sprintf("found %d event(s)", len(results)))
+	return results, nil
+}
+
+//
+// end of file
+//
@@ -0,0 +1,125 @@
+//
+// file system implementation of the datastore trash methods
+//
+
+// only include this file for service builds
+
+//go:build service
+// +build service
+
+package uvaeasystore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"time"
+)
+
+// deleted objects are marked with a tombstone file as follows:
+// root-dir/namespace/object-identifier/tombstone.json
+
+// TombstoneObjectByKey -- move the object to the trash
+func (s *fsStorage) TombstoneObjectByKey(ctx context.Context, key DataStoreKey) error {
+
+	// the object must exist and not already be in the trash
+	_, err := s.GetObjectByKey(ctx, key, NOCACHE)
+	if err != nil {
+		return err
+	}
+
+	// add the tombstone file, cannot fail, the tombstone is all simple types
+	tombstone := EasyStoreTombstone{Namespace: key.Namespace, Id: key.ObjectId, Deleted: time.Now()}
+	b, _ := json.Marshal(tombstone)
+	err = s.writeBuffer(ctx, s.assetPath(key.Namespace, key.ObjectId, S3TombstoneFileName), b)
+	if err != nil {
+		return err
+	}
+
+	// update the index
+	return s.updateIndex(ctx, key.Namespace, func(idx fsIndex) error {
+		e, err := idx.entry(key)
+		if err != nil {
+			return err
+		}
+		e.Deleted = &tombstone.Deleted
+		return nil
+	})
+}
+
+// RestoreObjectByKey -- restore the object from the trash
+func (s *fsStorage) RestoreObjectByKey(ctx context.Context, key DataStoreKey) error {
+
+	// the object must be in the trash
+	if s.checkAssetExists(key.Namespace, key.ObjectId, S3TombstoneFileName) == false {
+		return fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s/%s", key.Namespace, key.ObjectId, S3TombstoneFileName), ErrNotFound)
+	}
+
+	err := s.removeFile(ctx, s.assetPath(key.Namespace, key.ObjectId, S3TombstoneFileName))
+	if err != nil {
+		return err
+	}
+
+	// update the index
+	return s.updateIndex(ctx, key.Namespace, func(idx fsIndex) error {
+		e, err := idx.entry(key)
+		if err != nil {
+			return err
+		}
+		e.Deleted = nil
+		return nil
+	})
+}
+
+// PurgeObjectByKey -- remove the object from the trash along with its versions
+func (s *fsStorage) PurgeObjectByKey(ctx context.Context, key DataStoreKey) error {
+
+	// the object must be in the trash
+	if s.checkAssetExists(key.Namespace, key.ObjectId, S3TombstoneFileName) == false {
+		return fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s/%s", key.Namespace, key.ObjectId, S3TombstoneFileName), ErrNotFound)
+	}
+
+	// remove every file, including the file contents and the prior versions
+	for _, dir := range []string{
+		filepath.Join(s.root, key.Namespace, key.ObjectId),
+		filepath.Join(s.root, S3VersionPrefix, key.Namespace, key.ObjectId),
+	} {
+		files := make([]string, 0)
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() == false {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil && errors.Is(err, fs.ErrNotExist) == false {
+			return err
+		}
+		for _, f := range files {
+			err = s.removeFile(ctx, f)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// update the index
+	return s.updateIndex(ctx, key.Namespace, func(idx fsIndex) error {
+		delete(idx, key.ObjectId)
+		return nil
+	})
+}
+
+// GetTombstones -- get the objects in the trash that were deleted before the specified time
+func (s *fsStorage) GetTombstones(ctx context.Context, namespace string, before time.Time) ([]EasyStoreTombstone, error) {
+	return s.tombstonesBefore(ctx, namespace, before)
+}
+
+//
+// end of file
+//
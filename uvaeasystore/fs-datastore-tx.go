@@ -0,0 +1,160 @@
+//
+// file system implementation of the datastore transaction interface
+//
+
+// only include this file for service builds
+
+//go:build service
+// +build service
+
+package uvaeasystore
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// files are backed up under this directory before they are changed within a transaction
+var FsRollbackDir = ".rollback"
+
+// a single file changed within a transaction
+type fsJournalEntry struct {
+	path   string // the file that was changed
+	backup string // the backup of the original file (blank if it did not previously exist)
+}
+
+// the set of files changed within a transaction
+type fsJournal struct {
+	id      string           // journal identifier, used to name the backups
+	entries []fsJournalEntry // in the order they were made
+	seen    map[string]bool  // files already journaled
+}
+
+func newFsJournal() *fsJournal {
+	return &fsJournal{
+		id:      newObjectId(),
+		entries: make([]fsJournalEntry, 0),
+		seen:    make(map[string]bool),
+	}
+}
+
+// this is our file system transaction implementation
+type fsStorageTx struct {
+	*fsStorage // the storage, bound to the transaction
+}
+
+// Commit -- the changes are already made so discard the backups
+func (t *fsStorageTx) Commit() error {
+
+	if t.journal == nil {
+		return sql.ErrTxDone
+	}
+
+	// the changes are committed so failing to tidy up is not fatal
+	err := os.RemoveAll(t.backupDir())
+	if err != nil {
+		logWarning(t.log, fmt.Sprintf("unable to remove backups [%s] (%s)", t.backupDir(), err.Error()))
+	}
+	for _, e := range t.journal.entries {
+		t.removeEmptyDirs(e.path)
+	}
+
+	t.journal = nil
+	t.lock.Unlock()
+	return nil
+}
+
+// Rollback -- undo the changes, a no-op if the transaction is already complete
+func (t *fsStorageTx) Rollback() error {
+
+	if t.journal == nil {
+		return nil
+	}
+
+	// undo the changes in reverse order
+	var result error
+	for ix := len(t.journal.entries) - 1; ix >= 0; ix-- {
+		e := t.journal.entries[ix]
+		var err error
+		if len(e.backup) != 0 {
+			// put the original back
+			err = os.MkdirAll(filepath.Dir(e.path), 0755)
+			if err == nil {
+				err = os.Rename(e.backup, e.path)
+			}
+		} else {
+			// the file was created in this transaction, remove it
+			err = os.Remove(e.path)
+			if errors.Is(err, fs.ErrNotExist) == true {
+				err = nil
+			}
+			t.removeEmptyDirs(e.path)
+		}
+		if err != nil {
+			logError(t.log, fmt.Sprintf("unable to roll back [%s] (%s)", e.path, err.Error()))
+			if result == nil {
+				result = err
+			}
+		}
+	}
+
+	_ = os.RemoveAll(t.backupDir())
+	t.journal = nil
+	t.lock.Unlock()
+	return result
+}
+
+// Close -- roll back anything not committed
+func (t *fsStorageTx) Close() error {
+	return t.Rollback()
+}
+
+func (t *fsStorageTx) backupDir() string {
+	return filepath.Join(t.root, FsRollbackDir, t.journal.id)
+}
+
+// record the state of the specified file before it is first changed within a transaction
+func (s *fsStorage) journalPath(path string) error {
+
+	// not in a transaction or already journaled
+	if s.journal == nil || s.journal.seen[path] == true {
+		return nil
+	}
+
+	e := fsJournalEntry{path: path}
+	if _, err := os.Stat(path); err == nil {
+		rel, err := filepath.Rel(s.root, path)
+		if err != nil {
+			return err
+		}
+		e.backup = filepath.Join(s.root, FsRollbackDir, s.journal.id, rel)
+		if err := linkOrCopy(path, e.backup); err != nil {
+			return err
+		}
+	}
+
+	s.journal.entries = append(s.journal.entries, e)
+	s.journal.seen[path] = true
+	return nil
+}
+
+// remove the directories left empty when a file was removed, up to (but not including) the
+// top level directories
+func (s *fsStorage) removeEmptyDirs(path string) {
+	if _, err := os.Stat(path); err == nil {
+		return
+	}
+	for dir := filepath.Dir(path); filepath.Dir(dir) != s.root && dir != s.root; dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			return
+		}
+	}
+}
+
+//
+// end of file
+//
@@ -0,0 +1,132 @@
+//
+// file system implementation of the datastore version methods
+//
+
+// only include this file for service builds
+
+//go:build service
+// +build service
+
+package uvaeasystore
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// prior versions are copies of the object files, kept outside of the namespaces as follows:
+// root-dir/.versions/namespace/object-identifier/vtag/asset-name
+
+// AddVersion -- preserve the current object, fields, metadata and files as a prior version
+func (s *fsStorage) AddVersion(ctx context.Context, key DataStoreKey) error {
+
+	// we need the current vtag
+	obj, err := s.GetObjectByKey(ctx, key, NOCACHE)
+	if err != nil {
+		return err
+	}
+
+	names, err := s.listFiles(filepath.Join(s.root, key.Namespace, key.ObjectId))
+	if err != nil {
+		return err
+	}
+	vns, vid := s.versionKey(key, obj.VTag())
+	for _, name := range names {
+		err = s.copyFile(ctx, s.assetPath(key.Namespace, key.ObjectId, name), s.assetPath(vns, vid, name))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetVersionsByKey -- get the prior versions of the specified object, oldest first
+func (s *fsStorage) GetVersionsByKey(ctx context.Context, key DataStoreKey) ([]EasyStoreVersion, error) {
+
+	entries, err := os.ReadDir(filepath.Join(s.root, S3VersionPrefix, key.Namespace, key.ObjectId))
+	if err != nil && os.IsNotExist(err) == false {
+		return nil, err
+	}
+
+	// each version is a directory with an object file
+	results := make([]EasyStoreVersion, 0)
+	for _, e := range entries {
+		vns, vid := s.versionKey(key, e.Name())
+		if e.IsDir() == false || s.checkAssetExists(vns, vid, S3ObjectFileName) == false {
+			continue
+		}
+		obj, err := s.getObject(ctx, vns, vid)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, EasyStoreVersion{VTag: obj.VTag(), Created: obj.Created(), Modified: obj.Modified()})
+	}
+
+	// check for not found
+	if len(results) == 0 {
+		return nil, fmt.Errorf("%q: %w", "version(s) not found", ErrNotFound)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Modified.Before(results[j].Modified)
+	})
+
+	logDebug(s.log, fmt.Sprintf("found %d version(s)", len(results)))
+	return results, nil
+}
+
+// GetVersionByKey -- get the specified prior version of an object with all of its components
+func (s *fsStorage) GetVersionByKey(ctx context.Context, key DataStoreKey, vtag string) (EasyStoreObject, error) {
+
+	// versions have the same layout as the objects themselves
+	vns, vid := s.versionKey(key, vtag)
+	if checkAssetName(vtag) != nil || s.checkAssetExists(vns, vid, S3ObjectFileName) == false {
+		return nil, fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s/%s", vns, vid, S3ObjectFileName), ErrNotFound)
+	}
+	obj, err := s.getObject(ctx, vns, vid)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.checkAssetExists(vns, vid, S3FieldsFileName) == true {
+		fields, err := s.getFields(ctx, vns, vid)
+		if err != nil {
+			return nil, err
+		}
+		obj.SetMultiFields(*fields)
+	}
+
+	if s.checkAssetExists(vns, vid, S3MetadataFileName) == true {
+		md, err := s.getMetadata(ctx, vns, vid)
+		if err != nil {
+			return nil, err
+		}
+		obj.SetMetadata(md)
+	}
+
+	files, err := s.getBlobs(ctx, vns, vid)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) != 0 {
+		obj.SetFiles(files)
+	}
+
+	return obj, nil
+}
+
+//
+// private methods
+//
+
+// the namespace and identifier that locate a version using the standard asset layout
+func (s *fsStorage) versionKey(key DataStoreKey, vtag string) (string, string) {
+	return filepath.Join(S3VersionPrefix, key.Namespace), filepath.Join(key.ObjectId, vtag)
+}
+
+//
+// end of file
+//
@@ -0,0 +1,130 @@
+//
+// file system implementation of the datastore interface
+//
+
+// only include this file for service builds
+
+//go:build service
+// +build service
+
+package uvaeasystore
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// DatastoreFilesystemConfig -- this is our file system configuration implementation
+type DatastoreFilesystemConfig struct {
+	RootDir    string             // the root of the directory tree, created if it does not exist
+	BusName    string             // the message bus name
+	SourceName string             // the event source name
+	Sink       EasyStoreEventSink // where events are published (instead of the message bus)
+	Access     *EasyStorePolicy   // the access policy (nil allows everything)
+	Audit      EasyStoreAuditLog  // where denied requests are recorded (the logger when nil)
+	Log        *log.Logger        // the logger
+}
+
+func (impl DatastoreFilesystemConfig) Logger() *log.Logger {
+	return impl.Log
+}
+
+func (impl DatastoreFilesystemConfig) SetLogger(log *log.Logger) {
+	impl.Log = log
+}
+
+func (impl DatastoreFilesystemConfig) MessageBus() string {
+	return impl.BusName
+}
+
+func (impl DatastoreFilesystemConfig) SetMessageBus(busName string) {
+	impl.BusName = busName
+}
+
+func (impl DatastoreFilesystemConfig) EventSource() string {
+	return impl.SourceName
+}
+
+func (impl DatastoreFilesystemConfig) SetEventSource(sourceName string) {
+	impl.SourceName = sourceName
+}
+
+func (impl DatastoreFilesystemConfig) EventSink() EasyStoreEventSink {
+	return impl.Sink
+}
+
+func (impl DatastoreFilesystemConfig) SetEventSink(sink EasyStoreEventSink) {
+	impl.Sink = sink
+}
+
+func (impl DatastoreFilesystemConfig) Policy() *EasyStorePolicy {
+	return impl.Access
+}
+
+func (impl DatastoreFilesystemConfig) SetPolicy(policy *EasyStorePolicy) {
+	impl.Access = policy
+}
+
+func (impl DatastoreFilesystemConfig) AuditLog() EasyStoreAuditLog {
+	return impl.Audit
+}
+
+func (impl DatastoreFilesystemConfig) SetAuditLog(audit EasyStoreAuditLog) {
+	impl.Audit = audit
+}
+
+// newFilesystemStore -- create a file system version of the DataStore
+func newFilesystemStore(config EasyStoreImplConfig) (DataStore, error) {
+
+	// make sure its one of these
+	c, ok := config.(DatastoreFilesystemConfig)
+	if ok == false {
+		return nil, fmt.Errorf("%q: %w", "bad configuration, not a DatastoreFilesystemConfig", ErrBadParameter)
+	}
+
+	// validate our configuration
+	err := validateFilesystemConfig(c)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := filepath.Abs(c.RootDir)
+	if err != nil {
+		return nil, err
+	}
+
+	logDebug(config.Logger(), fmt.Sprintf("using [file://%s] for storage", root))
+
+	// make sure the tree exists
+	err = os.MkdirAll(filepath.Join(root, FsIndexDir), 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fsStorage{
+		root:      root,
+		serialize: newEasyStoreSerializer(),
+		log:       c.Log,
+		lock:      &sync.Mutex{},
+	}, nil
+}
+
+func validateFilesystemConfig(config DatastoreFilesystemConfig) error {
+
+	if len(config.RootDir) == 0 {
+		return fmt.Errorf("%q: %w", "config.RootDir is blank", ErrBadParameter)
+	}
+
+	if len(config.BusName) != 0 && len(config.SourceName) == 0 {
+		return fmt.Errorf("%q: %w", "config.SourceName is blank", ErrBadParameter)
+	}
+
+	return nil
+}
+
+//
+// end of file
+//
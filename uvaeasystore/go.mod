@@ -9,9 +9,9 @@ require (
 	github.com/aws/aws-sdk-go-v2/feature/s3/manager v1.22.12
 	github.com/aws/aws-sdk-go-v2/service/s3 v1.98.0
 	github.com/lib/pq v1.12.3
+	github.com/mattn/go-sqlite3 v1.14.52
 	github.com/rs/xid v1.6.0
 	github.com/uvalib/librabus-sdk/uvalibrabus v0.0.0-20250801130056-157231a1fcac
-	golang.org/x/exp v0.0.0-20260312153236-7ab1446f8b90
 )
 
 require (
@@ -0,0 +1,49 @@
+//
+// in-memory implementation of the datastore copy method
+//
+
+// only include this file for service builds
+
+//go:build service
+// +build service
+
+package uvaeasystore
+
+import (
+	"context"
+	"fmt"
+)
+
+// CopyObjectByKey -- copy the object, fields, metadata and files to the new key
+func (s *memStorage) CopyObjectByKey(ctx context.Context, from DataStoreKey, to DataStoreKey) error {
+
+	release, err := s.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	src := s.records[from]
+	if src == nil || src.live() == false {
+		return fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s", from.Namespace, from.ObjectId), ErrNotFound)
+	}
+
+	return s.update(to, func(rec *memRecord) error {
+		// the identifier is in use until the object is purged, even when it is in the trash
+		if rec.object != nil {
+			return fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s", to.Namespace, to.ObjectId), ErrAlreadyExists)
+		}
+
+		// records are never changed so the copy can share the components
+		*rec = *src.clone()
+		rec.versions = nil
+		obj := *src.object
+		obj.Namespace_, obj.Id_, obj.Vtag_ = to.Namespace, to.ObjectId, newVtag()
+		rec.object = &obj
+		return nil
+	})
+}
+
+//
+// end of file
+//
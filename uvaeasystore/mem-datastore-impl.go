@@ -0,0 +1,613 @@
+//
+// in-memory implementation of the datastore interface
+//
+
+// only include this file for service builds
+
+//go:build service
+// +build service
+
+package uvaeasystore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// this is our in-memory implementation
+type memStorage struct {
+	records map[DataStoreKey]*memRecord // everything we know about each object
+	events  map[string]*EasyStoreEvent  // the outbox, by event identifier
+	log     *log.Logger                 // logger
+	lock    *sync.Mutex                 // serializes access, held for the life of a transaction
+	journal *memJournal                 // records and events replaced in the current transaction (if any)
+}
+
+// an object and its components. Records are never changed once stored, changes replace the whole
+// record which is what makes rolling back a transaction simple
+type memRecord struct {
+	object   *easyStoreObjectImpl       // the object (nil if there is none)
+	fields   EasyStoreObjectMultiFields // the fields (nil if there are none)
+	metadata *easyStoreMetadataImpl     // the metadata (nil if there is none)
+	blobs    []*easyStoreBlobImpl       // the files, oldest first
+	deleted  *time.Time                 // when it was moved to the trash (if it was)
+	versions []*memRecord               // the prior versions, oldest first
+}
+
+// Check -- always available
+func (s *memStorage) Check(ctx context.Context) error {
+	return nil
+}
+
+// UpdateBlob -- update the contents of an existing blob
+func (s *memStorage) UpdateBlob(ctx context.Context, key DataStoreKey, blob EasyStoreBlob) error {
+
+	release, err := s.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return s.update(key, func(rec *memRecord) error {
+		ix := rec.blobIndex(blob.Name())
+		if ix < 0 {
+			return fmt.Errorf("%q: %w", blob.Name(), ErrNotFound)
+		}
+		b, err := newMemBlob(blob)
+		if err != nil {
+			return err
+		}
+
+		// the blob keeps its original created time
+		b.Created_ = rec.blobs[ix].Created_
+		rec.blobs = append(append(rec.blobs[:ix:ix], rec.blobs[ix+1:]...), b)
+		return nil
+	})
+}
+
+// UpdateFields -- update the contents of an existing field set
+func (s *memStorage) UpdateFields(ctx context.Context, key DataStoreKey, fields EasyStoreObjectMultiFields) error {
+	return s.AddFields(ctx, key, fields)
+}
+
+// UpdateMetadata -- update the contents of existing metadata
+func (s *memStorage) UpdateMetadata(ctx context.Context, key DataStoreKey, md EasyStoreMetadata) error {
+	return s.AddMetadata(ctx, key, md)
+}
+
+// UpdateObject -- update a couple of object fields
+func (s *memStorage) UpdateObject(ctx context.Context, key DataStoreKey) error {
+
+	release, err := s.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return s.update(key, func(rec *memRecord) error {
+		if rec.live() == false {
+			return fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s", key.Namespace, key.ObjectId), ErrNotFound)
+		}
+		obj := *rec.object
+		obj.Vtag_ = newVtag()
+		obj.Modified_ = time.Now()
+		rec.object = &obj
+		return nil
+	})
+}
+
+// LockObjectByKey -- check the vtag, a transaction already holds the storage lock until it completes
+func (s *memStorage) LockObjectByKey(ctx context.Context, key DataStoreKey, vtag string) error {
+
+	release, err := s.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	obj, err := s.getObject(key)
+	if err != nil {
+		return err
+	}
+	if obj.VTag() != vtag {
+		return fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s (vtag %s)", key.Namespace, key.ObjectId, vtag), ErrStaleObject)
+	}
+	return nil
+}
+
+// AddBlob -- add a new blob object
+func (s *memStorage) AddBlob(ctx context.Context, key DataStoreKey, blob EasyStoreBlob) error {
+
+	release, err := s.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return s.update(key, func(rec *memRecord) error {
+		if rec.blobIndex(blob.Name()) >= 0 {
+			return fmt.Errorf("%q: %w", blob.Name(), ErrAlreadyExists)
+		}
+		b, err := newMemBlob(blob)
+		if err != nil {
+			return err
+		}
+		rec.blobs = append(rec.blobs, b)
+		return nil
+	})
+}
+
+// AddFields -- add a new fields object
+func (s *memStorage) AddFields(ctx context.Context, key DataStoreKey, fields EasyStoreObjectMultiFields) error {
+
+	release, err := s.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return s.update(key, func(rec *memRecord) error {
+		rec.fields = copyFields(fields)
+		return nil
+	})
+}
+
+// AddMetadata -- add a new metadata object
+func (s *memStorage) AddMetadata(ctx context.Context, key DataStoreKey, metadata EasyStoreMetadata) error {
+
+	release, err := s.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// errors here are serialization errors
+	buf, err := metadata.Payload()
+	if err != nil {
+		return err
+	}
+
+	return s.update(key, func(rec *memRecord) error {
+		rec.metadata = &easyStoreMetadataImpl{
+			MimeType_: metadata.MimeType(),
+			Payload_:  bytes.Clone(buf),
+			Created_:  time.Now(),
+			Modified_: time.Now(),
+		}
+		return nil
+	})
+}
+
+// AddObject -- add a new object
+func (s *memStorage) AddObject(ctx context.Context, obj EasyStoreObject) error {
+
+	release, err := s.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// for setting the timestamps
+	impl, ok := obj.(*easyStoreObjectImpl)
+	if ok == false {
+		return fmt.Errorf("%q: %w", "cast failed, not an easyStoreObjectImpl", ErrBadParameter)
+	}
+
+	key := DataStoreKey{obj.Namespace(), obj.Id()}
+	return s.update(key, func(rec *memRecord) error {
+		// the identifier is in use until the object is purged, even when it is in the trash
+		if rec.object != nil {
+			return fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s", key.Namespace, key.ObjectId), ErrAlreadyExists)
+		}
+		impl.Created_, impl.Modified_ = time.Now(), time.Now()
+
+		// the components are stored separately
+		rec.object = &easyStoreObjectImpl{
+			Namespace_: impl.Namespace_,
+			Id_:        impl.Id_,
+			Vtag_:      impl.Vtag_,
+			Created_:   impl.Created_,
+			Modified_:  impl.Modified_,
+		}
+		return nil
+	})
+}
+
+// GetBlobsByKey -- get all blob data associated with the specified object
+func (s *memStorage) GetBlobsByKey(ctx context.Context, key DataStoreKey, useCache bool) ([]EasyStoreBlob, error) {
+
+	// this implementation does not use a cache so useCache is ignored
+
+	release, err := s.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	rec := s.records[key]
+	if rec == nil || len(rec.blobs) == 0 {
+		return nil, fmt.Errorf("%q: %w", "blobs(s) not found", ErrNotFound)
+	}
+	return rec.copyBlobs(), nil
+}
+
+// GetFieldsByKey -- get all field data associated with the specified object
+func (s *memStorage) GetFieldsByKey(ctx context.Context, key DataStoreKey, useCache bool) (*EasyStoreObjectMultiFields, error) {
+
+	// this implementation does not use a cache so useCache is ignored
+
+	release, err := s.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	rec := s.records[key]
+	if rec == nil || rec.fields == nil {
+		return nil, fmt.Errorf("%q: %w", "field(s) not found", ErrNotFound)
+	}
+	fields := copyFields(rec.fields)
+	return &fields, nil
+}
+
+// GetMetadataByKey -- get all field data associated with the specified object
+func (s *memStorage) GetMetadataByKey(ctx context.Context, key DataStoreKey, useCache bool) (EasyStoreMetadata, error) {
+
+	// this implementation does not use a cache so useCache is ignored
+
+	release, err := s.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	rec := s.records[key]
+	if rec == nil || rec.metadata == nil {
+		return nil, fmt.Errorf("%q: %w", "metadata not found", ErrNotFound)
+	}
+	return rec.copyMetadata(), nil
+}
+
+// GetBlobByKey -- get the named blob associated with the specified object
+func (s *memStorage) GetBlobByKey(ctx context.Context, key DataStoreKey, curName string, useCache bool) (EasyStoreBlob, error) {
+
+	// this implementation does not use a cache so useCache is ignored
+
+	release, err := s.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	rec := s.records[key]
+	if rec == nil || rec.blobIndex(curName) < 0 {
+		return nil, fmt.Errorf("%q: %w", curName, ErrFileNotFound)
+	}
+	return copyBlob(rec.blobs[rec.blobIndex(curName)]), nil
+}
+
+// GetObjectByKey -- get all field data associated with the specified object
+func (s *memStorage) GetObjectByKey(ctx context.Context, key DataStoreKey, useCache bool) (EasyStoreObject, error) {
+
+	// this implementation does not use a cache so useCache is ignored
+
+	release, err := s.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return s.getObject(key)
+}
+
+// GetObjectsByKey -- get all field data associated with the specified object
+func (s *memStorage) GetObjectsByKey(ctx context.Context, keys []DataStoreKey, useCache bool) ([]EasyStoreObject, error) {
+
+	release, err := s.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	results := make([]EasyStoreObject, 0, len(keys))
+	for _, key := range keys {
+		obj, err := s.getObject(key)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) == false {
+				// a real error
+				return nil, err
+			}
+		} else {
+			results = append(results, obj)
+		}
+	}
+	if len(results) == 0 {
+		return nil, ErrNotFound
+	}
+	return results, nil
+}
+
+// RenameBlobByKey -- rename the named blob to the new name
+func (s *memStorage) RenameBlobByKey(ctx context.Context, key DataStoreKey, curName string, newName string) error {
+
+	release, err := s.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return s.update(key, func(rec *memRecord) error {
+
+		// check currently named blob exists
+		ix := rec.blobIndex(curName)
+		if ix < 0 {
+			return fmt.Errorf("%q: %w", curName, ErrFileNotFound)
+		}
+
+		// check new name does not already exist
+		if rec.blobIndex(newName) >= 0 {
+			return fmt.Errorf("%q: %w", newName, ErrAlreadyExists)
+		}
+
+		// update the attributes
+		b := *rec.blobs[ix]
+		b.Name_ = newName
+		b.Modified_ = time.Now()
+		rec.blobs[ix] = &b
+		return nil
+	})
+}
+
+// DeleteBlobByKey -- delete a single blob associated with the specified object
+func (s *memStorage) DeleteBlobByKey(ctx context.Context, key DataStoreKey, curName string) error {
+
+	release, err := s.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return s.update(key, func(rec *memRecord) error {
+		if ix := rec.blobIndex(curName); ix >= 0 {
+			rec.blobs = append(rec.blobs[:ix:ix], rec.blobs[ix+1:]...)
+		}
+		return nil
+	})
+}
+
+// DeleteBlobsByKey -- delete all blob data associated with the specified object
+func (s *memStorage) DeleteBlobsByKey(ctx context.Context, key DataStoreKey) error {
+
+	release, err := s.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return s.update(key, func(rec *memRecord) error {
+		rec.blobs = nil
+		return nil
+	})
+}
+
+// DeleteFieldsByKey -- delete all field data associated with the specified object
+func (s *memStorage) DeleteFieldsByKey(ctx context.Context, key DataStoreKey) error {
+
+	release, err := s.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return s.update(key, func(rec *memRecord) error {
+		rec.fields = nil
+		return nil
+	})
+}
+
+// DeleteMetadataByKey -- delete all field data associated with the specified object
+func (s *memStorage) DeleteMetadataByKey(ctx context.Context, key DataStoreKey) error {
+
+	release, err := s.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return s.update(key, func(rec *memRecord) error {
+		rec.metadata = nil
+		return nil
+	})
+}
+
+// DeleteObjectByKey -- delete all field data associated with the specified object
+func (s *memStorage) DeleteObjectByKey(ctx context.Context, key DataStoreKey) error {
+
+	release, err := s.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return s.update(key, func(rec *memRecord) error {
+		rec.object = nil
+		rec.deleted = nil
+		return nil
+	})
+}
+
+// GetKeysByFields -- get a page of keys of objects matching the query and the token for the next page
+func (s *memStorage) GetKeysByFields(ctx context.Context, namespace string, query EasyStoreQuery, page EasyStorePage) ([]DataStoreKey, string, error) {
+
+	release, err := s.acquire(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	defer release()
+
+	keys := make([]pageKey, 0)
+	for key, rec := range s.records {
+		// deleted objects are never found
+		if rec.live() == false || (len(namespace) != 0 && key.Namespace != namespace) {
+			continue
+		}
+		match, err := matchQuery(query, rec.fields)
+		if err != nil {
+			return nil, "", err
+		}
+		if match == true {
+			keys = append(keys, pageKey{key: key, created: rec.object.Created_, modified: rec.object.Modified_})
+		}
+	}
+	return pageOfKeys(page, keys, s.log)
+}
+
+// Begin -- begin a new transaction. Replaced records are journaled so they can be put back on rollback
+// and everything else waits until the transaction is complete
+func (s *memStorage) Begin(ctx context.Context) (DataStoreTx, error) {
+
+	if s.journal != nil {
+		return nil, fmt.Errorf("%q: %w", "nested transactions are not supported", ErrNotImplemented)
+	}
+
+	s.lock.Lock()
+	ts := *s
+	ts.journal = newMemJournal()
+	return &memStorageTx{&ts}, nil
+}
+
+// Close -- nothing to close
+func (s *memStorage) Close() error {
+	return nil
+}
+
+//
+// private implementation methods
+//
+
+// access is one at a time, a transaction already holds the lock
+func (s *memStorage) acquire(ctx context.Context) (func(), error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if s.journal != nil {
+		return func() {}, nil
+	}
+	s.lock.Lock()
+	return s.lock.Unlock, nil
+}
+
+// replace the record for the specified key with an updated copy, nothing changes if the update fails
+func (s *memStorage) update(key DataStoreKey, update func(*memRecord) error) error {
+
+	rec := &memRecord{}
+	if cur := s.records[key]; cur != nil {
+		rec = cur.clone()
+	}
+	err := update(rec)
+	if err != nil {
+		return err
+	}
+
+	s.journalRecord(key)
+	if rec.empty() == true {
+		delete(s.records, key)
+	} else {
+		s.records[key] = rec
+	}
+	return nil
+}
+
+func (s *memStorage) getObject(key DataStoreKey) (EasyStoreObject, error) {
+	rec := s.records[key]
+	if rec == nil || rec.live() == false {
+		return nil, fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s", key.Namespace, key.ObjectId), ErrNotFound)
+	}
+	obj := *rec.object
+	return &obj, nil
+}
+
+// a copy of the record that can be changed without changing the original
+func (r *memRecord) clone() *memRecord {
+	c := *r
+	c.blobs = append([]*easyStoreBlobImpl{}, r.blobs...)
+	c.versions = append([]*memRecord{}, r.versions...)
+	return &c
+}
+
+// the record has nothing left in it
+func (r *memRecord) empty() bool {
+	return r.object == nil && r.fields == nil && r.metadata == nil && len(r.blobs) == 0 && len(r.versions) == 0
+}
+
+// the record has an object that is not in the trash
+func (r *memRecord) live() bool {
+	return r.object != nil && r.deleted == nil
+}
+
+// the position of the named blob (-1 if there is none)
+func (r *memRecord) blobIndex(name string) int {
+	for ix, b := range r.blobs {
+		if b.Name_ == name {
+			return ix
+		}
+	}
+	return -1
+}
+
+func (r *memRecord) copyBlobs() []EasyStoreBlob {
+	blobs := make([]EasyStoreBlob, 0, len(r.blobs))
+	for _, b := range r.blobs {
+		blobs = append(blobs, copyBlob(b))
+	}
+	return blobs
+}
+
+func (r *memRecord) copyMetadata() EasyStoreMetadata {
+	md := *r.metadata
+	md.Payload_ = bytes.Clone(r.metadata.Payload_)
+	return &md
+}
+
+// the stored blob, the payload is read now because the source may not be readable later
+func newMemBlob(blob EasyStoreBlob) (*easyStoreBlobImpl, error) {
+
+	// errors here are serialization errors
+	buf, err := blob.Payload()
+	if err != nil {
+		return nil, err
+	}
+	return &easyStoreBlobImpl{
+		Name_:     blob.Name(),
+		MimeType_: blob.MimeType(),
+		Payload_:  bytes.Clone(buf),
+		Checksum_: payloadChecksum(buf),
+		Created_:  time.Now(),
+		Modified_: time.Now(),
+	}, nil
+}
+
+func copyBlob(blob *easyStoreBlobImpl) EasyStoreBlob {
+	b := *blob
+	b.Payload_ = bytes.Clone(blob.Payload_)
+	return &b
+}
+
+// an empty set of fields is still a set of fields, nil means there are none
+func copyFields(fields EasyStoreObjectMultiFields) EasyStoreObjectMultiFields {
+	c := make(EasyStoreObjectMultiFields, len(fields))
+	for n, v := range fields {
+		c[n] = append([]string(nil), v...)
+	}
+	return c
+}
+
+//
+// end of file
+//
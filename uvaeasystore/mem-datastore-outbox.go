@@ -0,0 +1,129 @@
+//
+// in-memory implementation of the datastore outbox methods
+//
+
+// only include this file for service builds
+
+//go:build service
+// +build service
+
+package uvaeasystore
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// AddEvent -- add an event to the outbox
+func (s *memStorage) AddEvent(ctx context.Context, event EasyStoreEvent) error {
+
+	release, err := s.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if _, found := s.events[event.Id]; found == true {
+		return fmt.Errorf("%q: %w", fmt.Sprintf("event %s", event.Id), ErrAlreadyExists)
+	}
+	s.putEvent(event)
+	return nil
+}
+
+// GetPendingEvents -- get the events that have not been delivered and are not parked, oldest first (a maximum
+// or limit of 0 is no maximum or limit)
+func (s *memStorage) GetPendingEvents(ctx context.Context, maxAttempts uint, limit uint) ([]EasyStoreEvent, error) {
+
+	release, err := s.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	results := s.selectEvents(func(ev *EasyStoreEvent) bool {
+		return ev.Delivered == nil && eventParked(*ev, maxAttempts) == false
+	})
+	results = pageEvents(results, 0, limit)
+	if len(results) == 0 {
+		return nil, fmt.Errorf("%q: %w", "event(s) not found", ErrNotFound)
+	}
+	return results, nil
+}
+
+// GetEventsByTime -- get the events created at or after the first time and before the second, oldest first,
+// skipping the first offset events (limit of 0 is no limit)
+func (s *memStorage) GetEventsByTime(ctx context.Context, after time.Time, before time.Time, offset uint, limit uint) ([]EasyStoreEvent, error) {
+
+	release, err := s.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	results := s.selectEvents(func(ev *EasyStoreEvent) bool {
+		return ev.Created.Before(after) == false && ev.Created.Before(before) == true
+	})
+	results = pageEvents(results, offset, limit)
+	if len(results) == 0 {
+		return nil, fmt.Errorf("%q: %w", "event(s) not found", ErrNotFound)
+	}
+	return results, nil
+}
+
+// UpdateEvent -- update the delivery state of an event
+func (s *memStorage) UpdateEvent(ctx context.Context, event EasyStoreEvent) error {
+
+	release, err := s.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	cur, found := s.events[event.Id]
+	if found == false {
+		return fmt.Errorf("%q: %w", fmt.Sprintf("event %s", event.Id), ErrNotFound)
+	}
+	ev := *cur
+	ev.Attempts, ev.Delivered = event.Attempts, event.Delivered
+	s.putEvent(ev)
+	return nil
+}
+
+//
+// private implementation methods
+//
+
+// events are never changed once stored, changes replace the whole event
+func (s *memStorage) putEvent(event EasyStoreEvent) {
+	s.journalEvent(event.Id)
+	if event.Delivered != nil {
+		delivered := *event.Delivered
+		event.Delivered = &delivered
+	}
+	event.Detail = append([]byte{}, event.Detail...)
+	s.events[event.Id] = &event
+}
+
+// the selected events, oldest first
+func (s *memStorage) selectEvents(selected func(*EasyStoreEvent) bool) []EasyStoreEvent {
+
+	results := make([]EasyStoreEvent, 0)
+	for _, ev := range s.events {
+		if selected(ev) == true {
+			results = append(results, *ev)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Created.Equal(results[j].Created) == true {
+			return results[i].Id < results[j].Id
+		}
+		return results[i].Created.Before(results[j].Created)
+	})
+	return results
+}
+
+//
+// end of file
+//
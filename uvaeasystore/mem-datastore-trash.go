@@ -0,0 +1,111 @@
+//
+// in-memory implementation of the datastore trash methods
+//
+
+// only include this file for service builds
+
+//go:build service
+// +build service
+
+package uvaeasystore
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// TombstoneObjectByKey -- move the object to the trash
+func (s *memStorage) TombstoneObjectByKey(ctx context.Context, key DataStoreKey) error {
+
+	release, err := s.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return s.update(key, func(rec *memRecord) error {
+		// the object must exist and not already be in the trash
+		if rec.live() == false {
+			return fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s", key.Namespace, key.ObjectId), ErrNotFound)
+		}
+		deleted := time.Now()
+		rec.deleted = &deleted
+		return nil
+	})
+}
+
+// RestoreObjectByKey -- restore the object from the trash
+func (s *memStorage) RestoreObjectByKey(ctx context.Context, key DataStoreKey) error {
+
+	release, err := s.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return s.update(key, func(rec *memRecord) error {
+		// the object must be in the trash
+		if rec.object == nil || rec.deleted == nil {
+			return fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s", key.Namespace, key.ObjectId), ErrNotFound)
+		}
+		rec.deleted = nil
+		return nil
+	})
+}
+
+// PurgeObjectByKey -- remove the object from the trash along with its versions
+func (s *memStorage) PurgeObjectByKey(ctx context.Context, key DataStoreKey) error {
+
+	release, err := s.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return s.update(key, func(rec *memRecord) error {
+		// the object must be in the trash
+		if rec.object == nil || rec.deleted == nil {
+			return fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s", key.Namespace, key.ObjectId), ErrNotFound)
+		}
+		rec.object, rec.deleted, rec.versions = nil, nil, nil
+		return nil
+	})
+}
+
+// GetTombstones -- get the objects in the trash that were deleted before the specified time
+func (s *memStorage) GetTombstones(ctx context.Context, namespace string, before time.Time) ([]EasyStoreTombstone, error) {
+
+	release, err := s.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	results := make([]EasyStoreTombstone, 0)
+	for key, rec := range s.records {
+		if len(namespace) != 0 && key.Namespace != namespace {
+			continue
+		}
+		if rec.object != nil && rec.deleted != nil && rec.deleted.Before(before) == true {
+			results = append(results, EasyStoreTombstone{Namespace: key.Namespace, Id: key.ObjectId, Deleted: *rec.deleted})
+		}
+	}
+
+	// check for not found
+	if len(results) == 0 {
+		return nil, fmt.Errorf("%q: %w", "deleted object(s) not found", ErrNotFound)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Deleted.Before(results[j].Deleted)
+	})
+
+	logDebug(s.log, fmt.Sprintf("found %d deleted object(s)", len(results)))
+	return results, nil
+}
+
+//
+// end of file
+//
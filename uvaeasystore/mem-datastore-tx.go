@@ -0,0 +1,107 @@
+//
+// in-memory implementation of the datastore transaction interface
+//
+
+// only include this file for service builds
+
+//go:build service
+// +build service
+
+package uvaeasystore
+
+import (
+	"database/sql"
+)
+
+// the records and events replaced within a transaction as they were before it began (nil if there
+// was none)
+type memJournal struct {
+	records map[DataStoreKey]*memRecord
+	events  map[string]*EasyStoreEvent
+}
+
+func newMemJournal() *memJournal {
+	return &memJournal{
+		records: make(map[DataStoreKey]*memRecord),
+		events:  make(map[string]*EasyStoreEvent),
+	}
+}
+
+// this is our in-memory transaction implementation
+type memStorageTx struct {
+	*memStorage // the storage, bound to the transaction
+}
+
+// Commit -- the changes are already made so forget the originals
+func (t *memStorageTx) Commit() error {
+
+	if t.journal == nil {
+		return sql.ErrTxDone
+	}
+
+	t.journal = nil
+	t.lock.Unlock()
+	return nil
+}
+
+// Rollback -- put the original records back, a no-op if the transaction is already complete
+func (t *memStorageTx) Rollback() error {
+
+	if t.journal == nil {
+		return nil
+	}
+
+	for key, rec := range t.journal.records {
+		if rec == nil {
+			delete(t.records, key)
+		} else {
+			t.records[key] = rec
+		}
+	}
+	for id, ev := range t.journal.events {
+		if ev == nil {
+			delete(t.events, id)
+		} else {
+			t.events[id] = ev
+		}
+	}
+
+	t.journal = nil
+	t.lock.Unlock()
+	return nil
+}
+
+// Close -- roll back anything not committed
+func (t *memStorageTx) Close() error {
+	return t.Rollback()
+}
+
+// record the specified record before it is first replaced within a transaction
+func (s *memStorage) journalRecord(key DataStoreKey) {
+
+	// not in a transaction or already journaled
+	if s.journal == nil {
+		return
+	}
+	if _, found := s.journal.records[key]; found == true {
+		return
+	}
+	s.journal.records[key] = s.records[key]
+}
+
+// record the specified event before it is first replaced within a transaction
+func (s *memStorage) journalEvent(id string) {
+
+	// not in a transaction or already journaled
+	if s.journal == nil {
+		return
+	}
+	if _, found := s.journal.events[id]; found == true {
+		return
+	}
+	s.journal.events[id] = s.events[id]
+}
+
+//
+// end of file
+//
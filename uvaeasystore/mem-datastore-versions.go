@@ -0,0 +1,102 @@
+//
+// in-memory implementation of the datastore version methods
+//
+
+// only include this file for service builds
+
+//go:build service
+// +build service
+
+package uvaeasystore
+
+import (
+	"context"
+	"fmt"
+)
+
+// AddVersion -- preserve the current object, fields, metadata and files as a prior version
+func (s *memStorage) AddVersion(ctx context.Context, key DataStoreKey) error {
+
+	release, err := s.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return s.update(key, func(rec *memRecord) error {
+		if rec.live() == false {
+			return fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s", key.Namespace, key.ObjectId), ErrNotFound)
+		}
+
+		// records are never changed so the version can share the components
+		version := rec.clone()
+		version.versions = nil
+
+		// a version with the same vtag is the same version
+		versions := make([]*memRecord, 0, len(rec.versions)+1)
+		for _, v := range rec.versions {
+			if v.object.Vtag_ != rec.object.Vtag_ {
+				versions = append(versions, v)
+			}
+		}
+		rec.versions = append(versions, version)
+		return nil
+	})
+}
+
+// GetVersionsByKey -- get the prior versions of the specified object, oldest first
+func (s *memStorage) GetVersionsByKey(ctx context.Context, key DataStoreKey) ([]EasyStoreVersion, error) {
+
+	release, err := s.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	rec := s.records[key]
+	if rec == nil || len(rec.versions) == 0 {
+		return nil, fmt.Errorf("%q: %w", "version(s) not found", ErrNotFound)
+	}
+
+	results := make([]EasyStoreVersion, 0, len(rec.versions))
+	for _, v := range rec.versions {
+		results = append(results, EasyStoreVersion{VTag: v.object.Vtag_, Created: v.object.Created_, Modified: v.object.Modified_})
+	}
+
+	logDebug(s.log, fmt.Sprintf("found %d version(s)", len(results)))
+	return results, nil
+}
+
+// GetVersionByKey -- get the specified prior version of an object with all of its components
+func (s *memStorage) GetVersionByKey(ctx context.Context, key DataStoreKey, vtag string) (EasyStoreObject, error) {
+
+	release, err := s.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	if rec := s.records[key]; rec != nil {
+		for _, v := range rec.versions {
+			if v.object.Vtag_ != vtag {
+				continue
+			}
+			obj := *v.object
+			if v.fields != nil {
+				obj.SetMultiFields(copyFields(v.fields))
+			}
+			if v.metadata != nil {
+				obj.SetMetadata(v.copyMetadata())
+			}
+			if len(v.blobs) != 0 {
+				obj.SetFiles(v.copyBlobs())
+			}
+			return &obj, nil
+		}
+	}
+	return nil, fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s/%s", key.Namespace, key.ObjectId, vtag), ErrNotFound)
+}
+
+//
+// end of file
+//
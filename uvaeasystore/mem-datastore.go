@@ -0,0 +1,138 @@
+//
+// in-memory implementation of the datastore interface
+//
+
+// only include this file for service builds
+
+//go:build service
+// +build service
+
+package uvaeasystore
+
+import (
+	"fmt"
+	"log"
+	"sync"
+)
+
+// DatastoreMemoryConfig -- this is our in-memory configuration implementation. Nothing is persisted so it
+// is intended for unit testing
+type DatastoreMemoryConfig struct {
+	Name       string             // datastores with the same name share their contents (blank for a private datastore)
+	BusName    string             // the message bus name
+	SourceName string             // the event source name
+	Sink       EasyStoreEventSink // where events are published (instead of the message bus)
+	Access     *EasyStorePolicy   // the access policy (nil allows everything)
+	Audit      EasyStoreAuditLog  // where denied requests are recorded (the logger when nil)
+	Log        *log.Logger        // the logger
+}
+
+func (impl DatastoreMemoryConfig) Logger() *log.Logger {
+	return impl.Log
+}
+
+func (impl DatastoreMemoryConfig) SetLogger(log *log.Logger) {
+	impl.Log = log
+}
+
+func (impl DatastoreMemoryConfig) MessageBus() string {
+	return impl.BusName
+}
+
+func (impl DatastoreMemoryConfig) SetMessageBus(busName string) {
+	impl.BusName = busName
+}
+
+func (impl DatastoreMemoryConfig) EventSource() string {
+	return impl.SourceName
+}
+
+func (impl DatastoreMemoryConfig) SetEventSource(sourceName string) {
+	impl.SourceName = sourceName
+}
+
+func (impl DatastoreMemoryConfig) EventSink() EasyStoreEventSink {
+	return impl.Sink
+}
+
+func (impl DatastoreMemoryConfig) SetEventSink(sink EasyStoreEventSink) {
+	impl.Sink = sink
+}
+
+func (impl DatastoreMemoryConfig) Policy() *EasyStorePolicy {
+	return impl.Access
+}
+
+func (impl DatastoreMemoryConfig) SetPolicy(policy *EasyStorePolicy) {
+	impl.Access = policy
+}
+
+func (impl DatastoreMemoryConfig) AuditLog() EasyStoreAuditLog {
+	return impl.Audit
+}
+
+func (impl DatastoreMemoryConfig) SetAuditLog(audit EasyStoreAuditLog) {
+	impl.Audit = audit
+}
+
+// newMemoryStore -- create an in-memory version of the DataStore
+func newMemoryStore(config EasyStoreImplConfig) (DataStore, error) {
+
+	// make sure its one of these
+	c, ok := config.(DatastoreMemoryConfig)
+	if ok == false {
+		return nil, fmt.Errorf("%q: %w", "bad configuration, not a DatastoreMemoryConfig", ErrBadParameter)
+	}
+
+	// validate our configuration
+	err := validateMemoryConfig(c)
+	if err != nil {
+		return nil, err
+	}
+
+	logDebug(config.Logger(), fmt.Sprintf("using [memory:%s] for storage", c.Name))
+
+	// a private datastore
+	if len(c.Name) == 0 {
+		return &memStorage{
+			records: make(map[DataStoreKey]*memRecord),
+			events:  make(map[string]*EasyStoreEvent),
+			log:     c.Log,
+			lock:    &sync.Mutex{},
+		}, nil
+	}
+
+	// a named one, created the first time it is used
+	memNamed.Lock()
+	defer memNamed.Unlock()
+	shared, found := memNamed.stores[c.Name]
+	if found == false {
+		shared = &memStorage{records: make(map[DataStoreKey]*memRecord), events: make(map[string]*EasyStoreEvent), lock: &sync.Mutex{}}
+		memNamed.stores[c.Name] = shared
+	}
+	return &memStorage{
+		records: shared.records,
+		events:  shared.events,
+		log:     c.Log,
+		lock:    shared.lock,
+	}, nil
+}
+
+// the named datastores, they last for the life of the process
+var memNamed = struct {
+	sync.Mutex
+	stores map[string]*memStorage
+}{stores: make(map[string]*memStorage)}
+
+func validateMemoryConfig(config DatastoreMemoryConfig) error {
+
+	if len(config.BusName) != 0 && len(config.SourceName) == 0 {
+		return fmt.Errorf("%q: %w", "config.SourceName is blank", ErrBadParameter)
+	}
+
+	return nil
+}
+
+//
+// end of file
+//
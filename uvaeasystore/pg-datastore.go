@@ -20,15 +20,18 @@ import (
 
 // DatastorePostgresConfig -- this is our Postgres configuration implementation
 type DatastorePostgresConfig struct {
-	DbHost     string      // host endpoint
-	DbPort     int         // port
-	DbName     string      // database name
-	DbUser     string      // database user
-	DbPassword string      // database password
-	DbTimeout  int         // timeout
-	BusName    string      // the message bus name
-	SourceName string      // the event source name
-	Log        *log.Logger // the logger
+	DbHost     string             // host endpoint
+	DbPort     int                // port
+	DbName     string             // database name
+	DbUser     string             // database user
+	DbPassword string             // database password
+	DbTimeout  int                // timeout
+	BusName    string             // the message bus name
+	SourceName string             // the event source name
+	Sink       EasyStoreEventSink // where events are published (instead of the message bus)
+	Access     *EasyStorePolicy   // the access policy (nil allows everything)
+	Audit      EasyStoreAuditLog  // where denied requests are recorded (the logger when nil)
+	Log        *log.Logger        // the logger
 }
 
 func (impl DatastorePostgresConfig) Logger() *log.Logger {
@@ -55,6 +58,30 @@ func (impl DatastorePostgresConfig) SetEventSource(sourceName string) {
 	impl.SourceName = sourceName
 }
 
+func (impl DatastorePostgresConfig) EventSink() EasyStoreEventSink {
+	return impl.Sink
+}
+
+func (impl DatastorePostgresConfig) SetEventSink(sink EasyStoreEventSink) {
+	impl.Sink = sink
+}
+
+func (impl DatastorePostgresConfig) Policy() *EasyStorePolicy {
+	return impl.Access
+}
+
+func (impl DatastorePostgresConfig) SetPolicy(policy *EasyStorePolicy) {
+	impl.Access = policy
+}
+
+func (impl DatastorePostgresConfig) AuditLog() EasyStoreAuditLog {
+	return impl.Audit
+}
+
+func (impl DatastorePostgresConfig) SetAuditLog(audit EasyStoreAuditLog) {
+	impl.Audit = audit
+}
+
 // newPostgresStore -- create a postgres version of the DataStore
 func newPostgresStore(config EasyStoreImplConfig) (DataStore, error) {
 
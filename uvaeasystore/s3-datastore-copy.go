@@ -0,0 +1,91 @@
+//
+// S3 implementation of the datastore copy method
+//
+
+// only include this file for service builds
+
+//go:build service
+// +build service
+
+package uvaeasystore
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// CopyObjectByKey -- copy the object, fields, metadata and files to the new key
+func (s *S3Storage) CopyObjectByKey(ctx context.Context, from DataStoreKey, to DataStoreKey) error {
+
+	// the source must exist and not be in the trash
+	obj, err := s.GetObjectByKey(ctx, from, NOCACHE)
+	if err != nil {
+		return err
+	}
+	impl, ok := obj.(*easyStoreObjectImpl)
+	if ok == false {
+		return fmt.Errorf("%q: %w", "cast failed, not an easyStoreObjectImpl", ErrBadParameter)
+	}
+
+	// the identifier is in use until the object is purged, even when it is in the trash
+	if s.checkS3AssetExists(ctx, to.Namespace, to.ObjectId, S3ObjectFileName) == true {
+		return fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s", to.Namespace, to.ObjectId), ErrAlreadyExists)
+	}
+	impl.Namespace_, impl.Id_, impl.Vtag_ = to.Namespace, to.ObjectId, newVtag()
+
+	// update the cache (database) first, the distinct index also rejects a key that is in use
+	stmt, err := s.conn().PrepareContext(ctx, "INSERT INTO objects( namespace, oid, vtag, created_at, updated_at ) SELECT CAST( $1 AS VARCHAR ), CAST( $2 AS VARCHAR ), CAST( $3 AS VARCHAR ), created_at, updated_at FROM objects WHERE namespace = $4 AND oid = $5")
+	if err != nil {
+		return err
+	}
+	err = execPrepared(ctx, stmt, to.Namespace, to.ObjectId, impl.Vtag_, from.Namespace, from.ObjectId)
+	stmt.Close()
+	if err != nil {
+		return err
+	}
+
+	// the key was not in use so the copies are all new
+	s.journalCreate(to.Namespace, to.ObjectId)
+
+	stmt, err = s.conn().PrepareContext(ctx, "INSERT INTO fields( namespace, oid, name, value, created_at, updated_at ) SELECT CAST( $1 AS VARCHAR ), CAST( $2 AS VARCHAR ), name, value, created_at, updated_at FROM fields WHERE namespace = $3 AND oid = $4 ORDER BY id")
+	if err != nil {
+		return err
+	}
+	err = execPrepared(ctx, stmt, to.Namespace, to.ObjectId, from.Namespace, from.ObjectId)
+	stmt.Close()
+	if err != nil {
+		return err
+	}
+
+	// everything other than the object file is independent of the key, S3 does the copy so the
+	// payloads are not downloaded
+	prefix := fmt.Sprintf("%s/%s/", from.Namespace, from.ObjectId)
+	assets, err := s.s3List(ctx, s.Bucket, prefix)
+	if err != nil {
+		return err
+	}
+	for _, asset := range assets {
+		name := strings.TrimPrefix(asset, prefix)
+		if name == S3ObjectFileName || name == S3TombstoneFileName {
+			continue
+		}
+		dst := s.assetKey(to.Namespace, to.ObjectId, name)
+
+		// removed if the transaction is rolled back
+		if err = s.journalKey(ctx, s.Bucket, dst); err != nil {
+			return err
+		}
+		if err = s.s3Copy(ctx, s.Bucket, asset, dst); err != nil {
+			return err
+		}
+	}
+
+	// the object file last, it keeps the timestamps of the source
+	b := s.serialize.ObjectSerialize(impl).([]byte)
+	return s.s3UploadFromBuffer(ctx, s.Bucket, s.assetKey(to.Namespace, to.ObjectId, S3ObjectFileName), b)
+}
+
+//
+// end of file
+//
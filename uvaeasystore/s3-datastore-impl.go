@@ -15,7 +15,9 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net/url"
 	"path/filepath"
 	"strings"
 	"time"
@@ -23,14 +25,21 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"golang.org/x/exp/maps"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
 var S3ObjectFileName = "object.json"
 var S3FieldsFileName = "fields.json"
 var S3MetadataFileName = "metadata.json"
+var S3TombstoneFileName = "tombstone.json"
 var S3BlobFileNameSuffix = "-es.json"
 
+// S3 will not copy anything larger than this in a single request
+var s3MaxCopySize = int64(5 * 1024 * 1024 * 1024)
+
+// part size used when copying larger assets
+var s3CopyPartSize = int64(512 * 1024 * 1024)
+
 // this is our S3 implementation
 type S3Storage struct {
 	Bucket string // Bucket name
@@ -41,44 +50,52 @@ type S3Storage struct {
 	s3SignClient        *s3.PresignClient   // the signing client (creates signed access urls)
 	s3SignExpireMinutes int                 // signature expire time in minutes
 	log                 *log.Logger         // logger
+	tx                  *sql.Tx             // the current transaction (if any)
+	journal             *s3Journal          // S3 changes made in the current transaction (if any)
 	*sql.DB                                 // database connection
 }
 
 // Check -- check our database health
-func (s *S3Storage) Check() error {
+func (s *S3Storage) Check(ctx context.Context) error {
 
 	// perhaps check Bucket access too?
 
-	return s.Ping()
+	return s.PingContext(ctx)
 }
 
 // UpdateBlob -- update the contents of an existing blob
-func (s *S3Storage) UpdateBlob(key DataStoreKey, blob EasyStoreBlob) error {
+func (s *S3Storage) UpdateBlob(ctx context.Context, key DataStoreKey, blob EasyStoreBlob) error {
 
 	// check asset already exist
-	//jsonName := fmt.Sprintf("%s%s", blob.Name(), S3BlobFileNameSuffix)
-	//if s.checkS3AssetExists(key.Namespace, key.ObjectId, jsonName) == false {
-	//	return fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s/%s", key.Namespace, key.ObjectId, jsonName), ErrNotFound)
-	//}
-	return s.addS3Blob(key.Namespace, key.ObjectId, blob)
+	jsonName := fmt.Sprintf("%s%s", blob.Name(), S3BlobFileNameSuffix)
+	if s.checkS3AssetExists(ctx, key.Namespace, key.ObjectId, jsonName) == false {
+		return fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s/%s", key.Namespace, key.ObjectId, jsonName), ErrNotFound)
+	}
+
+	// the blob keeps its original created time
+	current, err := s.getS3Blob(ctx, s.assetKey(key.Namespace, key.ObjectId, jsonName))
+	if err != nil {
+		return err
+	}
+	return s.addS3Blob(ctx, key.Namespace, key.ObjectId, blob, (*current).Created())
 }
 
 // UpdateFields -- update the contents of an existing field set
-func (s *S3Storage) UpdateFields(key DataStoreKey, fields EasyStoreObjectFields) error {
+func (s *S3Storage) UpdateFields(ctx context.Context, key DataStoreKey, fields EasyStoreObjectMultiFields) error {
 
-	err := s.addS3Fields(key.Namespace, key.ObjectId, fields)
+	err := s.addS3Fields(ctx, key.Namespace, key.ObjectId, fields)
 	if err != nil {
 		return err
 	}
 
 	// update the cache (database)
 
-	stmt1, err := s.Prepare("DELETE FROM fields WHERE namespace = $1 AND oid = $2")
+	stmt1, err := s.conn().PrepareContext(ctx, "DELETE FROM fields WHERE namespace = $1 AND oid = $2")
 	if err != nil {
 		return err
 	}
 	defer stmt1.Close()
-	err = execPrepared(stmt1, key.Namespace, key.ObjectId)
+	err = execPrepared(ctx, stmt1, key.Namespace, key.ObjectId)
 	if err != nil {
 		return err
 	}
@@ -87,32 +104,34 @@ func (s *S3Storage) UpdateFields(key DataStoreKey, fields EasyStoreObjectFields)
 	args := make([]any, 0)
 	insert := "INSERT INTO fields( namespace, oid, name, value ) VALUES"
 	variableIx := 1
-	for n, v := range fields {
-		insert += fmt.Sprintf(" ($%d,$%d,$%d,$%d),", variableIx, variableIx+1, variableIx+2, variableIx+3)
-		args = append(args, key.Namespace, key.ObjectId, n, v)
-		variableIx += 4
+	for n, values := range fields {
+		for _, v := range values {
+			insert += fmt.Sprintf(" ($%d,$%d,$%d,$%d),", variableIx, variableIx+1, variableIx+2, variableIx+3)
+			args = append(args, key.Namespace, key.ObjectId, n, v)
+			variableIx += 4
+		}
 	}
 
 	// remove trailing comma
 	insert = strings.TrimRight(insert, ",")
 
-	stmt2, err := s.Prepare(insert)
+	stmt2, err := s.conn().PrepareContext(ctx, insert)
 	if err != nil {
 		return err
 	}
 	defer stmt2.Close()
 
-	return execPrepared(stmt2, args...)
+	return execPrepared(ctx, stmt2, args...)
 }
 
 // UpdateMetadata -- update the contents of existing metadata
-func (s *S3Storage) UpdateMetadata(key DataStoreKey, md EasyStoreMetadata) error {
-	return s.AddMetadata(key, md)
+func (s *S3Storage) UpdateMetadata(ctx context.Context, key DataStoreKey, md EasyStoreMetadata) error {
+	return s.AddMetadata(ctx, key, md)
 }
 
 // UpdateObject -- update a couple of object fields
-func (s *S3Storage) UpdateObject(key DataStoreKey) error {
-	obj, err := s.GetObjectByKey(key, NOCACHE)
+func (s *S3Storage) UpdateObject(ctx context.Context, key DataStoreKey) error {
+	obj, err := s.GetObjectByKey(ctx, key, NOCACHE)
 	if err != nil {
 		return err
 	}
@@ -126,34 +145,53 @@ func (s *S3Storage) UpdateObject(key DataStoreKey) error {
 	impl.Modified_ = time.Now()
 
 	// update the S3 asset
-	err = s.addS3Object(impl.Namespace(), impl.Id(), impl)
+	err = s.addS3Object(ctx, impl.Namespace(), impl.Id(), impl)
 	if err != nil {
 		return err
 	}
 
 	// update the cache (database)
-	stmt, err := s.Prepare("UPDATE objects set vtag = $1, updated_at = NOW() WHERE namespace = $2 AND oid = $3")
+	stmt, err := s.conn().PrepareContext(ctx, "UPDATE objects set vtag = $1, updated_at = NOW() WHERE namespace = $2 AND oid = $3 AND deleted_at IS NULL")
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
-	return execPrepared(stmt, impl.Vtag_, key.Namespace, key.ObjectId)
+	return execPrepared(ctx, stmt, impl.Vtag_, key.Namespace, key.ObjectId)
+}
+
+// LockObjectByKey -- lock the object in the cache (database) for the rest of the transaction, then
+// check the vtag of the S3 asset (it is the master copy)
+func (s *S3Storage) LockObjectByKey(ctx context.Context, key DataStoreKey, vtag string) error {
+
+	err := lockObject(ctx, s.conn(), key, vtag)
+	if err != nil {
+		return err
+	}
+
+	obj, err := s.GetObjectByKey(ctx, key, NOCACHE)
+	if err != nil {
+		return err
+	}
+	if obj.VTag() != vtag {
+		return fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s (vtag %s)", key.Namespace, key.ObjectId, vtag), ErrStaleObject)
+	}
+	return nil
 }
 
 // AddBlob -- add a new blob object
-func (s *S3Storage) AddBlob(key DataStoreKey, blob EasyStoreBlob) error {
+func (s *S3Storage) AddBlob(ctx context.Context, key DataStoreKey, blob EasyStoreBlob) error {
 	// check asset does not exist
-	//jsonName := fmt.Sprintf("%s%s", blob.Name(), S3BlobFileNameSuffix)
-	//if s.checkS3AssetExists(key.Namespace, key.ObjectId, jsonName) == true {
-	//	return fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s/%s", key.Namespace, key.ObjectId, jsonName), ErrAlreadyExists)
-	//}
-	return s.addS3Blob(key.Namespace, key.ObjectId, blob)
+	jsonName := fmt.Sprintf("%s%s", blob.Name(), S3BlobFileNameSuffix)
+	if s.checkS3AssetExists(ctx, key.Namespace, key.ObjectId, jsonName) == true {
+		return fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s/%s", key.Namespace, key.ObjectId, jsonName), ErrAlreadyExists)
+	}
+	return s.addS3Blob(ctx, key.Namespace, key.ObjectId, blob, time.Now())
 }
 
 // AddFields -- add a new fields object
-func (s *S3Storage) AddFields(key DataStoreKey, fields EasyStoreObjectFields) error {
+func (s *S3Storage) AddFields(ctx context.Context, key DataStoreKey, fields EasyStoreObjectMultiFields) error {
 
-	err := s.addS3Fields(key.Namespace, key.ObjectId, fields)
+	err := s.addS3Fields(ctx, key.Namespace, key.ObjectId, fields)
 	if err != nil {
 		return err
 	}
@@ -164,53 +202,62 @@ func (s *S3Storage) AddFields(key DataStoreKey, fields EasyStoreObjectFields) er
 	args := make([]any, 0)
 	insert := "INSERT INTO fields( namespace, oid, name, value ) VALUES"
 	variableIx := 1
-	for n, v := range fields {
-		insert += fmt.Sprintf(" ($%d,$%d,$%d,$%d),", variableIx, variableIx+1, variableIx+2, variableIx+3)
-		args = append(args, key.Namespace, key.ObjectId, n, v)
-		variableIx += 4
+	for n, values := range fields {
+		for _, v := range values {
+			insert += fmt.Sprintf(" ($%d,$%d,$%d,$%d),", variableIx, variableIx+1, variableIx+2, variableIx+3)
+			args = append(args, key.Namespace, key.ObjectId, n, v)
+			variableIx += 4
+		}
 	}
 
 	// remove trailing comma
 	insert = strings.TrimRight(insert, ",")
 
-	stmt, err := s.Prepare(insert)
+	stmt, err := s.conn().PrepareContext(ctx, insert)
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
-	return execPrepared(stmt, args...)
+	return execPrepared(ctx, stmt, args...)
 }
 
 // AddMetadata -- add a new metadata object
-func (s *S3Storage) AddMetadata(key DataStoreKey, metadata EasyStoreMetadata) error {
-	return s.addS3Metadata(key.Namespace, key.ObjectId, metadata)
+func (s *S3Storage) AddMetadata(ctx context.Context, key DataStoreKey, metadata EasyStoreMetadata) error {
+	return s.addS3Metadata(ctx, key.Namespace, key.ObjectId, metadata)
 }
 
 // AddObject -- add a new object
-func (s *S3Storage) AddObject(obj EasyStoreObject) error {
+func (s *S3Storage) AddObject(ctx context.Context, obj EasyStoreObject) error {
 
 	// add the asset
-	err := s.addS3Object(obj.Namespace(), obj.Id(), obj)
+	err := s.addS3Object(ctx, obj.Namespace(), obj.Id(), obj)
 	if err != nil {
 		return err
 	}
 
 	// update the cache (database)
-	stmt, err := s.Prepare("INSERT INTO objects( namespace, oid, vtag ) VALUES( $1,$2,$3 )")
+	stmt, err := s.conn().PrepareContext(ctx, "INSERT INTO objects( namespace, oid, vtag ) VALUES( $1,$2,$3 )")
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
-	return execPrepared(stmt, obj.Namespace(), obj.Id(), obj.VTag())
+	err = execPrepared(ctx, stmt, obj.Namespace(), obj.Id(), obj.VTag())
+	if err != nil {
+		return err
+	}
+
+	// the key was not in use so the remaining assets are all new
+	s.journalCreate(obj.Namespace(), obj.Id())
+	return nil
 }
 
 // GetBlobsByKey -- get all blob data associated with the specified object
-func (s *S3Storage) GetBlobsByKey(key DataStoreKey, useCache bool) ([]EasyStoreBlob, error) {
+func (s *S3Storage) GetBlobsByKey(ctx context.Context, key DataStoreKey, useCache bool) ([]EasyStoreBlob, error) {
 
 	// ignore useCache, we do not cache blob information
 
-	fset, err := s.s3List(s.Bucket, fmt.Sprintf("%s/%s", key.Namespace, key.ObjectId))
+	fset, err := s.s3List(ctx, s.Bucket, fmt.Sprintf("%s/%s", key.Namespace, key.ObjectId))
 	if err != nil {
 		return nil, err
 	}
@@ -218,7 +265,7 @@ func (s *S3Storage) GetBlobsByKey(key DataStoreKey, useCache bool) ([]EasyStoreB
 	for _, fname := range fset {
 		bname := filepath.Base(fname)
 		if s.isBlobName(bname) == true {
-			blob, err := s.getS3Blob(fname)
+			blob, err := s.getS3Blob(ctx, fname)
 			if err != nil {
 				return nil, err
 			}
@@ -234,19 +281,19 @@ func (s *S3Storage) GetBlobsByKey(key DataStoreKey, useCache bool) ([]EasyStoreB
 }
 
 // GetFieldsByKey -- get all field data associated with the specified object
-func (s *S3Storage) GetFieldsByKey(key DataStoreKey, useCache bool) (*EasyStoreObjectFields, error) {
+func (s *S3Storage) GetFieldsByKey(ctx context.Context, key DataStoreKey, useCache bool) (*EasyStoreObjectMultiFields, error) {
 
 	// dont use the cache
 	if useCache == NOCACHE {
 		// check asset exists
-		if s.checkS3AssetExists(key.Namespace, key.ObjectId, S3FieldsFileName) == false {
+		if s.checkS3AssetExists(ctx, key.Namespace, key.ObjectId, S3FieldsFileName) == false {
 			return nil, fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s/%s", key.Namespace, key.ObjectId, S3FieldsFileName), ErrNotFound)
 		}
-		return s.getS3Fields(key.Namespace, key.ObjectId)
+		return s.getS3Fields(ctx, key.Namespace, key.ObjectId)
 	}
 
 	// we can read from the cache (database)
-	rows, err := s.Query("SELECT name, value FROM fields WHERE namespace = $1 AND oid = $2 ORDER BY updated_at", key.Namespace, key.ObjectId)
+	rows, err := s.conn().QueryContext(ctx, "SELECT name, value FROM fields WHERE namespace = $1 AND oid = $2 ORDER BY id", key.Namespace, key.ObjectId)
 	if err != nil {
 		return nil, err
 	}
@@ -256,31 +303,53 @@ func (s *S3Storage) GetFieldsByKey(key DataStoreKey, useCache bool) (*EasyStoreO
 }
 
 // GetMetadataByKey -- get all field data associated with the specified object
-func (s *S3Storage) GetMetadataByKey(key DataStoreKey, useCache bool) (EasyStoreMetadata, error) {
+func (s *S3Storage) GetMetadataByKey(ctx context.Context, key DataStoreKey, useCache bool) (EasyStoreMetadata, error) {
 
 	// ignore useCache, we do not cache metadata
 
 	// check asset exists
-	if s.checkS3AssetExists(key.Namespace, key.ObjectId, S3MetadataFileName) == false {
+	if s.checkS3AssetExists(ctx, key.Namespace, key.ObjectId, S3MetadataFileName) == false {
 		return nil, fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s/%s", key.Namespace, key.ObjectId, S3MetadataFileName), ErrNotFound)
 	}
-	return s.getS3Metadata(key.Namespace, key.ObjectId)
+	return s.getS3Metadata(ctx, key.Namespace, key.ObjectId)
+}
+
+// GetBlobByKey -- get the named blob associated with the specified object
+func (s *S3Storage) GetBlobByKey(ctx context.Context, key DataStoreKey, curName string, useCache bool) (EasyStoreBlob, error) {
+
+	// ignore useCache, we do not cache blob information
+
+	// check asset exists
+	blobKey := s.assetKey(key.Namespace, key.ObjectId, fmt.Sprintf("%s%s", curName, S3BlobFileNameSuffix))
+	if s.s3Exists(ctx, s.Bucket, blobKey) == false {
+		return nil, fmt.Errorf("%q: %w", blobKey, ErrFileNotFound)
+	}
+
+	blob, err := s.getS3Blob(ctx, blobKey)
+	if err != nil {
+		return nil, err
+	}
+	return *blob, nil
 }
 
 // GetObjectByKey -- get all field data associated with the specified object
-func (s *S3Storage) GetObjectByKey(key DataStoreKey, useCache bool) (EasyStoreObject, error) {
+func (s *S3Storage) GetObjectByKey(ctx context.Context, key DataStoreKey, useCache bool) (EasyStoreObject, error) {
 
 	// dont use the cache
 	if useCache == NOCACHE {
 		// check asset exists
-		if s.checkS3AssetExists(key.Namespace, key.ObjectId, S3ObjectFileName) == false {
+		if s.checkS3AssetExists(ctx, key.Namespace, key.ObjectId, S3ObjectFileName) == false {
 			return nil, fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s/%s", key.Namespace, key.ObjectId, S3ObjectFileName), ErrNotFound)
 		}
-		return s.getS3Object(key.Namespace, key.ObjectId)
+		// deleted objects are in the trash
+		if s.checkS3AssetExists(ctx, key.Namespace, key.ObjectId, S3TombstoneFileName) == true {
+			return nil, fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s/%s", key.Namespace, key.ObjectId, S3TombstoneFileName), ErrNotFound)
+		}
+		return s.getS3Object(ctx, key.Namespace, key.ObjectId)
 	}
 
 	// we can read from the cache (database)
-	rows, err := s.Query("SELECT namespace, oid, vtag, created_at, updated_at FROM objects WHERE namespace = $1 AND oid = $2 LIMIT 1", key.Namespace, key.ObjectId)
+	rows, err := s.conn().QueryContext(ctx, "SELECT namespace, oid, vtag, created_at, updated_at FROM objects WHERE namespace = $1 AND oid = $2 AND deleted_at IS NULL LIMIT 1", key.Namespace, key.ObjectId)
 	if err != nil {
 		return nil, err
 	}
@@ -290,11 +359,11 @@ func (s *S3Storage) GetObjectByKey(key DataStoreKey, useCache bool) (EasyStoreOb
 }
 
 // GetObjectsByKey -- get all field data associated with the specified object
-func (s *S3Storage) GetObjectsByKey(keys []DataStoreKey, useCache bool) ([]EasyStoreObject, error) {
+func (s *S3Storage) GetObjectsByKey(ctx context.Context, keys []DataStoreKey, useCache bool) ([]EasyStoreObject, error) {
 
 	results := make([]EasyStoreObject, 0, len(keys))
 	for _, key := range keys {
-		obj, err := s.GetObjectByKey(key, useCache)
+		obj, err := s.GetObjectByKey(ctx, key, useCache)
 		if err != nil {
 			if errors.Is(err, ErrNotFound) == false {
 				// a real error
@@ -311,7 +380,7 @@ func (s *S3Storage) GetObjectsByKey(keys []DataStoreKey, useCache bool) ([]EasyS
 }
 
 // RenameBlobByKey -- rename the named blob to the new name
-func (s *S3Storage) RenameBlobByKey(key DataStoreKey, curName string, newName string) error {
+func (s *S3Storage) RenameBlobByKey(ctx context.Context, key DataStoreKey, curName string, newName string) error {
 
 	//fmt.Printf("INFO: Renaming [%s/%s] %s -> %s\n", key.Namespace, key.ObjectId, curName, newName)
 
@@ -320,21 +389,21 @@ func (s *S3Storage) RenameBlobByKey(key DataStoreKey, curName string, newName st
 	newBlobKey := s.assetKey(key.Namespace, key.ObjectId, fmt.Sprintf("%s%s", newName, S3BlobFileNameSuffix))
 
 	// check currently named asset exists
-	if s.s3Exists(s.Bucket, curBlobKey) == false {
+	if s.s3Exists(ctx, s.Bucket, curBlobKey) == false {
 		//fmt.Printf("ERROR: %s does not exist\n", curBlobKey)
-		return fmt.Errorf("%q: %w", curBlobKey, ErrNotFound)
+		return fmt.Errorf("%q: %w", curBlobKey, ErrFileNotFound)
 		//return ErrNotFound
 	}
 
 	// check new asset name does not already exist
-	if s.s3Exists(s.Bucket, newBlobKey) == true {
+	if s.s3Exists(ctx, s.Bucket, newBlobKey) == true {
 		//fmt.Printf("ERROR: %s already exist\n", newBlobKey)
 		return fmt.Errorf("%q: %w", newBlobKey, ErrAlreadyExists)
 		//return ErrAlreadyExists
 	}
 
 	// download from S3
-	b, err := s.s3DownloadToBuffer(s.Bucket, curBlobKey)
+	b, err := s.s3DownloadToBuffer(ctx, s.Bucket, curBlobKey)
 	if err != nil {
 		return err
 	}
@@ -356,13 +425,13 @@ func (s *S3Storage) RenameBlobByKey(key DataStoreKey, curName string, newName st
 
 	// serialize and upload new blob descriptor to S3
 	bBytes := s.serialize.BlobSerialize(impl).([]byte)
-	err = s.s3UploadFromBuffer(s.Bucket, newBlobKey, bBytes)
+	err = s.s3UploadFromBuffer(ctx, s.Bucket, newBlobKey, bBytes)
 	if err != nil {
 		return err
 	}
 
 	// remove the old blob descriptor file
-	err = s.s3Remove(s.Bucket, curBlobKey)
+	err = s.s3Remove(ctx, s.Bucket, curBlobKey)
 	if err != nil {
 		return err
 	}
@@ -370,33 +439,33 @@ func (s *S3Storage) RenameBlobByKey(key DataStoreKey, curName string, newName st
 	// rename the actual asset file
 	curKey := s.assetKey(key.Namespace, key.ObjectId, curName)
 	newKey := s.assetKey(key.Namespace, key.ObjectId, newName)
-	return s.s3Rename(s.Bucket, curKey, newKey)
+	return s.s3Rename(ctx, s.Bucket, curKey, newKey)
 }
 
 // DeleteBlobByKey -- delete a single blob associated with the specified object
-func (s *S3Storage) DeleteBlobByKey(key DataStoreKey, curName string) error {
+func (s *S3Storage) DeleteBlobByKey(ctx context.Context, key DataStoreKey, curName string) error {
 
 	// blob content is not cached
 
 	curBlobKey := s.assetKey(key.Namespace, key.ObjectId, fmt.Sprintf("%s%s", curName, S3BlobFileNameSuffix))
-	return s.s3Remove(s.Bucket, curBlobKey)
+	return s.s3Remove(ctx, s.Bucket, curBlobKey)
 
 	// FIXME, what about the actual asset!!!!
 }
 
 // DeleteBlobsByKey -- delete all blob data associated with the specified object
-func (s *S3Storage) DeleteBlobsByKey(key DataStoreKey) error {
+func (s *S3Storage) DeleteBlobsByKey(ctx context.Context, key DataStoreKey) error {
 
 	// blobs are not cached
 
-	fset, err := s.s3List(s.Bucket, fmt.Sprintf("%s/%s", key.Namespace, key.ObjectId))
+	fset, err := s.s3List(ctx, s.Bucket, fmt.Sprintf("%s/%s", key.Namespace, key.ObjectId))
 	if err != nil {
 		return err
 	}
 	for _, fname := range fset {
 		bname := filepath.Base(fname)
 		if s.isBlobName(bname) == true {
-			err = s.s3Remove(s.Bucket, fname)
+			err = s.s3Remove(ctx, s.Bucket, fname)
 			if err != nil {
 				return err
 			}
@@ -406,114 +475,96 @@ func (s *S3Storage) DeleteBlobsByKey(key DataStoreKey) error {
 }
 
 // DeleteFieldsByKey -- delete all field data associated with the specified object
-func (s *S3Storage) DeleteFieldsByKey(key DataStoreKey) error {
+func (s *S3Storage) DeleteFieldsByKey(ctx context.Context, key DataStoreKey) error {
 
 	// remove the asset
-	err := s.removeS3Asset(key.Namespace, key.ObjectId, S3FieldsFileName)
+	err := s.removeS3Asset(ctx, key.Namespace, key.ObjectId, S3FieldsFileName)
 	if err != nil {
 		return err
 	}
 
 	// update the cache (database)
-	stmt, err := s.Prepare("DELETE FROM fields WHERE namespace = $1 AND oid = $2")
+	stmt, err := s.conn().PrepareContext(ctx, "DELETE FROM fields WHERE namespace = $1 AND oid = $2")
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
-	return execPrepared(stmt, key.Namespace, key.ObjectId)
+	return execPrepared(ctx, stmt, key.Namespace, key.ObjectId)
 }
 
 // DeleteMetadataByKey -- delete all field data associated with the specified object
-func (s *S3Storage) DeleteMetadataByKey(key DataStoreKey) error {
+func (s *S3Storage) DeleteMetadataByKey(ctx context.Context, key DataStoreKey) error {
 
 	// metadata is not cached
 
-	return s.removeS3Asset(key.Namespace, key.ObjectId, S3MetadataFileName)
+	return s.removeS3Asset(ctx, key.Namespace, key.ObjectId, S3MetadataFileName)
 }
 
 // DeleteObjectByKey -- delete all field data associated with the specified object
-func (s *S3Storage) DeleteObjectByKey(key DataStoreKey) error {
+func (s *S3Storage) DeleteObjectByKey(ctx context.Context, key DataStoreKey) error {
 
 	// remove the asset
-	err := s.removeS3Asset(key.Namespace, key.ObjectId, S3ObjectFileName)
+	err := s.removeS3Asset(ctx, key.Namespace, key.ObjectId, S3ObjectFileName)
 	if err != nil {
 		return err
 	}
 
 	// update the cache (database)
-	stmt, err := s.Prepare("DELETE FROM objects WHERE namespace = $1 AND oid = $2")
+	stmt, err := s.conn().PrepareContext(ctx, "DELETE FROM objects WHERE namespace = $1 AND oid = $2")
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
-	return execPrepared(stmt, key.Namespace, key.ObjectId)
-}
-
-// GetKeysByFields -- get a list of keys that have the supplied fields/values
-func (s *S3Storage) GetKeysByFields(namespace string, fields EasyStoreObjectFields) ([]DataStoreKey, error) {
-	var err error
-	var rows *sql.Rows
-	var query string
-	//
-	// support the following cases:
-	// empty namespace (all namespaces) or specified namespace
-	// no fields (all objects) or variable set of fields
-	//
-	if len(fields) == 0 {
-		if len(namespace) == 0 {
-			query = "SELECT namespace, oid, 0 FROM objects ORDER BY namespace, oid"
-			rows, err = s.Query(query)
-		} else {
-			query = "SELECT namespace, oid, 0 FROM objects where namespace = $1 ORDER BY namespace, oid"
-			rows, err = s.Query(query, namespace)
-		}
-	} else {
-		// dynamically build the query because we have a variable number of fields
-		args := make([]any, 0)
-		query = "SELECT namespace, oid, count(*) FROM fields WHERE "
-		variableIx := 1
-		if len(namespace) != 0 {
-			query += fmt.Sprintf("namespace = $%d AND ", variableIx)
-			args = append(args, namespace)
-			variableIx++
-		}
+	return execPrepared(ctx, stmt, key.Namespace, key.ObjectId)
+}
 
-		for ix, k := range maps.Keys(fields) {
-			query += fmt.Sprintf("(name = $%d AND value = $%d) ", variableIx, variableIx+1)
-			variableIx += 2
-			args = append(args, k, fields[k])
-			if ix != (len(fields) - 1) {
-				query += "OR "
-			}
-		}
+// GetKeysByFields -- get a page of keys of objects matching the query and the token for the next page
+func (s *S3Storage) GetKeysByFields(ctx context.Context, namespace string, query EasyStoreQuery, page EasyStorePage) ([]DataStoreKey, string, error) {
+	return keysByQueryPage(ctx, s.conn(), namespace, query, page, s.log)
+}
+
+// Begin -- begin a new transaction. The cache (database) changes are made within a database
+// transaction and the S3 changes are journaled so they can be undone on rollback
+func (s *S3Storage) Begin(ctx context.Context) (DataStoreTx, error) {
 
-		query += fmt.Sprintf("GROUP BY namespace, oid HAVING count(*) = %d", len(fields))
-		rows, err = s.Query(query, args...)
+	if s.tx != nil {
+		return nil, fmt.Errorf("%q: %w", "nested transactions are not supported", ErrNotImplemented)
 	}
 
+	tx, err := s.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	return keyQueryResults(rows, s.log)
+	ts := *s
+	ts.tx = tx
+	ts.journal = newS3Journal()
+	return &s3StorageTx{&ts}, nil
 }
 
 //
 // private implementation methods
 //
 
-func (s *S3Storage) checkS3AssetExists(namespace string, identifier string, assetName string) bool {
+// statements go to the current transaction when we have one
+func (s *S3Storage) conn() dbHandle {
+	if s.tx != nil {
+		return s.tx
+	}
+	return s.DB
+}
+
+func (s *S3Storage) checkS3AssetExists(ctx context.Context, namespace string, identifier string, assetName string) bool {
 	key := s.assetKey(namespace, identifier, assetName)
-	return s.s3Exists(s.Bucket, key)
+	return s.s3Exists(ctx, s.Bucket, key)
 }
 
-func (s *S3Storage) removeS3Asset(namespace string, identifier string, assetName string) error {
+func (s *S3Storage) removeS3Asset(ctx context.Context, namespace string, identifier string, assetName string) error {
 	key := s.assetKey(namespace, identifier, assetName)
-	return s.s3Remove(s.Bucket, key)
+	return s.s3Remove(ctx, s.Bucket, key)
 }
 
-func (s *S3Storage) addS3Blob(namespace string, identifier string, blob EasyStoreBlob) error {
+func (s *S3Storage) addS3Blob(ctx context.Context, namespace string, identifier string, blob EasyStoreBlob, created time.Time) error {
 
 	// we add the serialized blob and create the original file
 	blobKey := s.assetKey(namespace, identifier, fmt.Sprintf("%s%s", blob.Name(), S3BlobFileNameSuffix))
@@ -524,34 +575,42 @@ func (s *S3Storage) addS3Blob(namespace string, identifier string, blob EasyStor
 	if ok == false {
 		return fmt.Errorf("%q: %w", "cast failed, not an easyStoreBlobImpl", ErrBadParameter)
 	}
-	impl.Created_, impl.Modified_ = time.Now(), time.Now()
+	impl.Created_, impl.Modified_ = created, time.Now()
 
 	// we want to store as the original file rather than a serialized byte stream...
-	fBytes := impl.Payload_
-	// upload to S3
-	err := s.s3UploadFromBuffer(s.Bucket, fileKey, fBytes)
+	reader, err := impl.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	// stream to S3, the checksum is computed as the file is uploaded
+	checksum := newChecksumReader(reader)
+	err = s.s3UploadFromReader(ctx, s.Bucket, fileKey, checksum)
 	if err != nil {
 		return err
 	}
+	impl.Checksum_, impl.Size_ = checksum.Checksum(), checksum.Size()
 
 	// dont want to serialize the payload
 	// interfaces are pointers
 	implClone := *impl
 	implClone.Payload_ = nil
+	implClone.stream = nil
 	bBytes := s.serialize.BlobSerialize(implClone).([]byte)
 
 	// upload to S3
-	return s.s3UploadFromBuffer(s.Bucket, blobKey, bBytes)
+	return s.s3UploadFromBuffer(ctx, s.Bucket, blobKey, bBytes)
 }
 
-func (s *S3Storage) addS3Fields(namespace string, identifier string, fields EasyStoreObjectFields) error {
+func (s *S3Storage) addS3Fields(ctx context.Context, namespace string, identifier string, fields EasyStoreObjectMultiFields) error {
 	key := s.assetKey(namespace, identifier, S3FieldsFileName)
-	b := s.serialize.FieldsSerialize(fields).([]byte)
+	b := s.serialize.MultiFieldsSerialize(fields).([]byte)
 	// upload to S3
-	return s.s3UploadFromBuffer(s.Bucket, key, b)
+	return s.s3UploadFromBuffer(ctx, s.Bucket, key, b)
 }
 
-func (s *S3Storage) addS3Metadata(namespace string, identifier string, metadata EasyStoreMetadata) error {
+func (s *S3Storage) addS3Metadata(ctx context.Context, namespace string, identifier string, metadata EasyStoreMetadata) error {
 	key := s.assetKey(namespace, identifier, S3MetadataFileName)
 
 	// for setting the timestamps
@@ -563,10 +622,10 @@ func (s *S3Storage) addS3Metadata(namespace string, identifier string, metadata
 
 	b := s.serialize.MetadataSerialize(impl).([]byte)
 	// upload to S3
-	return s.s3UploadFromBuffer(s.Bucket, key, b)
+	return s.s3UploadFromBuffer(ctx, s.Bucket, key, b)
 }
 
-func (s *S3Storage) addS3Object(namespace string, identifier string, obj EasyStoreObject) error {
+func (s *S3Storage) addS3Object(ctx context.Context, namespace string, identifier string, obj EasyStoreObject) error {
 	key := s.assetKey(namespace, identifier, S3ObjectFileName)
 
 	// for setting the timestamps
@@ -579,12 +638,12 @@ func (s *S3Storage) addS3Object(namespace string, identifier string, obj EasySto
 
 	b := s.serialize.ObjectSerialize(impl).([]byte)
 	// upload to S3
-	return s.s3UploadFromBuffer(s.Bucket, key, b)
+	return s.s3UploadFromBuffer(ctx, s.Bucket, key, b)
 }
 
-func (s *S3Storage) getS3Blob(key string) (*EasyStoreBlob, error) {
+func (s *S3Storage) getS3Blob(ctx context.Context, key string) (*EasyStoreBlob, error) {
 	// download from S3
-	b, err := s.s3DownloadToBuffer(s.Bucket, key)
+	b, err := s.s3DownloadToBuffer(ctx, s.Bucket, key)
 	if err != nil {
 		return nil, err
 	}
@@ -601,35 +660,43 @@ func (s *S3Storage) getS3Blob(key string) (*EasyStoreBlob, error) {
 		if ok == false {
 			return nil, fmt.Errorf("%q: %w", "cast failed, not an easyStoreBlobImpl", ErrBadParameter)
 		}
-		impl.Url_, err = s.signedUrl(s.Bucket, strings.TrimSuffix(key, S3BlobFileNameSuffix))
+		impl.Url_, err = s.signedUrl(ctx, s.Bucket, strings.TrimSuffix(key, S3BlobFileNameSuffix))
 		if err != nil {
 			return nil, err
 		}
+
+		// blobs stored before sizes were recorded get theirs from the original file
+		if impl.Size_ == 0 {
+			impl.Size_, err = s.s3Size(ctx, s.Bucket, strings.TrimSuffix(key, S3BlobFileNameSuffix))
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	return &blob, nil
 }
 
-func (s *S3Storage) getS3Fields(namespace string, identifier string) (*EasyStoreObjectFields, error) {
+func (s *S3Storage) getS3Fields(ctx context.Context, namespace string, identifier string) (*EasyStoreObjectMultiFields, error) {
 	key := s.assetKey(namespace, identifier, S3FieldsFileName)
 
 	// download from S3
-	b, err := s.s3DownloadToBuffer(s.Bucket, key)
+	b, err := s.s3DownloadToBuffer(ctx, s.Bucket, key)
 	if err != nil {
 		return nil, err
 	}
-	fields, err := s.serialize.FieldsDeserialize(b)
+	fields, err := s.serialize.MultiFieldsDeserialize(b)
 	if err != nil {
 		return nil, err
 	}
 	return &fields, nil
 }
 
-func (s *S3Storage) getS3Metadata(namespace string, identifier string) (EasyStoreMetadata, error) {
+func (s *S3Storage) getS3Metadata(ctx context.Context, namespace string, identifier string) (EasyStoreMetadata, error) {
 	key := s.assetKey(namespace, identifier, S3MetadataFileName)
 
 	// download from S3
-	b, err := s.s3DownloadToBuffer(s.Bucket, key)
+	b, err := s.s3DownloadToBuffer(ctx, s.Bucket, key)
 	if err != nil {
 		return nil, err
 	}
@@ -640,11 +707,11 @@ func (s *S3Storage) getS3Metadata(namespace string, identifier string) (EasyStor
 	return metadata, nil
 }
 
-func (s *S3Storage) getS3Object(namespace string, identifier string) (EasyStoreObject, error) {
+func (s *S3Storage) getS3Object(ctx context.Context, namespace string, identifier string) (EasyStoreObject, error) {
 	key := s.assetKey(namespace, identifier, S3ObjectFileName)
 
 	// download from S3
-	b, err := s.s3DownloadToBuffer(s.Bucket, key)
+	b, err := s.s3DownloadToBuffer(ctx, s.Bucket, key)
 	if err != nil {
 		return nil, err
 	}
@@ -666,7 +733,15 @@ func (s *S3Storage) isBlobName(name string) bool {
 // S3 helpers
 //
 
-func (s *S3Storage) s3UploadFromBuffer(bucket string, key string, buf []byte) error {
+func (s *S3Storage) s3UploadFromBuffer(ctx context.Context, bucket string, key string, buf []byte) error {
+	return s.s3UploadFromReader(ctx, bucket, key, bytes.NewReader(buf))
+}
+
+func (s *S3Storage) s3UploadFromReader(ctx context.Context, bucket string, key string, reader io.Reader) error {
+
+	if err := s.journalKey(ctx, bucket, key); err != nil {
+		return err
+	}
 
 	logDebug(s.log, fmt.Sprintf("uploading [%s/%s]", bucket, key))
 	start := time.Now()
@@ -676,10 +751,10 @@ func (s *S3Storage) s3UploadFromBuffer(bucket string, key string, buf []byte) er
 	uploader := manager.NewUploader(s.S3Client, func(u *manager.Uploader) {
 		u.PartSize = partMiBs * 1024 * 1024
 	})
-	_, err := uploader.Upload(context.TODO(), &s3.PutObjectInput{
+	_, err := uploader.Upload(ctx, &s3.PutObjectInput{
 		Bucket: aws.String(bucket),
 		Key:    aws.String(key),
-		Body:   bytes.NewReader(buf),
+		Body:   reader,
 	})
 
 	duration := time.Since(start)
@@ -692,7 +767,7 @@ func (s *S3Storage) s3UploadFromBuffer(bucket string, key string, buf []byte) er
 	return err
 }
 
-func (s *S3Storage) s3DownloadToBuffer(bucket string, key string) ([]byte, error) {
+func (s *S3Storage) s3DownloadToBuffer(ctx context.Context, bucket string, key string) ([]byte, error) {
 
 	logDebug(s.log, fmt.Sprintf("downloading [%s/%s]", bucket, key))
 	start := time.Now()
@@ -703,7 +778,7 @@ func (s *S3Storage) s3DownloadToBuffer(bucket string, key string) ([]byte, error
 		d.PartSize = partMiBs * 1024 * 1024
 	})
 	buffer := manager.NewWriteAtBuffer([]byte{})
-	_, err := downloader.Download(context.TODO(), buffer, &s3.GetObjectInput{
+	_, err := downloader.Download(ctx, buffer, &s3.GetObjectInput{
 		Bucket: aws.String(bucket),
 		Key:    aws.String(key),
 	})
@@ -718,12 +793,16 @@ func (s *S3Storage) s3DownloadToBuffer(bucket string, key string) ([]byte, error
 	return buffer.Bytes(), err
 }
 
-func (s *S3Storage) s3Remove(bucket string, key string) error {
+func (s *S3Storage) s3Remove(ctx context.Context, bucket string, key string) error {
+
+	if err := s.journalKey(ctx, bucket, key); err != nil {
+		return err
+	}
 
 	logDebug(s.log, fmt.Sprintf("deleting [%s/%s]", bucket, key))
 	start := time.Now()
 
-	_, err := s.S3Client.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
+	_, err := s.S3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
 		Bucket: aws.String(bucket),
 		Key:    aws.String(key),
 	})
@@ -738,17 +817,20 @@ func (s *S3Storage) s3Remove(bucket string, key string) error {
 	return err
 }
 
-func (s *S3Storage) s3Rename(bucket string, oldKey string, newKey string) error {
+func (s *S3Storage) s3Rename(ctx context.Context, bucket string, oldKey string, newKey string) error {
+
+	if err := s.journalKey(ctx, bucket, oldKey); err != nil {
+		return err
+	}
+	if err := s.journalKey(ctx, bucket, newKey); err != nil {
+		return err
+	}
 
 	logDebug(s.log, fmt.Sprintf("renaming [%s/%s]->[%s/%s]", bucket, oldKey, bucket, newKey))
 	start := time.Now()
 
 	// copy
-	_, err := s.S3Client.CopyObject(context.TODO(), &s3.CopyObjectInput{
-		Bucket:     aws.String(bucket),
-		Key:        aws.String(newKey),
-		CopySource: aws.String(fmt.Sprintf("%s/%s", bucket, oldKey)),
-	})
+	err := s.s3CopyKey(ctx, bucket, oldKey, newKey)
 	if err != nil {
 		duration := time.Since(start)
 		logError(s.log, fmt.Sprintf("copy [%s/%s]->[%s/%s] complete in %0.2f seconds (%s)", bucket, oldKey, bucket, newKey, duration.Seconds(), s.statusText(err)))
@@ -756,7 +838,7 @@ func (s *S3Storage) s3Rename(bucket string, oldKey string, newKey string) error
 	}
 
 	// then delete
-	_, err = s.S3Client.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
+	_, err = s.S3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
 		Bucket: aws.String(bucket),
 		Key:    aws.String(oldKey),
 	})
@@ -771,12 +853,119 @@ func (s *S3Storage) s3Rename(bucket string, oldKey string, newKey string) error
 	return err
 }
 
-func (s *S3Storage) s3Exists(bucket string, key string) bool {
+func (s *S3Storage) s3Copy(ctx context.Context, bucket string, srcKey string, dstKey string) error {
+
+	logDebug(s.log, fmt.Sprintf("copying [%s/%s]->[%s/%s]", bucket, srcKey, bucket, dstKey))
+	start := time.Now()
+
+	err := s.s3CopyKey(ctx, bucket, srcKey, dstKey)
+
+	duration := time.Since(start)
+	msg := fmt.Sprintf("copy [%s/%s]->[%s/%s] complete in %0.2f seconds (%s)", bucket, srcKey, bucket, dstKey, duration.Seconds(), s.statusText(err))
+	if err == nil {
+		logDebug(s.log, msg)
+	} else {
+		logError(s.log, msg)
+	}
+	return err
+}
+
+// server side copy, anything too large for a single request is copied in parts
+func (s *S3Storage) s3CopyKey(ctx context.Context, bucket string, srcKey string, dstKey string) error {
+
+	head, err := s.S3Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(srcKey),
+	})
+	if err != nil {
+		return err
+	}
+
+	source := s3CopySource(bucket, srcKey)
+	size := aws.ToInt64(head.ContentLength)
+	if size <= s3MaxCopySize {
+		_, err = s.S3Client.CopyObject(ctx, &s3.CopyObjectInput{
+			Bucket:     aws.String(bucket),
+			Key:        aws.String(dstKey),
+			CopySource: aws.String(source),
+		})
+		return err
+	}
+
+	logDebug(s.log, fmt.Sprintf("copying [%s/%s] (%d bytes) in parts", bucket, srcKey, size))
+	upload, err := s.S3Client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(dstKey),
+		ContentType: head.ContentType,
+		Metadata:    head.Metadata,
+	})
+	if err != nil {
+		return err
+	}
+
+	parts := make([]types.CompletedPart, 0)
+	for start := int64(0); start < size; start += s3CopyPartSize {
+		end := start + s3CopyPartSize - 1
+		if end >= size {
+			end = size - 1
+		}
+		number := int32(len(parts) + 1)
+		res, err := s.S3Client.UploadPartCopy(ctx, &s3.UploadPartCopyInput{
+			Bucket:          aws.String(bucket),
+			Key:             aws.String(dstKey),
+			CopySource:      aws.String(source),
+			CopySourceRange: aws.String(fmt.Sprintf("bytes=%d-%d", start, end)),
+			PartNumber:      aws.Int32(number),
+			UploadId:        upload.UploadId,
+		})
+		if err != nil {
+			s.s3AbortUpload(ctx, bucket, dstKey, upload.UploadId)
+			return err
+		}
+		parts = append(parts, types.CompletedPart{
+			ETag:       res.CopyPartResult.ETag,
+			PartNumber: aws.Int32(number),
+		})
+	}
+
+	_, err = s.S3Client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(bucket),
+		Key:             aws.String(dstKey),
+		UploadId:        upload.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		s.s3AbortUpload(ctx, bucket, dstKey, upload.UploadId)
+	}
+	return err
+}
+
+func (s *S3Storage) s3AbortUpload(ctx context.Context, bucket string, key string, uploadId *string) {
+	_, err := s.S3Client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(bucket),
+		Key:      aws.String(key),
+		UploadId: uploadId,
+	})
+	if err != nil {
+		logError(s.log, fmt.Sprintf("abort upload [%s/%s] (%s)", bucket, key, s.statusText(err)))
+	}
+}
+
+// the copy source is the bucket and the URL encoded key
+func s3CopySource(bucket string, key string) string {
+	segments := strings.Split(key, "/")
+	for ix := range segments {
+		segments[ix] = url.PathEscape(segments[ix])
+	}
+	return fmt.Sprintf("%s/%s", bucket, strings.Join(segments, "/"))
+}
+
+func (s *S3Storage) s3Exists(ctx context.Context, bucket string, key string) bool {
 
 	logDebug(s.log, fmt.Sprintf("head [%s/%s]", bucket, key))
 	start := time.Now()
 
-	_, err := s.S3Client.HeadObject(context.TODO(), &s3.HeadObjectInput{
+	_, err := s.S3Client.HeadObject(ctx, &s3.HeadObjectInput{
 		Bucket: aws.String(bucket),
 		Key:    aws.String(key),
 	})
@@ -786,26 +975,48 @@ func (s *S3Storage) s3Exists(bucket string, key string) bool {
 	return err == nil
 }
 
-func (s *S3Storage) s3List(bucket string, key string) ([]string, error) {
+func (s *S3Storage) s3Size(ctx context.Context, bucket string, key string) (int64, error) {
 
-	logDebug(s.log, fmt.Sprintf("list [%s/%s]", bucket, key))
+	logDebug(s.log, fmt.Sprintf("head [%s/%s]", bucket, key))
 	start := time.Now()
 
-	res, err := s.S3Client.ListObjectsV2(context.TODO(), &s3.ListObjectsV2Input{
+	res, err := s.S3Client.HeadObject(ctx, &s3.HeadObjectInput{
 		Bucket: aws.String(bucket),
-		Prefix: aws.String(key),
+		Key:    aws.String(key),
 	})
+
+	duration := time.Since(start)
+	logDebug(s.log, fmt.Sprintf("head [%s/%s] complete in %0.2f seconds (%s)", bucket, key, duration.Seconds(), s.statusText(err)))
 	if err != nil {
-		duration := time.Since(start)
-		logError(s.log, fmt.Sprintf("list [%s/%s] complete in %0.2f seconds (%s)", bucket, key, duration.Seconds(), s.statusText(err)))
-		return nil, err
+		return 0, err
 	}
+	return aws.ToInt64(res.ContentLength), nil
+}
+
+func (s *S3Storage) s3List(ctx context.Context, bucket string, key string) ([]string, error) {
+
+	logDebug(s.log, fmt.Sprintf("list [%s/%s]", bucket, key))
+	start := time.Now()
+
+	// a single request returns at most 1000 keys so page through them
+	paginate := s3.NewListObjectsV2Paginator(s.S3Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(key),
+	})
 
 	// make the result set
 	result := make([]string, 0)
-	for _, o := range res.Contents {
-		logDebug(s.log, fmt.Sprintf("found [%s]", *o.Key))
-		result = append(result, *o.Key)
+	for paginate.HasMorePages() == true {
+		page, err := paginate.NextPage(ctx)
+		if err != nil {
+			duration := time.Since(start)
+			logError(s.log, fmt.Sprintf("list [%s/%s] complete in %0.2f seconds (%s)", bucket, key, duration.Seconds(), s.statusText(err)))
+			return nil, err
+		}
+		for _, o := range page.Contents {
+			logDebug(s.log, fmt.Sprintf("found [%s]", *o.Key))
+			result = append(result, *o.Key)
+		}
 	}
 
 	duration := time.Since(start)
@@ -820,9 +1031,9 @@ func (s *S3Storage) assetKey(namespace string, identifier string, assetName stri
 }
 
 // create a signed access URL for this blob
-func (s *S3Storage) signedUrl(bucket string, key string) (string, error) {
+func (s *S3Storage) signedUrl(ctx context.Context, bucket string, key string) (string, error) {
 
-	ps, err := s.s3SignClient.PresignGetObject(context.Background(),
+	ps, err := s.s3SignClient.PresignGetObject(ctx,
 		&s3.GetObjectInput{
 			Bucket: aws.String(bucket),
 			Key:    aws.String(key),
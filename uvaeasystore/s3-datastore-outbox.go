@@ -0,0 +1,47 @@
+//
+// S3 implementation of the datastore outbox methods
+//
+
+// only include this file for service builds
+
+//go:build service
+// +build service
+
+package uvaeasystore
+
+import (
+	"context"
+	"time"
+)
+
+// the outbox is kept in the database only, it is not part of any object
+
+// AddEvent -- add an event to the outbox
+func (s *S3Storage) AddEvent(ctx context.Context, event EasyStoreEvent) error {
+	return addOutboxEvent(ctx, s.conn(), event, event.Created)
+}
+
+// GetPendingEvents -- get the events that have not been delivered and are not parked, oldest first (a maximum
+// or limit of 0 is no maximum or limit)
+func (s *S3Storage) GetPendingEvents(ctx context.Context, maxAttempts uint, limit uint) ([]EasyStoreEvent, error) {
+	return pendingOutboxEvents(ctx, s.conn(), maxAttempts, limit, s.log)
+}
+
+// GetEventsByTime -- get the events created at or after the first time and before the second, oldest first,
+// skipping the first offset events (limit of 0 is no limit)
+func (s *S3Storage) GetEventsByTime(ctx context.Context, after time.Time, before time.Time, offset uint, limit uint) ([]EasyStoreEvent, error) {
+	return outboxEventsByTime(ctx, s.conn(), after, before, offset, limit, s.log)
+}
+
+// UpdateEvent -- update the delivery state of an event
+func (s *S3Storage) UpdateEvent(ctx context.Context, event EasyStoreEvent) error {
+	var delivered any
+	if event.Delivered != nil {
+		delivered = *event.Delivered
+	}
+	return updateOutboxEvent(ctx, s.conn(), event, delivered)
+}
+
+//
+// end of file
+//
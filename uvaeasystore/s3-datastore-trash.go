@@ -0,0 +1,100 @@
+//
+// S3 implementation of the datastore trash methods
+//
+
+// only include this file for service builds
+
+//go:build service
+// +build service
+
+package uvaeasystore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// deleted objects are marked with a tombstone asset as follows:
+// s3://Bucket-name/namespace/object-identifier/tombstone.json
+
+// TombstoneObjectByKey -- move the object to the trash
+func (s *S3Storage) TombstoneObjectByKey(ctx context.Context, key DataStoreKey) error {
+
+	// the object must exist and not already be in the trash
+	_, err := s.GetObjectByKey(ctx, key, NOCACHE)
+	if err != nil {
+		return err
+	}
+
+	// add the tombstone asset, cannot fail, the tombstone is all simple types
+	tombstone := EasyStoreTombstone{Namespace: key.Namespace, Id: key.ObjectId, Deleted: time.Now()}
+	b, _ := json.Marshal(tombstone)
+	err = s.s3UploadFromBuffer(ctx, s.Bucket, s.assetKey(key.Namespace, key.ObjectId, S3TombstoneFileName), b)
+	if err != nil {
+		return err
+	}
+
+	// update the cache (database)
+	return updateTombstone(ctx, s.conn(), key, "UPDATE objects SET deleted_at = $1 WHERE namespace = $2 AND oid = $3 AND deleted_at IS NULL", tombstone.Deleted)
+}
+
+// RestoreObjectByKey -- restore the object from the trash
+func (s *S3Storage) RestoreObjectByKey(ctx context.Context, key DataStoreKey) error {
+
+	// the object must be in the trash
+	if s.checkS3AssetExists(ctx, key.Namespace, key.ObjectId, S3TombstoneFileName) == false {
+		return fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s/%s", key.Namespace, key.ObjectId, S3TombstoneFileName), ErrNotFound)
+	}
+
+	// remove the tombstone asset
+	err := s.removeS3Asset(ctx, key.Namespace, key.ObjectId, S3TombstoneFileName)
+	if err != nil {
+		return err
+	}
+
+	// update the cache (database)
+	return updateTombstone(ctx, s.conn(), key, "UPDATE objects SET deleted_at = NULL WHERE namespace = $1 AND oid = $2 AND deleted_at IS NOT NULL")
+}
+
+// PurgeObjectByKey -- remove the object from the trash along with its versions
+func (s *S3Storage) PurgeObjectByKey(ctx context.Context, key DataStoreKey) error {
+
+	// the object must be in the trash
+	if s.checkS3AssetExists(ctx, key.Namespace, key.ObjectId, S3TombstoneFileName) == false {
+		return fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s/%s", key.Namespace, key.ObjectId, S3TombstoneFileName), ErrNotFound)
+	}
+
+	// remove every asset, including the file contents that are not removed with the blob descriptors
+	// and the prior versions
+	for _, prefix := range []string{
+		fmt.Sprintf("%s/%s/", key.Namespace, key.ObjectId),
+		fmt.Sprintf("%s/%s/%s/", S3VersionPrefix, key.Namespace, key.ObjectId),
+	} {
+		assets, err := s.s3List(ctx, s.Bucket, prefix)
+		if err != nil {
+			return err
+		}
+		for _, asset := range assets {
+			err = s.s3Remove(ctx, s.Bucket, asset)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// update the cache (database)
+	return updateTombstone(ctx, s.conn(), key, "DELETE FROM objects WHERE namespace = $1 AND oid = $2 AND deleted_at IS NOT NULL")
+}
+
+// GetTombstones -- get the objects in the trash that were deleted before the specified time
+func (s *S3Storage) GetTombstones(ctx context.Context, namespace string, before time.Time) ([]EasyStoreTombstone, error) {
+
+	// we can read from the cache (database)
+	return tombstonesBefore(ctx, s.conn(), namespace, before, s.log)
+}
+
+//
+// end of file
+//
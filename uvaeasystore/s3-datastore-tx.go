@@ -0,0 +1,185 @@
+//
+// S3 implementation of the datastore transaction interface
+//
+
+// only include this file for service builds
+
+//go:build service
+// +build service
+
+package uvaeasystore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// S3 assets are backed up under this prefix before they are changed within a transaction
+var S3RollbackPrefix = ".rollback"
+
+// a single S3 asset changed within a transaction
+type s3JournalEntry struct {
+	bucket string // the bucket
+	key    string // the asset that was changed
+	backup string // the backup of the original asset (blank if it did not previously exist)
+}
+
+// the set of S3 assets changed within a transaction. S3 has no transactions of its own so the
+// assets are written in place and are visible to other readers before the transaction commits,
+// a reader can see an uncommitted (or later rolled back) asset until then. The cache (database)
+// is not updated until the commit so queries and searches never see the uncommitted objects.
+type s3Journal struct {
+	id      string           // journal identifier, used to name the backups
+	entries []s3JournalEntry // in the order they were made
+	seen    map[string]bool  // assets already journaled
+	created map[string]bool  // prefixes of the objects created in this transaction
+}
+
+func newS3Journal() *s3Journal {
+	return &s3Journal{
+		id:      newObjectId(),
+		entries: make([]s3JournalEntry, 0),
+		seen:    make(map[string]bool),
+		created: make(map[string]bool),
+	}
+}
+
+// this is our S3 transaction implementation
+type s3StorageTx struct {
+	*S3Storage // the storage, bound to the transaction
+}
+
+// Commit -- commit the cache (database) changes then discard the S3 backups
+func (t *s3StorageTx) Commit() error {
+
+	if t.journal == nil {
+		return sql.ErrTxDone
+	}
+
+	err := t.tx.Commit()
+	if err != nil {
+		// put S3 back the way it was
+		t.rollbackJournal()
+		return err
+	}
+
+	// the changes are committed so failing to remove a backup is not fatal
+	s := t.untracked()
+	for _, e := range t.journal.entries {
+		if len(e.backup) != 0 {
+			if err := s.s3Remove(context.Background(), e.bucket, e.backup); err != nil {
+				logWarning(t.log, fmt.Sprintf("unable to remove backup [%s/%s] (%s)", e.bucket, e.backup, err.Error()))
+			}
+		}
+	}
+	t.journal = nil
+	return nil
+}
+
+// Rollback -- roll back the cache (database) changes and undo the S3 changes, a no-op if the
+// transaction is already complete
+func (t *s3StorageTx) Rollback() error {
+
+	if t.journal == nil {
+		return nil
+	}
+
+	err := t.tx.Rollback()
+	if errors.Is(err, sql.ErrTxDone) == true {
+		err = nil
+	}
+	jerr := t.rollbackJournal()
+	if err != nil {
+		return err
+	}
+	return jerr
+}
+
+// Close -- roll back anything not committed, the underlying connection remains open
+func (t *s3StorageTx) Close() error {
+	return t.Rollback()
+}
+
+// undo the S3 changes in reverse order. Compensating actions use a fresh context because
+// they must run even when the transaction context has been cancelled
+func (t *s3StorageTx) rollbackJournal() error {
+
+	ctx := context.Background()
+	s := t.untracked()
+	var result error
+	for ix := len(t.journal.entries) - 1; ix >= 0; ix-- {
+		e := t.journal.entries[ix]
+		var err error
+		if len(e.backup) != 0 {
+			// restore the original then remove the backup
+			err = s.s3Copy(ctx, e.bucket, e.backup, e.key)
+			if err == nil {
+				err = s.s3Remove(ctx, e.bucket, e.backup)
+			}
+		} else {
+			// the asset was created in this transaction, remove it
+			err = s.s3Remove(ctx, e.bucket, e.key)
+		}
+		if err != nil {
+			logError(t.log, fmt.Sprintf("unable to roll back [%s/%s] (%s)", e.bucket, e.key, err.Error()))
+			if result == nil {
+				result = err
+			}
+		}
+	}
+	t.journal = nil
+	return result
+}
+
+// a view of the storage that does not journal changes
+func (t *s3StorageTx) untracked() *S3Storage {
+	s := *t.S3Storage
+	s.journal = nil
+	return &s
+}
+
+// record the state of the specified asset before it is first changed within a transaction. The
+// backup is a server side copy that is removed when the transaction commits
+func (s *S3Storage) journalKey(ctx context.Context, bucket string, key string) error {
+
+	// not in a transaction or already journaled
+	if s.journal == nil || s.journal.seen[key] == true {
+		return nil
+	}
+
+	e := s3JournalEntry{bucket: bucket, key: key}
+	if s.journalCreated(key) == false && s.s3Exists(ctx, bucket, key) == true {
+		e.backup = fmt.Sprintf("%s/%s/%s", S3RollbackPrefix, s.journal.id, key)
+		if err := s.s3Copy(ctx, bucket, key, e.backup); err != nil {
+			return err
+		}
+	}
+
+	s.journal.entries = append(s.journal.entries, e)
+	s.journal.seen[key] = true
+	return nil
+}
+
+// note an object created within the transaction, nothing under it needs a backup
+func (s *S3Storage) journalCreate(namespace string, identifier string) {
+	if s.journal != nil {
+		s.journal.created[fmt.Sprintf("%s/%s/", namespace, identifier)] = true
+	}
+}
+
+// is the specified asset part of an object created within the transaction
+func (s *S3Storage) journalCreated(key string) bool {
+	for prefix := range s.journal.created {
+		if strings.HasPrefix(key, prefix) == true {
+			return true
+		}
+	}
+	return false
+}
+
+//
+// end of file
+//
@@ -0,0 +1,153 @@
+//
+// S3 implementation of the datastore version methods
+//
+
+// only include this file for service builds
+
+//go:build service
+// +build service
+
+package uvaeasystore
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// prior versions are copies of the object assets, kept outside of the namespaces as follows:
+// s3://Bucket-name/.versions/namespace/object-identifier/vtag/asset-name
+var S3VersionPrefix = ".versions"
+
+// AddVersion -- preserve the current object, fields, metadata and files as a prior version
+func (s *S3Storage) AddVersion(ctx context.Context, key DataStoreKey) error {
+
+	// we need the current vtag
+	obj, err := s.GetObjectByKey(ctx, key, NOCACHE)
+	if err != nil {
+		return err
+	}
+
+	// copy every asset, S3 does the copy so the payloads are not downloaded
+	prefix := fmt.Sprintf("%s/%s/", key.Namespace, key.ObjectId)
+	assets, err := s.s3List(ctx, s.Bucket, prefix)
+	if err != nil {
+		return err
+	}
+	vns, vid := s.versionKey(key, obj.VTag())
+	for _, asset := range assets {
+		dst := s.assetKey(vns, vid, strings.TrimPrefix(asset, prefix))
+
+		// the copies are new so are removed if the transaction is rolled back
+		if err = s.journalKey(ctx, s.Bucket, dst); err != nil {
+			return err
+		}
+		if err = s.s3Copy(ctx, s.Bucket, asset, dst); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetVersionsByKey -- get the prior versions of the specified object, oldest first
+func (s *S3Storage) GetVersionsByKey(ctx context.Context, key DataStoreKey) ([]EasyStoreVersion, error) {
+
+	assets, err := s.s3List(ctx, s.Bucket, fmt.Sprintf("%s/%s/%s/", S3VersionPrefix, key.Namespace, key.ObjectId))
+	if err != nil {
+		return nil, err
+	}
+
+	// each version has an object asset
+	results := make([]EasyStoreVersion, 0)
+	for _, asset := range assets {
+		if filepath.Base(asset) != S3ObjectFileName {
+			continue
+		}
+		b, err := s.s3DownloadToBuffer(ctx, s.Bucket, asset)
+		if err != nil {
+			return nil, err
+		}
+		obj, err := s.serialize.ObjectDeserialize(b)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, EasyStoreVersion{VTag: obj.VTag(), Created: obj.Created(), Modified: obj.Modified()})
+	}
+
+	// check for not found
+	if len(results) == 0 {
+		return nil, fmt.Errorf("%q: %w", "version(s) not found", ErrNotFound)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Modified.Before(results[j].Modified)
+	})
+
+	logDebug(s.log, fmt.Sprintf("found %d version(s)", len(results)))
+	return results, nil
+}
+
+// GetVersionByKey -- get the specified prior version of an object with all of its components
+func (s *S3Storage) GetVersionByKey(ctx context.Context, key DataStoreKey, vtag string) (EasyStoreObject, error) {
+
+	// versions have the same layout as the objects themselves
+	vns, vid := s.versionKey(key, vtag)
+	if s.checkS3AssetExists(ctx, vns, vid, S3ObjectFileName) == false {
+		return nil, fmt.Errorf("%q: %w", fmt.Sprintf("%s/%s/%s", vns, vid, S3ObjectFileName), ErrNotFound)
+	}
+	obj, err := s.getS3Object(ctx, vns, vid)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.checkS3AssetExists(ctx, vns, vid, S3FieldsFileName) == true {
+		fields, err := s.getS3Fields(ctx, vns, vid)
+		if err != nil {
+			return nil, err
+		}
+		obj.SetMultiFields(*fields)
+	}
+
+	if s.checkS3AssetExists(ctx, vns, vid, S3MetadataFileName) == true {
+		md, err := s.getS3Metadata(ctx, vns, vid)
+		if err != nil {
+			return nil, err
+		}
+		obj.SetMetadata(md)
+	}
+
+	assets, err := s.s3List(ctx, s.Bucket, fmt.Sprintf("%s/%s/", vns, vid))
+	if err != nil {
+		return nil, err
+	}
+	files := make([]EasyStoreBlob, 0)
+	for _, asset := range assets {
+		if s.isBlobName(filepath.Base(asset)) == true {
+			blob, err := s.getS3Blob(ctx, asset)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, *blob)
+		}
+	}
+	if len(files) != 0 {
+		obj.SetFiles(files)
+	}
+
+	return obj, nil
+}
+
+//
+// private methods
+//
+
+// the namespace and identifier that locate a version using the standard asset layout
+func (s *S3Storage) versionKey(key DataStoreKey, vtag string) (string, string) {
+	return fmt.Sprintf("%s/%s", S3VersionPrefix, key.Namespace), fmt.Sprintf("%s/%s", key.ObjectId, vtag)
+}
+
+//
+// end of file
+//
@@ -23,19 +23,22 @@ import (
 
 // DatastoreS3Config -- this is our S3 configuration implementation
 type DatastoreS3Config struct {
-	Bucket              string      // storage Bucket name
-	SignerAccessKey     string      // the signer access key
-	SignerSecretKey     string      // the signer secret key
-	SignerExpireMinutes int         // signed link expire time in minutes
-	DbHost              string      // host endpoint
-	DbPort              int         // port
-	DbName              string      // database name
-	DbUser              string      // database user
-	DbPassword          string      // database password
-	DbTimeout           int         // timeout
-	BusName             string      // the message bus name
-	SourceName          string      // the event source name
-	Log                 *log.Logger // the logger
+	Bucket              string             // storage Bucket name
+	SignerAccessKey     string             // the signer access key
+	SignerSecretKey     string             // the signer secret key
+	SignerExpireMinutes int                // signed link expire time in minutes
+	DbHost              string             // host endpoint
+	DbPort              int                // port
+	DbName              string             // database name
+	DbUser              string             // database user
+	DbPassword          string             // database password
+	DbTimeout           int                // timeout
+	BusName             string             // the message bus name
+	SourceName          string             // the event source name
+	Sink                EasyStoreEventSink // where events are published (instead of the message bus)
+	Access              *EasyStorePolicy   // the access policy (nil allows everything)
+	Audit               EasyStoreAuditLog  // where denied requests are recorded (the logger when nil)
+	Log                 *log.Logger        // the logger
 }
 
 func (impl DatastoreS3Config) Logger() *log.Logger {
@@ -62,6 +65,30 @@ func (impl DatastoreS3Config) SetEventSource(sourceName string) {
 	impl.SourceName = sourceName
 }
 
+func (impl DatastoreS3Config) EventSink() EasyStoreEventSink {
+	return impl.Sink
+}
+
+func (impl DatastoreS3Config) SetEventSink(sink EasyStoreEventSink) {
+	impl.Sink = sink
+}
+
+func (impl DatastoreS3Config) Policy() *EasyStorePolicy {
+	return impl.Access
+}
+
+func (impl DatastoreS3Config) SetPolicy(policy *EasyStorePolicy) {
+	impl.Access = policy
+}
+
+func (impl DatastoreS3Config) AuditLog() EasyStoreAuditLog {
+	return impl.Audit
+}
+
+func (impl DatastoreS3Config) SetAuditLog(audit EasyStoreAuditLog) {
+	impl.Audit = audit
+}
+
 // newS3Store -- create an S3 version of the DataStore
 func newS3Store(config EasyStoreImplConfig) (DataStore, error) {
 
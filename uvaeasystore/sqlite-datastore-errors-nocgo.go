@@ -0,0 +1,19 @@
+//
+// SQLite error mapping for builds without cgo, the driver is a stub so there are no sqlite errors to map
+//
+
+// only include this file for service builds
+
+//go:build service && !cgo
+// +build service,!cgo
+
+package uvaeasystore
+
+// is this a sqlite uniqueness violation
+func sqliteUniqueViolation(err error) bool {
+	return false
+}
+
+//
+// end of file
+//
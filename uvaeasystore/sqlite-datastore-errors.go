@@ -0,0 +1,30 @@
+//
+// SQLite error mapping, the driver error types only exist in cgo builds
+//
+
+// only include this file for service builds
+
+//go:build service && cgo
+// +build service,cgo
+
+package uvaeasystore
+
+import (
+	"errors"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// is this a sqlite uniqueness violation
+func sqliteUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) == true {
+		return sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique ||
+			sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
+	}
+	return false
+}
+
+//
+// end of file
+//
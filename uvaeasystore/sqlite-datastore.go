@@ -0,0 +1,176 @@
+//
+// SQLite implementation of the datastore interface
+//
+
+// only include this file for service builds
+
+//go:build service
+// +build service
+
+package uvaeasystore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"regexp"
+	"time"
+
+	// sqlite
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// DatastoreSqliteConfig -- this is our SQLite configuration implementation
+type DatastoreSqliteConfig struct {
+	DataSource string             // the database file name, the schema must already exist
+	BusName    string             // the message bus name
+	SourceName string             // the event source name
+	Sink       EasyStoreEventSink // where events are published (instead of the message bus)
+	Access     *EasyStorePolicy   // the access policy (nil allows everything)
+	Audit      EasyStoreAuditLog  // where denied requests are recorded (the logger when nil)
+	Log        *log.Logger        // the logger
+}
+
+func (impl DatastoreSqliteConfig) Logger() *log.Logger {
+	return impl.Log
+}
+
+func (impl DatastoreSqliteConfig) SetLogger(log *log.Logger) {
+	impl.Log = log
+}
+
+func (impl DatastoreSqliteConfig) MessageBus() string {
+	return impl.BusName
+}
+
+func (impl DatastoreSqliteConfig) SetMessageBus(busName string) {
+	impl.BusName = busName
+}
+
+func (impl DatastoreSqliteConfig) EventSource() string {
+	return impl.SourceName
+}
+
+func (impl DatastoreSqliteConfig) SetEventSource(sourceName string) {
+	impl.SourceName = sourceName
+}
+
+func (impl DatastoreSqliteConfig) EventSink() EasyStoreEventSink {
+	return impl.Sink
+}
+
+func (impl DatastoreSqliteConfig) SetEventSink(sink EasyStoreEventSink) {
+	impl.Sink = sink
+}
+
+func (impl DatastoreSqliteConfig) Policy() *EasyStorePolicy {
+	return impl.Access
+}
+
+func (impl DatastoreSqliteConfig) SetPolicy(policy *EasyStorePolicy) {
+	impl.Access = policy
+}
+
+func (impl DatastoreSqliteConfig) AuditLog() EasyStoreAuditLog {
+	return impl.Audit
+}
+
+func (impl DatastoreSqliteConfig) SetAuditLog(audit EasyStoreAuditLog) {
+	impl.Audit = audit
+}
+
+// newSqliteStore -- create a SQLite version of the DataStore
+func newSqliteStore(config EasyStoreImplConfig) (DataStore, error) {
+
+	// make sure its one of these
+	c, ok := config.(DatastoreSqliteConfig)
+	if ok == false {
+		return nil, fmt.Errorf("%q: %w", "bad configuration, not a DatastoreSqliteConfig", ErrBadParameter)
+	}
+
+	// validate our configuration
+	err := validateSqliteConfig(c)
+	if err != nil {
+		return nil, err
+	}
+
+	// sqlite creates an empty database when the file does not exist, which is never what we want
+	_, err = os.Stat(c.DataSource)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) == true {
+			return nil, fmt.Errorf("%q: %w", c.DataSource, ErrFileNotFound)
+		}
+		return nil, err
+	}
+
+	logDebug(config.Logger(), fmt.Sprintf("using [sqlite:%s] for storage", c.DataSource))
+
+	// wait for the database lock rather than failing immediately
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?mode=rw&_busy_timeout=5000", c.DataSource))
+	if err != nil {
+		return nil, err
+	}
+	if err = db.Ping(); err != nil {
+		return nil, err
+	}
+
+	// one writer at a time, concurrent writers only get lock errors
+	db.SetMaxOpenConns(1)
+
+	return &dbStorage{
+		dbCurrentTimeFn: "STRFTIME('%Y-%m-%d %H:%M:%f000', 'NOW')",
+		sqlite:          true,
+		log:             c.Log,
+		DB:              db,
+	}, nil
+}
+
+func validateSqliteConfig(config DatastoreSqliteConfig) error {
+
+	if len(config.DataSource) == 0 {
+		return fmt.Errorf("%q: %w", "config.DataSource is blank", ErrBadParameter)
+	}
+
+	if len(config.BusName) != 0 && len(config.SourceName) == 0 {
+		return fmt.Errorf("%q: %w", "config.SourceName is blank", ErrBadParameter)
+	}
+
+	return nil
+}
+
+//
+// private methods
+//
+
+// sqlite statements go through this so the numbered placeholders mean the same as they do for Postgres
+type sqliteHandle struct {
+	dbHandle
+}
+
+// $n names a parameter in sqlite, numbered in the order they first appear, whereas ?n is the nth argument
+var sqlitePlaceholder = regexp.MustCompile(`\$([0-9]+)`)
+
+// sqlite keeps times as text so they must all be written the same way to compare correctly. This is the
+// layout of the current time function and the schema defaults, their milliseconds are padded to match
+var sqliteTimeFormat = "2006-01-02 15:04:05.000000"
+
+func (h sqliteHandle) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return h.dbHandle.PrepareContext(ctx, sqlitePlaceholder.ReplaceAllString(query, "?$1"))
+}
+
+func (h sqliteHandle) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	for ix, arg := range args {
+		if t, ok := arg.(time.Time); ok == true {
+			args[ix] = t.UTC().Format(sqliteTimeFormat)
+		}
+	}
+	return h.dbHandle.QueryContext(ctx, sqlitePlaceholder.ReplaceAllString(query, "?$1"), args...)
+}
+
+//
+// end of file
+//
@@ -5,17 +5,42 @@
 package uvaeasystore
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
 	"time"
 )
 
 // this is our easystore blob implementation
 type easyStoreBlobImpl struct {
-	Name_     string    `json:"name"`              // source file name
-	MimeType_ string    `json:"mimetype"`          // mime type (if we know it)
-	Url_      string    `json:"url,omitempty"`     // payload access url
-	Payload_  []byte    `json:"payload,omitempty"` // payload
-	Created_  time.Time `json:"created"`           // created time
-	Modified_ time.Time `json:"modified"`          // last modified time
+	Name_     string      `json:"name"`               // source file name
+	MimeType_ string      `json:"mimetype"`           // mime type (if we know it)
+	Url_      string      `json:"url,omitempty"`      // payload access url
+	Payload_  []byte      `json:"payload,omitempty"`  // payload
+	Checksum_ string      `json:"checksum,omitempty"` // SHA-256 of the payload, set when the blob is stored
+	Size_     int64       `json:"size"`               // payload size in bytes, set when the blob is stored
+	Created_  time.Time   `json:"created"`            // created time
+	Modified_ time.Time   `json:"modified"`           // last modified time
+	stream    *blobStream // streamed payload (if we have one)
+}
+
+// a streamed payload. It is shared between copies of the blob because it can only be read once
+type blobStream struct {
+	sync.Mutex
+	reader   io.Reader // the payload source
+	buf      []byte    // the payload, once buffered
+	buffered bool      // the payload has been read into the buffer
+	opened   bool      // the reader has been handed out
 }
 
 // factory for our easystore blob interface
@@ -23,6 +48,11 @@ func newEasyStoreBlob(name string, mimeType string, payload []byte) EasyStoreBlo
 	return &easyStoreBlobImpl{Name_: name, MimeType_: mimeType, Payload_: payload}
 }
 
+// factory for our easystore blob interface with a streamed payload
+func newEasyStoreBlobFromReader(name string, mimeType string, reader io.Reader) EasyStoreBlob {
+	return &easyStoreBlobImpl{Name_: name, MimeType_: mimeType, stream: &blobStream{reader: reader}}
+}
+
 func (impl easyStoreBlobImpl) Name() string {
 	return impl.Name_
 }
@@ -36,9 +66,56 @@ func (impl easyStoreBlobImpl) Url() string {
 }
 
 func (impl easyStoreBlobImpl) Payload() ([]byte, error) {
+	// a streamed payload is read into memory the first time it is requested
+	if impl.stream != nil && len(impl.Payload_) == 0 {
+		return impl.stream.bytes()
+	}
+
+	// stored contents are checked against their checksum, contents at a url are checked as they are read
+	if len(impl.Checksum_) != 0 && (len(impl.Payload_) != 0 || len(impl.Url_) == 0) {
+		if actual := payloadChecksum(impl.Payload_); actual != impl.Checksum_ {
+			return nil, fmt.Errorf("%q: %w", fmt.Sprintf("%s: expected %s but got %s", impl.Name_, impl.Checksum_, actual), ErrChecksum)
+		}
+	}
 	return impl.Payload_, nil
 }
 
+func (impl easyStoreBlobImpl) Open() (io.ReadCloser, error) {
+
+	// use the payload if we have it, then the stream, then the url
+	if len(impl.Payload_) != 0 {
+		return impl.verify(io.NopCloser(bytes.NewReader(impl.Payload_))), nil
+	}
+	if impl.stream != nil {
+		return impl.stream.open()
+	}
+	if len(impl.Url_) != 0 {
+		reader, err := openUrl(impl.Url_)
+		if err != nil {
+			return nil, err
+		}
+		return impl.verify(reader), nil
+	}
+	return io.NopCloser(bytes.NewReader(nil)), nil
+}
+
+func (impl easyStoreBlobImpl) Checksum() string {
+	return impl.Checksum_
+}
+
+func (impl easyStoreBlobImpl) Size() int64 {
+	// an in-memory payload is its own size, otherwise use the stored size
+	if len(impl.Payload_) != 0 {
+		return int64(len(impl.Payload_))
+	}
+	if impl.stream != nil {
+		if size, ok := impl.stream.size(); ok == true {
+			return size
+		}
+	}
+	return impl.Size_
+}
+
 func (impl easyStoreBlobImpl) Created() time.Time {
 	return impl.Created_
 }
@@ -47,6 +124,254 @@ func (impl easyStoreBlobImpl) Modified() time.Time {
 	return impl.Modified_
 }
 
+// MarshalJSON -- streamed payloads must be read before they can be encoded
+func (impl easyStoreBlobImpl) MarshalJSON() ([]byte, error) {
+	if impl.stream != nil && len(impl.Payload_) == 0 {
+		buf, err := impl.stream.bytes()
+		if err != nil {
+			return nil, err
+		}
+		impl.Payload_ = buf
+	}
+	impl.Size_ = impl.Size()
+
+	// avoid recursing back into this method
+	type blobAlias easyStoreBlobImpl
+	return json.Marshal(blobAlias(impl))
+}
+
+//
+// private methods
+//
+
+// stored contents are checked against their checksum as they are read
+func (impl easyStoreBlobImpl) verify(reader io.ReadCloser) io.ReadCloser {
+	if len(impl.Checksum_) == 0 {
+		return reader
+	}
+	return &verifyReader{reader: reader, hash: sha256.New(), name: impl.Name_, expected: impl.Checksum_}
+}
+
+// fails at the end of the contents when they do not match the expected checksum
+type verifyReader struct {
+	reader   io.ReadCloser
+	hash     hash.Hash
+	name     string
+	expected string
+}
+
+func (v *verifyReader) Read(p []byte) (int, error) {
+	n, err := v.reader.Read(p)
+	v.hash.Write(p[:n])
+	if err == io.EOF {
+		if actual := hex.EncodeToString(v.hash.Sum(nil)); actual != v.expected {
+			return n, fmt.Errorf("%q: %w", fmt.Sprintf("%s: expected %s but got %s", v.name, v.expected, actual), ErrChecksum)
+		}
+	}
+	return n, err
+}
+
+func (v *verifyReader) Close() error {
+	return v.reader.Close()
+}
+
+// computes the checksum and size of the contents read through it, used when a blob is stored
+type checksumReader struct {
+	reader io.Reader
+	hash   hash.Hash
+	size   int64
+}
+
+func (c *checksumReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.hash.Write(p[:n])
+	c.size += int64(n)
+	return n, err
+}
+
+// the checksum of a payload that is already in memory
+func payloadChecksum(buf []byte) string {
+	sum := sha256.Sum256(buf)
+	return hex.EncodeToString(sum[:])
+}
+
+func newChecksumReader(reader io.Reader) *checksumReader {
+	return &checksumReader{reader: reader, hash: sha256.New()}
+}
+
+func (c *checksumReader) Checksum() string {
+	return hex.EncodeToString(c.hash.Sum(nil))
+}
+
+func (c *checksumReader) Size() int64 {
+	return c.size
+}
+
+func (s *blobStream) bytes() ([]byte, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.buffered == true {
+		return s.buf, nil
+	}
+	if s.opened == true {
+		return nil, fmt.Errorf("%q: %w", "payload stream already consumed", ErrBadParameter)
+	}
+
+	buf, err := io.ReadAll(s.reader)
+	if c, ok := s.reader.(io.Closer); ok == true {
+		_ = c.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.buf, s.buffered = buf, true
+	return s.buf, nil
+}
+
+// the payload, if it has been buffered
+func (s *blobStream) contents() ([]byte, bool) {
+	s.Lock()
+	defer s.Unlock()
+	return s.buf, s.buffered
+}
+
+// the size is only known once the payload is buffered
+func (s *blobStream) size() (int64, bool) {
+	s.Lock()
+	defer s.Unlock()
+	return int64(len(s.buf)), s.buffered
+}
+
+func (s *blobStream) open() (io.ReadCloser, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.buffered == true {
+		return io.NopCloser(bytes.NewReader(s.buf)), nil
+	}
+	if s.opened == true {
+		return nil, fmt.Errorf("%q: %w", "payload stream already consumed", ErrBadParameter)
+	}
+
+	s.opened = true
+	if rc, ok := s.reader.(io.ReadCloser); ok == true {
+		return rc, nil
+	}
+	return io.NopCloser(s.reader), nil
+}
+
+func openUrl(url string) (io.ReadCloser, error) {
+
+	// local files (the file system datastore)
+	if strings.HasPrefix(url, "file://") == true {
+		f, err := os.Open(strings.TrimPrefix(url, "file://"))
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) == true {
+				return nil, fmt.Errorf("%q: %w", url, ErrFileNotFound)
+			}
+			return nil, err
+		}
+		return f, nil
+	}
+
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%q: %w", fmt.Sprintf("GET %s returns HTTP %d", url, resp.StatusCode), ErrFileNotFound)
+	}
+	return resp.Body, nil
+}
+
+// the blob implementation, when it is one of ours
+func blobImpl(blob EasyStoreBlob) (easyStoreBlobImpl, bool) {
+	switch b := blob.(type) {
+	case *easyStoreBlobImpl:
+		return *b, true
+	case easyStoreBlobImpl:
+		return b, true
+	}
+	return easyStoreBlobImpl{}, false
+}
+
+// the payload of a blob when it is already in memory, streamed payloads that have not been read and
+// payloads at a url are not
+func blobInMemory(blob EasyStoreBlob) ([]byte, bool) {
+
+	impl, ok := blobImpl(blob)
+	if ok == false {
+		return nil, false
+	}
+
+	if len(impl.Payload_) != 0 {
+		return impl.Payload_, true
+	}
+	if impl.stream != nil {
+		return impl.stream.contents()
+	}
+	return nil, len(impl.Url_) == 0
+}
+
+// the checksum of a stored blob, used to tell when a blob has changed
+func blobChecksum(blob EasyStoreBlob) (string, error) {
+
+	// stored blobs already know their checksum
+	if len(blob.Checksum()) != 0 {
+		return blob.Checksum(), nil
+	}
+
+	reader, err := blob.Open()
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+	return readerChecksum(reader)
+}
+
+// are the blob contents different from the stored blob. The checksum of a payload supplied by the caller
+// is always computed, a blob that only references stored contents (by url) is compared using its stored
+// checksum. Streamed payloads can only be read once so they are always different
+func blobChanged(current EasyStoreBlob, blob EasyStoreBlob) (bool, error) {
+
+	var sum string
+	if buf, ok := blobInMemory(blob); ok == true {
+		sum = payloadChecksum(buf)
+	} else if impl, ok := blobImpl(blob); ok == true && impl.stream == nil && len(impl.Url_) != 0 {
+		if len(impl.Checksum_) != 0 {
+			sum = impl.Checksum_
+		} else {
+			// stored before checksums were recorded
+			reader, err := openUrl(impl.Url_)
+			if err != nil {
+				return false, err
+			}
+			defer reader.Close()
+			if sum, err = readerChecksum(reader); err != nil {
+				return false, err
+			}
+		}
+	} else {
+		return true, nil
+	}
+
+	curSum, err := blobChecksum(current)
+	if err != nil {
+		return false, err
+	}
+	return curSum != sum, nil
+}
+
+func readerChecksum(reader io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, reader); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
 //
 // end of file
 //
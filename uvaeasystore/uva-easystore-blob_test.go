@@ -0,0 +1,273 @@
+//
+//
+//
+
+package uvaeasystore
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBlobOpen(t *testing.T) {
+	payload := []byte("the payload")
+
+	// payload, stream and empty blobs can all be opened
+	for _, b := range []EasyStoreBlob{
+		NewEasyStoreBlob("file.txt", "text/plain", payload),
+		NewEasyStoreBlobFromReader("file.txt", "text/plain", bytes.NewReader(payload)),
+	} {
+		reader, err := b.Open()
+		if err != nil {
+			t.Fatalf("expected 'OK' but got '%s'\n", err)
+		}
+		buf, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatalf("expected 'OK' but got '%s'\n", err)
+		}
+		_ = reader.Close()
+		testEqual(t, string(payload), string(buf))
+	}
+
+	reader, err := NewEasyStoreBlob("file.txt", "text/plain", nil).Open()
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	buf, _ := io.ReadAll(reader)
+	testEqual(t, "", string(buf))
+}
+
+func TestBlobStreamConsumed(t *testing.T) {
+	payload := []byte("the payload")
+
+	// once buffered, the payload can be read any number of times
+	b := NewEasyStoreBlobFromReader("file.txt", "text/plain", bytes.NewReader(payload))
+	for i := 0; i < 2; i++ {
+		buf, err := b.Payload()
+		if err != nil {
+			t.Fatalf("expected 'OK' but got '%s'\n", err)
+		}
+		testEqual(t, string(payload), string(buf))
+	}
+	reader, err := b.Open()
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	buf, _ := io.ReadAll(reader)
+	testEqual(t, string(payload), string(buf))
+
+	// once handed out, the stream cannot be read again
+	b = NewEasyStoreBlobFromReader("file.txt", "text/plain", bytes.NewReader(payload))
+	_, err = b.Open()
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	expected := ErrBadParameter
+	_, err = b.Open()
+	if errors.Is(err, expected) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", expected, err)
+	}
+	_, err = b.Payload()
+	if errors.Is(err, expected) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", expected, err)
+	}
+}
+
+func TestBlobStreamMarshal(t *testing.T) {
+	payload := []byte("the payload")
+	b := NewEasyStoreBlobFromReader("file.txt", "text/plain", bytes.NewReader(payload))
+
+	// streamed payloads are encoded like any other
+	buf, err := json.Marshal(b)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	var after easyStoreBlobImpl
+	err = json.Unmarshal(buf, &after)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	testEqual(t, b.Name(), after.Name())
+	testEqual(t, string(payload), string(after.Payload_))
+
+	// and do not count as empty
+	err = FileCreatePreflight(goodNamespace, "oid", NewEasyStoreBlobFromReader("file.txt", "text/plain", bytes.NewReader(payload)))
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+}
+
+func TestBlobChecksum(t *testing.T) {
+	es := testSetup(t)
+	defer es.Close()
+	o := NewEasyStoreObject(goodNamespace, "")
+	f1 := newBinaryBlob("file1.bin")
+	o.SetFiles([]EasyStoreBlob{f1})
+
+	// create the new object
+	_, err := es.ObjectCreate(o)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	// stored files know their checksum
+	after, err := es.FileGetByKey(goodNamespace, o.Id(), f1.Name())
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	buf, _ := f1.Payload()
+	sum := sha256.Sum256(buf)
+	testEqual(t, hex.EncodeToString(sum[:]), after.Checksum())
+
+	// and the contents match it
+	reader, err := after.Open()
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	defer reader.Close()
+	_, err = io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+}
+
+func TestBlobSize(t *testing.T) {
+	es := testSetup(t)
+	defer es.Close()
+	o := NewEasyStoreObject(goodNamespace, "")
+	f1 := newBinaryBlob("file1.bin")
+	f2 := NewEasyStoreBlob("file2.txt", "text/plain", []byte{})
+	o.SetFiles([]EasyStoreBlob{f1, f2})
+
+	// new files know their size
+	if f1.Size() != 512 {
+		t.Fatalf("expected 512 but got %d\n", f1.Size())
+	}
+	if f2.Size() != 0 {
+		t.Fatalf("expected 0 but got %d\n", f2.Size())
+	}
+
+	// create the new object
+	_, err := es.ObjectCreate(o)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	// and so do stored ones, without reading the contents
+	after, err := es.ObjectGetByKey(goodNamespace, o.Id(), Files)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	for _, f := range after.Files() {
+		if f.Name() == f1.Name() {
+			if f.Size() != 512 {
+				t.Fatalf("expected 512 but got %d\n", f.Size())
+			}
+		} else {
+			if f.Size() != 0 {
+				t.Fatalf("expected 0 but got %d\n", f.Size())
+			}
+		}
+	}
+
+	// streamed payloads are sized once they are read
+	b := NewEasyStoreBlobFromReader("file.txt", "text/plain", bytes.NewReader([]byte("the payload")))
+	_, _ = b.Payload()
+	if b.Size() != 11 {
+		t.Fatalf("expected 11 but got %d\n", b.Size())
+	}
+}
+
+func TestBlobChecksumMismatch(t *testing.T) {
+	payload := []byte("the payload")
+	sum := sha256.Sum256([]byte("another payload"))
+
+	// contents that do not match the checksum cannot be read, wherever they come from
+	name := filepath.Join(t.TempDir(), "file.txt")
+	if err := os.WriteFile(name, payload, 0644); err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	for _, b := range []EasyStoreBlob{
+		&easyStoreBlobImpl{Name_: "file.txt", Payload_: payload, Checksum_: hex.EncodeToString(sum[:])},
+		&easyStoreBlobImpl{Name_: "file.txt", Url_: fmt.Sprintf("file://%s", name), Checksum_: hex.EncodeToString(sum[:])},
+	} {
+		reader, err := b.Open()
+		if err != nil {
+			t.Fatalf("expected 'OK' but got '%s'\n", err)
+		}
+		expected := ErrChecksum
+		_, err = io.ReadAll(reader)
+		if errors.Is(err, expected) == false {
+			t.Fatalf("expected '%s' but got '%s'\n", expected, err)
+		}
+		_ = reader.Close()
+	}
+
+	// or as a payload, including one that has gone missing
+	for _, b := range []EasyStoreBlob{
+		&easyStoreBlobImpl{Name_: "file.txt", Payload_: payload, Checksum_: hex.EncodeToString(sum[:])},
+		&easyStoreBlobImpl{Name_: "file.txt", Checksum_: hex.EncodeToString(sum[:])},
+	} {
+		expected := ErrChecksum
+		_, err := b.Payload()
+		if errors.Is(err, expected) == false {
+			t.Fatalf("expected '%s' but got '%s'\n", expected, err)
+		}
+	}
+
+	// contents that match are returned as they are
+	b := &easyStoreBlobImpl{Name_: "file.txt", Payload_: payload, Checksum_: payloadChecksum(payload)}
+	buf, err := b.Payload()
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	if bytes.Equal(payload, buf) == false {
+		t.Fatalf("file payloads are unequal but should be\n")
+	}
+}
+
+func TestBlobChangedStored(t *testing.T) {
+	payload := []byte("the payload")
+	current := &easyStoreBlobImpl{Name_: "file.txt", Payload_: payload, Checksum_: payloadChecksum(payload)}
+
+	// a stored blob is compared by its checksum, the contents are not fetched
+	stored := &easyStoreBlobImpl{Name_: "file.txt", Url_: "file:///does/not/exist", Checksum_: current.Checksum_}
+	changed, err := blobChanged(current, stored)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	if changed == true {
+		t.Fatalf("stored blob is changed but should not be\n")
+	}
+
+	stored.Checksum_ = payloadChecksum([]byte("another payload"))
+	changed, err = blobChanged(current, stored)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	if changed == false {
+		t.Fatalf("stored blob is unchanged but should not be\n")
+	}
+
+	// a payload from the caller is always hashed, whatever checksum it claims
+	supplied := &easyStoreBlobImpl{Name_: "file.txt", Payload_: []byte("another payload"), Checksum_: current.Checksum_}
+	changed, err = blobChanged(current, supplied)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	if changed == false {
+		t.Fatalf("supplied blob is unchanged but should not be\n")
+	}
+}
+
+//
+// end of file
+//
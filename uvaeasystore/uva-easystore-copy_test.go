@@ -0,0 +1,163 @@
+//
+//
+//
+
+package uvaeasystore
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+var copyNamespace = "test-namespace-copy"
+
+func TestObjectCopy(t *testing.T) {
+	es := testSetup(t)
+	defer es.Close()
+	o := NewEasyStoreObject(goodNamespace, "")
+	fields := EasyStoreObjectFields{"copy": o.Id()}
+	o.SetFields(fields)
+	o.SetMetadata(newEasyStoreMetadata("application/json", jsonPayload))
+	f1 := newBinaryBlob("file1.bin")
+	o.SetFiles([]EasyStoreBlob{f1})
+
+	// create the new object
+	o, err := es.ObjectCreate(o)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	// copy it to another namespace, keeping the identifier
+	c, err := es.ObjectCopy(goodNamespace, o.Id(), copyNamespace, o.Id())
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	testEqual(t, copyNamespace, c.Namespace())
+	testEqual(t, o.Id(), c.Id())
+	validateObject(t, c, AllComponents)
+	ensureObjectHasFields(t, c, fields)
+
+	// the copy keeps the create time but is a different version
+	orig, err := es.ObjectGetByKey(goodNamespace, o.Id(), BaseComponent)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	if c.Created().Equal(orig.Created()) == false {
+		t.Fatalf("object create times are unequal but should be\n")
+	}
+	if c.VTag() == o.VTag() {
+		t.Fatalf("object vtags are equal but should not be\n")
+	}
+
+	// and has the same files
+	file, err := es.FileGetByKey(copyNamespace, o.Id(), "file1.bin")
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	buf, _ := f1.Payload()
+	if bytes.Equal(buf, getBlobContents(t, file)) == false {
+		t.Fatalf("file payloads are unequal but should be\n")
+	}
+
+	// copy it again with a new identifier
+	c2, err := es.ObjectCopy(goodNamespace, o.Id(), goodNamespace, "")
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	testEqual(t, goodNamespace, c2.Namespace())
+	if c2.Id() == o.Id() {
+		t.Fatalf("object identifiers are equal but should not be\n")
+	}
+	ensureObjectHasFields(t, c2, fields)
+
+	// the copies can be found by their fields
+	set, err := es.ObjectGetByFields(copyNamespace, fields, BaseComponent)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	if set.Count() != 1 {
+		t.Fatalf("expected 1 object but got %d\n", set.Count())
+	}
+
+	// copying onto an existing object fails
+	_, err = es.ObjectCopy(goodNamespace, o.Id(), copyNamespace, o.Id())
+	if errors.Is(err, ErrAlreadyExists) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", ErrAlreadyExists, err)
+	}
+}
+
+func TestObjectCopyBad(t *testing.T) {
+	es := testSetup(t)
+	defer es.Close()
+
+	// copying onto itself
+	_, err := es.ObjectCopy(goodNamespace, badId, goodNamespace, badId)
+	if errors.Is(err, ErrBadParameter) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", ErrBadParameter, err)
+	}
+
+	// copying to no namespace
+	_, err = es.ObjectCopy(goodNamespace, badId, "", badId)
+	if errors.Is(err, ErrBadParameter) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", ErrBadParameter, err)
+	}
+
+	// copying an object that does not exist
+	_, err = es.ObjectCopy(goodNamespace, badId, copyNamespace, "")
+	if errors.Is(err, ErrNotFound) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", ErrNotFound, err)
+	}
+}
+
+func TestObjectMove(t *testing.T) {
+	es := testSetup(t)
+	defer es.Close()
+	o := NewEasyStoreObject(goodNamespace, "")
+	fields := EasyStoreObjectFields{"move": o.Id()}
+	o.SetFields(fields)
+	o.SetMetadata(newEasyStoreMetadata("application/json", jsonPayload))
+	o.SetFiles([]EasyStoreBlob{newBinaryBlob("file1.bin")})
+
+	// create the new object
+	o, err := es.ObjectCreate(o)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	// move it to another namespace
+	m, err := es.ObjectMove(goodNamespace, o.Id(), copyNamespace, o.Id())
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	testEqual(t, copyNamespace, m.Namespace())
+	validateObject(t, m, AllComponents)
+	ensureObjectHasFields(t, m, fields)
+
+	// the original is gone, not in the trash
+	_, err = es.ObjectGetByKey(goodNamespace, o.Id(), BaseComponent)
+	if errors.Is(err, ErrNotFound) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", ErrNotFound, err)
+	}
+	_, err = es.ObjectRestore(goodNamespace, o.Id())
+	if errors.Is(err, ErrNotFound) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", ErrNotFound, err)
+	}
+	set, err := es.ObjectGetByFields(goodNamespace, fields, BaseComponent)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	if set.Count() != 0 {
+		t.Fatalf("expected 0 objects but got %d\n", set.Count())
+	}
+
+	// so the identifier can be used again
+	_, err = es.ObjectCreate(NewEasyStoreObject(goodNamespace, o.Id()))
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+}
+
+//
+// end of file
+//
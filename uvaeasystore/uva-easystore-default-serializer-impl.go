@@ -82,17 +82,56 @@ func (impl easyStoreSerializerImpl) FieldsDeserialize(i interface{}) (EasyStoreO
 	return f, nil
 }
 
+// a field with several values appears once for each of them, so the single valued form reads the last
+func (impl easyStoreSerializerImpl) MultiFieldsSerialize(f EasyStoreObjectMultiFields) interface{} {
+	nvTemplate := "{\"%s\":%s}"
+	arrTemplate := "[%s]"
+	fields := ""
+	for n, values := range f {
+		for _, v := range values {
+			if len(fields) != 0 {
+				fields += ","
+			}
+			b, _ := json.Marshal(v)
+			fields += fmt.Sprintf(nvTemplate, n, string(b))
+		}
+	}
+	str := fmt.Sprintf(arrTemplate, fields)
+	return []byte(str)
+}
+
+func (impl easyStoreSerializerImpl) MultiFieldsDeserialize(i interface{}) (EasyStoreObjectMultiFields, error) {
+
+	// convert to an array of maps
+	omap, err := interfaceToArrayMap(i)
+	if err != nil {
+		return nil, err
+	}
+
+	f := EasyStoreObjectMultiFields{}
+	for _, nv := range omap {
+		for n, v := range nv {
+			s, _ := v.(string)
+			f[n] = append(f[n], s)
+		}
+	}
+
+	return normalizeFields(f), nil
+}
+
 func (impl easyStoreSerializerImpl) BlobSerialize(b EasyStoreBlob) interface{} {
 
 	// assume no error here
 	buf, _ := b.Payload()
 	enc := base64.StdEncoding.EncodeToString(buf)
 
-	template := "{\"name\":%s,\"mimetype\":\"%s\",\"payload\":\"%s\",\"created\":\"%s\",\"modified\":\"%s\"}"
+	template := "{\"name\":%s,\"mimetype\":\"%s\",\"payload\":\"%s\",\"checksum\":\"%s\",\"size\":%d,\"created\":\"%s\",\"modified\":\"%s\"}"
 	str := fmt.Sprintf(template,
 		jsonEncode(b.Name()),
 		b.MimeType(),
 		enc,
+		b.Checksum(),
+		b.Size(),
 		b.Created().UTC(),
 		b.Modified().UTC(),
 	)
@@ -119,6 +158,15 @@ func (impl easyStoreSerializerImpl) BlobDeserialize(i interface{}) (EasyStoreBlo
 		buf)
 
 	blob := b.(*easyStoreBlobImpl)
+
+	// blobs serialized before checksums were introduced do not have one
+	if checksum, ok := omap["checksum"].(string); ok == true {
+		blob.Checksum_ = checksum
+	}
+	if size, ok := omap["size"].(float64); ok == true {
+		blob.Size_ = int64(size)
+	}
+
 	blob.Created_, blob.Modified_, err = timestampExtract(omap)
 	if err != nil {
 		return nil, err
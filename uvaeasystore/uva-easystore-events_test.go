@@ -0,0 +1,218 @@
+//
+//
+//
+
+package uvaeasystore
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/uvalib/librabus-sdk/uvalibrabus"
+)
+
+// what an event should be
+type expectedEvent struct {
+	name   string
+	oid    string
+	detail EasyStoreEventDetail
+}
+
+var testActor = "test-actor"
+var allComponents = []string{"fields", "files", "metadata"}
+var fileComponent = []string{"files"}
+var metadataComponent = []string{"metadata"}
+
+func TestObjectEvents(t *testing.T) {
+	es, _, sink := testOutboxSetup(t)
+	defer es.Close()
+	ctx := WithActor(context.Background(), testActor)
+
+	// create
+	o := NewEasyStoreObject(goodNamespace, "")
+	o.SetFields(EasyStoreObjectFields{"field1": "value1"})
+	o.SetMetadata(newEasyStoreMetadata("application/json", jsonPayload))
+	o.SetFiles([]EasyStoreBlob{newBinaryBlob("file1.bin"), newBinaryBlob("file3.bin")})
+	o, err := es.ObjectCreateCtx(ctx, o)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	created := o.VTag()
+	expectEvents(t, sink,
+		expectedEvent{uvalibrabus.EventObjectCreate, o.Id(), EasyStoreEventDetail{VTag: o.VTag(), Components: allComponents, FilesAdded: []string{"file1.bin", "file3.bin"}}})
+
+	// update, a file is added, one updated and one deleted
+	prior := o.VTag()
+	o.SetFields(EasyStoreObjectFields{"field1": "value2"})
+	o.SetMetadata(newEasyStoreMetadata("application/json", []byte("{}")))
+	o.SetFiles([]EasyStoreBlob{newBinaryBlob("file1.bin"), newBinaryBlob("file2.bin")})
+	o, err = es.ObjectUpdateCtx(ctx, o, AllComponents)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	expectEvents(t, sink,
+		expectedEvent{uvalibrabus.EventFileCreate, o.Id(), EasyStoreEventDetail{VTag: o.VTag(), PriorVTag: prior, Components: fileComponent, File: "file2.bin"}},
+		expectedEvent{uvalibrabus.EventFileUpdate, o.Id(), EasyStoreEventDetail{VTag: o.VTag(), PriorVTag: prior, Components: fileComponent, File: "file1.bin"}},
+		expectedEvent{uvalibrabus.EventFileDelete, o.Id(), EasyStoreEventDetail{VTag: o.VTag(), PriorVTag: prior, Components: fileComponent, File: "file3.bin"}},
+		expectedEvent{uvalibrabus.EventMetadataUpdate, o.Id(), EasyStoreEventDetail{VTag: o.VTag(), PriorVTag: prior, Components: metadataComponent}},
+		expectedEvent{uvalibrabus.EventObjectUpdate, o.Id(), EasyStoreEventDetail{VTag: o.VTag(), PriorVTag: prior, Components: allComponents,
+			FilesAdded: []string{"file2.bin"}, FilesUpdated: []string{"file1.bin"}, FilesDeleted: []string{"file3.bin"}}})
+
+	// delete some of the components, it is an update
+	prior = o.VTag()
+	_, err = es.ObjectDeleteCtx(ctx, o, Files|Metadata)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	o = testCurrentObject(t, es, o.Id())
+	expectEvents(t, sink,
+		expectedEvent{uvalibrabus.EventFileDelete, o.Id(), EasyStoreEventDetail{VTag: o.VTag(), PriorVTag: prior, Components: fileComponent, File: "file1.bin"}},
+		expectedEvent{uvalibrabus.EventFileDelete, o.Id(), EasyStoreEventDetail{VTag: o.VTag(), PriorVTag: prior, Components: fileComponent, File: "file2.bin"}},
+		expectedEvent{uvalibrabus.EventMetadataUpdate, o.Id(), EasyStoreEventDetail{VTag: o.VTag(), PriorVTag: prior, Components: metadataComponent}},
+		expectedEvent{uvalibrabus.EventObjectUpdate, o.Id(), EasyStoreEventDetail{VTag: o.VTag(), PriorVTag: prior, Components: []string{"files", "metadata"},
+			FilesDeleted: []string{"file1.bin", "file2.bin"}}})
+
+	// restore the original version
+	prior = o.VTag()
+	o, err = es.ObjectVersionRestoreCtx(ctx, goodNamespace, o.Id(), created)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	expectEvents(t, sink,
+		expectedEvent{uvalibrabus.EventObjectUpdate, o.Id(), EasyStoreEventDetail{VTag: o.VTag(), PriorVTag: prior, Components: allComponents, Version: created}})
+
+	// delete it and restore it from the trash, it comes back as it was deleted
+	deleted := o.VTag()
+	_, err = es.ObjectDeleteCtx(ctx, o, BaseComponent)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	expectEvents(t, sink,
+		expectedEvent{uvalibrabus.EventObjectDelete, o.Id(), EasyStoreEventDetail{VTag: deleted, Components: allComponents}})
+	o, err = es.ObjectRestoreCtx(ctx, goodNamespace, o.Id())
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	expectEvents(t, sink,
+		expectedEvent{uvalibrabus.EventObjectCreate, o.Id(), EasyStoreEventDetail{VTag: o.VTag(), PriorVTag: deleted, Components: allComponents,
+			FilesAdded: []string{"file1.bin", "file3.bin"}}})
+}
+
+func TestFileEvents(t *testing.T) {
+	es, _, sink := testOutboxSetup(t)
+	defer es.Close()
+	ctx := WithActor(context.Background(), testActor)
+
+	o, err := es.ObjectCreateCtx(ctx, NewEasyStoreObject(goodNamespace, ""))
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	sink.events = nil
+
+	// create
+	prior := o.VTag()
+	err = es.FileCreateCtx(ctx, goodNamespace, o.Id(), newBinaryBlob("file1.bin"))
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	o = testCurrentObject(t, es, o.Id())
+	expectEvents(t, sink,
+		expectedEvent{uvalibrabus.EventObjectUpdate, o.Id(), EasyStoreEventDetail{VTag: o.VTag(), PriorVTag: prior, Components: fileComponent, FilesAdded: []string{"file1.bin"}}},
+		expectedEvent{uvalibrabus.EventFileCreate, o.Id(), EasyStoreEventDetail{VTag: o.VTag(), PriorVTag: prior, Components: fileComponent, File: "file1.bin"}})
+
+	// update
+	prior = o.VTag()
+	err = es.FileUpdateCtx(ctx, goodNamespace, o.Id(), newBinaryBlob("file1.bin"))
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	o = testCurrentObject(t, es, o.Id())
+	expectEvents(t, sink,
+		expectedEvent{uvalibrabus.EventObjectUpdate, o.Id(), EasyStoreEventDetail{VTag: o.VTag(), PriorVTag: prior, Components: fileComponent, FilesUpdated: []string{"file1.bin"}}},
+		expectedEvent{uvalibrabus.EventFileUpdate, o.Id(), EasyStoreEventDetail{VTag: o.VTag(), PriorVTag: prior, Components: fileComponent, File: "file1.bin"}})
+
+	// rename
+	prior = o.VTag()
+	err = es.FileRenameCtx(ctx, goodNamespace, o.Id(), "file1.bin", "file2.bin")
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	o = testCurrentObject(t, es, o.Id())
+	renamed := []EasyStoreRename{{From: "file1.bin", To: "file2.bin"}}
+	expectEvents(t, sink,
+		expectedEvent{uvalibrabus.EventObjectUpdate, o.Id(), EasyStoreEventDetail{VTag: o.VTag(), PriorVTag: prior, Components: fileComponent, FilesRenamed: renamed}},
+		expectedEvent{uvalibrabus.EventFileUpdate, o.Id(), EasyStoreEventDetail{VTag: o.VTag(), PriorVTag: prior, Components: fileComponent, File: "file2.bin", FilesRenamed: renamed}})
+
+	// delete
+	prior = o.VTag()
+	err = es.FileDeleteCtx(ctx, goodNamespace, o.Id(), "file2.bin")
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	o = testCurrentObject(t, es, o.Id())
+	expectEvents(t, sink,
+		expectedEvent{uvalibrabus.EventObjectUpdate, o.Id(), EasyStoreEventDetail{VTag: o.VTag(), PriorVTag: prior, Components: fileComponent, FilesDeleted: []string{"file2.bin"}}},
+		expectedEvent{uvalibrabus.EventFileDelete, o.Id(), EasyStoreEventDetail{VTag: o.VTag(), PriorVTag: prior, Components: fileComponent, File: "file2.bin"}})
+}
+
+func TestCopyEvents(t *testing.T) {
+	es, _, sink := testOutboxSetup(t)
+	defer es.Close()
+	ctx := WithActor(context.Background(), testActor)
+
+	o, err := es.ObjectCreateCtx(ctx, NewEasyStoreObject(goodNamespace, ""))
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	sink.events = nil
+
+	c, err := es.ObjectCopyCtx(ctx, goodNamespace, o.Id(), copyNamespace, o.Id())
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	expectEvents(t, sink,
+		expectedEvent{uvalibrabus.EventObjectCreate, c.Id(), EasyStoreEventDetail{VTag: c.VTag(), Components: allComponents, Source: keyName(DataStoreKey{goodNamespace, o.Id()})}})
+
+	m, err := es.ObjectMoveCtx(ctx, goodNamespace, o.Id(), goodNamespace, "")
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	expectEvents(t, sink,
+		expectedEvent{uvalibrabus.EventObjectCreate, m.Id(), EasyStoreEventDetail{VTag: m.VTag(), Components: allComponents, Source: keyName(DataStoreKey{goodNamespace, o.Id()})}},
+		expectedEvent{uvalibrabus.EventObjectDelete, o.Id(), EasyStoreEventDetail{VTag: o.VTag(), Components: allComponents, Target: keyName(DataStoreKey{goodNamespace, m.Id()})}})
+}
+
+// check the events published since the last check, the actor is always the test actor
+func expectEvents(t *testing.T, sink *testSink, expected ...expectedEvent) {
+	t.Helper()
+	if len(sink.events) != len(expected) {
+		t.Fatalf("expected %d events but got %d\n", len(expected), len(sink.events))
+	}
+	for ix, ev := range sink.events {
+		testEqual(t, expected[ix].name, ev.Name)
+		testEqual(t, expected[ix].oid, ev.ObjectId)
+		detail, err := EventDetail(ev)
+		if err != nil {
+			t.Fatalf("expected 'OK' but got '%s'\n", err)
+		}
+		expected[ix].detail.Actor = testActor
+		want, _ := json.Marshal(expected[ix].detail)
+		got, _ := json.Marshal(detail)
+		testEqual(t, string(want), string(got))
+	}
+	sink.events = nil
+}
+
+func testCurrentObject(t *testing.T, es EasyStore, oid string) EasyStoreObject {
+	t.Helper()
+	o, err := es.ObjectGetByKey(goodNamespace, oid, BaseComponent)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	return o
+}
+
+//
+// end of file
+//
@@ -4,31 +4,36 @@
 
 package uvaeasystore
 
-//func TestReadonlyEmptyDatasource(t *testing.T) {
-//	// configure what we need
-//	config := DatastoreSqliteConfig{
-//		DataSource: "",
-//	}
-//
-//	_, err := NewEasyStoreReadonly(config)
-//	expected := ErrBadParameter
-//	if !errors.Is(err, expected) {
-//		t.Fatalf("expected '%s' but got '%s'\n", expected, err)
-//	}
-//}
+import (
+	"errors"
+	"testing"
+)
 
-//func TestReadonlyNotFoundDatasource(t *testing.T) {
-//	// configure what we need
-//	config := DatastoreSqliteConfig{
-//		DataSource: badSqliteFilename,
-//	}
-//
-//	_, err := NewEasyStoreReadonly(config)
-//	expected := ErrFileNotFound
-//	if !errors.Is(err, expected) {
-//		t.Fatalf("expected '%s' but got '%s'\n", expected, err)
-//	}
-//}
+func TestReadonlyEmptyDatasource(t *testing.T) {
+	// configure what we need
+	config := DatastoreSqliteConfig{
+		DataSource: "",
+	}
+
+	_, err := NewEasyStoreReadonly(config)
+	expected := ErrBadParameter
+	if !errors.Is(err, expected) {
+		t.Fatalf("expected '%s' but got '%s'\n", expected, err)
+	}
+}
+
+func TestReadonlyNotFoundDatasource(t *testing.T) {
+	// configure what we need
+	config := DatastoreSqliteConfig{
+		DataSource: badSqliteFilename,
+	}
+
+	_, err := NewEasyStoreReadonly(config)
+	expected := ErrFileNotFound
+	if !errors.Is(err, expected) {
+		t.Fatalf("expected '%s' but got '%s'\n", expected, err)
+	}
+}
 
 //
 // end of file
@@ -0,0 +1,111 @@
+//
+// multi-valued field support. Fields with a single value are encoded (as JSON) as a string so
+// they are compatible with those written before fields could have several values
+//
+
+package uvaeasystore
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// MarshalJSON - a field with a single value is a string, one with several is an array
+func (f EasyStoreObjectMultiFields) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{}, len(f))
+	for n, v := range f {
+		if len(v) == 1 {
+			m[n] = v[0]
+		} else {
+			m[n] = v
+		}
+	}
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON - accepts either a string or an array of strings for each field
+func (f *EasyStoreObjectMultiFields) UnmarshalJSON(data []byte) error {
+
+	var m map[string]json.RawMessage
+	err := json.Unmarshal(data, &m)
+	if err != nil {
+		return err
+	}
+
+	fields := make(EasyStoreObjectMultiFields, len(m))
+	for n, raw := range m {
+		var value string
+		if json.Unmarshal(raw, &value) == nil {
+			fields[n] = []string{value}
+			continue
+		}
+		var values []string
+		if err = json.Unmarshal(raw, &values); err != nil {
+			return fmt.Errorf("%q: %w", fmt.Sprintf("field [%s] is not a string or an array of strings", n), ErrDeserialize)
+		}
+		fields[n] = values
+	}
+	*f = normalizeFields(fields)
+	return nil
+}
+
+//
+// private implementation methods
+//
+
+// the single valued view of the fields, the first value of each
+func singleFields(fields EasyStoreObjectMultiFields) EasyStoreObjectFields {
+	if fields == nil {
+		return nil
+	}
+	single := make(EasyStoreObjectFields, len(fields))
+	for n, v := range fields {
+		if len(v) != 0 {
+			single[n] = v[0]
+		}
+	}
+	return single
+}
+
+// the multi valued form of the fields, each has its one value
+func multiFields(fields EasyStoreObjectFields) EasyStoreObjectMultiFields {
+	if fields == nil {
+		return nil
+	}
+	multi := make(EasyStoreObjectMultiFields, len(fields))
+	for n, v := range fields {
+		multi[n] = []string{v}
+	}
+	return multi
+}
+
+// remove the fields without values and any repeated values, the order of the values is kept
+func normalizeFields(fields EasyStoreObjectMultiFields) EasyStoreObjectMultiFields {
+	if fields == nil {
+		return nil
+	}
+	normal := make(EasyStoreObjectMultiFields, len(fields))
+	for n, v := range fields {
+		if len(v) != 0 {
+			normal[n] = distinctValues(v)
+		}
+	}
+	return normal
+}
+
+// the values with any repeats removed, in their original order
+func distinctValues(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	distinct := make([]string, 0, len(values))
+	for _, v := range values {
+		if seen[v] == false {
+			seen[v] = true
+			distinct = append(distinct, v)
+		}
+	}
+	return distinct
+}
+
+//
+// end of file
+//
@@ -0,0 +1,102 @@
+//
+//
+//
+
+package uvaeasystore
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMultiFieldsCreate(t *testing.T) {
+	es := testSetup(t)
+	defer es.Close()
+	o := NewEasyStoreObject(goodNamespace, "")
+	fields := EasyStoreObjectMultiFields{"author": {"smith", "jones", "brown"}, "title": {"a title"}}
+	o.SetMultiFields(fields)
+
+	o, err := es.ObjectCreate(o)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	// all the values come back, in order
+	if reflect.DeepEqual(fields, o.MultiFields()) == false {
+		t.Fatalf("expected '%v' but got '%v'\n", fields, o.MultiFields())
+	}
+
+	// the single valued view has the first of them
+	testEqual(t, "smith", o.Fields()["author"])
+	testEqual(t, "a title", o.Fields()["title"])
+
+	// add another value and update
+	o.AddFieldValue("author", "green")
+	o.AddFieldValue("author", "smith") // already there
+	o, err = es.ObjectUpdate(o, Fields)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	expected := []string{"smith", "jones", "brown", "green"}
+	if reflect.DeepEqual(expected, o.MultiFields()["author"]) == false {
+		t.Fatalf("expected '%v' but got '%v'\n", expected, o.MultiFields()["author"])
+	}
+}
+
+func TestMultiFieldsSearch(t *testing.T) {
+	es := testSetup(t)
+	defer es.Close()
+	o := NewEasyStoreObject(goodNamespace, "")
+	o.SetMultiFields(EasyStoreObjectMultiFields{"keyword": {"keyword-" + o.Id(), "other-" + o.Id()}})
+	o, err := es.ObjectCreate(o)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	// any of the values match
+	for _, value := range o.MultiFields()["keyword"] {
+		set, err := es.ObjectGetByFields(goodNamespace, EasyStoreObjectFields{"keyword": value}, Fields)
+		if err != nil {
+			t.Fatalf("expected 'OK' but got '%s'\n", err)
+		}
+		if set.Count() != 1 {
+			t.Fatalf("expected 1 but got %d\n", set.Count())
+		}
+		found, err := set.Next()
+		if err != nil {
+			t.Fatalf("expected 'OK' but got '%s'\n", err)
+		}
+		testEqual(t, o.Id(), found.Id())
+	}
+}
+
+func TestMultiFieldsJSON(t *testing.T) {
+
+	// single values are strings so those written before fields could have several are compatible
+	fields := EasyStoreObjectMultiFields{"one": {"1"}, "two": {"2", "3"}}
+	buf, err := json.Marshal(fields)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	testEqual(t, `{"one":"1","two":["2","3"]}`, string(buf))
+
+	var after EasyStoreObjectMultiFields
+	err = json.Unmarshal(buf, &after)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	if reflect.DeepEqual(fields, after) == false {
+		t.Fatalf("expected '%v' but got '%v'\n", fields, after)
+	}
+
+	// and other values are not fields
+	err = json.Unmarshal([]byte(`{"one":1}`), &after)
+	if err == nil {
+		t.Fatalf("expected error but got 'OK'\n")
+	}
+}
+
+//
+// end of file
+//
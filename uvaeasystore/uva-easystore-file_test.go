@@ -7,6 +7,8 @@ package uvaeasystore
 import (
 	"bytes"
 	"errors"
+	"io"
+	"math/rand"
 	"testing"
 )
 
@@ -79,21 +81,14 @@ func TestFileCreate(t *testing.T) {
 	testEqual(t, f2.Name(), b2.Name())
 	testEqual(t, f1.MimeType(), b1.MimeType())
 	testEqual(t, f2.MimeType(), b2.MimeType())
-	url1 := b1.Url()
-	url2 := b2.Url()
-
-	if len(url1) == 0 {
-		t.Fatalf("file 1 url is empty\n")
-	}
-	if len(url2) == 0 {
-		t.Fatalf("file 2 url is empty\n")
-	}
+	testBlobUrl(t, b1)
+	testBlobUrl(t, b2)
 
 	// verify payloads are correct
 	plBefore1, _ := f1.Payload()
 	plBefore2, _ := f2.Payload()
-	plAfter1, _ := getFileContents(url1)
-	plAfter2, _ := getFileContents(url2)
+	plAfter1 := getBlobContents(t, b1)
+	plAfter2 := getBlobContents(t, b2)
 
 	if !bytes.Equal(plBefore1, plAfter1) {
 		t.Fatalf("file payloads are unequal but should be\n")
@@ -169,15 +164,11 @@ func TestFileDelete(t *testing.T) {
 	file1 := files[0]
 	testEqual(t, f2.Name(), file1.Name())
 	testEqual(t, f2.MimeType(), file1.MimeType())
-	url1 := file1.Url()
-
-	if len(url1) == 0 {
-		t.Fatalf("file 1 url is empty\n")
-	}
+	testBlobUrl(t, file1)
 
 	// verify payloads are correct
 	plBefore2, _ := f2.Payload()
-	plAfter1, _ := getFileContents(url1)
+	plAfter1 := getBlobContents(t, file1)
 
 	if !bytes.Equal(plBefore2, plAfter1) {
 		t.Fatalf("file payloads are unequal but should be\n")
@@ -219,6 +210,7 @@ func TestFileRename(t *testing.T) {
 	}
 
 	// then attempt a non-existent name
+	expected = ErrFileNotFound
 	err = es.FileRename(o.Namespace(), o.Id(), newName, f1.Name())
 	if errors.Is(err, expected) == false {
 		t.Fatalf("expected '%s' but got '%s'\n", expected, err)
@@ -342,21 +334,14 @@ func TestFileUpdate(t *testing.T) {
 	testEqual(t, f2.Name(), b2.Name())
 	testEqual(t, f1.MimeType(), b1.MimeType())
 	testEqual(t, f2.MimeType(), b2.MimeType())
-	url1 := b1.Url()
-	url2 := b2.Url()
-
-	if len(url1) == 0 {
-		t.Fatalf("file 1 url is empty\n")
-	}
-	if len(url2) == 0 {
-		t.Fatalf("file 2 url is empty\n")
-	}
+	testBlobUrl(t, b1)
+	testBlobUrl(t, b2)
 
 	// verify payloads are correct
 	plBefore1, _ := f1.Payload()
 	plBefore2, _ := f3.Payload()
-	plAfter1, _ := getFileContents(url1)
-	plAfter2, _ := getFileContents(url2)
+	plAfter1 := getBlobContents(t, b1)
+	plAfter2 := getBlobContents(t, b2)
 
 	if !bytes.Equal(plBefore1, plAfter1) {
 		t.Fatalf("file payloads are unequal but should be\n")
@@ -372,6 +357,108 @@ func TestFileUpdate(t *testing.T) {
 	}
 }
 
+func TestFileGetByKey(t *testing.T) {
+	es := testSetup(t)
+	defer es.Close()
+	o := NewEasyStoreObject(goodNamespace, "")
+
+	// add some files
+	f1 := newBinaryBlob("file1.bin")
+	f2 := newBinaryBlob("file2.bin")
+	files := []EasyStoreBlob{f1, f2}
+	o.SetFiles(files)
+
+	// create the new object
+	_, err := es.ObjectCreate(o)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	// first attempt with bad values
+	expected := ErrNotFound
+	_, err = es.FileGetByKey(badNamespace, o.Id(), f1.Name())
+	if errors.Is(err, expected) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", expected, err)
+	}
+	_, err = es.FileGetByKey(o.Namespace(), badId, f1.Name())
+	if errors.Is(err, expected) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", expected, err)
+	}
+	expected = ErrFileNotFound
+	_, err = es.FileGetByKey(o.Namespace(), o.Id(), "not-there.bin")
+	if errors.Is(err, expected) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", expected, err)
+	}
+
+	// then try properly
+	file2, err := es.FileGetByKey(o.Namespace(), o.Id(), f2.Name())
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	testEqual(t, f2.Name(), file2.Name())
+	testEqual(t, f2.MimeType(), file2.MimeType())
+
+	// verify the payload is correct
+	reader, err := file2.Open()
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	defer reader.Close()
+	plBefore2, _ := f2.Payload()
+	plAfter2, _ := io.ReadAll(reader)
+
+	if !bytes.Equal(plBefore2, plAfter2) {
+		t.Fatalf("file payloads are unequal but should be\n")
+	}
+}
+
+func TestFileCreateStreamed(t *testing.T) {
+	es := testSetup(t)
+	defer es.Close()
+	o := NewEasyStoreObject(goodNamespace, "")
+
+	// create the new object
+	o, err := es.ObjectCreate(o)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	// a file larger than a single upload part
+	buf := make([]byte, 6*1024*1024)
+	_, _ = rand.Read(buf)
+	f1 := NewEasyStoreBlobFromReader("file1.bin", "application/octet-stream", bytes.NewReader(buf))
+
+	err = es.FileCreate(o.Namespace(), o.Id(), f1)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	// get the current object
+	after, err := es.ObjectGetByKey(o.Namespace(), o.Id(), Files)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	if len(after.Files()) != 1 {
+		t.Fatalf("expected 1 but got %d\n", len(after.Files()))
+	}
+	testEqual(t, f1.Name(), after.Files()[0].Name())
+
+	// verify the payload is correct
+	reader, err := after.Files()[0].Open()
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	defer reader.Close()
+	plAfter, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	if !bytes.Equal(buf, plAfter) {
+		t.Fatalf("file payloads are unequal but should be\n")
+	}
+}
+
 //
 // end of file
 //
@@ -6,6 +6,7 @@ package uvaeasystore
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"testing"
 )
@@ -191,6 +192,142 @@ func TestGetByFoundFields(t *testing.T) {
 	}
 }
 
+func TestGetByFieldsPaged(t *testing.T) {
+	es := testSetup(t)
+	defer es.Close()
+
+	// a common field so we can find all the objects
+	fields := DefaultEasyStoreFields()
+	fields["key1"] = newObjectId()
+
+	// create some objects
+	count := 5
+	for ix := 0; ix < count; ix++ {
+		o := NewEasyStoreObject(goodNamespace, "")
+		o.SetFields(fields)
+		_, err := es.ObjectCreate(o)
+		if err != nil {
+			t.Fatalf("expected 'OK' but got '%s'\n", err)
+		}
+	}
+
+	for _, order := range []EasyStoreOrder{OrderById, OrderByCreated, OrderByModified} {
+
+		// page through the objects
+		seen := make(map[string]bool)
+		page := EasyStorePage{Limit: 2, Order: order}
+		pages := 0
+		for {
+			iter, err := es.ObjectGetByFieldsPage(goodNamespace, fields, Fields, page)
+			if err != nil {
+				t.Fatalf("expected 'OK' but got '%s'\n", err)
+			}
+			if iter.Count() > page.Limit {
+				t.Fatalf("expected at most %d but got %d\n", page.Limit, iter.Count())
+			}
+			pages++
+
+			o, err := iter.Next()
+			for err == nil {
+				validateObject(t, o, Fields)
+				ensureObjectHasFields(t, o, fields)
+				if seen[o.Id()] == true {
+					t.Fatalf("object %s returned more than once\n", o.Id())
+				}
+				seen[o.Id()] = true
+				o, err = iter.Next()
+			}
+
+			if len(iter.Continuation()) == 0 {
+				break
+			}
+			page.Token = iter.Continuation()
+		}
+
+		if len(seen) != count {
+			t.Fatalf("expected %d but got %d\n", count, len(seen))
+		}
+		if pages != 3 {
+			t.Fatalf("expected 3 but got %d\n", pages)
+		}
+	}
+
+	// a token cannot be used with a different order
+	iter, err := es.ObjectGetByFieldsPage(goodNamespace, fields, BaseComponent, EasyStorePage{Limit: 2})
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	expected := ErrBadParameter
+	_, err = es.ObjectGetByFieldsPage(goodNamespace, fields, BaseComponent, EasyStorePage{Limit: 2, Token: iter.Continuation(), Order: OrderByCreated})
+	if errors.Is(err, expected) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", expected, err)
+	}
+
+	// and bad tokens are rejected
+	_, err = es.ObjectGetByFieldsPage(goodNamespace, fields, BaseComponent, EasyStorePage{Limit: 2, Token: "blablabla"})
+	if errors.Is(err, expected) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", expected, err)
+	}
+}
+
+func TestGetByQuery(t *testing.T) {
+	es := testSetup(t)
+	defer es.Close()
+
+	// a common field so we only find our objects
+	tag := newObjectId()
+
+	// create some objects
+	ids := make([]string, 0)
+	for ix, date := range []string{"2020-01-01", "2021-06-15", "2022-12-31"} {
+		o := NewEasyStoreObject(goodNamespace, "")
+		fields := DefaultEasyStoreFields()
+		fields["tag"] = tag
+		fields["date"] = date
+		fields["title"] = fmt.Sprintf("title %d", ix)
+		if ix == 0 {
+			fields["draft"] = "true"
+		}
+		o.SetFields(fields)
+		_, err := es.ObjectCreate(o)
+		if err != nil {
+			t.Fatalf("expected 'OK' but got '%s'\n", err)
+		}
+		ids = append(ids, o.Id())
+	}
+
+	tests := map[string][]string{
+		"date >= 2021-01-01":                           {ids[1], ids[2]},
+		"date < 2021-01-01 OR date > 2022-01-01":       {ids[0], ids[2]},
+		"NOT EXISTS draft":                             {ids[1], ids[2]},
+		"title ^= \"title \" AND title != \"title 1\"": {ids[0], ids[2]},
+		"title ~ \"*e ?\"":                             {ids[0], ids[1], ids[2]},
+		"title ~ \"title%\"":                           {},
+	}
+
+	for where, expected := range tests {
+		query, err := ParseEasyStoreQuery(where)
+		if err != nil {
+			t.Fatalf("expected 'OK' but got '%s'\n", err)
+		}
+		query = QueryAnd(QueryEquals("tag", tag), query)
+
+		iter, err := es.ObjectGetByQuery(goodNamespace, query, BaseComponent, EasyStorePage{Order: OrderByCreated})
+		if err != nil {
+			t.Fatalf("expected 'OK' but got '%s'\n", err)
+		}
+		found := make([]string, 0)
+		o, err := iter.Next()
+		for err == nil {
+			found = append(found, o.Id())
+			o, err = iter.Next()
+		}
+		if fmt.Sprintf("%v", found) != fmt.Sprintf("%v", expected) {
+			t.Fatalf("expected '%v' but got '%v' for [%s]\n", expected, found, where)
+		}
+	}
+}
+
 func TestGetByNotFoundFields(t *testing.T) {
 	esro := testSetupReadonly(t)
 	defer esro.Close()
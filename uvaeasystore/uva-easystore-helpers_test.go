@@ -12,14 +12,18 @@ import (
 	"log"
 	"math/rand"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"testing"
 )
 
 // test invariants
 var goodSqliteFilename = "/tmp/sqlite.db"
 var badSqliteFilename = "/tmp/blablabla.db"
+var memoryName = "easystore-test"
 var sourceName = "testing.unit.automated"
 var goodBusName = "uva-experiment-bus-staging"
 var goodNamespace = "test-namespace"
@@ -27,14 +31,19 @@ var badNamespace = "blablabla"
 var badId = "oid-blablabla"
 var jsonPayload = []byte("{\"id\":123,\"name\":\"the name\"}")
 
-// can be "sqlite", "postgres", "s3" or "proxy"
-// var datastore = "sqlite"
+// can be "sqlite", "postgres", "s3", "filesystem", "memory" or "proxy"
+//var datastore = "sqlite"
 //var datastore = "postgres"
 
 var datastore = "s3"
 
+//var datastore = "filesystem"
+//var datastore = "memory"
 //var datastore = "proxy"
 
+// run the tests through the proxy to a local service backed by the datastore
+var service = false
+
 // do we want event telemetry?
 var enableBus = false
 
@@ -59,60 +68,26 @@ func testSetupReadonly(t *testing.T) EasyStoreReadonly {
 	var esro EasyStoreReadonly
 	var err error
 
-	var implConfig EasyStoreImplConfig
 	var proxyConfig EasyStoreProxyConfig
 
-	switch datastore {
-	//case "sqlite":
-	//	implConfig = DatastoreSqliteConfig{
-	//		DataSource: goodSqliteFilename,
-	//		BusName:    busName,
-	//		SourceName: sourceName,
-	//		Log:        logger,
-	//	}
-	//	esro, err = NewEasyStoreReadonly(implConfig)
-
-	//case "postgres":
-	//	implConfig = DatastorePostgresConfig{
-	//		DbHost:     os.Getenv("DBHOST"),
-	//		DbPort:     asIntWithDefault(os.Getenv("DBPORT"), 0),
-	//		DbName:     os.Getenv("DBNAME"),
-	//		DbUser:     os.Getenv("DBUSER"),
-	//		DbPassword: os.Getenv("DBPASS"),
-	//		DbTimeout:  asIntWithDefault(os.Getenv("DBTIMEOUT"), 0),
-	//		BusName:    busName,
-	//		SourceName: sourceName,
-	//		Log:        logger,
-	//	}
-	//	esro, err = NewEasyStoreReadonly(implConfig)
-
-	case "s3":
-		implConfig = DatastoreS3Config{
-			Bucket:              os.Getenv("BUCKET"),
-			SignerAccessKey:     os.Getenv("SIGNER_ACCESS_KEY"),
-			SignerSecretKey:     os.Getenv("SIGNER_SECRET_KEY"),
-			SignerExpireMinutes: asIntWithDefault(os.Getenv("SIGN_EXPIRE_MINUTES"), 60),
-			DbHost:              os.Getenv("DBHOST"),
-			DbPort:              asIntWithDefault(os.Getenv("DBPORT"), 0),
-			DbName:              os.Getenv("DBNAME"),
-			DbUser:              os.Getenv("DBUSER"),
-			DbPassword:          os.Getenv("DBPASS"),
-			DbTimeout:           asIntWithDefault(os.Getenv("DBTIMEOUT"), 0),
-			BusName:             busName,
-			SourceName:          sourceName,
-			Log:                 logger,
+	switch {
+	case datastore == "proxy":
+		proxyConfig = ProxyConfigImpl{
+			ServiceEndpoint: os.Getenv("ESENDPOINT"),
+			ServiceToken:    os.Getenv("ESTOKEN"),
+			Log:             logger,
 		}
-		esro, err = NewEasyStoreReadonly(implConfig)
+		esro, err = NewEasyStoreProxyReadonly(proxyConfig)
 
-	case "proxy":
+	case service == true:
 		proxyConfig = ProxyConfigImpl{
-			ServiceEndpoint: os.Getenv("ESENDPOINT"),
+			ServiceEndpoint: testServiceEndpoint(t, busName, logger),
 			Log:             logger,
 		}
 		esro, err = NewEasyStoreProxyReadonly(proxyConfig)
 
 	default:
-		t.Fatalf("Unsupported dbStorage configuration")
+		esro, err = NewEasyStoreReadonly(testImplConfigFor(t, busName, logger))
 	}
 
 	if err != nil {
@@ -138,21 +113,48 @@ func testSetup(t *testing.T) EasyStore {
 	var es EasyStore
 	var err error
 
-	var implConfig EasyStoreImplConfig
 	var proxyConfig EasyStoreProxyConfig
 
+	switch {
+	case datastore == "proxy":
+		proxyConfig = ProxyConfigImpl{
+			ServiceEndpoint: os.Getenv("ESENDPOINT"),
+			ServiceToken:    os.Getenv("ESTOKEN"),
+			Log:             logger,
+		}
+		es, err = NewEasyStoreProxy(proxyConfig)
+
+	case service == true:
+		proxyConfig = ProxyConfigImpl{
+			ServiceEndpoint: testServiceEndpoint(t, busName, logger),
+			Log:             logger,
+		}
+		es, err = NewEasyStoreProxy(proxyConfig)
+
+	default:
+		es, err = NewEasyStore(testImplConfigFor(t, busName, logger))
+	}
+
+	if err != nil {
+		t.Fatalf("%t\n", err)
+	}
+	return es
+}
+
+// the configuration of the selected datastore
+func testImplConfigFor(t *testing.T, busName string, logger *log.Logger) EasyStoreImplConfig {
+
 	switch datastore {
-	//case "sqlite":
-	//	implConfig = DatastoreSqliteConfig{
-	//		DataSource: goodSqliteFilename,
-	//		BusName:    busName,
-	//		SourceName: sourceName,
-	//		Log:        logger,
-	//	}
-	//	es, err = NewEasyStore(implConfig)
+	case "sqlite":
+		return DatastoreSqliteConfig{
+			DataSource: goodSqliteFilename,
+			BusName:    busName,
+			SourceName: sourceName,
+			Log:        logger,
+		}
 
 	//case "postgres":
-	//	implConfig = DatastorePostgresConfig{
+	//	return DatastorePostgresConfig{
 	//		DbHost:     os.Getenv("DBHOST"),
 	//		DbPort:     asIntWithDefault(os.Getenv("DBPORT"), 0),
 	//		DbName:     os.Getenv("DBNAME"),
@@ -163,10 +165,9 @@ func testSetup(t *testing.T) EasyStore {
 	//		SourceName: sourceName,
 	//		Log:        logger,
 	//	}
-	//	es, err = NewEasyStore(implConfig)
 
 	case "s3":
-		implConfig = DatastoreS3Config{
+		return DatastoreS3Config{
 			Bucket:              os.Getenv("BUCKET"),
 			SignerAccessKey:     os.Getenv("SIGNER_ACCESS_KEY"),
 			SignerSecretKey:     os.Getenv("SIGNER_SECRET_KEY"),
@@ -181,23 +182,67 @@ func testSetup(t *testing.T) EasyStore {
 			SourceName:          sourceName,
 			Log:                 logger,
 		}
-		es, err = NewEasyStore(implConfig)
 
-	case "proxy":
-		proxyConfig = ProxyConfigImpl{
-			ServiceEndpoint: os.Getenv("ESENDPOINT"),
-			Log:             logger,
-		}
-		es, err = NewEasyStoreProxy(proxyConfig)
+	case "filesystem":
+		return testFilesystemConfig(busName, logger)
+
+	case "memory":
+		return DatastoreMemoryConfig{Name: memoryName, BusName: busName, SourceName: sourceName, Log: logger}
+	}
+
+	t.Fatalf("Unsupported dbStorage configuration")
+	return nil
+}
+
+// the file system configuration from the environment, defaults to a directory under the system temp directory
+func testFilesystemConfig(busName string, logger *log.Logger) DatastoreFilesystemConfig {
+	root := os.Getenv("FSROOT")
+	if len(root) == 0 {
+		root = filepath.Join(os.TempDir(), "easystore-test")
+	}
+	return DatastoreFilesystemConfig{
+		RootDir:    root,
+		BusName:    busName,
+		SourceName: sourceName,
+		Log:        logger,
+	}
+}
+
+// the underlying datastore, for tests that go below the easystore API
+func testDatastoreSetup(t *testing.T) DataStore {
+
+	var logger *log.Logger
+	if debug == true {
+		logger = log.Default()
+	}
 
+	switch datastore {
+	case "sqlite", "s3", "filesystem", "memory":
 	default:
-		t.Fatalf("Unsupported dbStorage configuration")
+		t.Skipf("no datastore available for the %s configuration", datastore)
 	}
 
+	ds, err := NewDatastore(testImplConfigFor(t, "", logger))
 	if err != nil {
 		t.Fatalf("%t\n", err)
 	}
-	return es
+	return ds
+}
+
+// creates a local easystore service backed by the datastore and returns its endpoint
+func testServiceEndpoint(t *testing.T, busName string, logger *log.Logger) string {
+
+	es, err := NewEasyStore(testImplConfigFor(t, busName, logger))
+	if err != nil {
+		t.Fatalf("%t\n", err)
+	}
+
+	server := httptest.NewServer(NewEasyStoreService(es, logger))
+	t.Cleanup(func() {
+		server.Close()
+		es.Close()
+	})
+	return server.URL
 }
 
 func validateObject(t *testing.T, obj EasyStoreObject, which EasyStoreComponents) {
@@ -342,10 +387,46 @@ func newBinaryBlob(filename string) EasyStoreBlob {
 	return NewEasyStoreBlob(filename, "application/octet-stream", buf)
 }
 
+// the datastores that return file contents by url rather than inline
+func expectBlobUrl() bool {
+	switch datastore {
+	case "sqlite", "postgres", "memory":
+		return false
+	}
+	return true
+}
+
+func testBlobUrl(t *testing.T, blob EasyStoreBlob) {
+	if expectBlobUrl() == true && len(blob.Url()) == 0 {
+		t.Fatalf("file %s url is empty\n", blob.Name())
+	}
+}
+
+// the blob contents, inline when the datastore returns them (sqlite, postgres) otherwise from the url
+func getBlobContents(t *testing.T, blob EasyStoreBlob) []byte {
+	buf, _ := blob.Payload()
+	if len(buf) != 0 {
+		return buf
+	}
+	if len(blob.Url()) == 0 {
+		t.Fatalf("file payload AND url are empty\n")
+	}
+	buf, err := getFileContents(blob.Url())
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	return buf
+}
+
 func getFileContents(url string) ([]byte, error) {
 
 	//fmt.Printf("streaming %s...\n", url)
 
+	// local files (the file system datastore)
+	if strings.HasPrefix(url, "file://") == true {
+		return os.ReadFile(strings.TrimPrefix(url, "file://"))
+	}
+
 	resp, err := http.Get(url)
 	if err != nil {
 		return nil, err
@@ -10,15 +10,17 @@
 package uvaeasystore
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/uvalib/librabus-sdk/uvalibrabus"
 )
 
 // this is our easystore implementation
 type easyStoreImpl struct {
-	messageBus            uvalibrabus.UvaBus // the event bus
+	eventSink             EasyStoreEventSink // where events are published
 	easyStoreReadonlyImpl                    // the read-only implementation
 }
 
@@ -31,17 +33,69 @@ func newEasyStore(config EasyStoreImplConfig) (EasyStore, error) {
 		return nil, err
 	}
 
-	// create the message bus
-	bus, err := NewEventBus(config.EventSource(), config.MessageBus(), config.Logger())
+	// create the event sink
+	sink, err := NewEventSink(config)
 	if err != nil {
 		return nil, err
 	}
 
 	logInfo(config.Logger(), fmt.Sprintf("new easystore"))
-	return easyStoreImpl{bus, easyStoreReadonlyImpl{config: config, store: store}}, nil
+	return easyStoreImpl{sink, easyStoreReadonlyImpl{config: config, store: store}}, nil
 }
 
 func (impl easyStoreImpl) ObjectCreate(obj EasyStoreObject) (EasyStoreObject, error) {
+	return impl.ObjectCreateCtx(context.Background(), obj)
+}
+
+func (impl easyStoreImpl) ObjectUpdate(obj EasyStoreObject, which EasyStoreComponents) (EasyStoreObject, error) {
+	return impl.ObjectUpdateCtx(context.Background(), obj, which)
+}
+
+func (impl easyStoreImpl) ObjectDelete(obj EasyStoreObject, which EasyStoreComponents) (EasyStoreObject, error) {
+	return impl.ObjectDeleteCtx(context.Background(), obj, which)
+}
+
+func (impl easyStoreImpl) FileCreate(namespace string, oid string, file EasyStoreBlob) error {
+	return impl.FileCreateCtx(context.Background(), namespace, oid, file)
+}
+
+func (impl easyStoreImpl) FileDelete(namespace string, oid string, name string) error {
+	return impl.FileDeleteCtx(context.Background(), namespace, oid, name)
+}
+
+func (impl easyStoreImpl) FileRename(namespace string, oid string, name string, newName string) error {
+	return impl.FileRenameCtx(context.Background(), namespace, oid, name, newName)
+}
+
+func (impl easyStoreImpl) FileUpdate(namespace string, oid string, file EasyStoreBlob) error {
+	return impl.FileUpdateCtx(context.Background(), namespace, oid, file)
+}
+
+func (impl easyStoreImpl) ObjectRestore(namespace string, oid string) (EasyStoreObject, error) {
+	return impl.ObjectRestoreCtx(context.Background(), namespace, oid)
+}
+
+func (impl easyStoreImpl) ObjectPurge(namespace string, oid string) error {
+	return impl.ObjectPurgeCtx(context.Background(), namespace, oid)
+}
+
+func (impl easyStoreImpl) ObjectPurgeTrash(namespace string, before time.Time) (uint, error) {
+	return impl.ObjectPurgeTrashCtx(context.Background(), namespace, before)
+}
+
+func (impl easyStoreImpl) ObjectCopy(namespace string, oid string, newNamespace string, newOid string) (EasyStoreObject, error) {
+	return impl.ObjectCopyCtx(context.Background(), namespace, oid, newNamespace, newOid)
+}
+
+func (impl easyStoreImpl) ObjectMove(namespace string, oid string, newNamespace string, newOid string) (EasyStoreObject, error) {
+	return impl.ObjectMoveCtx(context.Background(), namespace, oid, newNamespace, newOid)
+}
+
+func (impl easyStoreImpl) ObjectVersionRestore(namespace string, oid string, vtag string) (EasyStoreObject, error) {
+	return impl.ObjectVersionRestoreCtx(context.Background(), namespace, oid, vtag)
+}
+
+func (impl easyStoreImpl) ObjectCreateCtx(ctx context.Context, obj EasyStoreObject) (EasyStoreObject, error) {
 
 	// preflight validation
 	if err := ObjectCreatePreflight(obj); err != nil {
@@ -49,10 +103,22 @@ func (impl easyStoreImpl) ObjectCreate(obj EasyStoreObject) (EasyStoreObject, er
 		return nil, err
 	}
 
+	// access control
+	if err := impl.authorize(ctx, obj.Namespace(), obj.Id(), OperationCreate, objectComponents(obj)); err != nil {
+		return nil, err
+	}
+
 	logInfo(impl.config.Logger(), fmt.Sprintf("creating new ns/oid [%s/%s]", obj.Namespace(), obj.Id()))
 
+	// all changes are made within a transaction
+	tx, err := impl.store.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
 	// add the object
-	err := impl.store.AddObject(obj)
+	err = tx.AddObject(ctx, obj)
 	if err != nil {
 		return nil, err
 	}
@@ -60,16 +126,16 @@ func (impl easyStoreImpl) ObjectCreate(obj EasyStoreObject) (EasyStoreObject, er
 	// do we add metadata
 	if obj.Metadata() != nil {
 		logDebug(impl.config.Logger(), fmt.Sprintf("adding metadata for ns/oid [%s/%s]", obj.Namespace(), obj.Id()))
-		err = impl.store.AddMetadata(DataStoreKey{obj.Namespace(), obj.Id()}, obj.Metadata())
+		err = tx.AddMetadata(ctx, DataStoreKey{obj.Namespace(), obj.Id()}, obj.Metadata())
 		if err != nil {
 			return nil, err
 		}
 	}
 
 	// do we add fields
-	if len(obj.Fields()) != 0 {
+	if len(obj.MultiFields()) != 0 {
 		logDebug(impl.config.Logger(), fmt.Sprintf("adding fields for ns/oid [%s/%s]", obj.Namespace(), obj.Id()))
-		err = impl.store.AddFields(DataStoreKey{obj.Namespace(), obj.Id()}, obj.Fields())
+		err = tx.AddFields(ctx, DataStoreKey{obj.Namespace(), obj.Id()}, obj.MultiFields())
 		if err != nil {
 			return nil, err
 		}
@@ -79,24 +145,34 @@ func (impl easyStoreImpl) ObjectCreate(obj EasyStoreObject) (EasyStoreObject, er
 	if len(obj.Files()) != 0 {
 		logDebug(impl.config.Logger(), fmt.Sprintf("adding files for ns/oid [%s/%s]", obj.Namespace(), obj.Id()))
 		for _, b := range obj.Files() {
-			err = impl.store.AddBlob(DataStoreKey{obj.Namespace(), obj.Id()}, b)
+			err = tx.AddBlob(ctx, DataStoreKey{obj.Namespace(), obj.Id()}, b)
 			if err != nil {
 				return nil, err
 			}
 		}
 	}
 
-	// publish the appropriate event, errors are not too important
-	err = pubObjectCreate(impl.messageBus, obj)
-	if err != nil && errors.Is(err, ErrBusNotConfigured) == false {
-		logError(impl.config.Logger(), fmt.Sprintf("publishing event (%s)", err.Error()))
+	// queue the appropriate event
+	detail := EasyStoreEventDetail{Components: componentNames(objectComponents(obj)), FilesAdded: blobNames(obj.Files())}
+	events, err := impl.queueEvents(ctx, tx, obj, "", eventSpec{uvalibrabus.EventObjectCreate, detail})
+	if err != nil {
+		return nil, err
 	}
 
+	// commit the changes
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	// and publish it
+	impl.deliverEvents(ctx, events)
+
 	// get the full object
-	return impl.ObjectGetByKey(obj.Namespace(), obj.Id(), AllComponents)
+	return impl.getObject(ctx, obj.Namespace(), obj.Id(), AllComponents)
 }
 
-func (impl easyStoreImpl) ObjectUpdate(obj EasyStoreObject, which EasyStoreComponents) (EasyStoreObject, error) {
+func (impl easyStoreImpl) ObjectUpdateCtx(ctx context.Context, obj EasyStoreObject, which EasyStoreComponents) (EasyStoreObject, error) {
 
 	// preflight validation
 	if err := ObjectUpdatePreflight(obj, which); err != nil {
@@ -104,8 +180,13 @@ func (impl easyStoreImpl) ObjectUpdate(obj EasyStoreObject, which EasyStoreCompo
 		return nil, err
 	}
 
+	// access control
+	if err := impl.authorize(ctx, obj.Namespace(), obj.Id(), OperationUpdate, which); err != nil {
+		return nil, err
+	}
+
 	// get the current object and compare the vtag
-	current, err := impl.ObjectGetByKey(obj.Namespace(), obj.Id(), which)
+	current, err := impl.getObject(ctx, obj.Namespace(), obj.Id(), which)
 	if err != nil {
 		return nil, err
 	}
@@ -114,25 +195,52 @@ func (impl easyStoreImpl) ObjectUpdate(obj EasyStoreObject, which EasyStoreCompo
 		return nil, ErrStaleObject
 	}
 
+	// all changes are made within a transaction
+	tx, err := impl.store.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// and nobody else can change the object until it is complete, it must not have changed since we got it
+	err = tx.LockObjectByKey(ctx, DataStoreKey{obj.Namespace(), obj.Id()}, obj.VTag())
+	if err != nil {
+		if errors.Is(err, ErrStaleObject) == true {
+			logWarning(impl.config.Logger(), fmt.Sprintf("stale vtag; req [%s]", obj.VTag()))
+		}
+		return nil, err
+	}
+
+	// preserve the current version before changing anything
+	err = tx.AddVersion(ctx, DataStoreKey{obj.Namespace(), obj.Id()})
+	if err != nil {
+		return nil, err
+	}
+
+	// what changed, for the events
+	detail := EasyStoreEventDetail{}
+	metadataEvent := false
+
 	// do we update the fields
 	if (which & Fields) == Fields {
 		logDebug(impl.config.Logger(), fmt.Sprintf("updating fields for ns/oid [%s/%s]", obj.Namespace(), obj.Id()))
+		detail.Components = append(detail.Components, "fields")
 
 		// get the new field count
-		nfc := len(obj.Fields())
+		nfc := len(obj.MultiFields())
 
 		// do we have existing fields
 		if len(current.Fields()) != 0 {
 
 			// if we have new fields, update the current fields to match
 			if nfc != 0 {
-				err := impl.store.UpdateFields(DataStoreKey{obj.Namespace(), obj.Id()}, obj.Fields())
+				err := tx.UpdateFields(ctx, DataStoreKey{obj.Namespace(), obj.Id()}, obj.MultiFields())
 				if err != nil {
 					return nil, err
 				}
 			} else {
 				// otherwise delete any existing fields
-				err := impl.store.DeleteFieldsByKey(DataStoreKey{obj.Namespace(), obj.Id()})
+				err := tx.DeleteFieldsByKey(ctx, DataStoreKey{obj.Namespace(), obj.Id()})
 				if err != nil {
 					return nil, err
 				}
@@ -140,7 +248,7 @@ func (impl easyStoreImpl) ObjectUpdate(obj EasyStoreObject, which EasyStoreCompo
 		} else {
 			// if we have new fields, add them
 			if nfc != 0 {
-				err := impl.store.AddFields(DataStoreKey{obj.Namespace(), obj.Id()}, obj.Fields())
+				err := tx.AddFields(ctx, DataStoreKey{obj.Namespace(), obj.Id()}, obj.MultiFields())
 				if err != nil {
 					return nil, err
 				}
@@ -152,34 +260,36 @@ func (impl easyStoreImpl) ObjectUpdate(obj EasyStoreObject, which EasyStoreCompo
 	if (which & Files) == Files {
 		logDebug(impl.config.Logger(), fmt.Sprintf("updating files for ns/oid [%s/%s]", obj.Namespace(), obj.Id()))
 
-		// FIXME - try and update existing ones
-
-		// if we have existing files, just delete them
-		if len(current.Files()) != 0 {
+		// only the files that differ are changed, the rest are left alone
+		added, updated, deleted, err := diffFiles(current.Files(), obj.Files())
+		if err != nil {
+			return nil, err
+		}
 
-			// delete the current files
-			err := impl.store.DeleteBlobsByKey(DataStoreKey{obj.Namespace(), obj.Id()})
+		for _, name := range deleted {
+			err = tx.DeleteBlobByKey(ctx, DataStoreKey{obj.Namespace(), obj.Id()}, name)
 			if err != nil {
 				return nil, err
 			}
 		}
-
-		// if we have new files, add them
-		if len(obj.Files()) != 0 {
-
-			for _, b := range obj.Files() {
-				err = impl.store.AddBlob(DataStoreKey{obj.Namespace(), obj.Id()}, b)
-				if err != nil {
-					return nil, err
-				}
-
-				// publish the appropriate event, errors are not too important
-				err = pubFileCreate(impl.messageBus, obj)
-				if err != nil && errors.Is(err, ErrBusNotConfigured) == false {
-					logError(impl.config.Logger(), fmt.Sprintf("publishing event (%s)", err.Error()))
-				}
+		for _, b := range updated {
+			err = tx.UpdateBlob(ctx, DataStoreKey{obj.Namespace(), obj.Id()}, b)
+			if err != nil {
+				return nil, err
+			}
+		}
+		for _, b := range added {
+			err = tx.AddBlob(ctx, DataStoreKey{obj.Namespace(), obj.Id()}, b)
+			if err != nil {
+				return nil, err
 			}
 		}
+		logDebug(impl.config.Logger(), fmt.Sprintf("files for ns/oid [%s/%s]: %d added, %d updated, %d deleted",
+			obj.Namespace(), obj.Id(), len(added), len(updated), len(deleted)))
+		if len(added)+len(updated)+len(deleted) != 0 {
+			detail.Components = append(detail.Components, "files")
+			detail.FilesAdded, detail.FilesUpdated, detail.FilesDeleted = blobNames(added), blobNames(updated), deleted
+		}
 	}
 
 	// do we update metadata
@@ -194,57 +304,79 @@ func (impl easyStoreImpl) ObjectUpdate(obj EasyStoreObject, which EasyStoreCompo
 
 			// if we have existing AND new metadata, update it to the new
 			if nmd != nil {
-				err := impl.store.UpdateMetadata(DataStoreKey{obj.Namespace(), obj.Id()}, obj.Metadata())
+				err := tx.UpdateMetadata(ctx, DataStoreKey{obj.Namespace(), obj.Id()}, obj.Metadata())
 				if err != nil {
 					return nil, err
 				}
-
-				// publish the appropriate event, errors are not too important
-				err = pubMetadataUpdate(impl.messageBus, obj)
-				if err != nil && errors.Is(err, ErrBusNotConfigured) == false {
-					logError(impl.config.Logger(), fmt.Sprintf("publishing event (%s)", err.Error()))
-				}
+				metadataEvent = true
 			} else {
 				// otherwise nothing new so delete the current metadata
-				err := impl.store.DeleteMetadataByKey(DataStoreKey{obj.Namespace(), obj.Id()})
+				err := tx.DeleteMetadataByKey(ctx, DataStoreKey{obj.Namespace(), obj.Id()})
 				if err != nil {
 					return nil, err
 				}
+				metadataEvent = true
 			}
 		} else {
 			// otherwise just add the new metadata (if we have it)
 			if nmd != nil {
-				err := impl.store.AddMetadata(DataStoreKey{obj.Namespace(), obj.Id()}, obj.Metadata())
+				err := tx.AddMetadata(ctx, DataStoreKey{obj.Namespace(), obj.Id()}, obj.Metadata())
 				if err != nil {
 					return nil, err
 				}
-
-				// publish the appropriate event, errors are not too important
-				err = pubMetadataUpdate(impl.messageBus, obj)
-				if err != nil && errors.Is(err, ErrBusNotConfigured) == false {
-					logError(impl.config.Logger(), fmt.Sprintf("publishing event (%s)", err.Error()))
-				}
+				metadataEvent = true
 			}
 		}
 	}
 
+	if metadataEvent == true {
+		detail.Components = append(detail.Components, "metadata")
+	}
+
 	// update the object (timestamp and vtag)
-	err = impl.store.UpdateObject(DataStoreKey{obj.Namespace(), obj.Id()})
+	err = tx.UpdateObject(ctx, DataStoreKey{obj.Namespace(), obj.Id()})
+	if err != nil {
+		return nil, err
+	}
+
+	// queue the appropriate events, an event for each file changed then the metadata and the object
+	updated, err := tx.GetObjectByKey(ctx, DataStoreKey{obj.Namespace(), obj.Id()}, NOCACHE)
+	if err != nil {
+		return nil, err
+	}
+	specs := make([]eventSpec, 0)
+	for _, name := range detail.FilesAdded {
+		specs = append(specs, eventSpec{uvalibrabus.EventFileCreate, EasyStoreEventDetail{Components: []string{"files"}, File: name}})
+	}
+	for _, name := range detail.FilesUpdated {
+		specs = append(specs, eventSpec{uvalibrabus.EventFileUpdate, EasyStoreEventDetail{Components: []string{"files"}, File: name}})
+	}
+	for _, name := range detail.FilesDeleted {
+		specs = append(specs, eventSpec{uvalibrabus.EventFileDelete, EasyStoreEventDetail{Components: []string{"files"}, File: name}})
+	}
+	if metadataEvent == true {
+		specs = append(specs, eventSpec{uvalibrabus.EventMetadataUpdate, EasyStoreEventDetail{Components: []string{"metadata"}}})
+	}
+	specs = append(specs, eventSpec{uvalibrabus.EventObjectUpdate, detail})
+	events, err := impl.queueEvents(ctx, tx, updated, current.VTag(), specs...)
 	if err != nil {
 		return nil, err
 	}
 
-	// publish the appropriate event, errors are not too important
-	err = pubObjectUpdate(impl.messageBus, obj)
-	if err != nil && errors.Is(err, ErrBusNotConfigured) == false {
-		logError(impl.config.Logger(), fmt.Sprintf("publishing event (%s)", err.Error()))
+	// commit the changes
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
 	}
 
+	// and publish them
+	impl.deliverEvents(ctx, events)
+
 	// get the full object
-	return impl.ObjectGetByKey(obj.Namespace(), obj.Id(), AllComponents)
+	return impl.getObject(ctx, obj.Namespace(), obj.Id(), AllComponents)
 }
 
-func (impl easyStoreImpl) ObjectDelete(obj EasyStoreObject, which EasyStoreComponents) (EasyStoreObject, error) {
+func (impl easyStoreImpl) ObjectDeleteCtx(ctx context.Context, obj EasyStoreObject, which EasyStoreComponents) (EasyStoreObject, error) {
 
 	// preflight validation
 	if err := ObjectDeletePreflight(obj, which); err != nil {
@@ -252,8 +384,13 @@ func (impl easyStoreImpl) ObjectDelete(obj EasyStoreObject, which EasyStoreCompo
 		return nil, err
 	}
 
+	// access control
+	if err := impl.authorize(ctx, obj.Namespace(), obj.Id(), OperationDelete, which); err != nil {
+		return nil, err
+	}
+
 	// get the current object and compare the vtag
-	current, err := impl.ObjectGetByKey(obj.Namespace(), obj.Id(), BaseComponent)
+	current, err := impl.getObject(ctx, obj.Namespace(), obj.Id(), BaseComponent)
 	if err != nil {
 		return nil, err
 	}
@@ -262,24 +399,53 @@ func (impl easyStoreImpl) ObjectDelete(obj EasyStoreObject, which EasyStoreCompo
 		return nil, ErrStaleObject
 	}
 
-	// special case, if we are asking for the base component, it means delete everything
-	deleteAll := false
+	// all changes are made within a transaction
+	tx, err := impl.store.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// and nobody else can change the object until it is complete, it must not have changed since we got it
+	err = tx.LockObjectByKey(ctx, DataStoreKey{obj.Namespace(), obj.Id()}, obj.VTag())
+	if err != nil {
+		if errors.Is(err, ErrStaleObject) == true {
+			logWarning(impl.config.Logger(), fmt.Sprintf("stale vtag; req [%s]", obj.VTag()))
+		}
+		return nil, err
+	}
+
+	// the files deleted, for the events
+	key := DataStoreKey{obj.Namespace(), obj.Id()}
+	var deletedFiles []string
+	if which != BaseComponent && (which&Files) == Files {
+		blobs, err := tx.GetBlobsByKey(ctx, key, NOCACHE)
+		if err != nil && errors.Is(err, ErrNotFound) == false {
+			return nil, err
+		}
+		deletedFiles = blobNames(blobs)
+	}
+
+	// special case, if we are asking for the base component, it means delete everything. The object
+	// is moved to the trash with its components intact so it can be restored
 	if which == BaseComponent {
 		logDebug(impl.config.Logger(), fmt.Sprintf("deleting ns/oid [%s/%s]", obj.Namespace(), obj.Id()))
-		err := impl.store.DeleteObjectByKey(DataStoreKey{obj.Namespace(), obj.Id()})
+		err := tx.TombstoneObjectByKey(ctx, DataStoreKey{obj.Namespace(), obj.Id()})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// preserve the current version before changing anything
+		err = tx.AddVersion(ctx, DataStoreKey{obj.Namespace(), obj.Id()})
 		if err != nil {
 			return nil, err
 		}
-
-		// and delete remaining components
-		which = AllComponents
-		deleteAll = true
 	}
 
 	// do we delete fields
 	if (which & Fields) == Fields {
 		logDebug(impl.config.Logger(), fmt.Sprintf("deleting fields for ns/oid [%s/%s]", obj.Namespace(), obj.Id()))
-		err := impl.store.DeleteFieldsByKey(DataStoreKey{obj.Namespace(), obj.Id()})
+		err := tx.DeleteFieldsByKey(ctx, DataStoreKey{obj.Namespace(), obj.Id()})
 		if err != nil {
 			return nil, err
 		}
@@ -288,7 +454,7 @@ func (impl easyStoreImpl) ObjectDelete(obj EasyStoreObject, which EasyStoreCompo
 	// do we delete files
 	if (which & Files) == Files {
 		logDebug(impl.config.Logger(), fmt.Sprintf("deleting files for ns/oid [%s/%s]", obj.Namespace(), obj.Id()))
-		err := impl.store.DeleteBlobsByKey(DataStoreKey{obj.Namespace(), obj.Id()})
+		err := tx.DeleteBlobsByKey(ctx, DataStoreKey{obj.Namespace(), obj.Id()})
 		if err != nil {
 			return nil, err
 		}
@@ -297,33 +463,250 @@ func (impl easyStoreImpl) ObjectDelete(obj EasyStoreObject, which EasyStoreCompo
 	// do we delete metadata
 	if (which & Metadata) == Metadata {
 		logDebug(impl.config.Logger(), fmt.Sprintf("deleting metadata for ns/oid [%s/%s]", obj.Namespace(), obj.Id()))
-		err := impl.store.DeleteMetadataByKey(DataStoreKey{obj.Namespace(), obj.Id()})
+		err := tx.DeleteMetadataByKey(ctx, DataStoreKey{obj.Namespace(), obj.Id()})
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	// if we did not delete the component
-	if deleteAll == false {
+	// if we did not delete the object
+	if which != BaseComponent {
 		// update the object (timestamp and vtag)
-		err = impl.store.UpdateObject(DataStoreKey{obj.Namespace(), obj.Id()})
+		err = tx.UpdateObject(ctx, DataStoreKey{obj.Namespace(), obj.Id()})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// queue the appropriate events, deleting some of the components is an update
+	var events []EasyStoreEvent
+	if which == BaseComponent {
+		detail := EasyStoreEventDetail{Components: componentNames(AllComponents)}
+		events, err = impl.queueEvents(ctx, tx, obj, "", eventSpec{uvalibrabus.EventObjectDelete, detail})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		updated, err := tx.GetObjectByKey(ctx, key, NOCACHE)
+		if err != nil {
+			return nil, err
+		}
+		specs := make([]eventSpec, 0)
+		for _, name := range deletedFiles {
+			specs = append(specs, eventSpec{uvalibrabus.EventFileDelete, EasyStoreEventDetail{Components: []string{"files"}, File: name}})
+		}
+		if (which & Metadata) == Metadata {
+			specs = append(specs, eventSpec{uvalibrabus.EventMetadataUpdate, EasyStoreEventDetail{Components: []string{"metadata"}}})
+		}
+		detail := EasyStoreEventDetail{Components: componentNames(which), FilesDeleted: deletedFiles}
+		specs = append(specs, eventSpec{uvalibrabus.EventObjectUpdate, detail})
+		events, err = impl.queueEvents(ctx, tx, updated, obj.VTag(), specs...)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	// publish the appropriate event, errors are not too important
-	err = pubObjectDelete(impl.messageBus, obj)
-	if err != nil && errors.Is(err, ErrBusNotConfigured) == false {
-		logError(impl.config.Logger(), fmt.Sprintf("publishing event (%s)", err.Error()))
+	// commit the changes
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
 	}
 
+	// and publish it
+	impl.deliverEvents(ctx, events)
+
 	// return the original object
 	return obj, nil
 }
 
+// restore a deleted object from the trash
+func (impl easyStoreImpl) ObjectRestoreCtx(ctx context.Context, namespace string, oid string) (EasyStoreObject, error) {
+
+	// preflight validation
+	if err := TrashPreflight(namespace, oid); err != nil {
+		logError(impl.config.Logger(), "preflight failure")
+		return nil, err
+	}
+
+	// access control
+	if err := impl.authorize(ctx, namespace, oid, OperationCreate, BaseComponent); err != nil {
+		return nil, err
+	}
+
+	logInfo(impl.config.Logger(), fmt.Sprintf("restoring ns/oid [%s/%s]", namespace, oid))
+
+	// all changes are made within a transaction
+	tx, err := impl.store.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	err = tx.RestoreObjectByKey(ctx, DataStoreKey{namespace, oid})
+	if err != nil {
+		// known error
+		if errors.Is(err, ErrNotFound) {
+			logInfo(impl.config.Logger(), fmt.Sprintf("no deleted object found for ns/oid [%s/%s]", namespace, oid))
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	// queue the appropriate event, it describes the object as it was when it was deleted
+	key := DataStoreKey{namespace, oid}
+	restored, err := tx.GetObjectByKey(ctx, key, NOCACHE)
+	if err != nil {
+		return nil, err
+	}
+	which := BaseComponent
+	fields, err := tx.GetFieldsByKey(ctx, key, NOCACHE)
+	if err != nil && errors.Is(err, ErrNotFound) == false {
+		return nil, err
+	}
+	if fields != nil && len(*fields) != 0 {
+		which |= Fields
+	}
+	blobs, err := tx.GetBlobsByKey(ctx, key, NOCACHE)
+	if err != nil && errors.Is(err, ErrNotFound) == false {
+		return nil, err
+	}
+	if len(blobs) != 0 {
+		which |= Files
+	}
+	_, err = tx.GetMetadataByKey(ctx, key, NOCACHE)
+	if err != nil && errors.Is(err, ErrNotFound) == false {
+		return nil, err
+	}
+	if err == nil {
+		which |= Metadata
+	}
+
+	// the object keeps the vtag it was deleted with
+	detail := EasyStoreEventDetail{Components: componentNames(which), FilesAdded: blobNames(blobs)}
+	events, err := impl.queueEvents(ctx, tx, restored, restored.VTag(), eventSpec{uvalibrabus.EventObjectCreate, detail})
+	if err != nil {
+		return nil, err
+	}
+
+	// commit the changes
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	// and publish it
+	impl.deliverEvents(ctx, events)
+
+	// get the full object
+	return impl.getObject(ctx, namespace, oid, AllComponents)
+}
+
+// permanently remove a deleted object from the trash
+func (impl easyStoreImpl) ObjectPurgeCtx(ctx context.Context, namespace string, oid string) error {
+
+	// preflight validation
+	if err := TrashPreflight(namespace, oid); err != nil {
+		logError(impl.config.Logger(), "preflight failure")
+		return err
+	}
+
+	// access control
+	if err := impl.authorize(ctx, namespace, oid, OperationDelete, BaseComponent); err != nil {
+		return err
+	}
+
+	logInfo(impl.config.Logger(), fmt.Sprintf("purging ns/oid [%s/%s]", namespace, oid))
+
+	// all changes are made within a transaction
+	tx, err := impl.store.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// the object (and its versions), it must be in the trash
+	key := DataStoreKey{namespace, oid}
+	err = tx.PurgeObjectByKey(ctx, key)
+	if err != nil {
+		// known error
+		if errors.Is(err, ErrNotFound) {
+			logInfo(impl.config.Logger(), fmt.Sprintf("no deleted object found for ns/oid [%s/%s]", namespace, oid))
+			return ErrNotFound
+		}
+		return err
+	}
+
+	// then the components
+	err = tx.DeleteFieldsByKey(ctx, key)
+	if err != nil {
+		return err
+	}
+	err = tx.DeleteBlobsByKey(ctx, key)
+	if err != nil {
+		return err
+	}
+	err = tx.DeleteMetadataByKey(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	// commit the changes, no event, the object was deleted when it went into the trash
+	return tx.Commit()
+}
+
+// permanently remove the objects deleted before the specified time from the trash
+func (impl easyStoreImpl) ObjectPurgeTrashCtx(ctx context.Context, namespace string, before time.Time) (uint, error) {
+
+	// preflight validation
+	if err := PurgeTrashPreflight(before); err != nil {
+		logError(impl.config.Logger(), "preflight failure")
+		return 0, err
+	}
+
+	// access control
+	if err := impl.authorize(ctx, namespace, "", OperationDelete, BaseComponent); err != nil {
+		return 0, err
+	}
+
+	tombstones, err := impl.store.GetTombstones(ctx, namespace, before)
+	if err != nil {
+		// known error
+		if errors.Is(err, ErrNotFound) {
+			logInfo(impl.config.Logger(), fmt.Sprintf("no deleted objects to purge"))
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	// the trash of every namespace only includes the objects that can be purged in their own
+	if len(namespace) == 0 {
+		tombstones = impl.permittedTombstones(ctx, tombstones, OperationDelete)
+	}
+
+	// each object is purged on its own so a failure does not undo the others
+	var count uint
+	for _, t := range tombstones {
+		err = impl.ObjectPurgeCtx(ctx, t.Namespace, t.Id)
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// copy an object to a new namespace and/or identifier
+func (impl easyStoreImpl) ObjectCopyCtx(ctx context.Context, namespace string, oid string, newNamespace string, newOid string) (EasyStoreObject, error) {
+	return impl.copyObject(ctx, namespace, oid, newNamespace, newOid, false)
+}
+
+// move an object to a new namespace and/or identifier
+func (impl easyStoreImpl) ObjectMoveCtx(ctx context.Context, namespace string, oid string, newNamespace string, newOid string) (EasyStoreObject, error) {
+	return impl.copyObject(ctx, namespace, oid, newNamespace, newOid, true)
+}
+
 // create a file
-func (impl easyStoreImpl) FileCreate(namespace string, oid string, file EasyStoreBlob) error {
+func (impl easyStoreImpl) FileCreateCtx(ctx context.Context, namespace string, oid string, file EasyStoreBlob) error {
 
 	// preflight validation
 	if err := FileCreatePreflight(namespace, oid, file); err != nil {
@@ -331,46 +714,68 @@ func (impl easyStoreImpl) FileCreate(namespace string, oid string, file EasyStor
 		return err
 	}
 
+	// access control
+	if err := impl.authorize(ctx, namespace, oid, OperationCreate, Files); err != nil {
+		return err
+	}
+
+	// all changes are made within a transaction
+	tx, err := impl.store.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	// ensure containing object actually exists
 	key := DataStoreKey{namespace, oid}
-	_, err := impl.store.GetObjectByKey(key, NOCACHE)
+	current, err := tx.GetObjectByKey(ctx, key, NOCACHE)
+	if err != nil {
+		return err
+	}
+
+	// preserve the current version before changing anything
+	err = tx.AddVersion(ctx, key)
 	if err != nil {
 		return err
 	}
 
 	// add it
-	err = impl.store.AddBlob(key, file)
+	err = tx.AddBlob(ctx, key, file)
 	if err != nil {
 		return err
 	}
 
 	// update the object (timestamp and vtag)
-	err = impl.store.UpdateObject(key)
+	err = tx.UpdateObject(ctx, key)
 	if err != nil {
 		return err
 	}
 
-	// get the current object
-	o, err := impl.ObjectGetByKey(namespace, oid, BaseComponent)
+	// queue the appropriate events, they describe the updated object
+	o, err := tx.GetObjectByKey(ctx, key, NOCACHE)
 	if err != nil {
 		return err
 	}
-
-	// publish the appropriate events, errors are not too important
-	err = pubObjectUpdate(impl.messageBus, o)
-	if err != nil && errors.Is(err, ErrBusNotConfigured) == false {
-		logError(impl.config.Logger(), fmt.Sprintf("publishing event (%s)", err.Error()))
+	events, err := impl.queueEvents(ctx, tx, o, current.VTag(),
+		eventSpec{uvalibrabus.EventObjectUpdate, EasyStoreEventDetail{Components: []string{"files"}, FilesAdded: []string{file.Name()}}},
+		eventSpec{uvalibrabus.EventFileCreate, EasyStoreEventDetail{Components: []string{"files"}, File: file.Name()}})
+	if err != nil {
+		return err
 	}
-	err = pubFileCreate(impl.messageBus, o)
-	if err != nil && errors.Is(err, ErrBusNotConfigured) == false {
-		logError(impl.config.Logger(), fmt.Sprintf("publishing event (%s)", err.Error()))
+
+	// commit the changes
+	err = tx.Commit()
+	if err != nil {
+		return err
 	}
 
+	// and publish them
+	impl.deliverEvents(ctx, events)
 	return nil
 }
 
 // delete a file
-func (impl easyStoreImpl) FileDelete(namespace string, oid string, name string) error {
+func (impl easyStoreImpl) FileDeleteCtx(ctx context.Context, namespace string, oid string, name string) error {
 
 	// preflight validation
 	if err := FileDeletePreflight(namespace, oid, name); err != nil {
@@ -378,40 +783,68 @@ func (impl easyStoreImpl) FileDelete(namespace string, oid string, name string)
 		return err
 	}
 
-	// delete the file
+	// access control
+	if err := impl.authorize(ctx, namespace, oid, OperationDelete, Files); err != nil {
+		return err
+	}
+
+	// all changes are made within a transaction
+	tx, err := impl.store.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// the current object, for the events
 	key := DataStoreKey{namespace, oid}
-	err := impl.store.DeleteBlobByKey(key, name)
+	current, err := tx.GetObjectByKey(ctx, key, NOCACHE)
 	if err != nil {
 		return err
 	}
 
-	// update the object (timestamp and vtag)
-	err = impl.store.UpdateObject(key)
+	// preserve the current version before changing anything
+	err = tx.AddVersion(ctx, key)
 	if err != nil {
 		return err
 	}
 
-	// get the current object
-	o, err := impl.ObjectGetByKey(namespace, oid, BaseComponent)
+	// delete the file
+	err = tx.DeleteBlobByKey(ctx, key, name)
 	if err != nil {
 		return err
 	}
 
-	// publish the appropriate events, errors are not too important
-	err = pubObjectUpdate(impl.messageBus, o)
-	if err != nil && errors.Is(err, ErrBusNotConfigured) == false {
-		logError(impl.config.Logger(), fmt.Sprintf("publishing event (%s)", err.Error()))
+	// update the object (timestamp and vtag)
+	err = tx.UpdateObject(ctx, key)
+	if err != nil {
+		return err
 	}
-	err = pubFileDelete(impl.messageBus, o)
-	if err != nil && errors.Is(err, ErrBusNotConfigured) == false {
-		logError(impl.config.Logger(), fmt.Sprintf("publishing event (%s)", err.Error()))
+
+	// queue the appropriate events, they describe the updated object
+	o, err := tx.GetObjectByKey(ctx, key, NOCACHE)
+	if err != nil {
+		return err
+	}
+	events, err := impl.queueEvents(ctx, tx, o, current.VTag(),
+		eventSpec{uvalibrabus.EventObjectUpdate, EasyStoreEventDetail{Components: []string{"files"}, FilesDeleted: []string{name}}},
+		eventSpec{uvalibrabus.EventFileDelete, EasyStoreEventDetail{Components: []string{"files"}, File: name}})
+	if err != nil {
+		return err
 	}
 
+	// commit the changes
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	// and publish them
+	impl.deliverEvents(ctx, events)
 	return nil
 }
 
 // rename a file, old name, new name
-func (impl easyStoreImpl) FileRename(namespace string, oid string, name string, newName string) error {
+func (impl easyStoreImpl) FileRenameCtx(ctx context.Context, namespace string, oid string, name string, newName string) error {
 
 	// preflight validation
 	if err := FileRenamePreflight(namespace, oid, name, newName); err != nil {
@@ -419,46 +852,69 @@ func (impl easyStoreImpl) FileRename(namespace string, oid string, name string,
 		return err
 	}
 
+	// access control
+	if err := impl.authorize(ctx, namespace, oid, OperationUpdate, Files); err != nil {
+		return err
+	}
+
+	// all changes are made within a transaction
+	tx, err := impl.store.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	// ensure containing object actually exists
 	key := DataStoreKey{namespace, oid}
-	_, err := impl.store.GetObjectByKey(key, NOCACHE)
+	current, err := tx.GetObjectByKey(ctx, key, NOCACHE)
+	if err != nil {
+		return err
+	}
+
+	// preserve the current version before changing anything
+	err = tx.AddVersion(ctx, key)
 	if err != nil {
 		return err
 	}
 
 	// do the rename
-	err = impl.store.RenameBlobByKey(key, name, newName)
+	err = tx.RenameBlobByKey(ctx, key, name, newName)
 	if err != nil {
 		return err
 	}
 
 	// update the object (timestamp and vtag)
-	err = impl.store.UpdateObject(key)
+	err = tx.UpdateObject(ctx, key)
 	if err != nil {
 		return err
 	}
 
-	// get the current object
-	o, err := impl.ObjectGetByKey(namespace, oid, BaseComponent)
+	// queue the appropriate events, they describe the updated object
+	o, err := tx.GetObjectByKey(ctx, key, NOCACHE)
 	if err != nil {
 		return err
 	}
-
-	// publish the appropriate events, errors are not too important
-	err = pubObjectUpdate(impl.messageBus, o)
-	if err != nil && errors.Is(err, ErrBusNotConfigured) == false {
-		logError(impl.config.Logger(), fmt.Sprintf("publishing event (%s)", err.Error()))
+	renamed := []EasyStoreRename{{From: name, To: newName}}
+	events, err := impl.queueEvents(ctx, tx, o, current.VTag(),
+		eventSpec{uvalibrabus.EventObjectUpdate, EasyStoreEventDetail{Components: []string{"files"}, FilesRenamed: renamed}},
+		eventSpec{uvalibrabus.EventFileUpdate, EasyStoreEventDetail{Components: []string{"files"}, File: newName, FilesRenamed: renamed}})
+	if err != nil {
+		return err
 	}
-	err = pubFileUpdate(impl.messageBus, o)
-	if err != nil && errors.Is(err, ErrBusNotConfigured) == false {
-		logError(impl.config.Logger(), fmt.Sprintf("publishing event (%s)", err.Error()))
+
+	// commit the changes
+	err = tx.Commit()
+	if err != nil {
+		return err
 	}
 
+	// and publish them
+	impl.deliverEvents(ctx, events)
 	return nil
 }
 
 // update a file
-func (impl easyStoreImpl) FileUpdate(namespace string, oid string, file EasyStoreBlob) error {
+func (impl easyStoreImpl) FileUpdateCtx(ctx context.Context, namespace string, oid string, file EasyStoreBlob) error {
 
 	// preflight validation
 	if err := FileUpdatePreflight(namespace, oid, file); err != nil {
@@ -466,44 +922,358 @@ func (impl easyStoreImpl) FileUpdate(namespace string, oid string, file EasyStor
 		return err
 	}
 
+	// access control
+	if err := impl.authorize(ctx, namespace, oid, OperationUpdate, Files); err != nil {
+		return err
+	}
+
+	// all changes are made within a transaction
+	tx, err := impl.store.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	// ensure containing object actually exists
 	key := DataStoreKey{namespace, oid}
-	_, err := impl.store.GetObjectByKey(key, NOCACHE)
+	current, err := tx.GetObjectByKey(ctx, key, NOCACHE)
+	if err != nil {
+		return err
+	}
+
+	// preserve the current version before changing anything
+	err = tx.AddVersion(ctx, key)
 	if err != nil {
 		return err
 	}
 
 	// do the update
-	err = impl.store.UpdateBlob(key, file)
+	err = tx.UpdateBlob(ctx, key, file)
 	if err != nil {
 		return err
 	}
 
 	// update the object (timestamp and vtag)
-	err = impl.store.UpdateObject(key)
+	err = tx.UpdateObject(ctx, key)
 	if err != nil {
 		return err
 	}
 
-	// get the current object
-	o, err := impl.ObjectGetByKey(namespace, oid, BaseComponent)
+	// queue the appropriate events, they describe the updated object
+	o, err := tx.GetObjectByKey(ctx, key, NOCACHE)
 	if err != nil {
 		return err
 	}
-
-	// publish the appropriate events, errors are not too important
-	err = pubObjectUpdate(impl.messageBus, o)
-	if err != nil && errors.Is(err, ErrBusNotConfigured) == false {
-		logError(impl.config.Logger(), fmt.Sprintf("publishing event (%s)", err.Error()))
+	events, err := impl.queueEvents(ctx, tx, o, current.VTag(),
+		eventSpec{uvalibrabus.EventObjectUpdate, EasyStoreEventDetail{Components: []string{"files"}, FilesUpdated: []string{file.Name()}}},
+		eventSpec{uvalibrabus.EventFileUpdate, EasyStoreEventDetail{Components: []string{"files"}, File: file.Name()}})
+	if err != nil {
+		return err
 	}
-	err = pubFileUpdate(impl.messageBus, o)
-	if err != nil && errors.Is(err, ErrBusNotConfigured) == false {
-		logError(impl.config.Logger(), fmt.Sprintf("publishing event (%s)", err.Error()))
+
+	// commit the changes
+	err = tx.Commit()
+	if err != nil {
+		return err
 	}
 
+	// and publish them
+	impl.deliverEvents(ctx, events)
 	return nil
 }
 
+// restore a prior version of an object, the restored version becomes a new version
+func (impl easyStoreImpl) ObjectVersionRestoreCtx(ctx context.Context, namespace string, oid string, vtag string) (EasyStoreObject, error) {
+
+	// preflight validation
+	if err := VersionPreflight(namespace, oid, vtag); err != nil {
+		logError(impl.config.Logger(), "preflight failure")
+		return nil, err
+	}
+
+	// access control
+	if err := impl.authorize(ctx, namespace, oid, OperationUpdate, AllComponents); err != nil {
+		return nil, err
+	}
+
+	// get the current object, restoring the current version changes nothing
+	current, err := impl.getByKey(ctx, namespace, oid)
+	if err != nil {
+		return nil, err
+	}
+	if current.VTag() == vtag {
+		return impl.getObject(ctx, namespace, oid, AllComponents)
+	}
+
+	logInfo(impl.config.Logger(), fmt.Sprintf("restoring ns/oid [%s/%s] to vtag [%s]", namespace, oid, vtag))
+
+	// all changes are made within a transaction
+	tx, err := impl.store.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// get the version to restore
+	key := DataStoreKey{namespace, oid}
+	version, err := tx.GetVersionByKey(ctx, key, vtag)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			logInfo(impl.config.Logger(), fmt.Sprintf("no version found for ns/oid/vtag [%s/%s/%s]", namespace, oid, vtag))
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	// preserve the current version before changing anything
+	err = tx.AddVersion(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	// remove the current components
+	err = tx.DeleteFieldsByKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.DeleteBlobsByKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.DeleteMetadataByKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	// and replace them with those of the version
+	if len(version.MultiFields()) != 0 {
+		err = tx.AddFields(ctx, key, version.MultiFields())
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, b := range version.Files() {
+		err = tx.AddBlob(ctx, key, b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if version.Metadata() != nil {
+		err = tx.AddMetadata(ctx, key, version.Metadata())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// update the object (timestamp and vtag)
+	err = tx.UpdateObject(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	// queue the appropriate event, it describes the updated object
+	updated, err := tx.GetObjectByKey(ctx, key, NOCACHE)
+	if err != nil {
+		return nil, err
+	}
+	detail := EasyStoreEventDetail{Components: componentNames(AllComponents), Version: vtag}
+	events, err := impl.queueEvents(ctx, tx, updated, current.VTag(), eventSpec{uvalibrabus.EventObjectUpdate, detail})
+	if err != nil {
+		return nil, err
+	}
+
+	// commit the changes
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	// and publish it
+	impl.deliverEvents(ctx, events)
+
+	// get the full object
+	return impl.getObject(ctx, namespace, oid, AllComponents)
+}
+
+// copy (or move) an object, the datastore does the copy so the file contents are not read
+func (impl easyStoreImpl) copyObject(ctx context.Context, namespace string, oid string, newNamespace string, newOid string, move bool) (EasyStoreObject, error) {
+
+	// preflight validation
+	if err := ObjectCopyPreflight(namespace, oid, newNamespace, newOid); err != nil {
+		logError(impl.config.Logger(), "preflight failure")
+		return nil, err
+	}
+
+	// access control, the source is read (and deleted by a move) and the target created
+	if err := impl.authorize(ctx, namespace, oid, OperationRead, AllComponents); err != nil {
+		return nil, err
+	}
+	if move == true {
+		if err := impl.authorize(ctx, namespace, oid, OperationDelete, BaseComponent); err != nil {
+			return nil, err
+		}
+	}
+	if err := impl.authorize(ctx, newNamespace, newOid, OperationCreate, AllComponents); err != nil {
+		return nil, err
+	}
+
+	// a blank identifier gets a new one
+	if len(newOid) == 0 {
+		newOid = newObjectId()
+	}
+
+	operation := "copying"
+	if move == true {
+		operation = "moving"
+	}
+	logInfo(impl.config.Logger(), fmt.Sprintf("%s ns/oid [%s/%s] to [%s/%s]", operation, namespace, oid, newNamespace, newOid))
+
+	// all changes are made within a transaction
+	tx, err := impl.store.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// the original, for the delete event
+	from, to := DataStoreKey{namespace, oid}, DataStoreKey{newNamespace, newOid}
+	orig, err := tx.GetObjectByKey(ctx, from, NOCACHE)
+	if err != nil {
+		// known error
+		if errors.Is(err, ErrNotFound) {
+			logInfo(impl.config.Logger(), fmt.Sprintf("no object found for ns/oid [%s/%s]", namespace, oid))
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	// the copy must be valid for the schema of its new namespace
+	if newNamespace != namespace && schemaFor(newNamespace) != nil {
+		fields, err := tx.GetFieldsByKey(ctx, from, NOCACHE)
+		if err != nil && errors.Is(err, ErrNotFound) == false {
+			return nil, err
+		}
+		if fields == nil {
+			fields = &EasyStoreObjectMultiFields{}
+		}
+		if err = fieldsPreflight(newNamespace, *fields); err != nil {
+			logError(impl.config.Logger(), "preflight failure")
+			return nil, err
+		}
+		blobs, err := tx.GetBlobsByKey(ctx, from, NOCACHE)
+		if err != nil && errors.Is(err, ErrNotFound) == false {
+			return nil, err
+		}
+		if err = filesPreflight(newNamespace, blobs); err != nil {
+			logError(impl.config.Logger(), "preflight failure")
+			return nil, err
+		}
+	}
+
+	err = tx.CopyObjectByKey(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	// a move removes the original, the same way it is purged from the trash
+	if move == true {
+		err = tx.TombstoneObjectByKey(ctx, from)
+		if err != nil {
+			return nil, err
+		}
+		err = tx.PurgeObjectByKey(ctx, from)
+		if err != nil {
+			return nil, err
+		}
+		err = tx.DeleteFieldsByKey(ctx, from)
+		if err != nil {
+			return nil, err
+		}
+		err = tx.DeleteBlobsByKey(ctx, from)
+		if err != nil {
+			return nil, err
+		}
+		err = tx.DeleteMetadataByKey(ctx, from)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// queue the appropriate events
+	copied, err := tx.GetObjectByKey(ctx, to, NOCACHE)
+	if err != nil {
+		return nil, err
+	}
+	detail := EasyStoreEventDetail{Components: componentNames(AllComponents), Source: keyName(from)}
+	events, err := impl.queueEvents(ctx, tx, copied, "", eventSpec{uvalibrabus.EventObjectCreate, detail})
+	if err != nil {
+		return nil, err
+	}
+	if move == true {
+		detail := EasyStoreEventDetail{Components: componentNames(AllComponents), Target: keyName(to)}
+		deleted, err := impl.queueEvents(ctx, tx, orig, "", eventSpec{uvalibrabus.EventObjectDelete, detail})
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, deleted...)
+	}
+
+	// commit the changes
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	// and publish them
+	impl.deliverEvents(ctx, events)
+
+	// get the full object
+	return impl.getObject(ctx, newNamespace, newOid, AllComponents)
+}
+
+// compare the current files with the new ones, a file is unchanged when its name, mime type and
+// contents all match. Returns the files to add, the files to update and the names of the files to delete
+func diffFiles(current []EasyStoreBlob, files []EasyStoreBlob) ([]EasyStoreBlob, []EasyStoreBlob, []string, error) {
+
+	existing := make(map[string]EasyStoreBlob, len(current))
+	for _, b := range current {
+		existing[b.Name()] = b
+	}
+
+	added := make([]EasyStoreBlob, 0)
+	updated := make([]EasyStoreBlob, 0)
+	deleted := make([]string, 0)
+	wanted := make(map[string]bool, len(files))
+	for _, b := range files {
+		wanted[b.Name()] = true
+		cur, found := existing[b.Name()]
+		if found == false {
+			added = append(added, b)
+			continue
+		}
+		if cur.MimeType() != b.MimeType() {
+			updated = append(updated, b)
+			continue
+		}
+
+		// compare the contents
+		changed, err := blobChanged(cur, b)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if changed == true {
+			updated = append(updated, b)
+		}
+	}
+
+	for _, b := range current {
+		if wanted[b.Name()] == false {
+			deleted = append(deleted, b.Name())
+		}
+	}
+	return added, updated, deleted, nil
+}
+
 //
 // end of file
 //
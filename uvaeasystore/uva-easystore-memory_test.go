@@ -0,0 +1,61 @@
+//
+//
+//
+
+package uvaeasystore
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMemoryStoreContents(t *testing.T) {
+
+	// private stores do not share their contents, named ones do
+	private1, err := NewEasyStore(DatastoreMemoryConfig{})
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	defer private1.Close()
+	private2, err := NewEasyStore(DatastoreMemoryConfig{})
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	defer private2.Close()
+	named, err := NewEasyStore(DatastoreMemoryConfig{Name: t.Name()})
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	defer named.Close()
+
+	o := NewEasyStoreObject(goodNamespace, "")
+	o.SetFields(EasyStoreObjectFields{"field1": "value1"})
+	_, err = private1.ObjectCreate(o)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	_, err = named.ObjectCreate(o)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	_, err = private2.ObjectGetByKey(goodNamespace, o.Id(), BaseComponent)
+	if errors.Is(err, ErrNotFound) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", ErrNotFound, err)
+	}
+
+	esro, err := NewEasyStoreReadonly(DatastoreMemoryConfig{Name: t.Name()})
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	defer esro.Close()
+	after, err := esro.ObjectGetByKey(goodNamespace, o.Id(), Fields)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	ensureObjectHasFields(t, after, o.Fields())
+}
+
+//
+// end of file
+//
@@ -10,14 +10,14 @@ import (
 
 // this is our easystore object implementation
 type easyStoreObjectImpl struct {
-	Namespace_ string                `json:"namespace"`          // object namespace
-	Id_        string                `json:"id"`                 // object identifier
-	Vtag_      string                `json:"vtag"`               // object version tag (opaque)
-	Created_   time.Time             `json:"created"`            // created time
-	Modified_  time.Time             `json:"modified"`           // last modified time
-	Fields_    EasyStoreObjectFields `json:"fields,omitempty"`   // object fields
-	Metadata_  EasyStoreMetadata     `json:"metadata,omitempty"` // object metadata (its an opaque blob)
-	Files_     []EasyStoreBlob       `json:"files,omitempty"`    // object files
+	Namespace_ string                     `json:"namespace"`          // object namespace
+	Id_        string                     `json:"id"`                 // object identifier
+	Vtag_      string                     `json:"vtag"`               // object version tag (opaque)
+	Created_   time.Time                  `json:"created"`            // created time
+	Modified_  time.Time                  `json:"modified"`           // last modified time
+	Fields_    EasyStoreObjectMultiFields `json:"fields,omitempty"`   // object fields
+	Metadata_  EasyStoreMetadata          `json:"metadata,omitempty"` // object metadata (its an opaque blob)
+	Files_     []EasyStoreBlob            `json:"files,omitempty"`    // object files
 }
 
 // factory for our easystore object interface
@@ -64,6 +64,10 @@ func (impl *easyStoreObjectImpl) Modified() time.Time {
 }
 
 func (impl *easyStoreObjectImpl) Fields() EasyStoreObjectFields {
+	return singleFields(impl.Fields_)
+}
+
+func (impl *easyStoreObjectImpl) MultiFields() EasyStoreObjectMultiFields {
 	return impl.Fields_
 }
 
@@ -80,7 +84,18 @@ func (impl *easyStoreObjectImpl) SetNamespace(namespace string) {
 }
 
 func (impl *easyStoreObjectImpl) SetFields(fields EasyStoreObjectFields) {
-	impl.Fields_ = fields
+	impl.Fields_ = multiFields(fields)
+}
+
+func (impl *easyStoreObjectImpl) SetMultiFields(fields EasyStoreObjectMultiFields) {
+	impl.Fields_ = normalizeFields(fields)
+}
+
+func (impl *easyStoreObjectImpl) AddFieldValue(name string, value string) {
+	if impl.Fields_ == nil {
+		impl.Fields_ = EasyStoreObjectMultiFields{}
+	}
+	impl.Fields_[name] = distinctValues(append(impl.Fields_[name], value))
 }
 
 func (impl *easyStoreObjectImpl) SetMetadata(metadata EasyStoreMetadata) {
@@ -10,6 +10,7 @@
 package uvaeasystore
 
 import (
+	"context"
 	"io"
 )
 
@@ -19,15 +20,19 @@ type easyStoreObjectSetImpl struct {
 	which   EasyStoreComponents   // which components are we requesting
 	objects []EasyStoreObject     // object list
 	store   easyStoreReadonlyImpl // we get objects when required
+	ctx     context.Context       // the context used when getting objects
+	next    string                // the continuation token for the next page
 }
 
 // factory for our easystore object set interface
-func newEasyStoreObjectSet(store easyStoreReadonlyImpl, objs []EasyStoreObject, which EasyStoreComponents) EasyStoreObjectSet {
+func newEasyStoreObjectSet(ctx context.Context, store easyStoreReadonlyImpl, objs []EasyStoreObject, which EasyStoreComponents, next string) EasyStoreObjectSet {
 	return &easyStoreObjectSetImpl{
 		current: 0,
 		which:   which,
 		objects: objs,
 		store:   store,
+		ctx:     ctx,
+		next:    next,
 	}
 }
 
@@ -35,6 +40,10 @@ func (impl *easyStoreObjectSetImpl) Count() uint {
 	return uint(len(impl.objects))
 }
 
+func (impl *easyStoreObjectSetImpl) Continuation() string {
+	return impl.next
+}
+
 func (impl *easyStoreObjectSetImpl) Next() (EasyStoreObject, error) {
 	if impl.current == uint(len(impl.objects)) {
 		return nil, io.EOF
@@ -42,7 +51,7 @@ func (impl *easyStoreObjectSetImpl) Next() (EasyStoreObject, error) {
 
 	prev := impl.current
 	impl.current++
-	return impl.store.populateObject(impl.objects[prev], impl.which)
+	return impl.store.populateObject(impl.ctx, impl.objects[prev], impl.which)
 }
 
 //
@@ -0,0 +1,248 @@
+//
+//
+//
+
+package uvaeasystore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/uvalib/librabus-sdk/uvalibrabus"
+)
+
+// a sink that remembers what it was sent, or fails
+type testSink struct {
+	events []EasyStoreEvent
+	fail   bool
+	reject string // the object whose events are always rejected
+}
+
+func (sink *testSink) publish(ev EasyStoreEvent) error {
+	if sink.fail == true {
+		return errors.New("sink is down")
+	}
+	if ev.ObjectId == sink.reject {
+		return errors.New("event rejected")
+	}
+	sink.events = append(sink.events, ev)
+	return nil
+}
+
+func TestOutboxDelivered(t *testing.T) {
+	es, store, sink := testOutboxSetup(t)
+	defer es.Close()
+
+	o, err := es.ObjectCreate(NewEasyStoreObject(goodNamespace, ""))
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	err = es.FileCreate(goodNamespace, o.Id(), newBinaryBlob("file1.bin"))
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	// the events are published in order and describe the current version
+	if len(sink.events) != 3 {
+		t.Fatalf("expected 3 events but got %d\n", len(sink.events))
+	}
+	testEqual(t, uvalibrabus.EventObjectCreate, sink.events[0].Name)
+	testEqual(t, uvalibrabus.EventObjectUpdate, sink.events[1].Name)
+	testEqual(t, uvalibrabus.EventFileCreate, sink.events[2].Name)
+	testEqual(t, o.Id(), sink.events[2].ObjectId)
+	current, err := es.ObjectGetByKey(goodNamespace, o.Id(), BaseComponent)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	pl, err := uvalibrabus.MakeStorageEvent(sink.events[2].Detail)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	testEqual(t, current.VTag(), pl.VTag)
+
+	// and nothing is left in the outbox
+	_, err = store.GetPendingEvents(context.Background(), 0, 0)
+	if errors.Is(err, ErrNotFound) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", ErrNotFound, err)
+	}
+}
+
+func TestOutboxRelay(t *testing.T) {
+	es, store, sink := testOutboxSetup(t)
+	defer es.Close()
+	relay, err := NewEasyStoreRelay(DatastoreMemoryConfig{Name: t.Name(), Sink: NewCallbackEventSink(sink.publish)})
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	defer relay.Close()
+
+	// the change is made even though the event cannot be published
+	start := time.Now().Add(-time.Second)
+	sink.fail = true
+	o, err := es.ObjectCreate(NewEasyStoreObject(goodNamespace, ""))
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	_, err = es.ObjectDelete(o, BaseComponent)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	pending, err := store.GetPendingEvents(context.Background(), 0, 0)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	if len(pending) != 2 {
+		t.Fatalf("expected 2 events but got %d\n", len(pending))
+	}
+
+	// the relay delivers them once the sink is back
+	_, err = relay.Drain(context.Background())
+	if err == nil {
+		t.Fatalf("expected error but got 'OK'\n")
+	}
+	sink.fail = false
+	count, err := relay.Drain(context.Background())
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	if count != 2 || len(sink.events) != 2 {
+		t.Fatalf("expected 2 events but got %d (%d published)\n", count, len(sink.events))
+	}
+	testEqual(t, uvalibrabus.EventObjectCreate, sink.events[0].Name)
+	testEqual(t, uvalibrabus.EventObjectDelete, sink.events[1].Name)
+
+	// and can publish them again
+	count, err = relay.Replay(context.Background(), start, time.Now().Add(time.Second))
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	if count != 2 || len(sink.events) != 4 {
+		t.Fatalf("expected 2 events but got %d (%d published)\n", count, len(sink.events))
+	}
+	_, err = relay.Replay(context.Background(), time.Now(), start)
+	if errors.Is(err, ErrBadParameter) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", ErrBadParameter, err)
+	}
+}
+
+func TestOutboxParked(t *testing.T) {
+	es, store, sink := testOutboxSetup(t)
+	defer es.Close()
+	relay, err := NewEasyStoreRelay(DatastoreMemoryConfig{Name: t.Name(), Sink: NewCallbackEventSink(sink.publish)})
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	defer relay.Close()
+
+	// the sink rejects the events of the first object but accepts the second
+	start := time.Now().Add(-time.Second)
+	o1 := NewEasyStoreObject(goodNamespace, "")
+	sink.reject = o1.Id()
+	_, err = es.ObjectCreate(o1)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	sink.fail = true
+	_, err = es.ObjectCreate(NewEasyStoreObject(goodNamespace, ""))
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	sink.fail = false
+
+	// the relay keeps trying the rejected event until it is parked, then moves on
+	for attempt := uint(2); attempt < relayMaxAttempts; attempt++ {
+		_, err = relay.Drain(context.Background())
+		if err == nil {
+			t.Fatalf("expected error but got 'OK'\n")
+		}
+	}
+	count, err := relay.Drain(context.Background())
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	if count != 1 || len(sink.events) != 1 {
+		t.Fatalf("expected 1 event but got %d (%d published)\n", count, len(sink.events))
+	}
+
+	// the parked event is no longer pending
+	expected := ErrNotFound
+	_, err = store.GetPendingEvents(context.Background(), relayMaxAttempts, 0)
+	if errors.Is(err, expected) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", expected, err)
+	}
+	pending, err := store.GetPendingEvents(context.Background(), 0, 0)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	if len(pending) != 1 || pending[0].ObjectId != o1.Id() || pending[0].Attempts != relayMaxAttempts {
+		t.Fatalf("unexpected pending events\n")
+	}
+
+	// but it can be replayed
+	sink.reject = ""
+	count, err = relay.Replay(context.Background(), start, time.Now().Add(time.Second))
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	if count != 2 || len(sink.events) != 3 {
+		t.Fatalf("expected 2 events but got %d (%d published)\n", count, len(sink.events))
+	}
+	_, err = store.GetPendingEvents(context.Background(), 0, 0)
+	if errors.Is(err, expected) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", expected, err)
+	}
+}
+
+func TestOutboxReplayPaged(t *testing.T) {
+	es, _, sink := testOutboxSetup(t)
+	defer es.Close()
+	relay, err := NewEasyStoreRelay(DatastoreMemoryConfig{Name: t.Name(), Sink: NewCallbackEventSink(sink.publish)})
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	defer relay.Close()
+
+	defer func(size uint) { outboxPageSize = size }(outboxPageSize)
+	outboxPageSize = 2
+
+	start := time.Now().Add(-time.Second)
+	for ix := 0; ix < 5; ix++ {
+		_, err = es.ObjectCreate(NewEasyStoreObject(goodNamespace, ""))
+		if err != nil {
+			t.Fatalf("expected 'OK' but got '%s'\n", err)
+		}
+	}
+
+	// every event is replayed once, in order
+	count, err := relay.Replay(context.Background(), start, time.Now().Add(time.Second))
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	if count != 5 || len(sink.events) != 10 {
+		t.Fatalf("expected 5 events but got %d (%d published)\n", count, len(sink.events))
+	}
+	for ix := 0; ix < 5; ix++ {
+		testEqual(t, sink.events[ix].Id, sink.events[ix+5].Id)
+	}
+}
+
+// an easystore that publishes to the test sink, and its datastore
+func testOutboxSetup(t *testing.T) (EasyStore, DataStore, *testSink) {
+	sink := &testSink{}
+	config := DatastoreMemoryConfig{Name: t.Name(), Sink: NewCallbackEventSink(sink.publish)}
+	es, err := NewEasyStore(config)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	store, err := NewDatastore(config)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	return es, store, sink
+}
+
+//
+// end of file
+//
@@ -0,0 +1,224 @@
+//
+// access control, a policy decides which principals can make which requests of which namespaces and
+// the requests it denies are recorded in an audit log
+//
+
+package uvaeasystore
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// EasyStoreOperation - the kinds of request an access policy distinguishes
+type EasyStoreOperation string
+
+const (
+	OperationRead   EasyStoreOperation = "read"   // get, search and list objects, versions, files and the trash
+	OperationCreate EasyStoreOperation = "create" // create objects and files, the target of a copy or move, restore from the trash
+	OperationUpdate EasyStoreOperation = "update" // update objects and files, rename files, restore versions
+	OperationDelete EasyStoreOperation = "delete" // delete objects and files, the source of a move, purge the trash
+)
+
+// the effects of a policy rule
+const (
+	PolicyAllow = "allow"
+	PolicyDeny  = "deny"
+)
+
+// PolicyAny - matches any principal, namespace or operation in a policy rule
+const PolicyAny = "*"
+
+// EasyStorePolicy - an access policy. A request is allowed when a rule allows it and no rule denies
+// it, so a request no rule matches is denied. A request for several components must be allowed for each,
+// and deleting (or purging) the whole object must be allowed for the object and each of its components
+type EasyStorePolicy struct {
+	Rules []EasyStorePolicyRule `json:"rules"`
+}
+
+// EasyStorePolicyRule - allows or denies the requests it matches. Principals and namespaces can be
+// PolicyAny or end with a * to match by prefix, a blank namespace is a search of all namespaces
+type EasyStorePolicyRule struct {
+	Effect     string               `json:"effect"`               // PolicyAllow or PolicyDeny
+	Principals []string             `json:"principals"`           // the principals it applies to
+	Namespaces []string             `json:"namespaces"`           // the namespaces it applies to
+	Operations []EasyStoreOperation `json:"operations"`           // the operations it applies to
+	Components []string             `json:"components,omitempty"` // fields, files and/or metadata (empty for the whole object)
+}
+
+// EasyStoreAuditEntry - a denied request
+type EasyStoreAuditEntry struct {
+	When       time.Time          `json:"when"`                 // when it was denied
+	Principal  string             `json:"principal"`            // who made it
+	Actor      string             `json:"actor,omitempty"`      // on whose behalf (see WithActor)
+	Namespace  string             `json:"namespace"`            // the namespace
+	Id         string             `json:"id,omitempty"`         // the object identifier (blank for searches)
+	Operation  EasyStoreOperation `json:"operation"`            // the operation
+	Components []string           `json:"components,omitempty"` // the components
+	Reason     string             `json:"reason"`               // why it was denied
+}
+
+// EasyStoreAuditLog - where denied requests are recorded
+type EasyStoreAuditLog interface {
+	Record(EasyStoreAuditEntry) error
+}
+
+// ParseEasyStorePolicy - parse an access policy (JSON) and validate its rules
+func ParseEasyStorePolicy(buf []byte) (*EasyStorePolicy, error) {
+
+	var policy EasyStorePolicy
+	err := json.Unmarshal(buf, &policy)
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w", err.Error(), ErrDeserialize)
+	}
+
+	for ix, rule := range policy.Rules {
+		if rule.Effect != PolicyAllow && rule.Effect != PolicyDeny {
+			return nil, fmt.Errorf("%q: %w", fmt.Sprintf("rule %d has a bad effect [%s]", ix, rule.Effect), ErrBadParameter)
+		}
+		if len(rule.Principals) == 0 || len(rule.Namespaces) == 0 || len(rule.Operations) == 0 {
+			return nil, fmt.Errorf("%q: %w", fmt.Sprintf("rule %d needs principals, namespaces and operations", ix), ErrBadParameter)
+		}
+		for _, op := range rule.Operations {
+			switch op {
+			case OperationRead, OperationCreate, OperationUpdate, OperationDelete, PolicyAny:
+			default:
+				return nil, fmt.Errorf("%q: %w", fmt.Sprintf("rule %d has a bad operation [%s]", ix, op), ErrBadParameter)
+			}
+		}
+		for _, c := range rule.Components {
+			switch c {
+			case "fields", "files", "metadata":
+			default:
+				return nil, fmt.Errorf("%q: %w", fmt.Sprintf("rule %d has a bad component [%s]", ix, c), ErrBadParameter)
+			}
+		}
+	}
+	return &policy, nil
+}
+
+// LoadEasyStorePolicy - load an access policy from a file
+func LoadEasyStorePolicy(filename string) (*EasyStorePolicy, error) {
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseEasyStorePolicy(buf)
+}
+
+// Authorize - check the principal can make the request, it returns ErrNotAuthorized if it cannot
+func (policy *EasyStorePolicy) Authorize(principal string, namespace string, operation EasyStoreOperation, which EasyStoreComponents) error {
+
+	// the whole object when there are no components, deleting the whole object deletes every component
+	// so the rules for each apply as well
+	components := componentNames(which)
+	if len(components) == 0 {
+		components = []string{""}
+		if operation == OperationDelete {
+			components = append(components, componentNames(AllComponents)...)
+		}
+	}
+
+	for _, c := range components {
+		allowed := false
+		for _, rule := range policy.Rules {
+			if rule.matches(principal, namespace, operation, c) == false {
+				continue
+			}
+			if rule.Effect == PolicyDeny {
+				return fmt.Errorf("%q: %w", policyReason("is denied", principal, namespace, operation, c), ErrNotAuthorized)
+			}
+			allowed = true
+		}
+		if allowed == false {
+			return fmt.Errorf("%q: %w", policyReason("is not allowed", principal, namespace, operation, c), ErrNotAuthorized)
+		}
+	}
+	return nil
+}
+
+// NewLoggerAuditLog - an audit log that writes each entry (as JSON) to the logger
+func NewLoggerAuditLog(logger *log.Logger) EasyStoreAuditLog {
+	return loggerAuditLog{log: logger}
+}
+
+//
+// private implementation methods
+//
+
+// does the rule apply to the request for the component (blank for the whole object)
+func (rule EasyStorePolicyRule) matches(principal string, namespace string, operation EasyStoreOperation, component string) bool {
+
+	if policyMatch(rule.Principals, principal) == false || policyMatch(rule.Namespaces, namespace) == false {
+		return false
+	}
+
+	opMatch := false
+	for _, op := range rule.Operations {
+		if op == operation || op == PolicyAny {
+			opMatch = true
+		}
+	}
+	if opMatch == false {
+		return false
+	}
+
+	// rules for specific components do not apply to the whole object
+	if len(rule.Components) == 0 {
+		return true
+	}
+	return contains(rule.Components, component)
+}
+
+// the access policy and audit log of the configuration, if it has them
+func configPolicy(config EasyStoreImplConfig) (*EasyStorePolicy, EasyStoreAuditLog) {
+	policyConfig, ok := config.(EasyStorePolicyConfig)
+	if ok == false {
+		return nil, nil
+	}
+	return policyConfig.Policy(), policyConfig.AuditLog()
+}
+
+// does any of the patterns match the value
+func policyMatch(patterns []string, value string) bool {
+	for _, p := range patterns {
+		if p == PolicyAny || p == value {
+			return true
+		}
+		if strings.HasSuffix(p, "*") == true && len(value) != 0 && strings.HasPrefix(value, strings.TrimSuffix(p, "*")) == true {
+			return true
+		}
+	}
+	return false
+}
+
+func policyReason(why string, principal string, namespace string, operation EasyStoreOperation, component string) string {
+	if len(component) == 0 {
+		component = "object"
+	}
+	return fmt.Sprintf("principal [%s] %s %s of %s in namespace [%s]", principal, why, operation, component, namespace)
+}
+
+// our logger audit log implementation
+type loggerAuditLog struct {
+	log *log.Logger
+}
+
+func (audit loggerAuditLog) Record(entry EasyStoreAuditEntry) error {
+	buf, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if audit.log != nil {
+		audit.log.Printf("AUDIT: %s", string(buf))
+	}
+	return nil
+}
+
+//
+// end of file
+//
@@ -0,0 +1,431 @@
+//
+//
+//
+
+package uvaeasystore
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var policyNamespace = "test-namespace-policy"
+
+var testPolicy = `{"rules": [
+  {"effect": "allow", "principals": ["app-*"], "namespaces": ["test-namespace-policy"], "operations": ["read"]},
+  {"effect": "allow", "principals": ["app-writer", "app-admin"], "namespaces": ["test-namespace-policy"], "operations": ["*"]},
+  {"effect": "allow", "principals": ["svc-loader"], "namespaces": ["test-namespace-policy"], "operations": ["create", "update", "delete"]},
+  {"effect": "deny", "principals": ["app-writer"], "namespaces": ["test-namespace-policy"], "operations": ["delete"], "components": ["files"]}
+]}`
+
+// the bearer tokens of the test principals
+var testPolicyTokens = map[string]string{"writer-token": "app-writer", "reader-token": "app-reader"}
+
+// an audit log that remembers what it is given
+type testAuditLog struct {
+	entries []EasyStoreAuditEntry
+}
+
+func (audit *testAuditLog) Record(entry EasyStoreAuditEntry) error {
+	audit.entries = append(audit.entries, entry)
+	return nil
+}
+
+func TestPolicyParse(t *testing.T) {
+	_, err := ParseEasyStorePolicy([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	expected := ErrBadParameter
+	for _, bad := range []string{
+		`{"rules": [{"effect": "maybe", "principals": ["*"], "namespaces": ["*"], "operations": ["*"]}]}`,
+		`{"rules": [{"effect": "allow", "namespaces": ["*"], "operations": ["*"]}]}`,
+		`{"rules": [{"effect": "allow", "principals": ["*"], "namespaces": ["*"], "operations": ["write"]}]}`,
+		`{"rules": [{"effect": "allow", "principals": ["*"], "namespaces": ["*"], "operations": ["*"], "components": ["blobs"]}]}`,
+	} {
+		_, err = ParseEasyStorePolicy([]byte(bad))
+		if errors.Is(err, expected) == false {
+			t.Fatalf("expected '%s' but got '%s'\n", expected, err)
+		}
+	}
+
+	expected = ErrDeserialize
+	_, err = ParseEasyStorePolicy([]byte("blablabla"))
+	if errors.Is(err, expected) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", expected, err)
+	}
+}
+
+func TestPolicyAuthorize(t *testing.T) {
+	policy, err := ParseEasyStorePolicy([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	for _, tc := range []struct {
+		principal string
+		namespace string
+		operation EasyStoreOperation
+		which     EasyStoreComponents
+		allowed   bool
+	}{
+		{"app-reader", policyNamespace, OperationRead, AllComponents, true},
+		{"app-reader", policyNamespace, OperationUpdate, Fields, false},
+		{"app-reader", "another-namespace", OperationRead, BaseComponent, false},
+		{"other", policyNamespace, OperationRead, BaseComponent, false},
+		{"", policyNamespace, OperationRead, BaseComponent, false},
+		{"app-writer", policyNamespace, OperationDelete, Fields | Metadata, true},
+		{"app-writer", policyNamespace, OperationDelete, BaseComponent, false},
+		{"app-writer", policyNamespace, OperationDelete, Files, false},
+		{"app-writer", policyNamespace, OperationDelete, AllComponents, false},
+	} {
+		err = policy.Authorize(tc.principal, tc.namespace, tc.operation, tc.which)
+		if tc.allowed == true && err != nil {
+			t.Fatalf("expected 'OK' but got '%s' for %v\n", err, tc)
+		}
+		if tc.allowed == false && errors.Is(err, ErrNotAuthorized) == false {
+			t.Fatalf("expected '%s' but got '%s' for %v\n", ErrNotAuthorized, err, tc)
+		}
+	}
+}
+
+func TestPolicyEnforced(t *testing.T) {
+	es, audit := testPolicySetup(t)
+	defer es.Close()
+
+	writer := NewEasyStorePrincipal(es, "app-writer")
+	reader := NewEasyStorePrincipal(es, "app-reader")
+
+	o := NewEasyStoreObject(policyNamespace, "")
+	o.SetFields(EasyStoreObjectFields{"title": "a title"})
+	o.SetFiles([]EasyStoreBlob{NewEasyStoreBlob("file1.txt", "text/plain", []byte("file1"))})
+	o, err := writer.ObjectCreate(o)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	// the reader can read but not change it
+	_, err = reader.ObjectGetByKey(policyNamespace, o.Id(), AllComponents)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	_, err = reader.ObjectUpdate(o, Fields)
+	if errors.Is(err, ErrNotAuthorized) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", ErrNotAuthorized, err)
+	}
+
+	// the writer cannot delete the files
+	err = writer.FileDelete(policyNamespace, o.Id(), "file1.txt")
+	if errors.Is(err, ErrNotAuthorized) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", ErrNotAuthorized, err)
+	}
+
+	// and an easystore with no principal can do nothing
+	_, err = es.ObjectGetByKey(policyNamespace, o.Id(), BaseComponent)
+	if errors.Is(err, ErrNotAuthorized) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", ErrNotAuthorized, err)
+	}
+
+	// each denial is audited
+	if len(audit.entries) != 3 {
+		t.Fatalf("expected 3 audit entries but got %d\n", len(audit.entries))
+	}
+	entry := audit.entries[0]
+	testEqual(t, "app-reader", entry.Principal)
+	testEqual(t, policyNamespace, entry.Namespace)
+	testEqual(t, o.Id(), entry.Id)
+	testEqual(t, string(OperationUpdate), string(entry.Operation))
+}
+
+func TestPolicyWriteOnly(t *testing.T) {
+	es, audit := testPolicySetup(t)
+	defer es.Close()
+
+	// a principal that can change objects but not read them
+	loader := NewEasyStorePrincipal(es, "svc-loader")
+	o := NewEasyStoreObject(policyNamespace, "")
+	o.SetFields(EasyStoreObjectFields{"title": "a title"})
+	o, err := loader.ObjectCreate(o)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	o.SetFields(EasyStoreObjectFields{"title": "another title"})
+	o, err = loader.ObjectUpdate(o, Fields)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	testEqual(t, "another title", o.Fields()["title"])
+	_, err = loader.ObjectDelete(o, BaseComponent)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	// but cannot read them
+	expected := ErrNotAuthorized
+	_, err = loader.ObjectGetByKey(policyNamespace, o.Id(), BaseComponent)
+	if errors.Is(err, expected) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", expected, err)
+	}
+
+	// and only that is audited
+	if len(audit.entries) != 1 {
+		t.Fatalf("expected 1 audit entry but got %d\n", len(audit.entries))
+	}
+	testEqual(t, string(OperationRead), string(audit.entries[0].Operation))
+}
+
+func TestPolicyDeleteObjectFiles(t *testing.T) {
+	es, audit := testPolicySetup(t)
+	defer es.Close()
+
+	writer := NewEasyStorePrincipal(es, "app-writer")
+	admin := NewEasyStorePrincipal(es, "app-admin")
+
+	o := NewEasyStoreObject(policyNamespace, "")
+	o.SetFiles([]EasyStoreBlob{NewEasyStoreBlob("file1.txt", "text/plain", []byte("file1"))})
+	o, err := writer.ObjectCreate(o)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	// the writer cannot delete the files so it cannot delete the object
+	expected := ErrNotAuthorized
+	_, err = writer.ObjectDelete(o, BaseComponent)
+	if errors.Is(err, expected) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", expected, err)
+	}
+
+	// or purge it from the trash
+	_, err = admin.ObjectDelete(o, BaseComponent)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	err = writer.ObjectPurge(policyNamespace, o.Id())
+	if errors.Is(err, expected) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", expected, err)
+	}
+	if len(audit.entries) != 2 {
+		t.Fatalf("expected 2 audit entries but got %d\n", len(audit.entries))
+	}
+
+	// so the files are still there
+	_, err = admin.ObjectRestore(policyNamespace, o.Id())
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	_, err = admin.FileGetByKey(policyNamespace, o.Id(), "file1.txt")
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+}
+
+func TestPolicyAllNamespaces(t *testing.T) {
+	policy, err := ParseEasyStorePolicy([]byte(`{"rules": [
+  {"effect": "allow", "principals": ["*"], "namespaces": ["*"], "operations": ["*"]},
+  {"effect": "deny", "principals": ["app-reader"], "namespaces": ["test-namespace-secret"], "operations": ["read", "delete"]}
+]}`))
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	es, err := NewEasyStore(DatastoreMemoryConfig{Name: t.Name(), Access: policy, Audit: &testAuditLog{}})
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	defer es.Close()
+	reader := NewEasyStorePrincipal(es, "app-reader")
+
+	// the same object in an open namespace and a secret one
+	search := NewEasyStoreObject(policyNamespace, "").Id()
+	for _, ns := range []string{policyNamespace, "test-namespace-secret"} {
+		o := NewEasyStoreObject(ns, "")
+		o.SetFields(EasyStoreObjectFields{"search": search})
+		if _, err = es.ObjectCreate(o); err != nil {
+			t.Fatalf("expected 'OK' but got '%s'\n", err)
+		}
+		if _, err = es.ObjectDelete(o, BaseComponent); err != nil {
+			t.Fatalf("expected 'OK' but got '%s'\n", err)
+		}
+		o = NewEasyStoreObject(ns, "")
+		o.SetFields(EasyStoreObjectFields{"search": search})
+		if _, err = es.ObjectCreate(o); err != nil {
+			t.Fatalf("expected 'OK' but got '%s'\n", err)
+		}
+	}
+
+	// searching every namespace leaves out the secret one
+	set, err := reader.ObjectGetByQuery("", QueryEquals("search", search), BaseComponent, EasyStorePage{})
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	if set.Count() != 1 {
+		t.Fatalf("expected 1 object but got %d\n", set.Count())
+	}
+	o, err := set.Next()
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	testEqual(t, policyNamespace, o.Namespace())
+
+	// as does the trash of every namespace
+	tombstones, err := reader.ObjectTrash("")
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	for _, ts := range tombstones {
+		if ts.Namespace == "test-namespace-secret" {
+			t.Fatalf("unexpected deleted object in '%s'\n", ts.Namespace)
+		}
+	}
+
+	// and purging it
+	_, err = reader.ObjectPurgeTrash("", time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	tombstones, err = es.ObjectTrash("test-namespace-secret")
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	if len(tombstones) != 1 {
+		t.Fatalf("expected 1 deleted object but got %d\n", len(tombstones))
+	}
+}
+
+func TestPolicyAllNamespacesPaging(t *testing.T) {
+	policy, err := ParseEasyStorePolicy([]byte(`{"rules": [
+  {"effect": "allow", "principals": ["*"], "namespaces": ["*"], "operations": ["*"]},
+  {"effect": "deny", "principals": ["app-reader"], "namespaces": ["test-namespace-secret"], "operations": ["read"]}
+]}`))
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	es, err := NewEasyStore(DatastoreMemoryConfig{Name: t.Name(), Access: policy, Audit: &testAuditLog{}})
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	defer es.Close()
+	reader := NewEasyStorePrincipal(es, "app-reader")
+
+	// the first objects created are the ones left out
+	search := NewEasyStoreObject(policyNamespace, "").Id()
+	for _, ns := range []string{"test-namespace-secret", "test-namespace-secret", "test-namespace-secret", policyNamespace, policyNamespace, policyNamespace} {
+		o := NewEasyStoreObject(ns, "")
+		o.SetFields(EasyStoreObjectFields{"search": search})
+		if _, err = es.ObjectCreate(o); err != nil {
+			t.Fatalf("expected 'OK' but got '%s'\n", err)
+		}
+	}
+
+	// every page is full until the last one
+	counts := make([]uint, 0)
+	page := EasyStorePage{Limit: 2, Order: OrderByCreated}
+	for {
+		set, err := reader.ObjectGetByQuery("", QueryEquals("search", search), BaseComponent, page)
+		if err != nil {
+			t.Fatalf("expected 'OK' but got '%s'\n", err)
+		}
+		counts = append(counts, set.Count())
+		if len(set.Continuation()) == 0 {
+			break
+		}
+		page.Token = set.Continuation()
+	}
+	testEqual(t, "[2 1]", fmt.Sprintf("%v", counts))
+}
+
+func TestProxyNotAuthorized(t *testing.T) {
+	es, audit := testPolicySetup(t)
+	defer es.Close()
+	svc := httptest.NewServer(NewEasyStoreService(es, nil, NewEasyStoreTokenAuthenticator(testPolicyTokens)))
+	defer svc.Close()
+	writer := testPolicyProxy(t, svc.URL, "writer-token")
+	reader := testPolicyProxy(t, svc.URL, "reader-token")
+
+	// the principal the credentials identify goes through the service to the policy
+	o := NewEasyStoreObject(policyNamespace, "")
+	_, err := writer.ObjectCreate(o)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	_, err = reader.ObjectDelete(o, BaseComponent)
+	if errors.Is(err, ErrNotAuthorized) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", ErrNotAuthorized, err)
+	}
+	if len(audit.entries) != 1 {
+		t.Fatalf("expected 1 audit entry but got %d\n", len(audit.entries))
+	}
+	testEqual(t, "app-reader", audit.entries[0].Principal)
+}
+
+func TestProxyForgedPrincipal(t *testing.T) {
+	es, audit := testPolicySetup(t)
+	defer es.Close()
+	svc := httptest.NewServer(NewEasyStoreService(es, nil, NewEasyStoreTokenAuthenticator(testPolicyTokens)))
+	defer svc.Close()
+	expected := ErrNotAuthorized
+
+	// a principal that does not match the credentials is rejected
+	reader := testPolicyProxy(t, svc.URL, "reader-token")
+	_, err := NewEasyStorePrincipal(reader, "app-writer").ObjectCreate(NewEasyStoreObject(policyNamespace, ""))
+	if errors.Is(err, expected) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", expected, err)
+	}
+
+	// as are requests with no credentials or unknown ones
+	for _, token := range []string{"", "forged-token"} {
+		_, err = NewEasyStorePrincipal(testPolicyProxy(t, svc.URL, token), "app-writer").ObjectCreate(NewEasyStoreObject(policyNamespace, ""))
+		if errors.Is(err, expected) == false {
+			t.Fatalf("expected '%s' but got '%s'\n", expected, err)
+		}
+	}
+
+	// none of them reach the policy
+	if len(audit.entries) != 0 {
+		t.Fatalf("expected 0 audit entries but got %d\n", len(audit.entries))
+	}
+
+	// without authenticators the claimed principal is ignored, the request is made without one
+	open := httptest.NewServer(NewEasyStoreService(es, nil))
+	defer open.Close()
+	_, err = NewEasyStorePrincipal(testPolicyProxy(t, open.URL, ""), "app-writer").ObjectCreate(NewEasyStoreObject(policyNamespace, ""))
+	if errors.Is(err, expected) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", expected, err)
+	}
+	if len(audit.entries) != 1 {
+		t.Fatalf("expected 1 audit entry but got %d\n", len(audit.entries))
+	}
+	testEqual(t, "", audit.entries[0].Principal)
+}
+
+// a proxy to the service using the bearer token
+func testPolicyProxy(t *testing.T, endpoint string, token string) EasyStore {
+	proxy, err := NewEasyStoreProxy(ProxyConfigImpl{ServiceEndpoint: endpoint, ServiceToken: token})
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	t.Cleanup(func() { proxy.Close() })
+	return proxy
+}
+
+// an easystore with the test policy, and its audit log
+func testPolicySetup(t *testing.T) (EasyStore, *testAuditLog) {
+	policy, err := ParseEasyStorePolicy([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	audit := &testAuditLog{}
+	config := DatastoreMemoryConfig{Name: t.Name(), Access: policy, Audit: audit}
+	es, err := NewEasyStore(config)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	return es, audit
+}
+
+//
+// end of file
+//
@@ -4,6 +4,11 @@
 
 package uvaeasystore
 
+import (
+	"fmt"
+	"time"
+)
+
 func GetByKeyPreflight(namespace string, id string, which EasyStoreComponents) error {
 
 	// validate the namespace
@@ -76,6 +81,81 @@ func GetByFieldsPreflight(namespace string, fields EasyStoreObjectFields, which
 	return nil
 }
 
+func GetByFieldsPagePreflight(namespace string, fields EasyStoreObjectFields, which EasyStoreComponents, page EasyStorePage) error {
+
+	// validate the query
+	if err := GetByFieldsPreflight(namespace, fields, which); err != nil {
+		return err
+	}
+
+	// validate the order, the token is opaque and validated by the datastore
+	if page.Order > OrderByModified {
+		return ErrBadParameter
+	}
+
+	// preflight good
+	return nil
+}
+
+func GetByQueryPreflight(namespace string, query EasyStoreQuery, which EasyStoreComponents, page EasyStorePage) error {
+
+	// namespace can be blank for this query
+
+	// validate the query
+	if err := queryPreflight(query); err != nil {
+		return err
+	}
+
+	// validate the component request
+	if which > AllComponents {
+		return ErrBadParameter
+	}
+
+	// validate the order, the token is opaque and validated by the datastore
+	if page.Order > OrderByModified {
+		return ErrBadParameter
+	}
+
+	// preflight good
+	return nil
+}
+
+func queryPreflight(query EasyStoreQuery) error {
+
+	switch query.Op {
+	case QueryOpAnd:
+		// no terms is valid, it matches everything
+
+	case QueryOpOr:
+		if len(query.Terms) == 0 {
+			return ErrBadParameter
+		}
+
+	case QueryOpNot:
+		if len(query.Terms) != 1 {
+			return ErrBadParameter
+		}
+
+	case QueryOpEquals, QueryOpPrefix, QueryOpWildcard, QueryOpExists,
+		QueryOpLess, QueryOpLessEqual, QueryOpGreater, QueryOpGreaterEqual:
+		if len(query.Field) == 0 || len(query.Terms) != 0 {
+			return ErrBadParameter
+		}
+		return nil
+
+	default:
+		return ErrBadParameter
+	}
+
+	// validate each term
+	for _, t := range query.Terms {
+		if err := queryPreflight(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func ObjectCreatePreflight(obj EasyStoreObject) error {
 
 	// validate the object
@@ -90,6 +170,17 @@ func ObjectCreatePreflight(obj EasyStoreObject) error {
 	if len(obj.Id()) == 0 {
 		return ErrBadParameter
 	}
+	if err := keyPreflight(obj.Namespace(), obj.Id()); err != nil {
+		return err
+	}
+
+	// validate the fields and files against the namespace schema
+	if err := fieldsPreflight(obj.Namespace(), obj.MultiFields()); err != nil {
+		return err
+	}
+	if err := filesPreflight(obj.Namespace(), obj.Files()); err != nil {
+		return err
+	}
 
 	// preflight good
 	return nil
@@ -120,6 +211,18 @@ func ObjectUpdatePreflight(obj EasyStoreObject, which EasyStoreComponents) error
 		return ErrBadParameter
 	}
 
+	// validate the updated fields and files against the namespace schema
+	if which&Fields == Fields {
+		if err := fieldsPreflight(obj.Namespace(), obj.MultiFields()); err != nil {
+			return err
+		}
+	}
+	if which&Files == Files {
+		if err := filesPreflight(obj.Namespace(), obj.Files()); err != nil {
+			return err
+		}
+	}
+
 	// preflight good
 	return nil
 }
@@ -149,6 +252,13 @@ func ObjectDeletePreflight(obj EasyStoreObject, which EasyStoreComponents) error
 		return ErrBadParameter
 	}
 
+	// removing the fields must leave the object valid for the namespace schema
+	if which&Fields == Fields && which != AllComponents {
+		if err := fieldsDeletePreflight(obj.Namespace()); err != nil {
+			return err
+		}
+	}
+
 	// preflight good
 	return nil
 }
@@ -170,11 +280,103 @@ func FileCreatePreflight(namespace string, oid string, file EasyStoreBlob) error
 	if len(file.MimeType()) == 0 {
 		return ErrBadParameter
 	}
-	pl, err := file.Payload()
-	if err != nil {
+	if fileHasPayload(file) == false {
+		return ErrBadParameter
+	}
+
+	// validate the file against the namespace schema
+	if err := filesPreflight(namespace, []EasyStoreBlob{file}); err != nil {
+		return err
+	}
+
+	// preflight good
+	return nil
+}
+
+func VersionPreflight(namespace string, oid string, vtag string) error {
+
+	// validate the object namespace/id
+	if len(namespace) == 0 {
+		return ErrBadParameter
+	}
+	if len(oid) == 0 {
 		return ErrBadParameter
 	}
-	if len(pl) == 0 {
+
+	// validate the version
+	if len(vtag) == 0 {
+		return ErrBadParameter
+	}
+
+	// preflight good
+	return nil
+}
+
+func TrashPreflight(namespace string, oid string) error {
+
+	// validate the object namespace/id
+	if len(namespace) == 0 {
+		return ErrBadParameter
+	}
+	if len(oid) == 0 {
+		return ErrBadParameter
+	}
+
+	// preflight good
+	return nil
+}
+
+func PurgeTrashPreflight(before time.Time) error {
+
+	// validate the cutoff time
+	if before.IsZero() == true {
+		return ErrBadParameter
+	}
+
+	// preflight good
+	return nil
+}
+
+func ObjectCopyPreflight(namespace string, oid string, newNamespace string, newOid string) error {
+
+	// validate the object namespace/id
+	if len(namespace) == 0 {
+		return ErrBadParameter
+	}
+	if len(oid) == 0 {
+		return ErrBadParameter
+	}
+
+	// validate the new namespace, a blank identifier gets a new one
+	if len(newNamespace) == 0 {
+		return ErrBadParameter
+	}
+
+	if err := keyPreflight(newNamespace, newOid); err != nil {
+		return err
+	}
+
+	// cannot copy onto itself
+	if newNamespace == namespace && newOid == oid {
+		return ErrBadParameter
+	}
+
+	// preflight good
+	return nil
+}
+
+func FileGetByKeyPreflight(namespace string, oid string, name string) error {
+
+	// validate the object namespace/id
+	if len(namespace) == 0 {
+		return ErrBadParameter
+	}
+	if len(oid) == 0 {
+		return ErrBadParameter
+	}
+
+	// validate the filename
+	if len(name) == 0 {
 		return ErrBadParameter
 	}
 
@@ -218,6 +420,9 @@ func FileRenamePreflight(namespace string, oid string, name string, newName stri
 	if len(newName) == 0 {
 		return ErrBadParameter
 	}
+	if len(newName) > MaxFileNameLength {
+		return fmt.Errorf("%q: %w", fmt.Sprintf("file name [%s] is longer than %d", newName, MaxFileNameLength), ErrBadParameter)
+	}
 
 	// preflight good
 	return nil
@@ -240,12 +445,13 @@ func FileUpdatePreflight(namespace string, oid string, file EasyStoreBlob) error
 	if len(file.MimeType()) == 0 {
 		return ErrBadParameter
 	}
-	pl, err := file.Payload()
-	if err != nil {
+	if fileHasPayload(file) == false {
 		return ErrBadParameter
 	}
-	if len(pl) == 0 {
-		return ErrBadParameter
+
+	// validate the file against the namespace schema
+	if err := filesPreflight(namespace, []EasyStoreBlob{file}); err != nil {
+		return err
 	}
 
 	// preflight good
@@ -292,6 +498,22 @@ func RenamePreflight(obj EasyStoreObject, which EasyStoreComponents, curName str
 	return nil
 }
 
+// checks the file has a payload without consuming a streamed one
+func fileHasPayload(file EasyStoreBlob) bool {
+	switch impl := file.(type) {
+	case *easyStoreBlobImpl:
+		if impl.stream != nil {
+			return true
+		}
+	case easyStoreBlobImpl:
+		if impl.stream != nil {
+			return true
+		}
+	}
+	pl, err := file.Payload()
+	return err == nil && len(pl) != 0
+}
+
 //
 // end of file
 //
@@ -0,0 +1,222 @@
+//
+// an easystore bound to a principal, so applications sharing a store can each be given their own
+//
+
+package uvaeasystore
+
+import (
+	"context"
+	"time"
+)
+
+// this is our principal implementation
+type easyStorePrincipalImpl struct {
+	store     EasyStore // the easystore the requests are made of
+	principal string    // who makes them
+}
+
+// factory for our principal implementation
+func newEasyStorePrincipal(store EasyStore, principal string) EasyStore {
+	return easyStorePrincipalImpl{store: store, principal: principal}
+}
+
+// the context for a request, with our principal in place of any other
+func (impl easyStorePrincipalImpl) with(ctx context.Context) context.Context {
+	return WithPrincipal(ctx, impl.principal)
+}
+
+func (impl easyStorePrincipalImpl) ObjectGetByKey(namespace string, id string, which EasyStoreComponents) (EasyStoreObject, error) {
+	return impl.ObjectGetByKeyCtx(context.Background(), namespace, id, which)
+}
+
+func (impl easyStorePrincipalImpl) ObjectGetByKeys(namespace string, ids []string, which EasyStoreComponents) (EasyStoreObjectSet, error) {
+	return impl.ObjectGetByKeysCtx(context.Background(), namespace, ids, which)
+}
+
+func (impl easyStorePrincipalImpl) ObjectGetByFields(namespace string, fields EasyStoreObjectFields, which EasyStoreComponents) (EasyStoreObjectSet, error) {
+	return impl.ObjectGetByFieldsCtx(context.Background(), namespace, fields, which)
+}
+
+func (impl easyStorePrincipalImpl) ObjectGetByFieldsPage(namespace string, fields EasyStoreObjectFields, which EasyStoreComponents, page EasyStorePage) (EasyStoreObjectSet, error) {
+	return impl.ObjectGetByFieldsPageCtx(context.Background(), namespace, fields, which, page)
+}
+
+func (impl easyStorePrincipalImpl) ObjectGetByQuery(namespace string, query EasyStoreQuery, which EasyStoreComponents, page EasyStorePage) (EasyStoreObjectSet, error) {
+	return impl.ObjectGetByQueryCtx(context.Background(), namespace, query, which, page)
+}
+
+func (impl easyStorePrincipalImpl) ObjectVersions(namespace string, oid string) ([]EasyStoreVersion, error) {
+	return impl.ObjectVersionsCtx(context.Background(), namespace, oid)
+}
+
+func (impl easyStorePrincipalImpl) ObjectGetByVersion(namespace string, oid string, vtag string, which EasyStoreComponents) (EasyStoreObject, error) {
+	return impl.ObjectGetByVersionCtx(context.Background(), namespace, oid, vtag, which)
+}
+
+func (impl easyStorePrincipalImpl) ObjectTrash(namespace string) ([]EasyStoreTombstone, error) {
+	return impl.ObjectTrashCtx(context.Background(), namespace)
+}
+
+func (impl easyStorePrincipalImpl) FileGetByKey(namespace string, oid string, name string) (EasyStoreBlob, error) {
+	return impl.FileGetByKeyCtx(context.Background(), namespace, oid, name)
+}
+
+func (impl easyStorePrincipalImpl) Close() error {
+	return impl.store.Close()
+}
+
+func (impl easyStorePrincipalImpl) Check() error {
+	return impl.CheckCtx(context.Background())
+}
+
+func (impl easyStorePrincipalImpl) ObjectCreate(obj EasyStoreObject) (EasyStoreObject, error) {
+	return impl.ObjectCreateCtx(context.Background(), obj)
+}
+
+func (impl easyStorePrincipalImpl) ObjectUpdate(obj EasyStoreObject, which EasyStoreComponents) (EasyStoreObject, error) {
+	return impl.ObjectUpdateCtx(context.Background(), obj, which)
+}
+
+func (impl easyStorePrincipalImpl) ObjectDelete(obj EasyStoreObject, which EasyStoreComponents) (EasyStoreObject, error) {
+	return impl.ObjectDeleteCtx(context.Background(), obj, which)
+}
+
+func (impl easyStorePrincipalImpl) ObjectRestore(namespace string, oid string) (EasyStoreObject, error) {
+	return impl.ObjectRestoreCtx(context.Background(), namespace, oid)
+}
+
+func (impl easyStorePrincipalImpl) ObjectPurge(namespace string, oid string) error {
+	return impl.ObjectPurgeCtx(context.Background(), namespace, oid)
+}
+
+func (impl easyStorePrincipalImpl) ObjectPurgeTrash(namespace string, before time.Time) (uint, error) {
+	return impl.ObjectPurgeTrashCtx(context.Background(), namespace, before)
+}
+
+func (impl easyStorePrincipalImpl) ObjectCopy(namespace string, oid string, newNamespace string, newOid string) (EasyStoreObject, error) {
+	return impl.ObjectCopyCtx(context.Background(), namespace, oid, newNamespace, newOid)
+}
+
+func (impl easyStorePrincipalImpl) ObjectMove(namespace string, oid string, newNamespace string, newOid string) (EasyStoreObject, error) {
+	return impl.ObjectMoveCtx(context.Background(), namespace, oid, newNamespace, newOid)
+}
+
+func (impl easyStorePrincipalImpl) ObjectVersionRestore(namespace string, oid string, vtag string) (EasyStoreObject, error) {
+	return impl.ObjectVersionRestoreCtx(context.Background(), namespace, oid, vtag)
+}
+
+func (impl easyStorePrincipalImpl) FileCreate(namespace string, oid string, file EasyStoreBlob) error {
+	return impl.FileCreateCtx(context.Background(), namespace, oid, file)
+}
+
+func (impl easyStorePrincipalImpl) FileDelete(namespace string, oid string, name string) error {
+	return impl.FileDeleteCtx(context.Background(), namespace, oid, name)
+}
+
+func (impl easyStorePrincipalImpl) FileRename(namespace string, oid string, name string, newName string) error {
+	return impl.FileRenameCtx(context.Background(), namespace, oid, name, newName)
+}
+
+func (impl easyStorePrincipalImpl) FileUpdate(namespace string, oid string, file EasyStoreBlob) error {
+	return impl.FileUpdateCtx(context.Background(), namespace, oid, file)
+}
+
+//
+// context aware variants, the principal is added to the context
+//
+
+func (impl easyStorePrincipalImpl) ObjectGetByKeyCtx(ctx context.Context, namespace string, id string, which EasyStoreComponents) (EasyStoreObject, error) {
+	return impl.store.ObjectGetByKeyCtx(impl.with(ctx), namespace, id, which)
+}
+
+func (impl easyStorePrincipalImpl) ObjectGetByKeysCtx(ctx context.Context, namespace string, ids []string, which EasyStoreComponents) (EasyStoreObjectSet, error) {
+	return impl.store.ObjectGetByKeysCtx(impl.with(ctx), namespace, ids, which)
+}
+
+func (impl easyStorePrincipalImpl) ObjectGetByFieldsCtx(ctx context.Context, namespace string, fields EasyStoreObjectFields, which EasyStoreComponents) (EasyStoreObjectSet, error) {
+	return impl.store.ObjectGetByFieldsCtx(impl.with(ctx), namespace, fields, which)
+}
+
+func (impl easyStorePrincipalImpl) ObjectGetByFieldsPageCtx(ctx context.Context, namespace string, fields EasyStoreObjectFields, which EasyStoreComponents, page EasyStorePage) (EasyStoreObjectSet, error) {
+	return impl.store.ObjectGetByFieldsPageCtx(impl.with(ctx), namespace, fields, which, page)
+}
+
+func (impl easyStorePrincipalImpl) ObjectGetByQueryCtx(ctx context.Context, namespace string, query EasyStoreQuery, which EasyStoreComponents, page EasyStorePage) (EasyStoreObjectSet, error) {
+	return impl.store.ObjectGetByQueryCtx(impl.with(ctx), namespace, query, which, page)
+}
+
+func (impl easyStorePrincipalImpl) ObjectVersionsCtx(ctx context.Context, namespace string, oid string) ([]EasyStoreVersion, error) {
+	return impl.store.ObjectVersionsCtx(impl.with(ctx), namespace, oid)
+}
+
+func (impl easyStorePrincipalImpl) ObjectGetByVersionCtx(ctx context.Context, namespace string, oid string, vtag string, which EasyStoreComponents) (EasyStoreObject, error) {
+	return impl.store.ObjectGetByVersionCtx(impl.with(ctx), namespace, oid, vtag, which)
+}
+
+func (impl easyStorePrincipalImpl) ObjectTrashCtx(ctx context.Context, namespace string) ([]EasyStoreTombstone, error) {
+	return impl.store.ObjectTrashCtx(impl.with(ctx), namespace)
+}
+
+func (impl easyStorePrincipalImpl) FileGetByKeyCtx(ctx context.Context, namespace string, oid string, name string) (EasyStoreBlob, error) {
+	return impl.store.FileGetByKeyCtx(impl.with(ctx), namespace, oid, name)
+}
+
+func (impl easyStorePrincipalImpl) CheckCtx(ctx context.Context) error {
+	return impl.store.CheckCtx(impl.with(ctx))
+}
+
+func (impl easyStorePrincipalImpl) ObjectCreateCtx(ctx context.Context, obj EasyStoreObject) (EasyStoreObject, error) {
+	return impl.store.ObjectCreateCtx(impl.with(ctx), obj)
+}
+
+func (impl easyStorePrincipalImpl) ObjectUpdateCtx(ctx context.Context, obj EasyStoreObject, which EasyStoreComponents) (EasyStoreObject, error) {
+	return impl.store.ObjectUpdateCtx(impl.with(ctx), obj, which)
+}
+
+func (impl easyStorePrincipalImpl) ObjectDeleteCtx(ctx context.Context, obj EasyStoreObject, which EasyStoreComponents) (EasyStoreObject, error) {
+	return impl.store.ObjectDeleteCtx(impl.with(ctx), obj, which)
+}
+
+func (impl easyStorePrincipalImpl) ObjectRestoreCtx(ctx context.Context, namespace string, oid string) (EasyStoreObject, error) {
+	return impl.store.ObjectRestoreCtx(impl.with(ctx), namespace, oid)
+}
+
+func (impl easyStorePrincipalImpl) ObjectPurgeCtx(ctx context.Context, namespace string, oid string) error {
+	return impl.store.ObjectPurgeCtx(impl.with(ctx), namespace, oid)
+}
+
+func (impl easyStorePrincipalImpl) ObjectPurgeTrashCtx(ctx context.Context, namespace string, before time.Time) (uint, error) {
+	return impl.store.ObjectPurgeTrashCtx(impl.with(ctx), namespace, before)
+}
+
+func (impl easyStorePrincipalImpl) ObjectCopyCtx(ctx context.Context, namespace string, oid string, newNamespace string, newOid string) (EasyStoreObject, error) {
+	return impl.store.ObjectCopyCtx(impl.with(ctx), namespace, oid, newNamespace, newOid)
+}
+
+func (impl easyStorePrincipalImpl) ObjectMoveCtx(ctx context.Context, namespace string, oid string, newNamespace string, newOid string) (EasyStoreObject, error) {
+	return impl.store.ObjectMoveCtx(impl.with(ctx), namespace, oid, newNamespace, newOid)
+}
+
+func (impl easyStorePrincipalImpl) ObjectVersionRestoreCtx(ctx context.Context, namespace string, oid string, vtag string) (EasyStoreObject, error) {
+	return impl.store.ObjectVersionRestoreCtx(impl.with(ctx), namespace, oid, vtag)
+}
+
+func (impl easyStorePrincipalImpl) FileCreateCtx(ctx context.Context, namespace string, oid string, file EasyStoreBlob) error {
+	return impl.store.FileCreateCtx(impl.with(ctx), namespace, oid, file)
+}
+
+func (impl easyStorePrincipalImpl) FileDeleteCtx(ctx context.Context, namespace string, oid string, name string) error {
+	return impl.store.FileDeleteCtx(impl.with(ctx), namespace, oid, name)
+}
+
+func (impl easyStorePrincipalImpl) FileRenameCtx(ctx context.Context, namespace string, oid string, name string, newName string) error {
+	return impl.store.FileRenameCtx(impl.with(ctx), namespace, oid, name, newName)
+}
+
+func (impl easyStorePrincipalImpl) FileUpdateCtx(ctx context.Context, namespace string, oid string, file EasyStoreBlob) error {
+	return impl.store.FileUpdateCtx(impl.with(ctx), namespace, oid, file)
+}
+
+//
+// end of file
+//
@@ -5,19 +5,32 @@
 package uvaeasystore
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
+	"mime/multipart"
 	"net/http"
+	"net/textproto"
+	neturl "net/url"
 	"strings"
+	"time"
 )
 
 // ProxyConfigImpl -- this is our proxy configuration implementation
 type ProxyConfigImpl struct {
-	ServiceEndpoint string      // service endpoint
-	ServiceTimeout  int         // service call timeout
-	Log             *log.Logger // the logger
+	ServiceEndpoint    string               // service endpoint
+	ServiceTimeout     int                  // service call timeout
+	ServiceToken       string               // static bearer token (optional)
+	ServiceTokenSource EasyStoreTokenSource // bearer token source, refreshed when the token is rejected (optional)
+	ServiceUser        string               // basic auth user (optional)
+	ServicePassword    string               // basic auth password (optional)
+	ClientCertFile     string               // client certificate file for mutual TLS (optional)
+	ClientKeyFile      string               // client key file for mutual TLS (optional)
+	CACertFile         string               // CA bundle used to verify the service (optional)
+	Log                *log.Logger          // the logger
 }
 
 func (impl ProxyConfigImpl) Logger() *log.Logger {
@@ -44,6 +57,48 @@ func (impl ProxyConfigImpl) SetTimeout(timeout int) {
 	impl.ServiceTimeout = timeout
 }
 
+func (impl ProxyConfigImpl) Token() string {
+	return impl.ServiceToken
+}
+
+func (impl ProxyConfigImpl) SetToken(token string) {
+	impl.ServiceToken = token
+}
+
+func (impl ProxyConfigImpl) TokenSource() EasyStoreTokenSource {
+	return impl.ServiceTokenSource
+}
+
+func (impl ProxyConfigImpl) SetTokenSource(source EasyStoreTokenSource) {
+	impl.ServiceTokenSource = source
+}
+
+func (impl ProxyConfigImpl) BasicAuth() (string, string) {
+	return impl.ServiceUser, impl.ServicePassword
+}
+
+func (impl ProxyConfigImpl) SetBasicAuth(user string, password string) {
+	impl.ServiceUser = user
+	impl.ServicePassword = password
+}
+
+func (impl ProxyConfigImpl) ClientCertificate() (string, string) {
+	return impl.ClientCertFile, impl.ClientKeyFile
+}
+
+func (impl ProxyConfigImpl) SetClientCertificate(certFile string, keyFile string) {
+	impl.ClientCertFile = certFile
+	impl.ClientKeyFile = keyFile
+}
+
+func (impl ProxyConfigImpl) CACertificate() string {
+	return impl.CACertFile
+}
+
+func (impl ProxyConfigImpl) SetCACertificate(caFile string) {
+	impl.CACertFile = caFile
+}
+
 // these are our proxy implementations
 type easyStoreProxyImpl struct {
 	easyStoreProxyReadonlyImpl
@@ -60,12 +115,18 @@ type easyStoreProxyObjectSetImpl struct {
 	which   EasyStoreComponents        // which components are we requesting
 	objects []easyStoreObjectImpl      // object list
 	proxy   easyStoreProxyReadonlyImpl // ourself so we can get the next item
+	ctx     context.Context            // the context used when getting the next item
+	next    string                     // the continuation token for the next page
 }
 
 func (impl *easyStoreProxyObjectSetImpl) Count() uint {
 	return uint(len(impl.objects))
 }
 
+func (impl *easyStoreProxyObjectSetImpl) Continuation() string {
+	return impl.next
+}
+
 func (impl *easyStoreProxyObjectSetImpl) Next() (EasyStoreObject, error) {
 	if impl.current == len(impl.objects) {
 		return nil, io.EOF
@@ -76,7 +137,7 @@ func (impl *easyStoreProxyObjectSetImpl) Next() (EasyStoreObject, error) {
 
 	// do we need to get any more bits?
 	if impl.which > BaseComponent {
-		return impl.proxy.ObjectGetByKey(obj.Namespace(), obj.Id(), impl.which)
+		return impl.proxy.ObjectGetByKeyCtx(impl.ctx, obj.Namespace(), obj.Id(), impl.which)
 	}
 
 	return &obj, nil
@@ -85,17 +146,77 @@ func (impl *easyStoreProxyObjectSetImpl) Next() (EasyStoreObject, error) {
 // factory for our easystore interface
 func newEasyStoreProxy(config EasyStoreProxyConfig) (EasyStore, error) {
 	logInfo(config.Logger(), fmt.Sprintf("new easystore proxy"))
-	i := easyStoreProxyImpl{easyStoreProxyReadonlyImpl{config: config, HTTPClient: newHTTPClient(config.Timeout())}}
+	client, err := newProxyHTTPClient(config)
+	if err != nil {
+		return nil, err
+	}
+	i := easyStoreProxyImpl{easyStoreProxyReadonlyImpl{config: config, HTTPClient: client}}
 	return i, i.Check()
 }
 
 func newEasyStoreProxyReadonly(config EasyStoreProxyConfig) (EasyStoreReadonly, error) {
 	logInfo(config.Logger(), fmt.Sprintf("new easystore readonly proxy"))
-	i := easyStoreProxyReadonlyImpl{config: config, HTTPClient: newHTTPClient(config.Timeout())}
+	client, err := newProxyHTTPClient(config)
+	if err != nil {
+		return nil, err
+	}
+	i := easyStoreProxyReadonlyImpl{config: config, HTTPClient: client}
 	return i, i.Check()
 }
 
 func (impl easyStoreProxyImpl) ObjectCreate(obj EasyStoreObject) (EasyStoreObject, error) {
+	return impl.ObjectCreateCtx(context.Background(), obj)
+}
+
+func (impl easyStoreProxyImpl) ObjectUpdate(obj EasyStoreObject, which EasyStoreComponents) (EasyStoreObject, error) {
+	return impl.ObjectUpdateCtx(context.Background(), obj, which)
+}
+
+func (impl easyStoreProxyImpl) ObjectDelete(obj EasyStoreObject, which EasyStoreComponents) (EasyStoreObject, error) {
+	return impl.ObjectDeleteCtx(context.Background(), obj, which)
+}
+
+func (impl easyStoreProxyImpl) FileCreate(namespace string, oid string, file EasyStoreBlob) error {
+	return impl.FileCreateCtx(context.Background(), namespace, oid, file)
+}
+
+func (impl easyStoreProxyImpl) FileDelete(namespace string, oid string, name string) error {
+	return impl.FileDeleteCtx(context.Background(), namespace, oid, name)
+}
+
+func (impl easyStoreProxyImpl) FileRename(namespace string, oid string, name string, newName string) error {
+	return impl.FileRenameCtx(context.Background(), namespace, oid, name, newName)
+}
+
+func (impl easyStoreProxyImpl) FileUpdate(namespace string, oid string, file EasyStoreBlob) error {
+	return impl.FileUpdateCtx(context.Background(), namespace, oid, file)
+}
+
+func (impl easyStoreProxyImpl) ObjectRestore(namespace string, oid string) (EasyStoreObject, error) {
+	return impl.ObjectRestoreCtx(context.Background(), namespace, oid)
+}
+
+func (impl easyStoreProxyImpl) ObjectPurge(namespace string, oid string) error {
+	return impl.ObjectPurgeCtx(context.Background(), namespace, oid)
+}
+
+func (impl easyStoreProxyImpl) ObjectPurgeTrash(namespace string, before time.Time) (uint, error) {
+	return impl.ObjectPurgeTrashCtx(context.Background(), namespace, before)
+}
+
+func (impl easyStoreProxyImpl) ObjectCopy(namespace string, oid string, newNamespace string, newOid string) (EasyStoreObject, error) {
+	return impl.ObjectCopyCtx(context.Background(), namespace, oid, newNamespace, newOid)
+}
+
+func (impl easyStoreProxyImpl) ObjectMove(namespace string, oid string, newNamespace string, newOid string) (EasyStoreObject, error) {
+	return impl.ObjectMoveCtx(context.Background(), namespace, oid, newNamespace, newOid)
+}
+
+func (impl easyStoreProxyImpl) ObjectVersionRestore(namespace string, oid string, vtag string) (EasyStoreObject, error) {
+	return impl.ObjectVersionRestoreCtx(context.Background(), namespace, oid, vtag)
+}
+
+func (impl easyStoreProxyImpl) ObjectCreateCtx(ctx context.Context, obj EasyStoreObject) (EasyStoreObject, error) {
 
 	// preflight validation
 	if err := ObjectCreatePreflight(obj); err != nil {
@@ -106,17 +227,15 @@ func (impl easyStoreProxyImpl) ObjectCreate(obj EasyStoreObject) (EasyStoreObjec
 	logInfo(impl.config.Logger(), fmt.Sprintf("creating new object ns/oid [%s/%s]", obj.Namespace(), obj.Id()))
 
 	// create the request payload
-	reqBytes, err := json.Marshal(obj)
+	payload, contentType, err := objectPayload(obj)
 	if err != nil {
 		log.Printf("ERROR: Unable to marshal request (%s)", err.Error())
 		return nil, ErrSerialize
 	}
 
-	//log.Printf("REQ: [%s]", string(reqBytes))
-
 	// issue the request
 	url := fmt.Sprintf("%s/%s", impl.config.Endpoint(), obj.Namespace())
-	respBytes, err := httpPost(impl.HTTPClient, url, reqBytes, jsonContentType)
+	respBytes, err := httpPost(ctx, impl.HTTPClient, url, payload, contentType)
 	if err != nil {
 		if len(respBytes) > 0 {
 			//log.Printf("RESP: [%s]", string(respBytes))
@@ -138,7 +257,7 @@ func (impl easyStoreProxyImpl) ObjectCreate(obj EasyStoreObject) (EasyStoreObjec
 	return &resp, nil
 }
 
-func (impl easyStoreProxyImpl) ObjectUpdate(obj EasyStoreObject, which EasyStoreComponents) (EasyStoreObject, error) {
+func (impl easyStoreProxyImpl) ObjectUpdateCtx(ctx context.Context, obj EasyStoreObject, which EasyStoreComponents) (EasyStoreObject, error) {
 
 	// preflight validation
 	if err := ObjectUpdatePreflight(obj, which); err != nil {
@@ -158,17 +277,15 @@ func (impl easyStoreProxyImpl) ObjectUpdate(obj EasyStoreObject, which EasyStore
 	logInfo(impl.config.Logger(), fmt.Sprintf("updating object ns/oid [%s/%s]", obj.Namespace(), obj.Id()))
 
 	// create the request payload
-	reqBytes, err := json.Marshal(obj)
+	payload, contentType, err := objectPayload(obj)
 	if err != nil {
 		log.Printf("ERROR: Unable to marshal request (%s)", err.Error())
 		return nil, ErrSerialize
 	}
 
-	//log.Printf("REQ: [%s]", string(reqBytes))
-
 	// issue the request
 	url := fmt.Sprintf("%s/%s/%s%s", impl.config.Endpoint(), obj.Namespace(), obj.Id(), query)
-	respBytes, err := httpPut(impl.HTTPClient, url, reqBytes, jsonContentType)
+	respBytes, err := httpPut(ctx, impl.HTTPClient, url, payload, contentType)
 	if err != nil {
 		if len(respBytes) > 0 {
 			//log.Printf("RESP: [%s]", string(respBytes))
@@ -188,7 +305,7 @@ func (impl easyStoreProxyImpl) ObjectUpdate(obj EasyStoreObject, which EasyStore
 	return &resp, nil
 }
 
-func (impl easyStoreProxyImpl) ObjectDelete(obj EasyStoreObject, which EasyStoreComponents) (EasyStoreObject, error) {
+func (impl easyStoreProxyImpl) ObjectDeleteCtx(ctx context.Context, obj EasyStoreObject, which EasyStoreComponents) (EasyStoreObject, error) {
 
 	// preflight validation
 	if err := ObjectDeletePreflight(obj, which); err != nil {
@@ -214,7 +331,7 @@ func (impl easyStoreProxyImpl) ObjectDelete(obj EasyStoreObject, which EasyStore
 
 	// issue the request
 	url := fmt.Sprintf("%s/%s/%s%s", impl.config.Endpoint(), obj.Namespace(), obj.Id(), query)
-	respBytes, err := httpDelete(impl.HTTPClient, url)
+	respBytes, err := httpDelete(ctx, impl.HTTPClient, url)
 	if err != nil {
 		if len(respBytes) > 0 {
 			//log.Printf("RESP: [%s]", string(respBytes))
@@ -258,7 +375,7 @@ func (impl easyStoreProxyImpl) ObjectDelete(obj EasyStoreObject, which EasyStore
 //
 //	// issue the request
 //	url := fmt.Sprintf("%s/%s/%s%s", impl.config.Endpoint(), obj.Namespace(), obj.Id(), query)
-//	respBytes, err := httpPost(impl.HTTPClient, url, reqBytes, jsonContentType)
+//	respBytes, err := httpPost(ctx, impl.HTTPClient, url, bytes.NewReader(reqBytes), jsonContentType)
 //	if err != nil {
 //		if len(respBytes) > 0 {
 //			//log.Printf("RESP: [%s]", string(respBytes))
@@ -279,7 +396,7 @@ func (impl easyStoreProxyImpl) ObjectDelete(obj EasyStoreObject, which EasyStore
 //}
 
 // create a file
-func (impl easyStoreProxyImpl) FileCreate(namespace string, oid string, file EasyStoreBlob) error {
+func (impl easyStoreProxyImpl) FileCreateCtx(ctx context.Context, namespace string, oid string, file EasyStoreBlob) error {
 
 	// preflight validation
 	if err := FileCreatePreflight(namespace, oid, file); err != nil {
@@ -289,18 +406,20 @@ func (impl easyStoreProxyImpl) FileCreate(namespace string, oid string, file Eas
 
 	logInfo(impl.config.Logger(), fmt.Sprintf("creating new file for ns/oid [%s/%s]", namespace, oid))
 
-	// create the request payload
-	reqBytes, err := json.Marshal(file)
+	// send the file payload, the name is a query parameter and the mime type is the content type
+	payload, err := filePayload(file)
 	if err != nil {
-		log.Printf("ERROR: Unable to marshal request (%s)", err.Error())
+		log.Printf("ERROR: Unable to open file payload (%s)", err.Error())
 		return ErrSerialize
 	}
-
-	//log.Printf("REQ: [%s]", string(reqBytes))
+	if closer, ok := payload.(io.Closer); ok == true {
+		defer closer.Close()
+	}
+	name := neturl.QueryEscape(file.Name())
 
 	// issue the request
-	url := fmt.Sprintf("%s/%s/%s/file", impl.config.Endpoint(), namespace, oid)
-	respBytes, err := httpPost(impl.HTTPClient, url, reqBytes, jsonContentType)
+	url := fmt.Sprintf("%s/%s/%s/file?name=%s", impl.config.Endpoint(), namespace, oid, name)
+	respBytes, err := httpPost(ctx, impl.HTTPClient, url, payload, file.MimeType())
 	if err != nil {
 		if len(respBytes) > 0 {
 			//log.Printf("RESP: [%s]", string(respBytes))
@@ -313,7 +432,7 @@ func (impl easyStoreProxyImpl) FileCreate(namespace string, oid string, file Eas
 }
 
 // delete a file
-func (impl easyStoreProxyImpl) FileDelete(namespace string, oid string, name string) error {
+func (impl easyStoreProxyImpl) FileDeleteCtx(ctx context.Context, namespace string, oid string, name string) error {
 
 	// preflight validation
 	if err := FileDeletePreflight(namespace, oid, name); err != nil {
@@ -324,8 +443,8 @@ func (impl easyStoreProxyImpl) FileDelete(namespace string, oid string, name str
 	logInfo(impl.config.Logger(), fmt.Sprintf("deleting file ns/oid/name [%s/%s/%s]", namespace, oid, name))
 
 	// issue the request
-	url := fmt.Sprintf("%s/%s/%s/file/%s", impl.config.Endpoint(), namespace, oid, name)
-	respBytes, err := httpDelete(impl.HTTPClient, url)
+	url := fmt.Sprintf("%s/%s/%s/file/%s", impl.config.Endpoint(), namespace, oid, neturl.PathEscape(name))
+	respBytes, err := httpDelete(ctx, impl.HTTPClient, url)
 	if err != nil {
 		if len(respBytes) > 0 {
 			//log.Printf("RESP: [%s]", string(respBytes))
@@ -338,7 +457,7 @@ func (impl easyStoreProxyImpl) FileDelete(namespace string, oid string, name str
 }
 
 // rename a file, old name, new name
-func (impl easyStoreProxyImpl) FileRename(namespace string, oid string, name string, newName string) error {
+func (impl easyStoreProxyImpl) FileRenameCtx(ctx context.Context, namespace string, oid string, name string, newName string) error {
 
 	// preflight validation
 	if err := FileRenamePreflight(namespace, oid, name, newName); err != nil {
@@ -349,8 +468,8 @@ func (impl easyStoreProxyImpl) FileRename(namespace string, oid string, name str
 	logInfo(impl.config.Logger(), fmt.Sprintf("renaming file ns/oid/name [%s/%s/%s] -> [%s]", namespace, oid, name, newName))
 
 	// issue the request
-	url := fmt.Sprintf("%s/%s/%s/file/%s?new=%s", impl.config.Endpoint(), namespace, oid, name, newName)
-	respBytes, err := httpPost(impl.HTTPClient, url, nil, "")
+	url := fmt.Sprintf("%s/%s/%s/file/%s?new=%s", impl.config.Endpoint(), namespace, oid, neturl.PathEscape(name), neturl.QueryEscape(newName))
+	respBytes, err := httpPost(ctx, impl.HTTPClient, url, nil, "")
 	if err != nil {
 		if len(respBytes) > 0 {
 			//log.Printf("RESP: [%s]", string(respBytes))
@@ -363,7 +482,7 @@ func (impl easyStoreProxyImpl) FileRename(namespace string, oid string, name str
 }
 
 // update a file
-func (impl easyStoreProxyImpl) FileUpdate(namespace string, oid string, file EasyStoreBlob) error {
+func (impl easyStoreProxyImpl) FileUpdateCtx(ctx context.Context, namespace string, oid string, file EasyStoreBlob) error {
 
 	// preflight validation
 	if err := FileUpdatePreflight(namespace, oid, file); err != nil {
@@ -373,18 +492,78 @@ func (impl easyStoreProxyImpl) FileUpdate(namespace string, oid string, file Eas
 
 	logInfo(impl.config.Logger(), fmt.Sprintf("updating file ns/oid [%s/%s/%s]", namespace, oid, file.Name()))
 
-	// create the request payload
-	reqBytes, err := json.Marshal(file)
+	// send the file payload, the name is a query parameter and the mime type is the content type
+	payload, err := filePayload(file)
 	if err != nil {
-		log.Printf("ERROR: Unable to marshal request (%s)", err.Error())
+		log.Printf("ERROR: Unable to open file payload (%s)", err.Error())
 		return ErrSerialize
 	}
+	if closer, ok := payload.(io.Closer); ok == true {
+		defer closer.Close()
+	}
+	name := neturl.QueryEscape(file.Name())
+
+	// issue the request
+	url := fmt.Sprintf("%s/%s/%s/file?name=%s", impl.config.Endpoint(), namespace, oid, name)
+	respBytes, err := httpPut(ctx, impl.HTTPClient, url, payload, file.MimeType())
+	if err != nil {
+		if len(respBytes) > 0 {
+			//log.Printf("RESP: [%s]", string(respBytes))
+			return mapResponseToError(string(respBytes))
+		}
+		return err
+	}
+
+	return nil
+}
+
+// restore a deleted object from the trash
+func (impl easyStoreProxyImpl) ObjectRestoreCtx(ctx context.Context, namespace string, oid string) (EasyStoreObject, error) {
+
+	// preflight validation
+	if err := TrashPreflight(namespace, oid); err != nil {
+		logError(impl.config.Logger(), "preflight failure")
+		return nil, err
+	}
+
+	logInfo(impl.config.Logger(), fmt.Sprintf("restoring ns/oid [%s/%s]", namespace, oid))
+
+	// issue the request
+	url := fmt.Sprintf("%s/%s/%s/restore", impl.config.Endpoint(), namespace, oid)
+	respBytes, err := httpPost(ctx, impl.HTTPClient, url, nil, "")
+	if err != nil {
+		if len(respBytes) > 0 {
+			//log.Printf("RESP: [%s]", string(respBytes))
+			return nil, mapResponseToError(string(respBytes))
+		}
+		return nil, err
+	}
+
+	// process the response payload
+	var resp easyStoreObjectImpl
+	err = json.Unmarshal(respBytes, &resp)
+	if err != nil {
+		log.Printf("ERROR: Unable to unmarshal response (%s)", err.Error())
+		return nil, ErrDeserialize
+	}
+
+	return &resp, nil
+}
+
+// permanently remove a deleted object from the trash
+func (impl easyStoreProxyImpl) ObjectPurgeCtx(ctx context.Context, namespace string, oid string) error {
+
+	// preflight validation
+	if err := TrashPreflight(namespace, oid); err != nil {
+		logError(impl.config.Logger(), "preflight failure")
+		return err
+	}
 
-	//log.Printf("REQ: [%s]", string(reqBytes))
+	logInfo(impl.config.Logger(), fmt.Sprintf("purging ns/oid [%s/%s]", namespace, oid))
 
 	// issue the request
-	url := fmt.Sprintf("%s/%s/%s/file", impl.config.Endpoint(), namespace, oid)
-	respBytes, err := httpPut(impl.HTTPClient, url, reqBytes, jsonContentType)
+	url := fmt.Sprintf("%s/%s/%s/purge", impl.config.Endpoint(), namespace, oid)
+	respBytes, err := httpDelete(ctx, impl.HTTPClient, url)
 	if err != nil {
 		if len(respBytes) > 0 {
 			//log.Printf("RESP: [%s]", string(respBytes))
@@ -396,6 +575,82 @@ func (impl easyStoreProxyImpl) FileUpdate(namespace string, oid string, file Eas
 	return nil
 }
 
+// permanently remove the objects deleted before the specified time from the trash
+func (impl easyStoreProxyImpl) ObjectPurgeTrashCtx(ctx context.Context, namespace string, before time.Time) (uint, error) {
+
+	// preflight validation
+	if err := PurgeTrashPreflight(before); err != nil {
+		logError(impl.config.Logger(), "preflight failure")
+		return 0, err
+	}
+
+	logInfo(impl.config.Logger(), fmt.Sprintf("purging ns [%s] deleted before %s", namespace, before.Format(time.RFC3339)))
+
+	// issue the request
+	url := fmt.Sprintf("%s/%s/trash?before=%s", impl.config.Endpoint(), namespace, neturl.QueryEscape(before.Format(time.RFC3339)))
+	respBytes, err := httpDelete(ctx, impl.HTTPClient, url)
+	if err != nil {
+		if len(respBytes) > 0 {
+			//log.Printf("RESP: [%s]", string(respBytes))
+			return 0, mapResponseToError(string(respBytes))
+		}
+		return 0, err
+	}
+
+	// process the response payload
+	var resp PurgeTrashResponse
+	err = json.Unmarshal(respBytes, &resp)
+	if err != nil {
+		log.Printf("ERROR: Unable to unmarshal response (%s)", err.Error())
+		return 0, ErrDeserialize
+	}
+
+	return resp.Count, nil
+}
+
+// copy an object to a new namespace and/or identifier
+func (impl easyStoreProxyImpl) ObjectCopyCtx(ctx context.Context, namespace string, oid string, newNamespace string, newOid string) (EasyStoreObject, error) {
+	return impl.copyObject(ctx, namespace, oid, newNamespace, newOid, "copy")
+}
+
+// move an object to a new namespace and/or identifier
+func (impl easyStoreProxyImpl) ObjectMoveCtx(ctx context.Context, namespace string, oid string, newNamespace string, newOid string) (EasyStoreObject, error) {
+	return impl.copyObject(ctx, namespace, oid, newNamespace, newOid, "move")
+}
+
+// restore a prior version of an object
+func (impl easyStoreProxyImpl) ObjectVersionRestoreCtx(ctx context.Context, namespace string, oid string, vtag string) (EasyStoreObject, error) {
+
+	// preflight validation
+	if err := VersionPreflight(namespace, oid, vtag); err != nil {
+		logError(impl.config.Logger(), "preflight failure")
+		return nil, err
+	}
+
+	logInfo(impl.config.Logger(), fmt.Sprintf("restoring ns/oid [%s/%s] to vtag [%s]", namespace, oid, vtag))
+
+	// issue the request
+	url := fmt.Sprintf("%s/%s/%s/versions/%s", impl.config.Endpoint(), namespace, oid, vtag)
+	respBytes, err := httpPost(ctx, impl.HTTPClient, url, nil, "")
+	if err != nil {
+		if len(respBytes) > 0 {
+			//log.Printf("RESP: [%s]", string(respBytes))
+			return nil, mapResponseToError(string(respBytes))
+		}
+		return nil, err
+	}
+
+	// process the response payload
+	var resp easyStoreObjectImpl
+	err = json.Unmarshal(respBytes, &resp)
+	if err != nil {
+		log.Printf("ERROR: Unable to unmarshal response (%s)", err.Error())
+		return nil, ErrDeserialize
+	}
+
+	return &resp, nil
+}
+
 func (impl easyStoreProxyReadonlyImpl) Close() error {
 
 	// need to do this
@@ -404,8 +659,48 @@ func (impl easyStoreProxyReadonlyImpl) Close() error {
 }
 
 func (impl easyStoreProxyReadonlyImpl) Check() error {
+	return impl.CheckCtx(context.Background())
+}
+
+func (impl easyStoreProxyReadonlyImpl) ObjectGetByKey(namespace string, id string, which EasyStoreComponents) (EasyStoreObject, error) {
+	return impl.ObjectGetByKeyCtx(context.Background(), namespace, id, which)
+}
+
+func (impl easyStoreProxyReadonlyImpl) ObjectGetByKeys(namespace string, ids []string, which EasyStoreComponents) (EasyStoreObjectSet, error) {
+	return impl.ObjectGetByKeysCtx(context.Background(), namespace, ids, which)
+}
+
+func (impl easyStoreProxyReadonlyImpl) ObjectGetByFields(namespace string, fields EasyStoreObjectFields, which EasyStoreComponents) (EasyStoreObjectSet, error) {
+	return impl.ObjectGetByFieldsCtx(context.Background(), namespace, fields, which)
+}
+
+func (impl easyStoreProxyReadonlyImpl) ObjectGetByFieldsPage(namespace string, fields EasyStoreObjectFields, which EasyStoreComponents, page EasyStorePage) (EasyStoreObjectSet, error) {
+	return impl.ObjectGetByFieldsPageCtx(context.Background(), namespace, fields, which, page)
+}
+
+func (impl easyStoreProxyReadonlyImpl) ObjectGetByQuery(namespace string, query EasyStoreQuery, which EasyStoreComponents, page EasyStorePage) (EasyStoreObjectSet, error) {
+	return impl.ObjectGetByQueryCtx(context.Background(), namespace, query, which, page)
+}
+
+func (impl easyStoreProxyReadonlyImpl) FileGetByKey(namespace string, oid string, name string) (EasyStoreBlob, error) {
+	return impl.FileGetByKeyCtx(context.Background(), namespace, oid, name)
+}
+
+func (impl easyStoreProxyReadonlyImpl) ObjectVersions(namespace string, oid string) ([]EasyStoreVersion, error) {
+	return impl.ObjectVersionsCtx(context.Background(), namespace, oid)
+}
+
+func (impl easyStoreProxyReadonlyImpl) ObjectGetByVersion(namespace string, oid string, vtag string, which EasyStoreComponents) (EasyStoreObject, error) {
+	return impl.ObjectGetByVersionCtx(context.Background(), namespace, oid, vtag, which)
+}
+
+func (impl easyStoreProxyReadonlyImpl) ObjectTrash(namespace string) ([]EasyStoreTombstone, error) {
+	return impl.ObjectTrashCtx(context.Background(), namespace)
+}
+
+func (impl easyStoreProxyReadonlyImpl) CheckCtx(ctx context.Context) error {
 	url := fmt.Sprintf("%s/healthcheck", impl.config.Endpoint())
-	respBytes, err := httpGet(impl.HTTPClient, url)
+	respBytes, err := httpGet(ctx, impl.HTTPClient, url)
 	if err != nil {
 		if len(respBytes) > 0 {
 			//log.Printf("RESP: [%s]", string(respBytes))
@@ -416,7 +711,7 @@ func (impl easyStoreProxyReadonlyImpl) Check() error {
 	return nil
 }
 
-func (impl easyStoreProxyReadonlyImpl) ObjectGetByKey(namespace string, id string, which EasyStoreComponents) (EasyStoreObject, error) {
+func (impl easyStoreProxyReadonlyImpl) ObjectGetByKeyCtx(ctx context.Context, namespace string, id string, which EasyStoreComponents) (EasyStoreObject, error) {
 
 	// preflight validation
 	if err := GetByKeyPreflight(namespace, id, which); err != nil {
@@ -437,7 +732,7 @@ func (impl easyStoreProxyReadonlyImpl) ObjectGetByKey(namespace string, id strin
 
 	// issue the request
 	url := fmt.Sprintf("%s/%s/%s%s", impl.config.Endpoint(), namespace, id, query)
-	respBytes, err := httpGet(impl.HTTPClient, url)
+	respBytes, err := httpGet(ctx, impl.HTTPClient, url)
 	if err != nil {
 		if len(respBytes) > 0 {
 			//log.Printf("RESP: [%s]", string(respBytes))
@@ -457,7 +752,7 @@ func (impl easyStoreProxyReadonlyImpl) ObjectGetByKey(namespace string, id strin
 	return &resp, nil
 }
 
-func (impl easyStoreProxyReadonlyImpl) ObjectGetByKeys(namespace string, ids []string, which EasyStoreComponents) (EasyStoreObjectSet, error) {
+func (impl easyStoreProxyReadonlyImpl) ObjectGetByKeysCtx(ctx context.Context, namespace string, ids []string, which EasyStoreComponents) (EasyStoreObjectSet, error) {
 
 	// preflight validation
 	if err := GetByKeysPreflight(namespace, ids, which); err != nil {
@@ -484,7 +779,7 @@ func (impl easyStoreProxyReadonlyImpl) ObjectGetByKeys(namespace string, ids []s
 
 	// issue the request
 	url := fmt.Sprintf("%s/%s", impl.config.Endpoint(), namespace)
-	respBytes, err := httpPut(impl.HTTPClient, url, reqBytes, jsonContentType)
+	respBytes, err := httpPut(ctx, impl.HTTPClient, url, bytes.NewReader(reqBytes), jsonContentType)
 	if err != nil {
 		if len(respBytes) > 0 {
 			//log.Printf("RESP: [%s]", string(respBytes))
@@ -506,23 +801,23 @@ func (impl easyStoreProxyReadonlyImpl) ObjectGetByKeys(namespace string, ids []s
 		current: 0,
 		which:   which,
 		objects: resp.Results,
-		proxy:   impl}, nil
+		proxy:   impl,
+		ctx:     ctx}, nil
 }
 
-func (impl easyStoreProxyReadonlyImpl) ObjectGetByFields(namespace string, fields EasyStoreObjectFields, which EasyStoreComponents) (EasyStoreObjectSet, error) {
+func (impl easyStoreProxyReadonlyImpl) ObjectGetByFieldsCtx(ctx context.Context, namespace string, fields EasyStoreObjectFields, which EasyStoreComponents) (EasyStoreObjectSet, error) {
+	// a single page with no limit
+	return impl.ObjectGetByFieldsPageCtx(ctx, namespace, fields, which, EasyStorePage{})
+}
+
+func (impl easyStoreProxyReadonlyImpl) ObjectGetByFieldsPageCtx(ctx context.Context, namespace string, fields EasyStoreObjectFields, which EasyStoreComponents, page EasyStorePage) (EasyStoreObjectSet, error) {
 
 	// preflight validation
-	if err := GetByFieldsPreflight(namespace, fields, which); err != nil {
+	if err := GetByFieldsPagePreflight(namespace, fields, which, page); err != nil {
 		logError(impl.config.Logger(), "preflight failure")
 		return nil, err
 	}
 
-	// build the attributes list. We get these items lazily so just request the base component for now
-	//attribs := impl.componentHelper(BaseComponent)
-	//if len(attribs) != 0 {
-	//	attribs = fmt.Sprintf("?%s", attribs)
-	//}
-
 	logDebug(impl.config.Logger(), fmt.Sprintf("getting by fields ns/fields [%s/%v]", namespace, fields))
 
 	// create the request payload
@@ -532,9 +827,177 @@ func (impl easyStoreProxyReadonlyImpl) ObjectGetByFields(namespace string, field
 		return nil, ErrSerialize
 	}
 
+	return impl.search(ctx, fmt.Sprintf("%s/%s/search", impl.config.Endpoint(), namespace), reqBytes, which, page)
+}
+
+func (impl easyStoreProxyReadonlyImpl) ObjectGetByQueryCtx(ctx context.Context, namespace string, query EasyStoreQuery, which EasyStoreComponents, page EasyStorePage) (EasyStoreObjectSet, error) {
+
+	// preflight validation
+	if err := GetByQueryPreflight(namespace, query, which, page); err != nil {
+		logError(impl.config.Logger(), "preflight failure")
+		return nil, err
+	}
+
+	logDebug(impl.config.Logger(), fmt.Sprintf("getting by query ns/query [%s/%v]", namespace, query))
+
+	// create the request payload
+	reqBytes, err := json.Marshal(query)
+	if err != nil {
+		log.Printf("ERROR: Unable to marshal request (%s)", err.Error())
+		return nil, ErrSerialize
+	}
+
+	return impl.search(ctx, fmt.Sprintf("%s/%s/query", impl.config.Endpoint(), namespace), reqBytes, which, page)
+}
+
+func (impl easyStoreProxyReadonlyImpl) FileGetByKeyCtx(ctx context.Context, namespace string, oid string, name string) (EasyStoreBlob, error) {
+
+	// preflight validation
+	if err := FileGetByKeyPreflight(namespace, oid, name); err != nil {
+		logError(impl.config.Logger(), "preflight failure")
+		return nil, err
+	}
+
+	logInfo(impl.config.Logger(), fmt.Sprintf("getting file ns/oid/name [%s/%s/%s]", namespace, oid, name))
+
 	// issue the request
-	url := fmt.Sprintf("%s/%s/search", impl.config.Endpoint(), namespace)
-	respBytes, err := httpPut(impl.HTTPClient, url, reqBytes, jsonContentType)
+	url := fmt.Sprintf("%s/%s/%s/file/%s", impl.config.Endpoint(), namespace, oid, neturl.PathEscape(name))
+	respBytes, err := httpGet(ctx, impl.HTTPClient, url)
+	if err != nil {
+		if len(respBytes) > 0 {
+			//log.Printf("RESP: [%s]", string(respBytes))
+			return nil, mapResponseToError(string(respBytes))
+		}
+		return nil, err
+	}
+
+	// process the response payload
+	var resp easyStoreBlobImpl
+	err = json.Unmarshal(respBytes, &resp)
+	if err != nil {
+		log.Printf("ERROR: Unable to unmarshal response (%s)", err.Error())
+		return nil, ErrDeserialize
+	}
+
+	return &resp, nil
+}
+
+func (impl easyStoreProxyReadonlyImpl) ObjectVersionsCtx(ctx context.Context, namespace string, oid string) ([]EasyStoreVersion, error) {
+
+	// preflight validation
+	if err := GetByKeyPreflight(namespace, oid, BaseComponent); err != nil {
+		logError(impl.config.Logger(), "preflight failure")
+		return nil, err
+	}
+
+	logInfo(impl.config.Logger(), fmt.Sprintf("getting versions ns/oid [%s/%s]", namespace, oid))
+
+	// issue the request
+	url := fmt.Sprintf("%s/%s/%s/versions", impl.config.Endpoint(), namespace, oid)
+	respBytes, err := httpGet(ctx, impl.HTTPClient, url)
+	if err != nil {
+		if len(respBytes) > 0 {
+			//log.Printf("RESP: [%s]", string(respBytes))
+			return nil, mapResponseToError(string(respBytes))
+		}
+		return nil, err
+	}
+
+	// process the response payload
+	var resp GetVersionsResponse
+	err = json.Unmarshal(respBytes, &resp)
+	if err != nil {
+		log.Printf("ERROR: Unable to unmarshal response (%s)", err.Error())
+		return nil, ErrDeserialize
+	}
+
+	return resp.Results, nil
+}
+
+func (impl easyStoreProxyReadonlyImpl) ObjectGetByVersionCtx(ctx context.Context, namespace string, oid string, vtag string, which EasyStoreComponents) (EasyStoreObject, error) {
+
+	// preflight validation
+	if err := VersionPreflight(namespace, oid, vtag); err != nil {
+		logError(impl.config.Logger(), "preflight failure")
+		return nil, err
+	}
+
+	// build the attributes list (this is optional)
+	attribs := impl.componentHelper(which)
+
+	// build the query parameters
+	query := ""
+	if len(attribs) != 0 {
+		query = fmt.Sprintf("?%s", attribs)
+	}
+
+	logInfo(impl.config.Logger(), fmt.Sprintf("getting ns/oid/vtag [%s/%s/%s]", namespace, oid, vtag))
+
+	// issue the request
+	url := fmt.Sprintf("%s/%s/%s/versions/%s%s", impl.config.Endpoint(), namespace, oid, vtag, query)
+	respBytes, err := httpGet(ctx, impl.HTTPClient, url)
+	if err != nil {
+		if len(respBytes) > 0 {
+			//log.Printf("RESP: [%s]", string(respBytes))
+			return nil, mapResponseToError(string(respBytes))
+		}
+		return nil, err
+	}
+
+	// process the response payload
+	var resp easyStoreObjectImpl
+	err = json.Unmarshal(respBytes, &resp)
+	if err != nil {
+		log.Printf("ERROR: Unable to unmarshal response (%s)", err.Error())
+		return nil, ErrDeserialize
+	}
+
+	return &resp, nil
+}
+
+func (impl easyStoreProxyReadonlyImpl) ObjectTrashCtx(ctx context.Context, namespace string) ([]EasyStoreTombstone, error) {
+
+	logInfo(impl.config.Logger(), fmt.Sprintf("getting deleted objects ns [%s]", namespace))
+
+	// issue the request
+	url := fmt.Sprintf("%s/%s/trash", impl.config.Endpoint(), namespace)
+	respBytes, err := httpGet(ctx, impl.HTTPClient, url)
+	if err != nil {
+		if len(respBytes) > 0 {
+			//log.Printf("RESP: [%s]", string(respBytes))
+			return nil, mapResponseToError(string(respBytes))
+		}
+		return nil, err
+	}
+
+	// process the response payload
+	var resp GetTrashResponse
+	err = json.Unmarshal(respBytes, &resp)
+	if err != nil {
+		log.Printf("ERROR: Unable to unmarshal response (%s)", err.Error())
+		return nil, ErrDeserialize
+	}
+
+	return resp.Results, nil
+}
+
+// issue a search request for a page of objects
+func (impl easyStoreProxyReadonlyImpl) search(ctx context.Context, url string, reqBytes []byte, which EasyStoreComponents, page EasyStorePage) (EasyStoreObjectSet, error) {
+
+	// build the paging parameters (this is optional)
+	query := impl.pageHelper(page)
+	if len(query) != 0 {
+		url = fmt.Sprintf("%s?%s", url, query)
+	}
+
+	// build the attributes list. We get these items lazily so just request the base component for now
+	//attribs := impl.componentHelper(BaseComponent)
+	//if len(attribs) != 0 {
+	//	attribs = fmt.Sprintf("?%s", attribs)
+	//}
+
+	// issue the request
+	respBytes, err := httpPut(ctx, impl.HTTPClient, url, bytes.NewReader(reqBytes), jsonContentType)
 	if err != nil {
 		if len(respBytes) > 0 {
 			//log.Printf("RESP: [%s]", string(respBytes))
@@ -556,11 +1019,9 @@ func (impl easyStoreProxyReadonlyImpl) ObjectGetByFields(namespace string, field
 		current: 0,
 		which:   which,
 		objects: resp.Results,
-		proxy:   impl}, nil
-}
-
-func (impl easyStoreProxyReadonlyImpl) FileGetByKey(namespace string, oid string, name string) (EasyStoreBlob, error) {
-	return nil, ErrNotImplemented
+		proxy:   impl,
+		ctx:     ctx,
+		next:    resp.Next}, nil
 }
 
 func (impl easyStoreProxyReadonlyImpl) componentHelper(which EasyStoreComponents) string {
@@ -585,6 +1046,144 @@ func (impl easyStoreProxyReadonlyImpl) componentHelper(which EasyStoreComponents
 	return strings.TrimSuffix(components, ",")
 }
 
+func (impl easyStoreProxyReadonlyImpl) pageHelper(page EasyStorePage) string {
+
+	params := neturl.Values{}
+	if page.Limit != 0 {
+		params.Set("limit", fmt.Sprintf("%d", page.Limit))
+	}
+	if len(page.Token) != 0 {
+		params.Set("token", page.Token)
+	}
+	switch page.Order {
+	case OrderByCreated:
+		params.Set("order", "created")
+	case OrderByModified:
+		params.Set("order", "modified")
+	}
+	return params.Encode()
+}
+
+// copy or move an object, the operation names the endpoint
+func (impl easyStoreProxyImpl) copyObject(ctx context.Context, namespace string, oid string, newNamespace string, newOid string, operation string) (EasyStoreObject, error) {
+
+	// preflight validation
+	if err := ObjectCopyPreflight(namespace, oid, newNamespace, newOid); err != nil {
+		logError(impl.config.Logger(), "preflight failure")
+		return nil, err
+	}
+
+	logInfo(impl.config.Logger(), fmt.Sprintf("%s ns/oid [%s/%s] to [%s/%s]", operation, namespace, oid, newNamespace, newOid))
+
+	// issue the request
+	params := neturl.Values{}
+	params.Set("ns", newNamespace)
+	params.Set("id", newOid)
+	url := fmt.Sprintf("%s/%s/%s/%s?%s", impl.config.Endpoint(), namespace, oid, operation, params.Encode())
+	respBytes, err := httpPost(ctx, impl.HTTPClient, url, nil, "")
+	if err != nil {
+		if len(respBytes) > 0 {
+			//log.Printf("RESP: [%s]", string(respBytes))
+			return nil, mapResponseToError(string(respBytes))
+		}
+		return nil, err
+	}
+
+	// process the response payload
+	var resp easyStoreObjectImpl
+	err = json.Unmarshal(respBytes, &resp)
+	if err != nil {
+		log.Printf("ERROR: Unable to unmarshal response (%s)", err.Error())
+		return nil, ErrDeserialize
+	}
+
+	return &resp, nil
+}
+
+// the payload of a file request. An in-memory payload is sent from a bytes.Reader so the request can be
+// retried (see httpSend), anything else is streamed as it is read and the caller closes it
+func filePayload(file EasyStoreBlob) (io.Reader, error) {
+	if buf, ok := blobInMemory(file); ok == true {
+		return bytes.NewReader(buf), nil
+	}
+	return file.Open()
+}
+
+// the payload of an object request. Objects with streamed files are sent as multipart so the files are
+// not read into memory, the object (without those files) is the first part and each file follows in its
+// own part. Anything else is sent as json so the request can be retried
+func objectPayload(obj EasyStoreObject) (io.Reader, string, error) {
+
+	// in-memory payloads and urls go in the object
+	inline := make([]EasyStoreBlob, 0)
+	streamed := make([]EasyStoreBlob, 0)
+	for _, f := range obj.Files() {
+		if _, ok := blobInMemory(f); ok == true || len(f.Url()) != 0 {
+			inline = append(inline, f)
+		} else {
+			streamed = append(streamed, f)
+		}
+	}
+
+	if len(streamed) == 0 {
+		buf, err := json.Marshal(obj)
+		if err != nil {
+			return nil, "", err
+		}
+		return bytes.NewReader(buf), jsonContentType, nil
+	}
+
+	head := ProxyEasyStoreObject(obj.Namespace(), obj.Id(), obj.VTag())
+	head.SetMultiFields(obj.MultiFields())
+	head.SetMetadata(obj.Metadata())
+	head.SetFiles(inline)
+	buf, err := json.Marshal(head)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// the parts are written as the request is sent, closing the request body stops the writer
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		pw.CloseWithError(writeObjectParts(mw, buf, streamed))
+	}()
+	return pr, mw.FormDataContentType(), nil
+}
+
+func writeObjectParts(mw *multipart.Writer, object []byte, files []EasyStoreBlob) error {
+
+	part, err := mw.CreateFormField(objectPartName)
+	if err != nil {
+		return err
+	}
+	if _, err = part.Write(object); err != nil {
+		return err
+	}
+
+	// file names can contain anything so they are in a header of their own
+	for _, f := range files {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", fmt.Sprintf("form-data; name=\"%s\"", filePartName))
+		header.Set("Content-Type", f.MimeType())
+		header.Set(fileNameHeader, neturl.QueryEscape(f.Name()))
+		part, err = mw.CreatePart(header)
+		if err != nil {
+			return err
+		}
+		reader, err := f.Open()
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(part, reader)
+		reader.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
 //
 // end of file
 //
@@ -0,0 +1,355 @@
+//
+// field queries, a small AST and its string syntax
+//
+
+package uvaeasystore
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// EasyStoreQueryOp - the query operators
+type EasyStoreQueryOp string
+
+const (
+	QueryOpAnd          EasyStoreQueryOp = "and"      // all terms match (no terms matches every object)
+	QueryOpOr           EasyStoreQueryOp = "or"       // any term matches
+	QueryOpNot          EasyStoreQueryOp = "not"      // the single term does not match
+	QueryOpEquals       EasyStoreQueryOp = "eq"       // field value is equal to the value
+	QueryOpPrefix       EasyStoreQueryOp = "prefix"   // field value starts with the value
+	QueryOpWildcard     EasyStoreQueryOp = "wildcard" // field value matches the value, * is any run of characters, ? is any one character
+	QueryOpExists       EasyStoreQueryOp = "exists"   // field exists with any value
+	QueryOpLess         EasyStoreQueryOp = "lt"       // field value is less than the value
+	QueryOpLessEqual    EasyStoreQueryOp = "le"       // field value is less than or equal to the value
+	QueryOpGreater      EasyStoreQueryOp = "gt"       // field value is greater than the value
+	QueryOpGreaterEqual EasyStoreQueryOp = "ge"       // field value is greater than or equal to the value
+)
+
+// EasyStoreQuery - a query over object fields. Field values are strings and ranges compare them
+// as such so date-like values must be in a sortable form (e.g. RFC3339) for ranges to be meaningful
+type EasyStoreQuery struct {
+	Op    EasyStoreQueryOp `json:"op"`              // the operator
+	Field string           `json:"field,omitempty"` // the field name (field operators only)
+	Value string           `json:"value,omitempty"` // the field value (field operators except exists)
+	Terms []EasyStoreQuery `json:"terms,omitempty"` // the terms (and, or and not only)
+}
+
+// QueryAnd - all the terms match
+func QueryAnd(terms ...EasyStoreQuery) EasyStoreQuery {
+	return EasyStoreQuery{Op: QueryOpAnd, Terms: terms}
+}
+
+// QueryOr - any of the terms match
+func QueryOr(terms ...EasyStoreQuery) EasyStoreQuery {
+	return EasyStoreQuery{Op: QueryOpOr, Terms: terms}
+}
+
+// QueryNot - the term does not match
+func QueryNot(term EasyStoreQuery) EasyStoreQuery {
+	return EasyStoreQuery{Op: QueryOpNot, Terms: []EasyStoreQuery{term}}
+}
+
+// QueryEquals - the field has the value
+func QueryEquals(field string, value string) EasyStoreQuery {
+	return EasyStoreQuery{Op: QueryOpEquals, Field: field, Value: value}
+}
+
+// QueryPrefix - the field value starts with the prefix
+func QueryPrefix(field string, prefix string) EasyStoreQuery {
+	return EasyStoreQuery{Op: QueryOpPrefix, Field: field, Value: prefix}
+}
+
+// QueryWildcard - the field value matches the pattern
+func QueryWildcard(field string, pattern string) EasyStoreQuery {
+	return EasyStoreQuery{Op: QueryOpWildcard, Field: field, Value: pattern}
+}
+
+// QueryExists - the field exists
+func QueryExists(field string) EasyStoreQuery {
+	return EasyStoreQuery{Op: QueryOpExists, Field: field}
+}
+
+// QueryLess - the field value is less than the value
+func QueryLess(field string, value string) EasyStoreQuery {
+	return EasyStoreQuery{Op: QueryOpLess, Field: field, Value: value}
+}
+
+// QueryLessEqual - the field value is less than or equal to the value
+func QueryLessEqual(field string, value string) EasyStoreQuery {
+	return EasyStoreQuery{Op: QueryOpLessEqual, Field: field, Value: value}
+}
+
+// QueryGreater - the field value is greater than the value
+func QueryGreater(field string, value string) EasyStoreQuery {
+	return EasyStoreQuery{Op: QueryOpGreater, Field: field, Value: value}
+}
+
+// QueryGreaterEqual - the field value is greater than or equal to the value
+func QueryGreaterEqual(field string, value string) EasyStoreQuery {
+	return EasyStoreQuery{Op: QueryOpGreaterEqual, Field: field, Value: value}
+}
+
+// QueryFields - the query equivalent of the fields, all of them match
+func QueryFields(fields EasyStoreObjectFields) EasyStoreQuery {
+
+	// sorted so the same fields always make the same query
+	names := make([]string, 0, len(fields))
+	for k := range fields {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	terms := make([]EasyStoreQuery, 0, len(fields))
+	for _, k := range names {
+		terms = append(terms, QueryEquals(k, fields[k]))
+	}
+	return QueryAnd(terms...)
+}
+
+// ParseEasyStoreQuery - parse the string form of a query. The syntax is as follows:
+//
+//	expr   = term { "OR" term }
+//	term   = factor { "AND" factor }
+//	factor = "NOT" factor | "(" expr ")" | "EXISTS" name | name op value
+//	op     = "=" | "!=" | "^=" | "~" | "<" | "<=" | ">" | ">="
+//
+// where ^= is a prefix match and ~ is a wildcard match. Keywords are case-insensitive, names and
+// values are either bare words or double quoted strings (with \" and \\ escapes). For example:
+//
+//	author = "Smith, J" AND (type ~ "thesis*" OR NOT EXISTS draft) AND published >= 2020-01-01
+func ParseEasyStoreQuery(str string) (EasyStoreQuery, error) {
+
+	tokens, err := queryTokens(str)
+	if err != nil {
+		return EasyStoreQuery{}, err
+	}
+
+	p := queryParser{tokens: tokens}
+	query, err := p.expr()
+	if err != nil {
+		return EasyStoreQuery{}, err
+	}
+	if p.pos != len(p.tokens) {
+		return EasyStoreQuery{}, p.errorf("unexpected '%s'", p.tokens[p.pos].text)
+	}
+	return query, nil
+}
+
+//
+// private methods
+//
+
+// a query token, quoted tokens are never keywords or operators
+type queryToken struct {
+	text   string
+	quoted bool
+}
+
+// the query operators, longest first so they match greedily
+var queryOperators = []string{"!=", "^=", "<=", ">=", "=", "~", "<", ">", "(", ")"}
+
+func queryTokens(str string) ([]queryToken, error) {
+
+	tokens := make([]queryToken, 0)
+	runes := []rune(str)
+	for ix := 0; ix < len(runes); {
+		r := runes[ix]
+		switch {
+		case unicode.IsSpace(r):
+			ix++
+
+		case r == '"':
+			var sb strings.Builder
+			ix++
+			for {
+				if ix == len(runes) {
+					return nil, fmt.Errorf("%q: %w", "unterminated quoted string in query", ErrBadParameter)
+				}
+				if runes[ix] == '"' {
+					ix++
+					break
+				}
+				if runes[ix] == '\\' && ix+1 < len(runes) {
+					ix++
+				}
+				sb.WriteRune(runes[ix])
+				ix++
+			}
+			tokens = append(tokens, queryToken{text: sb.String(), quoted: true})
+
+		default:
+			op := ""
+			for _, o := range queryOperators {
+				if strings.HasPrefix(string(runes[ix:]), o) {
+					op = o
+					break
+				}
+			}
+			if len(op) != 0 {
+				tokens = append(tokens, queryToken{text: op})
+				ix += len([]rune(op))
+				continue
+			}
+
+			// a bare word runs until whitespace, a quote or an operator
+			start := ix
+			for ix < len(runes) && unicode.IsSpace(runes[ix]) == false && runes[ix] != '"' && strings.ContainsRune("!^<>=~()", runes[ix]) == false {
+				ix++
+			}
+			if start == ix {
+				return nil, fmt.Errorf("%q: %w", fmt.Sprintf("unexpected '%c' in query", r), ErrBadParameter)
+			}
+			tokens = append(tokens, queryToken{text: string(runes[start:ix])})
+		}
+	}
+	return tokens, nil
+}
+
+// a recursive descent parser for the query syntax
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+}
+
+func (p *queryParser) expr() (EasyStoreQuery, error) {
+	return p.list(QueryOpOr, "OR", p.term)
+}
+
+func (p *queryParser) term() (EasyStoreQuery, error) {
+	return p.list(QueryOpAnd, "AND", p.factor)
+}
+
+// one or more items separated by the keyword, a single item is not wrapped
+func (p *queryParser) list(op EasyStoreQueryOp, keyword string, item func() (EasyStoreQuery, error)) (EasyStoreQuery, error) {
+
+	first, err := item()
+	if err != nil {
+		return first, err
+	}
+	terms := []EasyStoreQuery{first}
+	for p.keyword(keyword) {
+		next, err := item()
+		if err != nil {
+			return next, err
+		}
+		terms = append(terms, next)
+	}
+	if len(terms) == 1 {
+		return first, nil
+	}
+	return EasyStoreQuery{Op: op, Terms: terms}, nil
+}
+
+func (p *queryParser) factor() (EasyStoreQuery, error) {
+
+	switch {
+	case p.keyword("NOT"):
+		term, err := p.factor()
+		if err != nil {
+			return term, err
+		}
+		return QueryNot(term), nil
+
+	case p.symbol("("):
+		query, err := p.expr()
+		if err != nil {
+			return query, err
+		}
+		if p.symbol(")") == false {
+			return query, p.errorf("expected ')'")
+		}
+		return query, nil
+
+	case p.keyword("EXISTS"):
+		name, err := p.word("field name")
+		if err != nil {
+			return EasyStoreQuery{}, err
+		}
+		return QueryExists(name), nil
+	}
+
+	name, err := p.word("field name")
+	if err != nil {
+		return EasyStoreQuery{}, err
+	}
+	if p.pos == len(p.tokens) || p.tokens[p.pos].quoted == true {
+		return EasyStoreQuery{}, p.errorf("expected an operator after '%s'", name)
+	}
+	op := p.tokens[p.pos].text
+	p.pos++
+	value, err := p.word("value")
+	if err != nil {
+		return EasyStoreQuery{}, err
+	}
+
+	switch op {
+	case "=":
+		return QueryEquals(name, value), nil
+	case "!=":
+		return QueryNot(QueryEquals(name, value)), nil
+	case "^=":
+		return QueryPrefix(name, value), nil
+	case "~":
+		return QueryWildcard(name, value), nil
+	case "<":
+		return QueryLess(name, value), nil
+	case "<=":
+		return QueryLessEqual(name, value), nil
+	case ">":
+		return QueryGreater(name, value), nil
+	case ">=":
+		return QueryGreaterEqual(name, value), nil
+	}
+	return EasyStoreQuery{}, p.errorf("unknown operator '%s'", op)
+}
+
+// consume the keyword if it is next
+func (p *queryParser) keyword(keyword string) bool {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].quoted == false && strings.EqualFold(p.tokens[p.pos].text, keyword) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// consume the symbol if it is next
+func (p *queryParser) symbol(symbol string) bool {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].quoted == false && p.tokens[p.pos].text == symbol {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// consume a name or value
+func (p *queryParser) word(what string) (string, error) {
+	if p.pos == len(p.tokens) {
+		return "", p.errorf("expected a %s", what)
+	}
+	t := p.tokens[p.pos]
+	if t.quoted == false && isQueryOperator(t.text) == true {
+		return "", p.errorf("expected a %s but got '%s'", what, t.text)
+	}
+	p.pos++
+	return t.text, nil
+}
+
+func isQueryOperator(str string) bool {
+	for _, o := range queryOperators {
+		if str == o {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *queryParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%q: %w", fmt.Sprintf(format, args...), ErrBadParameter)
+}
+
+//
+// end of file
+//
@@ -0,0 +1,110 @@
+//
+//
+//
+
+package uvaeasystore
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestQueryParse(t *testing.T) {
+
+	tests := map[string]EasyStoreQuery{
+		"a=1":                       QueryEquals("a", "1"),
+		"a != 1":                    QueryNot(QueryEquals("a", "1")),
+		`a = "x y \"z\""`:           QueryEquals("a", `x y "z"`),
+		"a ^= pre":                  QueryPrefix("a", "pre"),
+		"a ~ th?s*":                 QueryWildcard("a", "th?s*"),
+		"exists a":                  QueryExists("a"),
+		"a < 1 and b <= 2":          QueryAnd(QueryLess("a", "1"), QueryLessEqual("b", "2")),
+		"a > 1 OR b >= 2":           QueryOr(QueryGreater("a", "1"), QueryGreaterEqual("b", "2")),
+		"a=1 OR b=2 AND c=3":        QueryOr(QueryEquals("a", "1"), QueryAnd(QueryEquals("b", "2"), QueryEquals("c", "3"))),
+		"(a=1 OR b=2) AND NOT c=3":  QueryAnd(QueryOr(QueryEquals("a", "1"), QueryEquals("b", "2")), QueryNot(QueryEquals("c", "3"))),
+		`"and" = "or"`:              QueryEquals("and", "or"),
+		"d >= 2020-01-01T00:00:00Z": QueryGreaterEqual("d", "2020-01-01T00:00:00Z"),
+	}
+
+	for str, expected := range tests {
+		query, err := ParseEasyStoreQuery(str)
+		if err != nil {
+			t.Fatalf("expected 'OK' but got '%s' for [%s]\n", err, str)
+		}
+		if reflect.DeepEqual(query, expected) == false {
+			t.Fatalf("expected '%v' but got '%v' for [%s]\n", expected, query, str)
+		}
+	}
+
+	// and some bad queries
+	expected := ErrBadParameter
+	for _, str := range []string{"", "a", "a =", "= 1", "a = 1 AND", "(a = 1", "a = 1)", "a ! 1", `a = "1`, "NOT"} {
+		_, err := ParseEasyStoreQuery(str)
+		if errors.Is(err, expected) == false {
+			t.Fatalf("expected '%s' but got '%v' for [%s]\n", expected, err, str)
+		}
+	}
+}
+
+func TestQueryEncode(t *testing.T) {
+	query, _ := ParseEasyStoreQuery("(a=1 OR b ^= 2) AND NOT EXISTS c")
+
+	// queries must survive the trip through the proxy
+	buf, err := json.Marshal(query)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	var after EasyStoreQuery
+	err = json.Unmarshal(buf, &after)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	if reflect.DeepEqual(query, after) == false {
+		t.Fatalf("expected '%v' but got '%v'\n", query, after)
+	}
+}
+
+func TestQueryCompile(t *testing.T) {
+
+	// the values are always parameters, never part of the SQL
+	args := make([]any, 0)
+	sql, err := compileQuery(QueryOr(QueryWildcard("a", "50%_*"), QueryNot(QueryExists("b"))), &args)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	testEqual(t, "(EXISTS (SELECT 1 FROM fields f WHERE f.namespace = o.namespace AND f.oid = o.oid AND f.name = $1 AND f.value LIKE $2 ESCAPE '\\') OR NOT EXISTS (SELECT 1 FROM fields f WHERE f.namespace = o.namespace AND f.oid = o.oid AND f.name = $3))", sql)
+	if reflect.DeepEqual(args, []any{"a", "50\\%\\_%", "b"}) == false {
+		t.Fatalf("unexpected arguments '%v'\n", args)
+	}
+
+	// empty lists
+	args = make([]any, 0)
+	sql, _ = compileQuery(QueryAnd(), &args)
+	testEqual(t, "1 = 1", sql)
+}
+
+func TestQueryPreflight(t *testing.T) {
+
+	good := []EasyStoreQuery{QueryAnd(), QueryFields(EasyStoreObjectFields{"a": "1", "b": "2"}), QueryNot(QueryExists("a"))}
+	for _, query := range good {
+		err := GetByQueryPreflight(goodNamespace, query, BaseComponent, EasyStorePage{})
+		if err != nil {
+			t.Fatalf("expected 'OK' but got '%s' for '%v'\n", err, query)
+		}
+	}
+
+	expected := ErrBadParameter
+	bad := []EasyStoreQuery{{}, QueryOr(), {Op: QueryOpNot}, QueryEquals("", "1"), QueryAnd(QueryExists(""))}
+	for _, query := range bad {
+		err := GetByQueryPreflight(goodNamespace, query, BaseComponent, EasyStorePage{})
+		if errors.Is(err, expected) == false {
+			t.Fatalf("expected '%s' but got '%v' for '%v'\n", expected, err, query)
+		}
+	}
+}
+
+//
+// end of file
+//
@@ -10,8 +10,10 @@
 package uvaeasystore
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"time"
 )
 
 // this is our easystore readonly implementation
@@ -41,10 +43,50 @@ func (impl easyStoreReadonlyImpl) Close() error {
 }
 
 func (impl easyStoreReadonlyImpl) Check() error {
-	return impl.store.Check()
+	return impl.CheckCtx(context.Background())
+}
+
+func (impl easyStoreReadonlyImpl) CheckCtx(ctx context.Context) error {
+	return impl.store.Check(ctx)
 }
 
 func (impl easyStoreReadonlyImpl) ObjectGetByKey(namespace string, id string, which EasyStoreComponents) (EasyStoreObject, error) {
+	return impl.ObjectGetByKeyCtx(context.Background(), namespace, id, which)
+}
+
+func (impl easyStoreReadonlyImpl) ObjectGetByKeys(namespace string, ids []string, which EasyStoreComponents) (EasyStoreObjectSet, error) {
+	return impl.ObjectGetByKeysCtx(context.Background(), namespace, ids, which)
+}
+
+func (impl easyStoreReadonlyImpl) ObjectGetByFields(namespace string, fields EasyStoreObjectFields, which EasyStoreComponents) (EasyStoreObjectSet, error) {
+	return impl.ObjectGetByFieldsCtx(context.Background(), namespace, fields, which)
+}
+
+func (impl easyStoreReadonlyImpl) ObjectGetByFieldsPage(namespace string, fields EasyStoreObjectFields, which EasyStoreComponents, page EasyStorePage) (EasyStoreObjectSet, error) {
+	return impl.ObjectGetByFieldsPageCtx(context.Background(), namespace, fields, which, page)
+}
+
+func (impl easyStoreReadonlyImpl) ObjectGetByQuery(namespace string, query EasyStoreQuery, which EasyStoreComponents, page EasyStorePage) (EasyStoreObjectSet, error) {
+	return impl.ObjectGetByQueryCtx(context.Background(), namespace, query, which, page)
+}
+
+func (impl easyStoreReadonlyImpl) FileGetByKey(namespace string, oid string, name string) (EasyStoreBlob, error) {
+	return impl.FileGetByKeyCtx(context.Background(), namespace, oid, name)
+}
+
+func (impl easyStoreReadonlyImpl) ObjectVersions(namespace string, oid string) ([]EasyStoreVersion, error) {
+	return impl.ObjectVersionsCtx(context.Background(), namespace, oid)
+}
+
+func (impl easyStoreReadonlyImpl) ObjectGetByVersion(namespace string, oid string, vtag string, which EasyStoreComponents) (EasyStoreObject, error) {
+	return impl.ObjectGetByVersionCtx(context.Background(), namespace, oid, vtag, which)
+}
+
+func (impl easyStoreReadonlyImpl) ObjectTrash(namespace string) ([]EasyStoreTombstone, error) {
+	return impl.ObjectTrashCtx(context.Background(), namespace)
+}
+
+func (impl easyStoreReadonlyImpl) ObjectGetByKeyCtx(ctx context.Context, namespace string, id string, which EasyStoreComponents) (EasyStoreObject, error) {
 
 	// preflight validation
 	if err := GetByKeyPreflight(namespace, id, which); err != nil {
@@ -52,17 +94,15 @@ func (impl easyStoreReadonlyImpl) ObjectGetByKey(namespace string, id string, wh
 		return nil, err
 	}
 
-	// get the base object
-	o, err := impl.getByKey(namespace, id)
-	if err != nil {
+	// access control
+	if err := impl.authorize(ctx, namespace, id, OperationRead, which); err != nil {
 		return nil, err
 	}
 
-	// populate the object and return it
-	return impl.populateObject(o, which)
+	return impl.getObject(ctx, namespace, id, which)
 }
 
-func (impl easyStoreReadonlyImpl) ObjectGetByKeys(namespace string, ids []string, which EasyStoreComponents) (EasyStoreObjectSet, error) {
+func (impl easyStoreReadonlyImpl) ObjectGetByKeysCtx(ctx context.Context, namespace string, ids []string, which EasyStoreComponents) (EasyStoreObjectSet, error) {
 
 	// preflight validation
 	if err := GetByKeysPreflight(namespace, ids, which); err != nil {
@@ -70,13 +110,18 @@ func (impl easyStoreReadonlyImpl) ObjectGetByKeys(namespace string, ids []string
 		return nil, err
 	}
 
+	// access control
+	if err := impl.authorize(ctx, namespace, "", OperationRead, which); err != nil {
+		return nil, err
+	}
+
 	// build our list of objects
 	keys := make([]DataStoreKey, 0)
 	for _, id := range ids {
 		keys = append(keys, DataStoreKey{namespace, id})
 	}
 
-	objs, err := impl.getByKeys(keys)
+	objs, err := impl.getByKeys(ctx, keys)
 	if err != nil {
 		if errors.Is(err, ErrNotFound) {
 			return nil, ErrNotFound
@@ -91,21 +136,43 @@ func (impl easyStoreReadonlyImpl) ObjectGetByKeys(namespace string, ids []string
 	}
 
 	// we get objects only when they are required
-	return newEasyStoreObjectSet(impl, objs, which), nil
+	return newEasyStoreObjectSet(ctx, impl, objs, which, ""), nil
 }
 
-func (impl easyStoreReadonlyImpl) ObjectGetByFields(namespace string, fields EasyStoreObjectFields, which EasyStoreComponents) (EasyStoreObjectSet, error) {
+func (impl easyStoreReadonlyImpl) ObjectGetByFieldsCtx(ctx context.Context, namespace string, fields EasyStoreObjectFields, which EasyStoreComponents) (EasyStoreObjectSet, error) {
+	// a single page with no limit
+	return impl.ObjectGetByFieldsPageCtx(ctx, namespace, fields, which, EasyStorePage{})
+}
+
+func (impl easyStoreReadonlyImpl) ObjectGetByFieldsPageCtx(ctx context.Context, namespace string, fields EasyStoreObjectFields, which EasyStoreComponents, page EasyStorePage) (EasyStoreObjectSet, error) {
+
+	// preflight validation
+	if err := GetByFieldsPagePreflight(namespace, fields, which, page); err != nil {
+		logError(impl.config.Logger(), "preflight failure")
+		return nil, err
+	}
+
+	// fields are a simple query
+	return impl.ObjectGetByQueryCtx(ctx, namespace, QueryFields(fields), which, page)
+}
+
+func (impl easyStoreReadonlyImpl) ObjectGetByQueryCtx(ctx context.Context, namespace string, query EasyStoreQuery, which EasyStoreComponents, page EasyStorePage) (EasyStoreObjectSet, error) {
 
 	// preflight validation
-	if err := GetByFieldsPreflight(namespace, fields, which); err != nil {
+	if err := GetByQueryPreflight(namespace, query, which, page); err != nil {
 		logError(impl.config.Logger(), "preflight failure")
 		return nil, err
 	}
 
-	logDebug(impl.config.Logger(), fmt.Sprintf("getting by fields"))
+	// access control
+	if err := impl.authorize(ctx, namespace, "", OperationRead, which); err != nil {
+		return nil, err
+	}
+
+	logDebug(impl.config.Logger(), fmt.Sprintf("getting by query (limit %d)", page.Limit))
 
 	// first get the base objects (always required)
-	keys, err := impl.store.GetKeysByFields(namespace, fields)
+	keys, next, err := impl.store.GetKeysByFields(ctx, namespace, query, page)
 	if err != nil {
 		// known error
 		if errors.Is(err, ErrNotFound) {
@@ -115,36 +182,204 @@ func (impl easyStoreReadonlyImpl) ObjectGetByFields(namespace string, fields Eas
 		}
 	}
 
+	// searches of every namespace are allowed by wildcard rules, each object must be readable in its own.
+	// The objects left out are made up from the following pages so only the last page is short
+	if len(namespace) == 0 {
+		keys = impl.permittedKeys(ctx, keys, OperationRead, which)
+		for page.Limit != 0 && uint(len(keys)) < page.Limit && len(next) != 0 {
+			more, token, err := impl.store.GetKeysByFields(ctx, namespace, query, EasyStorePage{Limit: page.Limit - uint(len(keys)), Token: next, Order: page.Order})
+			if err != nil && errors.Is(err, ErrNotFound) == false {
+				return nil, err
+			}
+			keys = append(keys, impl.permittedKeys(ctx, more, OperationRead, which)...)
+			next = token
+		}
+	}
+
 	// bail out if we did not find any
 	// I think returning an error is better but this is what was requested
 	objs := make([]EasyStoreObject, 0)
 	if len(keys) == 0 {
-		return newEasyStoreObjectSet(impl, objs, which), nil
+		return newEasyStoreObjectSet(ctx, impl, objs, which, next), nil
 	}
 
-	objs, err = impl.getByKeys(keys)
+	objs, err = impl.getByKeys(ctx, keys)
 	if err != nil {
 		return nil, err
 	}
 
 	// we get objects only when they are required
-	return newEasyStoreObjectSet(impl, objs, which), nil
+	return newEasyStoreObjectSet(ctx, impl, orderByKeys(objs, keys), which, next), nil
 }
 
-func (impl easyStoreReadonlyImpl) FileGetByKey(namespace string, oid string, name string) (EasyStoreBlob, error) {
-	return nil, ErrNotImplemented
+func (impl easyStoreReadonlyImpl) FileGetByKeyCtx(ctx context.Context, namespace string, oid string, name string) (EasyStoreBlob, error) {
+
+	// preflight validation
+	if err := FileGetByKeyPreflight(namespace, oid, name); err != nil {
+		logError(impl.config.Logger(), "preflight failure")
+		return nil, err
+	}
+
+	// access control
+	if err := impl.authorize(ctx, namespace, oid, OperationRead, Files); err != nil {
+		return nil, err
+	}
+
+	// the object must exist
+	_, err := impl.getByKey(ctx, namespace, oid)
+	if err != nil {
+		return nil, err
+	}
+
+	logDebug(impl.config.Logger(), fmt.Sprintf("getting ns/oid/name [%s/%s/%s]", namespace, oid, name))
+
+	// get the file, the other object files are not touched
+	blob, err := impl.store.GetBlobByKey(ctx, DataStoreKey{namespace, oid}, name, FROMCACHE)
+	if err != nil {
+		// known error
+		if errors.Is(err, ErrFileNotFound) {
+			logInfo(impl.config.Logger(), fmt.Sprintf("no file found for ns/oid/name [%s/%s/%s]", namespace, oid, name))
+			return nil, ErrFileNotFound
+		}
+		return nil, err
+	}
+	return blob, nil
+}
+
+func (impl easyStoreReadonlyImpl) ObjectVersionsCtx(ctx context.Context, namespace string, oid string) ([]EasyStoreVersion, error) {
+
+	// preflight validation
+	if err := GetByKeyPreflight(namespace, oid, BaseComponent); err != nil {
+		logError(impl.config.Logger(), "preflight failure")
+		return nil, err
+	}
+
+	// access control
+	if err := impl.authorize(ctx, namespace, oid, OperationRead, BaseComponent); err != nil {
+		return nil, err
+	}
+
+	// the object must exist, it is the current version
+	current, err := impl.getByKey(ctx, namespace, oid)
+	if err != nil {
+		return nil, err
+	}
+
+	logDebug(impl.config.Logger(), fmt.Sprintf("getting versions for ns/oid [%s/%s]", namespace, oid))
+
+	versions, err := impl.store.GetVersionsByKey(ctx, DataStoreKey{namespace, oid})
+	if err != nil {
+		// known error, objects that have never changed have no prior versions
+		if errors.Is(err, ErrNotFound) {
+			versions = make([]EasyStoreVersion, 0)
+		} else {
+			return nil, err
+		}
+	}
+
+	return append(versions, EasyStoreVersion{
+		VTag:     current.VTag(),
+		Created:  current.Created(),
+		Modified: current.Modified(),
+		Current:  true}), nil
+}
+
+func (impl easyStoreReadonlyImpl) ObjectGetByVersionCtx(ctx context.Context, namespace string, oid string, vtag string, which EasyStoreComponents) (EasyStoreObject, error) {
+
+	// preflight validation
+	if err := VersionPreflight(namespace, oid, vtag); err != nil {
+		logError(impl.config.Logger(), "preflight failure")
+		return nil, err
+	}
+
+	// access control
+	if err := impl.authorize(ctx, namespace, oid, OperationRead, which); err != nil {
+		return nil, err
+	}
+
+	// the current version is the object itself
+	current, err := impl.getByKey(ctx, namespace, oid)
+	if err != nil {
+		return nil, err
+	}
+	if current.VTag() == vtag {
+		return impl.populateObject(ctx, current, which)
+	}
+
+	logDebug(impl.config.Logger(), fmt.Sprintf("getting ns/oid/vtag [%s/%s/%s]", namespace, oid, vtag))
+
+	obj, err := impl.store.GetVersionByKey(ctx, DataStoreKey{namespace, oid}, vtag)
+	if err != nil {
+		// known error
+		if errors.Is(err, ErrNotFound) {
+			logInfo(impl.config.Logger(), fmt.Sprintf("no version found for ns/oid/vtag [%s/%s/%s]", namespace, oid, vtag))
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	// versions come with all their components, remove the ones not asked for
+	if (which & Fields) != Fields {
+		obj.SetFields(EasyStoreObjectFields{})
+	}
+	if (which & Files) != Files {
+		obj.SetFiles(nil)
+	}
+	if (which & Metadata) != Metadata {
+		obj.SetMetadata(nil)
+	}
+	return obj, nil
+}
+
+func (impl easyStoreReadonlyImpl) ObjectTrashCtx(ctx context.Context, namespace string) ([]EasyStoreTombstone, error) {
+
+	// access control
+	if err := impl.authorize(ctx, namespace, "", OperationRead, BaseComponent); err != nil {
+		return nil, err
+	}
+
+	logDebug(impl.config.Logger(), fmt.Sprintf("getting deleted objects for ns [%s]", namespace))
+
+	// everything deleted so far
+	tombstones, err := impl.store.GetTombstones(ctx, namespace, time.Now())
+	if err != nil {
+		// known error, an empty trash is not an error
+		if errors.Is(err, ErrNotFound) {
+			return make([]EasyStoreTombstone, 0), nil
+		}
+		return nil, err
+	}
+
+	// the trash of every namespace only includes the objects readable in their own
+	if len(namespace) == 0 {
+		tombstones = impl.permittedTombstones(ctx, tombstones, OperationRead)
+	}
+	return tombstones, nil
 }
 
 //
 // private methods
 //
 
-func (impl easyStoreReadonlyImpl) getByKey(namespace string, id string) (EasyStoreObject, error) {
+// the object with the requested components, callers have already authorized the request
+func (impl easyStoreReadonlyImpl) getObject(ctx context.Context, namespace string, id string, which EasyStoreComponents) (EasyStoreObject, error) {
+
+	// get the base object
+	o, err := impl.getByKey(ctx, namespace, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// populate the object and return it
+	return impl.populateObject(ctx, o, which)
+}
+
+func (impl easyStoreReadonlyImpl) getByKey(ctx context.Context, namespace string, id string) (EasyStoreObject, error) {
 
 	logDebug(impl.config.Logger(), fmt.Sprintf("getting ns/oid [%s/%s]", namespace, id))
 
 	// get the base object (always required)
-	o, err := impl.store.GetObjectByKey(DataStoreKey{namespace, id}, FROMCACHE)
+	o, err := impl.store.GetObjectByKey(ctx, DataStoreKey{namespace, id}, FROMCACHE)
 	if err != nil {
 		// known error
 		if errors.Is(err, ErrNotFound) {
@@ -157,7 +392,24 @@ func (impl easyStoreReadonlyImpl) getByKey(namespace string, id string) (EasySto
 	return o, nil
 }
 
-func (impl easyStoreReadonlyImpl) getByKeys(keys []DataStoreKey) ([]EasyStoreObject, error) {
+// the datastore returns objects in its own order, put them back into the order of the keys
+func orderByKeys(objs []EasyStoreObject, keys []DataStoreKey) []EasyStoreObject {
+
+	lookup := make(map[DataStoreKey]EasyStoreObject, len(objs))
+	for _, o := range objs {
+		lookup[DataStoreKey{o.Namespace(), o.Id()}] = o
+	}
+
+	results := make([]EasyStoreObject, 0, len(objs))
+	for _, k := range keys {
+		if o, ok := lookup[k]; ok == true {
+			results = append(results, o)
+		}
+	}
+	return results
+}
+
+func (impl easyStoreReadonlyImpl) getByKeys(ctx context.Context, keys []DataStoreKey) ([]EasyStoreObject, error) {
 
 	if len(keys) > querySplitCount {
 
@@ -169,8 +421,8 @@ func (impl easyStoreReadonlyImpl) getByKeys(keys []DataStoreKey) ([]EasyStoreObj
 		}
 
 		logDebug(impl.config.Logger(), fmt.Sprintf("blocksize too large, splitting at %d", half))
-		obj1, err1 := impl.getByKeys(keys[0:half])
-		obj2, err2 := impl.getByKeys(keys[half:])
+		obj1, err1 := impl.getByKeys(ctx, keys[0:half])
+		obj2, err2 := impl.getByKeys(ctx, keys[half:])
 		obj1 = append(obj1, obj2...)
 		if err1 != nil {
 			return obj1, err1
@@ -179,17 +431,17 @@ func (impl easyStoreReadonlyImpl) getByKeys(keys []DataStoreKey) ([]EasyStoreObj
 		}
 
 	}
-	return impl.store.GetObjectsByKey(keys, FROMCACHE)
+	return impl.store.GetObjectsByKey(ctx, keys, FROMCACHE)
 }
 
-func (impl easyStoreReadonlyImpl) populateObject(obj EasyStoreObject, which EasyStoreComponents) (EasyStoreObject, error) {
+func (impl easyStoreReadonlyImpl) populateObject(ctx context.Context, obj EasyStoreObject, which EasyStoreComponents) (EasyStoreObject, error) {
 
 	// first get the fields (if required)
 	if (which & Fields) == Fields {
 		logDebug(impl.config.Logger(), fmt.Sprintf("getting fields for ns/oid [%s/%s]", obj.Namespace(), obj.Id()))
-		fields, err := impl.store.GetFieldsByKey(DataStoreKey{obj.Namespace(), obj.Id()}, FROMCACHE)
+		fields, err := impl.store.GetFieldsByKey(ctx, DataStoreKey{obj.Namespace(), obj.Id()}, FROMCACHE)
 		if err == nil {
-			obj.SetFields(*fields)
+			obj.SetMultiFields(*fields)
 		} else {
 			// known error
 			if errors.Is(err, ErrNotFound) {
@@ -203,7 +455,7 @@ func (impl easyStoreReadonlyImpl) populateObject(obj EasyStoreObject, which Easy
 	// then, the blobs (if required)
 	if (which & Files) == Files {
 		logDebug(impl.config.Logger(), fmt.Sprintf("getting blobs for ns/oid [%s/%s]", obj.Namespace(), obj.Id()))
-		blobs, err := impl.store.GetBlobsByKey(DataStoreKey{obj.Namespace(), obj.Id()}, FROMCACHE)
+		blobs, err := impl.store.GetBlobsByKey(ctx, DataStoreKey{obj.Namespace(), obj.Id()}, FROMCACHE)
 		if err == nil {
 			obj.SetFiles(blobs)
 		} else {
@@ -219,7 +471,7 @@ func (impl easyStoreReadonlyImpl) populateObject(obj EasyStoreObject, which Easy
 	// lastly the opaque metadata (if required)
 	if (which & Metadata) == Metadata {
 		logDebug(impl.config.Logger(), fmt.Sprintf("getting metadata for ns/oid [%s/%s]", obj.Namespace(), obj.Id()))
-		md, err := impl.store.GetMetadataByKey(DataStoreKey{obj.Namespace(), obj.Id()}, FROMCACHE)
+		md, err := impl.store.GetMetadataByKey(ctx, DataStoreKey{obj.Namespace(), obj.Id()}, FROMCACHE)
 		if err == nil {
 			obj.SetMetadata(md)
 		} else {
@@ -235,6 +487,84 @@ func (impl easyStoreReadonlyImpl) populateObject(obj EasyStoreObject, which Easy
 	return obj, nil
 }
 
+// check the access policy (if there is one) allows the request, denied requests are audited
+func (impl easyStoreReadonlyImpl) authorize(ctx context.Context, namespace string, oid string, operation EasyStoreOperation, which EasyStoreComponents) error {
+
+	policy, audit := configPolicy(impl.config)
+	if policy == nil {
+		return nil
+	}
+
+	principal := PrincipalFromContext(ctx)
+	err := policy.Authorize(principal, namespace, operation, which)
+	if err == nil {
+		return nil
+	}
+
+	logInfo(impl.config.Logger(), fmt.Sprintf("denied %s of ns/oid [%s/%s] for principal [%s]", operation, namespace, oid, principal))
+
+	if audit == nil {
+		audit = NewLoggerAuditLog(impl.config.Logger())
+	}
+	entry := EasyStoreAuditEntry{
+		When:       time.Now(),
+		Principal:  principal,
+		Actor:      ActorFromContext(ctx),
+		Namespace:  namespace,
+		Id:         oid,
+		Operation:  operation,
+		Components: componentNames(which),
+		Reason:     err.Error(),
+	}
+	if aerr := audit.Record(entry); aerr != nil {
+		logError(impl.config.Logger(), fmt.Sprintf("recording audit entry (%s)", aerr.Error()))
+	}
+	return err
+}
+
+// the keys the principal is permitted the operation on in their own namespace, the others are left out
+// without an audit entry because they were not asked for by name
+func (impl easyStoreReadonlyImpl) permittedKeys(ctx context.Context, keys []DataStoreKey, operation EasyStoreOperation, which EasyStoreComponents) []DataStoreKey {
+
+	policy, _ := configPolicy(impl.config)
+	if policy == nil {
+		return keys
+	}
+
+	principal := PrincipalFromContext(ctx)
+	permitted := make([]DataStoreKey, 0, len(keys))
+	for _, key := range keys {
+		if policy.Authorize(principal, key.Namespace, operation, which) == nil {
+			permitted = append(permitted, key)
+		}
+	}
+	if len(permitted) != len(keys) {
+		logDebug(impl.config.Logger(), fmt.Sprintf("left out %d object(s) for principal [%s]", len(keys)-len(permitted), principal))
+	}
+	return permitted
+}
+
+// the tombstones the principal is permitted the operation on in their own namespace
+func (impl easyStoreReadonlyImpl) permittedTombstones(ctx context.Context, tombstones []EasyStoreTombstone, operation EasyStoreOperation) []EasyStoreTombstone {
+
+	keys := make([]DataStoreKey, 0, len(tombstones))
+	for _, t := range tombstones {
+		keys = append(keys, DataStoreKey{t.Namespace, t.Id})
+	}
+	permitted := make(map[DataStoreKey]bool)
+	for _, key := range impl.permittedKeys(ctx, keys, operation, BaseComponent) {
+		permitted[key] = true
+	}
+
+	results := make([]EasyStoreTombstone, 0, len(tombstones))
+	for _, t := range tombstones {
+		if permitted[DataStoreKey{t.Namespace, t.Id}] == true {
+			results = append(results, t)
+		}
+	}
+	return results
+}
+
 //
 // end of file
 //
@@ -4,73 +4,176 @@
 
 package uvaeasystore
 
-//func TestRenameFiles(t *testing.T) {
-//	es := testSetup(t)
-//	defer es.Close()
-//	o := NewEasyStoreObject(goodNamespace, "")
-//
-//	// add some files
-//	f1 := newBinaryBlob("file1.bin")
-//	f2 := newBinaryBlob("file2.bin")
-//	files := []EasyStoreBlob{f1, f2}
-//	o.SetFiles(files)
-//
-//	// create it
-//	o, err := es.ObjectCreate(o)
-//	if err != nil {
-//		t.Fatalf("expected 'OK' but got '%s'\n", err)
-//	}
-//
-//	if len(o.Files()) != 2 {
-//		t.Fatalf("missing object files\n")
-//	}
-//
-//	// rename a non existent file
-//	expected := ErrNotFound
-//	_, err = es.Rename(o, Files, "file99.bin", "file100.bin")
-//	if errors.Is(err, expected) == false {
-//		t.Fatalf("expected '%s' but got '%s'\n", expected, err)
-//	}
-//
-//	// rename a duplicate file
-//	expected = ErrAlreadyExists
-//	_, err = es.Rename(o, Files, "file1.bin", "file2.bin")
-//	if errors.Is(err, expected) == false {
-//		t.Fatalf("expected '%s' but got '%s'\n", expected, err)
-//	}
-//
-//	// correct file rename
-//	after, err := es.Rename(o, Files, "file1.bin", "file3.bin")
-//	if err != nil {
-//		t.Fatalf("expected 'OK' but got '%s'\n", err)
-//	}
-//
-//	files = after.Files()
-//	if len(after.Files()) != 2 {
-//		t.Fatalf("missing object files\n")
-//	}
-//
-//	//fmt.Printf("Name 0: [%s]\n", files[0].Name())
-//	//fmt.Printf("Name 1: [%s]\n", files[1].Name())
-//	if files[0].Name() == "file2.bin" {
-//		if files[1].Name() != "file3.bin" {
-//			t.Fatalf("expected 'file3.bin' but got '%s'\n", files[1].Name())
-//		}
-//	} else {
-//		if files[0].Name() == "file3.bin" {
-//			if files[1].Name() != "file2.bin" {
-//				t.Fatalf("expected 'file2.bin' but got '%s'\n", files[1].Name())
-//			}
-//		} else {
-//			t.Fatalf("unexpected name, got '%s'\n", files[0].Name())
-//		}
-//	}
-//
-//	// check the vtags are updated
-//	if o.VTag() == after.VTag() {
-//		t.Fatalf("object vtags are equal but should not be\n")
-//	}
-//}
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestRenameFiles(t *testing.T) {
+	es := testSetup(t)
+	defer es.Close()
+	o := NewEasyStoreObject(goodNamespace, "")
+
+	// add some files
+	f1 := newBinaryBlob("file1.bin")
+	f2 := newBinaryBlob("file2.bin")
+	files := []EasyStoreBlob{f1, f2}
+	o.SetFiles(files)
+	o.SetMetadata(newEasyStoreMetadata("application/json", jsonPayload))
+
+	// create it
+	o, err := es.ObjectCreate(o)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	if len(o.Files()) != 2 {
+		t.Fatalf("missing object files\n")
+	}
+
+	// rename a non existent file
+	expected := ErrFileNotFound
+	err = es.FileRename(o.Namespace(), o.Id(), "file99.bin", "file100.bin")
+	if errors.Is(err, expected) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", expected, err)
+	}
+
+	// rename a duplicate file
+	expected = ErrAlreadyExists
+	err = es.FileRename(o.Namespace(), o.Id(), "file1.bin", "file2.bin")
+	if errors.Is(err, expected) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", expected, err)
+	}
+
+	// correct file rename
+	err = es.FileRename(o.Namespace(), o.Id(), "file1.bin", "file3.bin")
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	after, err := es.ObjectGetByKey(o.Namespace(), o.Id(), AllComponents)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	files = after.Files()
+	if len(after.Files()) != 2 {
+		t.Fatalf("missing object files\n")
+	}
+
+	//fmt.Printf("Name 0: [%s]\n", files[0].Name())
+	//fmt.Printf("Name 1: [%s]\n", files[1].Name())
+	if files[0].Name() == "file2.bin" {
+		if files[1].Name() != "file3.bin" {
+			t.Fatalf("expected 'file3.bin' but got '%s'\n", files[1].Name())
+		}
+	} else {
+		if files[0].Name() == "file3.bin" {
+			if files[1].Name() != "file2.bin" {
+				t.Fatalf("expected 'file2.bin' but got '%s'\n", files[1].Name())
+			}
+		} else {
+			t.Fatalf("unexpected name, got '%s'\n", files[0].Name())
+		}
+	}
+
+	// the contents moved with the name
+	renamed, err := es.FileGetByKey(o.Namespace(), o.Id(), "file3.bin")
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	buf, _ := f1.Payload()
+	if bytes.Equal(buf, getBlobContents(t, renamed)) == false {
+		t.Fatalf("file payloads are unequal but should be\n")
+	}
+
+	// the metadata is unaffected
+	if after.Metadata() == nil {
+		t.Fatalf("missing object metadata\n")
+	}
+
+	// check the vtags are updated
+	if o.VTag() == after.VTag() {
+		t.Fatalf("object vtags are equal but should not be\n")
+	}
+}
+
+func TestRenameReservedName(t *testing.T) {
+
+	// the database datastores keep the metadata as a blob with a reserved name
+	if datastore != "sqlite" && datastore != "postgres" {
+		t.Skip("the reserved name only applies to the database datastores")
+	}
+
+	es := testSetup(t)
+	defer es.Close()
+	o := NewEasyStoreObject(goodNamespace, "")
+	o.SetFiles([]EasyStoreBlob{newBinaryBlob("file1.bin")})
+	o.SetMetadata(newEasyStoreMetadata("application/json", jsonPayload))
+
+	// create it
+	o, err := es.ObjectCreate(o)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	// a file cannot take the reserved name
+	expected := ErrBadParameter
+	err = es.FileRename(o.Namespace(), o.Id(), "file1.bin", blobMetadataName)
+	if errors.Is(err, expected) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", expected, err)
+	}
+
+	// and the metadata is not a file
+	expected = ErrFileNotFound
+	err = es.FileRename(o.Namespace(), o.Id(), blobMetadataName, "file2.bin")
+	if errors.Is(err, expected) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", expected, err)
+	}
+
+	// neither changed
+	after, err := es.ObjectGetByKey(o.Namespace(), o.Id(), AllComponents)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	if len(after.Files()) != 1 || after.Files()[0].Name() != "file1.bin" {
+		t.Fatalf("unexpected object files\n")
+	}
+	if after.Metadata() == nil {
+		t.Fatalf("missing object metadata\n")
+	}
+}
+
+func TestRenameEscapedName(t *testing.T) {
+	es := testSetup(t)
+	defer es.Close()
+	o := NewEasyStoreObject(goodNamespace, "")
+
+	// names the S3 datastore must encode when copying
+	f1 := newBinaryBlob("my file+1 %20.bin")
+	o.SetFiles([]EasyStoreBlob{f1})
+
+	// create it
+	o, err := es.ObjectCreate(o)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	err = es.FileRename(o.Namespace(), o.Id(), f1.Name(), "my file#2?.bin")
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	renamed, err := es.FileGetByKey(o.Namespace(), o.Id(), "my file#2?.bin")
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	buf, _ := f1.Payload()
+	if bytes.Equal(buf, getBlobContents(t, renamed)) == false {
+		t.Fatalf("file payloads are unequal but should be\n")
+	}
+}
 
 //
 // end of file
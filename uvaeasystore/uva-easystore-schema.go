@@ -0,0 +1,210 @@
+//
+// namespace schemas, the rules the objects in a namespace must follow. They are checked in
+// preflight so a bad object is refused before anything is written
+//
+
+package uvaeasystore
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// the longest names the datastores can hold, they match the database columns
+const (
+	MaxNamespaceLength = 32  // objects.namespace
+	MaxObjectIdLength  = 64  // objects.oid
+	MaxFieldNameLength = 32  // fields.name
+	MaxFileNameLength  = 256 // blobs.name
+	MaxMimeTypeLength  = 32  // blobs.mimetype
+)
+
+// EasyStoreSchema - the rules for the objects in a namespace
+type EasyStoreSchema struct {
+	Namespace   string                          `json:"namespace"`    // the namespace the rules apply to
+	Fields      map[string]EasyStoreFieldSchema `json:"fields"`       // the known fields, by name
+	OtherFields bool                            `json:"other_fields"` // are fields that are not known allowed
+	MimeTypes   []string                        `json:"mime_types"`   // the file mime types allowed, "type/*" for any subtype (empty for any)
+}
+
+// EasyStoreFieldSchema - the rules for a field
+type EasyStoreFieldSchema struct {
+	Required  bool     `json:"required"`   // must the object have the field
+	MaxLength int      `json:"max_length"` // the longest value (0 for no limit)
+	Pattern   string   `json:"pattern"`    // a regular expression the whole value must match (blank for any)
+	Values    []string `json:"values"`     // the values allowed (empty for any)
+}
+
+// RegisterSchema - register the schema for a namespace, it replaces any existing one
+func RegisterSchema(schema EasyStoreSchema) error {
+
+	// validate the schema
+	if len(schema.Namespace) == 0 || len(schema.Namespace) > MaxNamespaceLength {
+		return fmt.Errorf("%q: %w", fmt.Sprintf("bad schema namespace [%s]", schema.Namespace), ErrBadParameter)
+	}
+	patterns := make(map[string]*regexp.Regexp)
+	for name, field := range schema.Fields {
+		if len(name) == 0 || len(name) > MaxFieldNameLength {
+			return fmt.Errorf("%q: %w", fmt.Sprintf("bad schema field name [%s]", name), ErrBadParameter)
+		}
+		if field.MaxLength < 0 {
+			return fmt.Errorf("%q: %w", fmt.Sprintf("bad schema field [%s] max length", name), ErrBadParameter)
+		}
+		if len(field.Pattern) != 0 {
+			re, err := regexp.Compile(fmt.Sprintf("^(?:%s)$", field.Pattern))
+			if err != nil {
+				return fmt.Errorf("%q: %w", fmt.Sprintf("bad schema field [%s] pattern (%s)", name, err.Error()), ErrBadParameter)
+			}
+			patterns[name] = re
+		}
+	}
+
+	schemas.Lock()
+	defer schemas.Unlock()
+	schemas.byNamespace[schema.Namespace] = &namespaceSchema{schema: schema, patterns: patterns}
+	return nil
+}
+
+// UnregisterSchema - remove the schema for a namespace, the objects in it are no longer checked
+func UnregisterSchema(namespace string) {
+	schemas.Lock()
+	defer schemas.Unlock()
+	delete(schemas.byNamespace, namespace)
+}
+
+//
+// private implementation methods
+//
+
+// a registered schema and its compiled patterns
+type namespaceSchema struct {
+	schema   EasyStoreSchema
+	patterns map[string]*regexp.Regexp
+}
+
+// the registered schemas
+var schemas = struct {
+	sync.RWMutex
+	byNamespace map[string]*namespaceSchema
+}{byNamespace: make(map[string]*namespaceSchema)}
+
+// the schema for the namespace (nil if there is none)
+func schemaFor(namespace string) *namespaceSchema {
+	schemas.RLock()
+	defer schemas.RUnlock()
+	return schemas.byNamespace[namespace]
+}
+
+// validate an object key
+func keyPreflight(namespace string, oid string) error {
+	if len(namespace) > MaxNamespaceLength {
+		return fmt.Errorf("%q: %w", fmt.Sprintf("namespace [%s] is longer than %d", namespace, MaxNamespaceLength), ErrBadParameter)
+	}
+	if len(oid) > MaxObjectIdLength {
+		return fmt.Errorf("%q: %w", fmt.Sprintf("identifier [%s] is longer than %d", oid, MaxObjectIdLength), ErrBadParameter)
+	}
+	return nil
+}
+
+// validate the complete set of fields for an object in the namespace
+func fieldsPreflight(namespace string, fields EasyStoreObjectMultiFields) error {
+
+	for name := range fields {
+		if len(name) > MaxFieldNameLength {
+			return fmt.Errorf("%q: %w", fmt.Sprintf("field name [%s] is longer than %d", name, MaxFieldNameLength), ErrBadParameter)
+		}
+	}
+
+	s := schemaFor(namespace)
+	if s == nil {
+		return nil
+	}
+
+	// the required fields
+	for name, field := range s.schema.Fields {
+		if _, found := fields[name]; found == false && field.Required == true {
+			return fmt.Errorf("%q: %w", fmt.Sprintf("field [%s] is required in namespace [%s]", name, namespace), ErrBadParameter)
+		}
+	}
+
+	// and the values, each one of them
+	for name, values := range fields {
+		field, found := s.schema.Fields[name]
+		if found == false {
+			if s.schema.OtherFields == false {
+				return fmt.Errorf("%q: %w", fmt.Sprintf("field [%s] is not allowed in namespace [%s]", name, namespace), ErrBadParameter)
+			}
+			continue
+		}
+		for _, value := range values {
+			if err := fieldValuePreflight(name, field, s.patterns[name], value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validate a field value
+func fieldValuePreflight(name string, field EasyStoreFieldSchema, re *regexp.Regexp, value string) error {
+	if field.MaxLength != 0 && len(value) > field.MaxLength {
+		return fmt.Errorf("%q: %w", fmt.Sprintf("field [%s] is longer than %d", name, field.MaxLength), ErrBadParameter)
+	}
+	if re != nil && re.MatchString(value) == false {
+		return fmt.Errorf("%q: %w", fmt.Sprintf("field [%s] value [%s] does not match [%s]", name, value, field.Pattern), ErrBadParameter)
+	}
+	if len(field.Values) != 0 && contains(field.Values, value) == false {
+		return fmt.Errorf("%q: %w", fmt.Sprintf("field [%s] value [%s] is not one of [%s]", name, value, strings.Join(field.Values, ", ")), ErrBadParameter)
+	}
+	return nil
+}
+
+// validate that an object in the namespace can have its fields removed
+func fieldsDeletePreflight(namespace string) error {
+	return fieldsPreflight(namespace, EasyStoreObjectMultiFields{})
+}
+
+// validate files for an object in the namespace
+func filesPreflight(namespace string, files []EasyStoreBlob) error {
+
+	s := schemaFor(namespace)
+	for _, file := range files {
+		if len(file.Name()) > MaxFileNameLength {
+			return fmt.Errorf("%q: %w", fmt.Sprintf("file name [%s] is longer than %d", file.Name(), MaxFileNameLength), ErrBadParameter)
+		}
+		if len(file.MimeType()) > MaxMimeTypeLength {
+			return fmt.Errorf("%q: %w", fmt.Sprintf("file [%s] mime type [%s] is longer than %d", file.Name(), file.MimeType(), MaxMimeTypeLength), ErrBadParameter)
+		}
+		if s != nil && len(s.schema.MimeTypes) != 0 && mimeTypeAllowed(s.schema.MimeTypes, file.MimeType()) == false {
+			return fmt.Errorf("%q: %w", fmt.Sprintf("file [%s] mime type [%s] is not allowed in namespace [%s]", file.Name(), file.MimeType(), namespace), ErrBadParameter)
+		}
+	}
+	return nil
+}
+
+func mimeTypeAllowed(allowed []string, mimeType string) bool {
+	for _, a := range allowed {
+		if a == mimeType {
+			return true
+		}
+		if strings.HasSuffix(a, "/*") == true && strings.HasPrefix(mimeType, strings.TrimSuffix(a, "*")) == true {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+//
+// end of file
+//
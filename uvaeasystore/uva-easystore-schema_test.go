@@ -0,0 +1,176 @@
+//
+//
+//
+
+package uvaeasystore
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+var schemaNamespace = "test-namespace-schema"
+
+var testSchema = EasyStoreSchema{
+	Namespace: schemaNamespace,
+	Fields: map[string]EasyStoreFieldSchema{
+		"title":  {Required: true, MaxLength: 16},
+		"year":   {Pattern: "[0-9]{4}"},
+		"status": {Values: []string{"draft", "published"}},
+	},
+	MimeTypes: []string{"application/octet-stream", "image/*"},
+}
+
+func TestSchemaRegister(t *testing.T) {
+	bad := EasyStoreSchema{Namespace: schemaNamespace, Fields: map[string]EasyStoreFieldSchema{"year": {Pattern: "[0-9"}}}
+	err := RegisterSchema(bad)
+	if errors.Is(err, ErrBadParameter) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", ErrBadParameter, err)
+	}
+	err = RegisterSchema(EasyStoreSchema{})
+	if errors.Is(err, ErrBadParameter) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", ErrBadParameter, err)
+	}
+}
+
+func TestSchemaCreate(t *testing.T) {
+	es := testSetup(t)
+	defer es.Close()
+	if err := RegisterSchema(testSchema); err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	defer UnregisterSchema(schemaNamespace)
+
+	for _, fields := range []EasyStoreObjectFields{
+		{"year": "2024"},                               // required field missing
+		{"title": "a title", "year": "24"},             // pattern mismatch
+		{"title": "a title", "status": "unknown"},      // not an allowed value
+		{"title": "a title that is far too long"},      // too long
+		{"title": "a title", "other": "not in schema"}, // unknown field
+	} {
+		o := NewEasyStoreObject(schemaNamespace, "")
+		o.SetFields(fields)
+		_, err := es.ObjectCreate(o)
+		if errors.Is(err, ErrBadParameter) == false {
+			t.Fatalf("expected '%s' but got '%s' for %v\n", ErrBadParameter, err, fields)
+		}
+	}
+
+	// a file with a mime type that is not allowed
+	o := NewEasyStoreObject(schemaNamespace, "")
+	o.SetFields(EasyStoreObjectFields{"title": "a title"})
+	o.SetFiles([]EasyStoreBlob{NewEasyStoreBlob("file1.txt", "text/plain", []byte("text"))})
+	_, err := es.ObjectCreate(o)
+	if errors.Is(err, ErrBadParameter) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", ErrBadParameter, err)
+	}
+
+	// and a good one
+	o.SetFields(EasyStoreObjectFields{"title": "a title", "year": "2024", "status": "draft"})
+	o.SetFiles([]EasyStoreBlob{newBinaryBlob("file1.bin"), NewEasyStoreBlob("file2.png", "image/png", []byte("png"))})
+	_, err = es.ObjectCreate(o)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+}
+
+func TestSchemaUpdate(t *testing.T) {
+	es := testSetup(t)
+	defer es.Close()
+	if err := RegisterSchema(testSchema); err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	defer UnregisterSchema(schemaNamespace)
+
+	o := NewEasyStoreObject(schemaNamespace, "")
+	o.SetFields(EasyStoreObjectFields{"title": "a title"})
+	o, err := es.ObjectCreate(o)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	// the fields are checked when they are updated
+	o.SetFields(EasyStoreObjectFields{"year": "2024"})
+	_, err = es.ObjectUpdate(o, Fields)
+	if errors.Is(err, ErrBadParameter) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", ErrBadParameter, err)
+	}
+
+	// but not when they are not
+	o.SetMetadata(newEasyStoreMetadata("application/json", jsonPayload))
+	o, err = es.ObjectUpdate(o, Metadata)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	// the fields are required so cannot be removed
+	_, err = es.ObjectDelete(o, Fields)
+	if errors.Is(err, ErrBadParameter) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", ErrBadParameter, err)
+	}
+
+	// files are checked when they are added
+	err = es.FileCreate(schemaNamespace, o.Id(), NewEasyStoreBlob("file1.txt", "text/plain", []byte("text")))
+	if errors.Is(err, ErrBadParameter) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", ErrBadParameter, err)
+	}
+	err = es.FileCreate(schemaNamespace, o.Id(), newBinaryBlob("file1.bin"))
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+}
+
+func TestSchemaCopy(t *testing.T) {
+	es := testSetup(t)
+	defer es.Close()
+	if err := RegisterSchema(testSchema); err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	defer UnregisterSchema(schemaNamespace)
+
+	// an object without the required fields
+	o := NewEasyStoreObject(goodNamespace, "")
+	o.SetFields(EasyStoreObjectFields{"year": "2024"})
+	o, err := es.ObjectCreate(o)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	_, err = es.ObjectCopy(goodNamespace, o.Id(), schemaNamespace, "")
+	if errors.Is(err, ErrBadParameter) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", ErrBadParameter, err)
+	}
+
+	// and one with them
+	o.SetFields(EasyStoreObjectFields{"title": "a title", "year": "2024"})
+	o, err = es.ObjectUpdate(o, Fields)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	_, err = es.ObjectCopy(goodNamespace, o.Id(), schemaNamespace, "")
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+}
+
+func TestSchemaLimits(t *testing.T) {
+	es := testSetup(t)
+	defer es.Close()
+
+	// the storage limits apply without a schema
+	o := NewEasyStoreObject(goodNamespace, "")
+	o.SetFields(EasyStoreObjectFields{strings.Repeat("x", MaxFieldNameLength+1): "value"})
+	_, err := es.ObjectCreate(o)
+	if errors.Is(err, ErrBadParameter) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", ErrBadParameter, err)
+	}
+	o = NewEasyStoreObject(goodNamespace, strings.Repeat("x", MaxObjectIdLength+1))
+	_, err = es.ObjectCreate(o)
+	if errors.Is(err, ErrBadParameter) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", ErrBadParameter, err)
+	}
+}
+
+//
+// end of file
+//
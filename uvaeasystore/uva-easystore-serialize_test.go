@@ -6,6 +6,7 @@ package uvaeasystore
 
 import (
 	"bytes"
+	"reflect"
 	"testing"
 )
 
@@ -174,6 +175,31 @@ func TestMetadataDeserialize(t *testing.T) {
 	}
 }
 
+func TestMultiFieldsDeserialize(t *testing.T) {
+	fields := EasyStoreObjectMultiFields{"field1": {"value1", "value2"}, "field2": {"value3"}}
+	serializer := DefaultEasyStoreSerializer()
+
+	// serialize and deserialize
+	i := serializer.MultiFieldsSerialize(fields)
+	c, err := serializer.MultiFieldsDeserialize(i)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	if reflect.DeepEqual(fields, c) == false {
+		t.Fatalf("expected '%v' but got '%v'\n", fields, c)
+	}
+
+	// single valued fields are read as fields with one value
+	i = serializer.FieldsSerialize(EasyStoreObjectFields{"field1": "value1"})
+	c, err = serializer.MultiFieldsDeserialize(i)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	if reflect.DeepEqual(EasyStoreObjectMultiFields{"field1": {"value1"}}, c) == false {
+		t.Fatalf("expected one value but got '%v'\n", c)
+	}
+}
+
 //
 // end of file
 //
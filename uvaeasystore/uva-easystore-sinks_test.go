@@ -0,0 +1,172 @@
+//
+//
+//
+
+package uvaeasystore
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/uvalib/librabus-sdk/uvalibrabus"
+)
+
+func TestChannelSink(t *testing.T) {
+	events := make(chan EasyStoreEvent, 10)
+	es, err := NewEasyStore(DatastoreMemoryConfig{Sink: NewChannelEventSink(events)})
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	defer es.Close()
+
+	o, err := es.ObjectCreate(NewEasyStoreObject(goodNamespace, ""))
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	ev := <-events
+	testEqual(t, uvalibrabus.EventObjectCreate, ev.Name)
+	testEqual(t, goodNamespace, ev.Namespace)
+	testEqual(t, o.Id(), ev.ObjectId)
+}
+
+func TestImplConfigOptional(t *testing.T) {
+	// a configuration without an event sink or a message bus has no sink
+	sink, err := NewEventSink(testImplConfig{})
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	if sink != nil {
+		t.Fatalf("expected no event sink\n")
+	}
+
+	// and no access policy
+	if policy, _ := configPolicy(testImplConfig{}); policy != nil {
+		t.Fatalf("expected no access policy\n")
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "events.json")
+	sink, err := NewFileEventSink(filename)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	es, err := NewEasyStore(DatastoreMemoryConfig{Sink: sink})
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	defer es.Close()
+
+	o, err := es.ObjectCreate(NewEasyStoreObject(goodNamespace, ""))
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	_, err = es.ObjectDelete(o, BaseComponent)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	err = sink.Close()
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	// one event per line
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	defer file.Close()
+	names := make([]string, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var ev EasyStoreEvent
+		err = json.Unmarshal(scanner.Bytes(), &ev)
+		if err != nil {
+			t.Fatalf("expected 'OK' but got '%s'\n", err)
+		}
+		testEqual(t, o.Id(), ev.ObjectId)
+		names = append(names, ev.Name)
+	}
+	if len(names) != 2 {
+		t.Fatalf("expected 2 events but got %d\n", len(names))
+	}
+	testEqual(t, uvalibrabus.EventObjectCreate, names[0])
+	testEqual(t, uvalibrabus.EventObjectDelete, names[1])
+}
+
+func TestWebhookSink(t *testing.T) {
+	secret := "the-secret"
+	received := make([]EasyStoreEvent, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if VerifyEventPayload(secret, body, r.Header.Get(WebhookSignatureHeader)) == false {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var ev EasyStoreEvent
+		_ = json.Unmarshal(body, &ev)
+		testEqual(t, ev.Name, r.Header.Get(WebhookEventHeader))
+		received = append(received, ev)
+	}))
+	defer server.Close()
+
+	es, err := NewEasyStore(DatastoreMemoryConfig{Sink: NewWebhookEventSink(server.URL, secret, 5)})
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	defer es.Close()
+
+	o, err := es.ObjectCreate(NewEasyStoreObject(goodNamespace, ""))
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	if len(received) != 1 {
+		t.Fatalf("expected 1 event but got %d\n", len(received))
+	}
+	testEqual(t, o.Id(), received[0].ObjectId)
+
+	// a bad signature is refused, the event stays in the outbox
+	store, err := NewDatastore(DatastoreMemoryConfig{Name: t.Name()})
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	defer store.Close()
+	bad, err := NewEasyStore(DatastoreMemoryConfig{Name: t.Name(), Sink: NewWebhookEventSink(server.URL, "wrong-secret", 5)})
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	defer bad.Close()
+	_, err = bad.ObjectCreate(NewEasyStoreObject(goodNamespace, ""))
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	pending, err := store.GetPendingEvents(context.Background(), 0, 0)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	if len(pending) != 1 || len(received) != 1 {
+		t.Fatalf("expected 1 pending event but got %d (%d received)\n", len(pending), len(received))
+	}
+}
+
+// an implementation configuration with only the required settings
+type testImplConfig struct{}
+
+func (c testImplConfig) Logger() *log.Logger   { return nil }
+func (c testImplConfig) SetLogger(*log.Logger) {}
+func (c testImplConfig) MessageBus() string    { return "" }
+func (c testImplConfig) SetMessageBus(string)  {}
+func (c testImplConfig) EventSource() string   { return "" }
+func (c testImplConfig) SetEventSource(string) {}
+
+//
+// end of file
+//
@@ -0,0 +1,199 @@
+//
+//
+//
+
+package uvaeasystore
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestTransactionCommit(t *testing.T) {
+	ds := testDatastoreSetup(t)
+	defer ds.Close()
+	ctx := context.Background()
+	o := NewEasyStoreObject(goodNamespace, "")
+	key := DataStoreKey{o.Namespace(), o.Id()}
+
+	tx, err := ds.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	defer tx.Close()
+
+	// add the object and some fields
+	err = tx.AddObject(ctx, o)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	err = tx.AddFields(ctx, key, EasyStoreObjectMultiFields{"field1": {"value1"}})
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	// the object and its fields should exist, both in storage and in the cache
+	for _, useCache := range []bool{NOCACHE, FROMCACHE} {
+		_, err = ds.GetObjectByKey(ctx, key, useCache)
+		if err != nil {
+			t.Fatalf("expected 'OK' but got '%s'\n", err)
+		}
+		fields, err := ds.GetFieldsByKey(ctx, key, useCache)
+		if err != nil {
+			t.Fatalf("expected 'OK' but got '%s'\n", err)
+		}
+		testEqual(t, "value1", (*fields)["field1"][0])
+	}
+
+	// a rollback after commit does nothing
+	err = tx.Rollback()
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	_, err = ds.GetObjectByKey(ctx, key, NOCACHE)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+}
+
+func TestTransactionRollback(t *testing.T) {
+	ds := testDatastoreSetup(t)
+	defer ds.Close()
+	ctx := context.Background()
+	o := NewEasyStoreObject(goodNamespace, "")
+	key := DataStoreKey{o.Namespace(), o.Id()}
+
+	tx, err := ds.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	defer tx.Close()
+
+	// add the object, some fields and a file
+	err = tx.AddObject(ctx, o)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	err = tx.AddFields(ctx, key, EasyStoreObjectMultiFields{"field1": {"value1"}})
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	err = tx.AddBlob(ctx, key, newBinaryBlob("file1.bin"))
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	err = tx.Rollback()
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	// none of it should exist
+	expected := ErrNotFound
+	for _, useCache := range []bool{NOCACHE, FROMCACHE} {
+		_, err = ds.GetObjectByKey(ctx, key, useCache)
+		if errors.Is(err, expected) == false {
+			t.Fatalf("expected '%s' but got '%s'\n", expected, err)
+		}
+		_, err = ds.GetFieldsByKey(ctx, key, useCache)
+		if errors.Is(err, expected) == false {
+			t.Fatalf("expected '%s' but got '%s'\n", expected, err)
+		}
+	}
+	_, err = ds.GetBlobsByKey(ctx, key, NOCACHE)
+	if errors.Is(err, expected) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", expected, err)
+	}
+}
+
+func TestTransactionRollbackRestores(t *testing.T) {
+	es := testSetup(t)
+	defer es.Close()
+	ds := testDatastoreSetup(t)
+	defer ds.Close()
+	ctx := context.Background()
+
+	o := NewEasyStoreObject(goodNamespace, "")
+	o.SetFields(EasyStoreObjectFields{"field1": "value1"})
+	o, err := es.ObjectCreate(o)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	key := DataStoreKey{o.Namespace(), o.Id()}
+
+	tx, err := ds.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	// change and delete things
+	err = tx.UpdateFields(ctx, key, EasyStoreObjectMultiFields{"field1": {"value2"}})
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	err = tx.DeleteObjectByKey(ctx, key)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	// closing an uncommitted transaction rolls it back
+	err = tx.Close()
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	// the original object should be unchanged
+	after, err := es.ObjectGetByKey(o.Namespace(), o.Id(), Fields)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	testEqual(t, o.VTag(), after.VTag())
+	testEqual(t, "value1", after.Fields()["field1"])
+	for _, useCache := range []bool{NOCACHE, FROMCACHE} {
+		fields, err := ds.GetFieldsByKey(ctx, key, useCache)
+		if err != nil {
+			t.Fatalf("expected 'OK' but got '%s'\n", err)
+		}
+		testEqual(t, "value1", (*fields)["field1"][0])
+	}
+}
+
+func TestDuplicateObjectCreateUnchanged(t *testing.T) {
+	es := testSetup(t)
+	defer es.Close()
+	o := NewEasyStoreObject(goodNamespace, "")
+
+	// create the new object
+	o, err := es.ObjectCreate(o)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	// a failed create must not change the existing object
+	dup := NewEasyStoreObject(o.Namespace(), o.Id())
+	dup.SetFields(EasyStoreObjectFields{"field1": "value1"})
+	expected := ErrAlreadyExists
+	_, err = es.ObjectCreate(dup)
+	if errors.Is(err, expected) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", expected, err)
+	}
+
+	after, err := es.ObjectGetByKey(o.Namespace(), o.Id(), AllComponents)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	testEqual(t, o.VTag(), after.VTag())
+	if len(after.Fields()) != 0 {
+		t.Fatalf("unexpected object fields\n")
+	}
+}
+
+//
+// end of file
+//
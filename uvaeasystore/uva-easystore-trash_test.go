@@ -0,0 +1,172 @@
+//
+//
+//
+
+package uvaeasystore
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDeleteRestore(t *testing.T) {
+	es := testSetup(t)
+	defer es.Close()
+	o := NewEasyStoreObject(goodNamespace, "")
+	fields := EasyStoreObjectFields{"restore": o.Id()}
+	o.SetFields(fields)
+
+	// create the new object
+	_, err := es.ObjectCreate(o)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	// delete it
+	_, err = es.ObjectDelete(o, BaseComponent)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	// it is hidden
+	_, err = es.ObjectGetByKey(goodNamespace, o.Id(), BaseComponent)
+	if errors.Is(err, ErrNotFound) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", ErrNotFound, err)
+	}
+	set, err := es.ObjectGetByFields(goodNamespace, fields, BaseComponent)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	if set.Count() != 0 {
+		t.Fatalf("expected 0 objects but got %d\n", set.Count())
+	}
+
+	// but in the trash
+	trash, err := es.ObjectTrash(goodNamespace)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	found := false
+	for _, ts := range trash {
+		if ts.Id == o.Id() {
+			found = true
+		}
+	}
+	if found == false {
+		t.Fatalf("deleted object is not in the trash\n")
+	}
+
+	// and the identifier is still in use
+	_, err = es.ObjectCreate(NewEasyStoreObject(goodNamespace, o.Id()))
+	if errors.Is(err, ErrAlreadyExists) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", ErrAlreadyExists, err)
+	}
+
+	// restore it, the components are intact
+	after, err := es.ObjectRestore(goodNamespace, o.Id())
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	ensureObjectHasFields(t, after, fields)
+
+	// restoring again fails
+	_, err = es.ObjectRestore(goodNamespace, o.Id())
+	if errors.Is(err, ErrNotFound) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", ErrNotFound, err)
+	}
+}
+
+func TestDeletePurge(t *testing.T) {
+	es := testSetup(t)
+	defer es.Close()
+	o := NewEasyStoreObject(goodNamespace, "")
+	o.SetFiles([]EasyStoreBlob{newBinaryBlob("file1.bin")})
+
+	// create the new object
+	obj, err := es.ObjectCreate(o)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	// live objects cannot be purged
+	err = es.ObjectPurge(goodNamespace, o.Id())
+	if errors.Is(err, ErrNotFound) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", ErrNotFound, err)
+	}
+
+	// delete it
+	_, err = es.ObjectDelete(obj, BaseComponent)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	// purge it
+	err = es.ObjectPurge(goodNamespace, o.Id())
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	// it cannot be restored
+	_, err = es.ObjectRestore(goodNamespace, o.Id())
+	if errors.Is(err, ErrNotFound) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", ErrNotFound, err)
+	}
+
+	// and the identifier can be used again
+	_, err = es.ObjectCreate(NewEasyStoreObject(goodNamespace, o.Id()))
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+}
+
+func TestPurgeTrash(t *testing.T) {
+	es := testSetup(t)
+	defer es.Close()
+	o := NewEasyStoreObject(goodNamespace, "")
+
+	// create and delete the new object
+	obj, err := es.ObjectCreate(o)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	_, err = es.ObjectDelete(obj, BaseComponent)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	// nothing is older than the retention window
+	_, err = es.ObjectPurgeTrash(goodNamespace, time.Now().Add(-DefaultTrashRetention))
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	_, err = es.ObjectRestore(goodNamespace, o.Id())
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	// delete it again and purge everything
+	obj, err = es.ObjectGetByKey(goodNamespace, o.Id(), BaseComponent)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	_, err = es.ObjectDelete(obj, BaseComponent)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	count, err := es.ObjectPurgeTrash(goodNamespace, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	if count == 0 {
+		t.Fatalf("expected purged objects but got none\n")
+	}
+	_, err = es.ObjectRestore(goodNamespace, o.Id())
+	if errors.Is(err, ErrNotFound) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", ErrNotFound, err)
+	}
+}
+
+//
+// end of file
+//
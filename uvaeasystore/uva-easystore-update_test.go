@@ -5,6 +5,11 @@
 package uvaeasystore
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
 	"testing"
 )
 
@@ -96,6 +101,138 @@ func TestUpdateFiles(t *testing.T) {
 	}
 }
 
+func TestUpdateFilesChanged(t *testing.T) {
+	es := testSetup(t)
+	defer es.Close()
+	o := NewEasyStoreObject(goodNamespace, "")
+
+	// create the new object with some files
+	o.SetFiles([]EasyStoreBlob{newBinaryBlob("file1.bin"), newBinaryBlob("file2.bin"), newBinaryBlob("file3.bin")})
+	_, err := es.ObjectCreate(o)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	before, err := es.ObjectGetByKey(goodNamespace, o.Id(), AllComponents)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	current := make(map[string]EasyStoreBlob)
+	for _, f := range before.Files() {
+		current[f.Name()] = f
+	}
+
+	// keep the first as it is, change the second, drop the third and add a fourth
+	f2 := newBinaryBlob("file2.bin")
+	f4 := newBinaryBlob("file4.bin")
+	before.SetFiles([]EasyStoreBlob{current["file1.bin"], f2, f4})
+	_, err = es.ObjectUpdate(before, Files)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	after, err := es.ObjectGetByKey(goodNamespace, o.Id(), AllComponents)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	if len(after.Files()) != 3 {
+		t.Fatalf("expected 3 files but got %d\n", len(after.Files()))
+	}
+
+	for _, f := range after.Files() {
+		switch f.Name() {
+		case "file1.bin":
+			// unchanged files are left alone
+			if f.Modified().Equal(current["file1.bin"].Modified()) == false {
+				t.Fatalf("unchanged file was updated\n")
+			}
+			if bytes.Equal(getBlobContents(t, current["file1.bin"]), getBlobContents(t, f)) == false {
+				t.Fatalf("file payloads are unequal but should be\n")
+			}
+		case "file2.bin":
+			// changed files keep their created time
+			if f.Created().Equal(current["file2.bin"].Created()) == false {
+				t.Fatalf("updated file has a new created time\n")
+			}
+			buf, _ := f2.Payload()
+			if bytes.Equal(buf, getBlobContents(t, f)) == false {
+				t.Fatalf("file payloads are unequal but should be\n")
+			}
+		case "file4.bin":
+			buf, _ := f4.Payload()
+			if bytes.Equal(buf, getBlobContents(t, f)) == false {
+				t.Fatalf("file payloads are unequal but should be\n")
+			}
+		default:
+			t.Fatalf("unexpected file '%s'\n", f.Name())
+		}
+	}
+}
+
+func TestUpdateFilesChecksumIgnored(t *testing.T) {
+	es := testSetup(t)
+	defer es.Close()
+	o := NewEasyStoreObject(goodNamespace, "")
+
+	// create the new object with a file
+	o.SetFiles([]EasyStoreBlob{newBinaryBlob("file1.bin")})
+	_, err := es.ObjectCreate(o)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	before, err := es.ObjectGetByKey(goodNamespace, o.Id(), AllComponents)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	// new contents claiming the checksum of the stored file
+	buf, _ := newBinaryBlob("file1.bin").Payload()
+	forged := &easyStoreBlobImpl{Name_: "file1.bin", MimeType_: "application/octet-stream", Payload_: buf, Checksum_: before.Files()[0].Checksum()}
+	before.SetFiles([]EasyStoreBlob{forged})
+	_, err = es.ObjectUpdate(before, Files)
+
+	// the file is updated, or rejected by datastores that verify the checksum as they store it
+	if err != nil {
+		expected := ErrChecksum
+		if errors.Is(err, expected) == false {
+			t.Fatalf("expected '%s' but got '%s'\n", expected, err)
+		}
+		return
+	}
+
+	after, err := es.ObjectGetByKey(goodNamespace, o.Id(), AllComponents)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	if len(after.Files()) != 1 {
+		t.Fatalf("expected 1 file but got %d\n", len(after.Files()))
+	}
+	if bytes.Equal(buf, getBlobContents(t, after.Files()[0])) == false {
+		t.Fatalf("file payloads are unequal but should be\n")
+	}
+	testEqual(t, payloadChecksum(buf), after.Files()[0].Checksum())
+}
+
+func TestUpdateFilesStreamedChanged(t *testing.T) {
+	buf, _ := newBinaryBlob("file1.bin").Payload()
+	current := NewEasyStoreBlob("file1.bin", "application/octet-stream", buf)
+	reader := &countingReader{reader: bytes.NewReader(buf)}
+	streamed := NewEasyStoreBlobFromReader("file1.bin", "application/octet-stream", reader)
+
+	// streamed files are not read to compare them, they are always updated
+	_, updated, _, err := diffFiles([]EasyStoreBlob{current}, []EasyStoreBlob{streamed})
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	if len(updated) != 1 {
+		t.Fatalf("expected 1 updated file but got %d\n", len(updated))
+	}
+	if reader.count != 0 {
+		t.Fatalf("streamed file was read %d times\n", reader.count)
+	}
+}
+
 func TestUpdateMetadata(t *testing.T) {
 	es := testSetup(t)
 	defer es.Close()
@@ -139,6 +276,58 @@ func TestUpdateMetadata(t *testing.T) {
 	}
 }
 
+func TestUpdateConcurrent(t *testing.T) {
+	es := testSetup(t)
+	defer es.Close()
+	o := NewEasyStoreObject(goodNamespace, "")
+
+	_, err := es.ObjectCreate(o)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	// several writers holding the same vtag, exactly one of them wins
+	writers := 20
+	results := make([]error, writers)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for ix := 0; ix < writers; ix++ {
+		obj, err := es.ObjectGetByKey(goodNamespace, o.Id(), BaseComponent)
+		if err != nil {
+			t.Fatalf("expected 'OK' but got '%s'\n", err)
+		}
+		obj.SetFields(EasyStoreObjectFields{"writer": fmt.Sprintf("%d", ix)})
+		wg.Add(1)
+		go func(ix int, obj EasyStoreObject) {
+			defer wg.Done()
+			<-start
+			_, results[ix] = es.ObjectUpdate(obj, Fields)
+		}(ix, obj)
+	}
+	close(start)
+	wg.Wait()
+
+	winners := 0
+	for _, err := range results {
+		if err == nil {
+			winners++
+		} else if errors.Is(err, ErrStaleObject) == false {
+			t.Fatalf("expected '%s' but got '%s'\n", ErrStaleObject, err)
+		}
+	}
+	testEqual(t, "1", fmt.Sprintf("%d", winners))
+}
+
+type countingReader struct {
+	reader io.Reader
+	count  int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	c.count++
+	return c.reader.Read(p)
+}
+
 //
 // end of file
 //
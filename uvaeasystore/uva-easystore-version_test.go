@@ -0,0 +1,119 @@
+//
+//
+//
+
+package uvaeasystore
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestObjectVersions(t *testing.T) {
+	es := testSetup(t)
+	defer es.Close()
+	o := NewEasyStoreObject(goodNamespace, "")
+
+	// create the new object with some fields
+	o.SetFields(EasyStoreObjectFields{"version": "one"})
+	first, err := es.ObjectCreate(o)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	// a new object has only the current version
+	versions, err := es.ObjectVersions(first.Namespace(), first.Id())
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	if len(versions) != 1 || versions[0].Current == false || versions[0].VTag != first.VTag() {
+		t.Fatalf("unexpected versions for a new object\n")
+	}
+
+	// update the fields and add a file
+	first.SetFields(EasyStoreObjectFields{"version": "two"})
+	first.SetFiles([]EasyStoreBlob{newBinaryBlob("file1.bin")})
+	second, err := es.ObjectUpdate(first, Fields+Files)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	// we now have the first and the current versions
+	versions, err = es.ObjectVersions(second.Namespace(), second.Id())
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("expected 2 versions but got %d\n", len(versions))
+	}
+	testEqual(t, first.VTag(), versions[0].VTag)
+	testEqual(t, second.VTag(), versions[1].VTag)
+	if versions[0].Current == true || versions[1].Current == false {
+		t.Fatalf("unexpected current version\n")
+	}
+
+	// get the first version, it has the original fields and no files
+	old, err := es.ObjectGetByVersion(second.Namespace(), second.Id(), first.VTag(), AllComponents)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	testEqual(t, first.VTag(), old.VTag())
+	testEqual(t, "one", old.Fields()["version"])
+	if len(old.Files()) != 0 {
+		t.Fatalf("expected no files but got %d\n", len(old.Files()))
+	}
+
+	// restore the first version, it becomes a new version
+	restored, err := es.ObjectVersionRestore(second.Namespace(), second.Id(), first.VTag())
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	if restored.VTag() == first.VTag() || restored.VTag() == second.VTag() {
+		t.Fatalf("restored object has an existing vtag\n")
+	}
+	testEqual(t, "one", restored.Fields()["version"])
+	if len(restored.Files()) != 0 {
+		t.Fatalf("expected no files but got %d\n", len(restored.Files()))
+	}
+
+	// and the history is preserved
+	versions, err = es.ObjectVersions(restored.Namespace(), restored.Id())
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+	if len(versions) != 3 {
+		t.Fatalf("expected 3 versions but got %d\n", len(versions))
+	}
+	testEqual(t, second.VTag(), versions[1].VTag)
+}
+
+func TestObjectVersionNotFound(t *testing.T) {
+	es := testSetup(t)
+	defer es.Close()
+	o := NewEasyStoreObject(goodNamespace, "")
+
+	obj, err := es.ObjectCreate(o)
+	if err != nil {
+		t.Fatalf("expected 'OK' but got '%s'\n", err)
+	}
+
+	expected := ErrNotFound
+	_, err = es.ObjectGetByVersion(obj.Namespace(), obj.Id(), "no-such-vtag", AllComponents)
+	if errors.Is(err, expected) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", expected, err)
+	}
+	_, err = es.ObjectVersionRestore(obj.Namespace(), obj.Id(), "no-such-vtag")
+	if errors.Is(err, expected) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", expected, err)
+	}
+
+	expected = ErrBadParameter
+	_, err = es.ObjectGetByVersion(obj.Namespace(), obj.Id(), "", AllComponents)
+	if errors.Is(err, expected) == false {
+		t.Fatalf("expected '%s' but got '%s'\n", expected, err)
+	}
+}
+
+//
+// end of file
+//
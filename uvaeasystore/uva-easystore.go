@@ -11,8 +11,12 @@
 package uvaeasystore
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
 	"time"
 )
 
@@ -28,6 +32,8 @@ var ErrSerialize = fmt.Errorf("serialization error")
 var ErrDeserialize = fmt.Errorf("deserialization error")
 var ErrBusNotConfigured = fmt.Errorf("bus not configured")
 var ErrRecurse = fmt.Errorf("cannot recurse further")
+var ErrChecksum = fmt.Errorf("the file checksum does not match")
+var ErrNotAuthorized = fmt.Errorf("not authorized")
 
 // EasyStoreComponents - the components that can appear in an object
 type EasyStoreComponents uint
@@ -42,9 +48,49 @@ const (
 	AllComponents = 0x111 // all components
 )
 
+// EasyStoreOrder - the order in which a page of objects is returned
+type EasyStoreOrder uint
+
+const (
+	OrderById       EasyStoreOrder = iota // by namespace and identifier
+	OrderByCreated                        // by create time, oldest first
+	OrderByModified                       // by last modified time, oldest first
+)
+
+// EasyStorePage - options for getting objects a page at a time
+type EasyStorePage struct {
+	Limit uint           // the maximum number of objects in the page, 0 is no limit
+	Token string         // the continuation token from the previous page, blank for the first page
+	Order EasyStoreOrder // the order objects are returned in, must not change between pages
+}
+
+// EasyStoreVersion - a version of an object. Every change to an object makes a new version
+// and the version it replaces is preserved
+type EasyStoreVersion struct {
+	VTag     string    `json:"vtag"`     // the version tag
+	Created  time.Time `json:"created"`  // the object create time
+	Modified time.Time `json:"modified"` // the time this version was made
+	Current  bool      `json:"current"`  // is this the current version
+}
+
+// EasyStoreTombstone - a deleted object. Deleted objects are kept in the trash, hidden from
+// the get methods, until they are restored or purged
+type EasyStoreTombstone struct {
+	Namespace string    `json:"namespace"` // the object namespace
+	Id        string    `json:"id"`        // the object identifier
+	Deleted   time.Time `json:"deleted"`   // the time the object was deleted
+}
+
+// DefaultTrashRetention - how long deleted objects are usually kept before they are purged
+var DefaultTrashRetention = 30 * 24 * time.Hour
+
 // EasyStoreObjectFields - zero or more name/value pairs
 type EasyStoreObjectFields map[string]string // name value pairs
 
+// EasyStoreObjectMultiFields - zero or more names, each with one or more values (in order). Each
+// value is stored separately and the find operations match any of them
+type EasyStoreObjectMultiFields map[string][]string // name values pairs
+
 // EasyStoreCommon - common fields that appear in objects and blobs
 type EasyStoreCommon interface {
 	Created() time.Time  // Created_ time
@@ -55,6 +101,7 @@ type EasyStoreCommon interface {
 type EasyStoreObjectSet interface {
 	Count() uint                    // the number of items in the set
 	Next() (EasyStoreObject, error) // the next object in the set
+	Continuation() string           // the token for the next page, blank if there are no more
 }
 
 // EasyStoreBlobSet - an iterator for enumerating a set of blobs
@@ -75,6 +122,25 @@ type EasyStoreReadonly interface {
 	// get object(s) by fields, all specified are combined in an AND operation
 	ObjectGetByFields(string, EasyStoreObjectFields, EasyStoreComponents) (EasyStoreObjectSet, error)
 
+	// get a page of objects by fields, pass the continuation token of the returned set to get the next page
+	ObjectGetByFieldsPage(string, EasyStoreObjectFields, EasyStoreComponents, EasyStorePage) (EasyStoreObjectSet, error)
+
+	// get a page of objects by query, see EasyStoreQuery and ParseEasyStoreQuery
+	ObjectGetByQuery(string, EasyStoreQuery, EasyStoreComponents, EasyStorePage) (EasyStoreObjectSet, error)
+
+	// version API calls
+
+	// get the versions of an object, oldest first (the current version is last)
+	ObjectVersions(namespace string, oid string) ([]EasyStoreVersion, error)
+
+	// get an object as of the specified version
+	ObjectGetByVersion(namespace string, oid string, vtag string, which EasyStoreComponents) (EasyStoreObject, error)
+
+	// trash API calls
+
+	// get the deleted objects in the namespace (blank for all namespaces), oldest first
+	ObjectTrash(namespace string) ([]EasyStoreTombstone, error)
+
 	// file API calls
 
 	// get file by identifier
@@ -87,6 +153,20 @@ type EasyStoreReadonly interface {
 
 	// check health/current status
 	Check() error
+
+	// context aware variants of the above, the context is passed to the underlying
+	// datastore (or service) and can be used to cancel an operation or impose a deadline
+
+	ObjectGetByKeyCtx(context.Context, string, string, EasyStoreComponents) (EasyStoreObject, error)
+	ObjectGetByKeysCtx(context.Context, string, []string, EasyStoreComponents) (EasyStoreObjectSet, error)
+	ObjectGetByFieldsCtx(context.Context, string, EasyStoreObjectFields, EasyStoreComponents) (EasyStoreObjectSet, error)
+	ObjectGetByFieldsPageCtx(context.Context, string, EasyStoreObjectFields, EasyStoreComponents, EasyStorePage) (EasyStoreObjectSet, error)
+	ObjectGetByQueryCtx(context.Context, string, EasyStoreQuery, EasyStoreComponents, EasyStorePage) (EasyStoreObjectSet, error)
+	ObjectVersionsCtx(ctx context.Context, namespace string, oid string) ([]EasyStoreVersion, error)
+	ObjectGetByVersionCtx(ctx context.Context, namespace string, oid string, vtag string, which EasyStoreComponents) (EasyStoreObject, error)
+	ObjectTrashCtx(ctx context.Context, namespace string) ([]EasyStoreTombstone, error)
+	FileGetByKeyCtx(ctx context.Context, namespace string, oid string, name string) (EasyStoreBlob, error)
+	CheckCtx(context.Context) error
 }
 
 // EasyStore - the store abstraction (read/write)
@@ -103,12 +183,34 @@ type EasyStore interface {
 	// update all or part of existing object, specify which components are to be updated
 	ObjectUpdate(EasyStoreObject, EasyStoreComponents) (EasyStoreObject, error)
 
-	// delete all or part of an existing object, specify which components are to be deleted
+	// delete all or part of an existing object, specify which components are to be deleted. Deleting
+	// the base component moves the whole object to the trash, other components are deleted immediately
 	ObjectDelete(EasyStoreObject, EasyStoreComponents) (EasyStoreObject, error)
 
+	// restore a deleted object from the trash
+	ObjectRestore(namespace string, oid string) (EasyStoreObject, error)
+
+	// permanently remove a deleted object from the trash, along with its versions
+	ObjectPurge(namespace string, oid string) error
+
+	// permanently remove the objects deleted before the specified time from the trash (blank namespace
+	// for all namespaces), returns the number of objects purged
+	ObjectPurgeTrash(namespace string, before time.Time) (uint, error)
+
+	// copy an object with all of its components to a new namespace and/or identifier (blank for a new
+	// identifier), the copy keeps the timestamps but not the prior versions of the original
+	ObjectCopy(namespace string, oid string, newNamespace string, newOid string) (EasyStoreObject, error)
+
+	// move an object with all of its components to a new namespace and/or identifier (blank for a new
+	// identifier), the original is permanently removed along with its prior versions
+	ObjectMove(namespace string, oid string, newNamespace string, newOid string) (EasyStoreObject, error)
+
 	// rename one of the blobs within the object, old name, new name
 	//Rename(EasyStoreObject, EasyStoreComponents, string, string) (EasyStoreObject, error)
 
+	// restore a prior version of an object, the restored version becomes a new version
+	ObjectVersionRestore(namespace string, oid string, vtag string) (EasyStoreObject, error)
+
 	// file API calls
 
 	// create a file
@@ -122,6 +224,22 @@ type EasyStore interface {
 
 	// update a file
 	FileUpdate(namespace string, oid string, file EasyStoreBlob) error
+
+	// context aware variants of the above
+
+	ObjectCreateCtx(context.Context, EasyStoreObject) (EasyStoreObject, error)
+	ObjectUpdateCtx(context.Context, EasyStoreObject, EasyStoreComponents) (EasyStoreObject, error)
+	ObjectDeleteCtx(context.Context, EasyStoreObject, EasyStoreComponents) (EasyStoreObject, error)
+	ObjectRestoreCtx(ctx context.Context, namespace string, oid string) (EasyStoreObject, error)
+	ObjectPurgeCtx(ctx context.Context, namespace string, oid string) error
+	ObjectPurgeTrashCtx(ctx context.Context, namespace string, before time.Time) (uint, error)
+	ObjectCopyCtx(ctx context.Context, namespace string, oid string, newNamespace string, newOid string) (EasyStoreObject, error)
+	ObjectMoveCtx(ctx context.Context, namespace string, oid string, newNamespace string, newOid string) (EasyStoreObject, error)
+	ObjectVersionRestoreCtx(ctx context.Context, namespace string, oid string, vtag string) (EasyStoreObject, error)
+	FileCreateCtx(ctx context.Context, namespace string, oid string, file EasyStoreBlob) error
+	FileDeleteCtx(ctx context.Context, namespace string, oid string, name string) error
+	FileRenameCtx(ctx context.Context, namespace string, oid string, name string, new string) error
+	FileUpdateCtx(ctx context.Context, namespace string, oid string, file EasyStoreBlob) error
 }
 
 // EasyStoreObject - the objects stored in the easystore
@@ -130,14 +248,17 @@ type EasyStoreObject interface {
 	Id() string        // object Id
 	VTag() string      // object version tag
 
-	Fields() EasyStoreObjectFields // the fields
-	Metadata() EasyStoreMetadata   // the opaque metadata
-	Files() []EasyStoreBlob        // the associated file(s)
+	Fields() EasyStoreObjectFields           // the fields (the first value of those with several)
+	MultiFields() EasyStoreObjectMultiFields // the fields with all their values
+	Metadata() EasyStoreMetadata             // the opaque metadata
+	Files() []EasyStoreBlob                  // the associated file(s)
 
-	SetNamespace(string)             // allows us to relocate into a different namespace
-	SetFields(EasyStoreObjectFields) // the fields
-	SetMetadata(EasyStoreMetadata)   // the opaque metadata
-	SetFiles([]EasyStoreBlob)        // the associated file(s)
+	SetNamespace(string)                       // allows us to relocate into a different namespace
+	SetFields(EasyStoreObjectFields)           // the fields
+	SetMultiFields(EasyStoreObjectMultiFields) // the fields with all their values
+	AddFieldValue(string, string)              // add a value to a field
+	SetMetadata(EasyStoreMetadata)             // the opaque metadata
+	SetFiles([]EasyStoreBlob)                  // the associated file(s)
 
 	EasyStoreCommon // any common fields
 }
@@ -147,9 +268,14 @@ type EasyStoreBlob interface {
 	Name() string     // original name
 	MimeType() string // can we type this in some way
 
-	// access to actual payload, one of the following
-	Url() string              // a url to stream the payload
-	Payload() ([]byte, error) // the payload
+	// access to actual payload, one of the following. Stored payloads are checked against their
+	// checksum when they are read (ErrChecksum if they do not match)
+	Url() string                  // a url to stream the payload
+	Payload() ([]byte, error)     // the payload (a streamed payload is read into memory)
+	Open() (io.ReadCloser, error) // the payload as a stream, the caller must close it
+
+	Checksum() string // the SHA-256 checksum of the payload (hex encoded), blank until the blob is stored
+	Size() int64      // the payload size in bytes, without having to fetch it
 
 	EasyStoreCommon // any common fields
 }
@@ -162,6 +288,49 @@ type EasyStoreMetadata interface {
 	EasyStoreCommon // any common fields
 }
 
+// EasyStoreEvent - an event describing a change. Events are added to the outbox along with the change
+// and published to the event sink once the change is committed
+type EasyStoreEvent struct {
+	Id        string          `json:"id"`                  // the event identifier
+	Name      string          `json:"name"`                // the event name
+	Namespace string          `json:"namespace"`           // the object namespace
+	ObjectId  string          `json:"oid"`                 // the object identifier
+	Detail    json.RawMessage `json:"detail"`              // the event specific detail
+	Created   time.Time       `json:"created"`             // when the change was made
+	Delivered *time.Time      `json:"delivered,omitempty"` // when the event was published (nil if it is pending)
+	Attempts  uint            `json:"attempts"`            // the delivery attempts
+}
+
+// EasyStoreEventDetail - the detail of the storage events. The vtag is always present, the rest only
+// when they apply to the event
+type EasyStoreEventDetail struct {
+	VTag         string            `json:"vtag"`                    // the object vtag after the change
+	PriorVTag    string            `json:"prior_vtag,omitempty"`    // the object vtag before the change
+	Components   []string          `json:"components,omitempty"`    // the components changed (fields, files, metadata)
+	File         string            `json:"file,omitempty"`          // the file, for the file events
+	FilesAdded   []string          `json:"files_added,omitempty"`   // the files added
+	FilesUpdated []string          `json:"files_updated,omitempty"` // the files updated
+	FilesDeleted []string          `json:"files_deleted,omitempty"` // the files deleted
+	FilesRenamed []EasyStoreRename `json:"files_renamed,omitempty"` // the files renamed
+	Version      string            `json:"version,omitempty"`       // the version restored
+	Source       string            `json:"source,omitempty"`        // the object copied or moved from (ns/oid)
+	Target       string            `json:"target,omitempty"`        // the object moved to (ns/oid)
+	Actor        string            `json:"actor,omitempty"`         // who made the change (see WithActor)
+}
+
+// EasyStoreRename - a file rename
+type EasyStoreRename struct {
+	From string `json:"from"` // the old name
+	To   string `json:"to"`   // the new name
+}
+
+// EasyStoreEventSink - where events are published. The easystore does not close the sink, whoever
+// created it does
+type EasyStoreEventSink interface {
+	PublishEvent(event EasyStoreEvent) error // publish an event
+	Close() error                            // close the sink
+}
+
 // EasyStoreImplConfig - the configuration structure for an implementation
 type EasyStoreImplConfig interface {
 	// logging support
@@ -175,6 +344,23 @@ type EasyStoreImplConfig interface {
 	SetEventSource(string) // telemetry events are tagged as coming from this source
 }
 
+// EasyStoreEventSinkConfig - the event sink for an implementation, when the configuration implements this
+// interface and the sink is set it is used instead of the message bus
+type EasyStoreEventSinkConfig interface {
+	EventSink() EasyStoreEventSink
+	SetEventSink(EasyStoreEventSink)
+}
+
+// EasyStorePolicyConfig - access control for an implementation, when the configuration implements this
+// interface and there is a policy each request is checked against it and denied requests are recorded in
+// the audit log
+type EasyStorePolicyConfig interface {
+	Policy() *EasyStorePolicy
+	SetPolicy(*EasyStorePolicy)
+	AuditLog() EasyStoreAuditLog
+	SetAuditLog(EasyStoreAuditLog)
+}
+
 // EasyStoreProxyConfig - the configuration structure for a proxy
 type EasyStoreProxyConfig interface {
 	// logging support
@@ -188,12 +374,36 @@ type EasyStoreProxyConfig interface {
 	SetTimeout(int)
 }
 
+// EasyStoreProxyAuthConfig - the authentication settings for a proxy (all optional), they are used when the
+// proxy configuration implements this interface. A bearer token is used in preference to basic auth
+type EasyStoreProxyAuthConfig interface {
+	Token() string // a static bearer token
+	SetToken(string)
+	TokenSource() EasyStoreTokenSource // supplies the bearer token, it is called again when the token is rejected
+	SetTokenSource(EasyStoreTokenSource)
+	BasicAuth() (string, string) // user and password
+	SetBasicAuth(string, string)
+	ClientCertificate() (string, string) // certificate and key files (PEM) for mutual TLS
+	SetClientCertificate(string, string)
+	CACertificate() string // CA bundle (PEM) used to verify the service, the system roots when blank
+	SetCACertificate(string)
+}
+
+// EasyStoreTokenSource - supplies a bearer token for the proxy
+type EasyStoreTokenSource func(ctx context.Context) (string, error)
+
+// EasyStoreAuthenticator - identifies the principal making a service request from its credentials. It returns
+// a blank principal when it does not recognize the credentials and ErrNotAuthorized when they are not valid
+type EasyStoreAuthenticator func(r *http.Request) (string, error)
+
 // EasyStoreSerializer - used to serialize and deserialize our objects
 type EasyStoreSerializer interface {
 	BlobDeserialize(interface{}) (EasyStoreBlob, error)
 	BlobSerialize(EasyStoreBlob) interface{}
 	FieldsDeserialize(interface{}) (EasyStoreObjectFields, error)
 	FieldsSerialize(EasyStoreObjectFields) interface{}
+	MultiFieldsDeserialize(interface{}) (EasyStoreObjectMultiFields, error)
+	MultiFieldsSerialize(EasyStoreObjectMultiFields) interface{}
 	MetadataDeserialize(interface{}) (EasyStoreMetadata, error)
 	MetadataSerialize(EasyStoreMetadata) interface{}
 	ObjectDeserialize(interface{}) (EasyStoreObject, error)
@@ -214,6 +424,36 @@ func NewEasyStoreProxyReadonly(config EasyStoreProxyConfig) (EasyStoreReadonly,
 	return newEasyStoreProxyReadonly(config)
 }
 
+// NewEasyStoreService - factory for an http.Handler that serves the proxy REST contract for any EasyStore.
+// Requests are made by the principal identified by the authenticators (tried in order), requests without
+// credentials are rejected. With no authenticators requests are made without a principal
+func NewEasyStoreService(store EasyStore, logger *log.Logger, authenticators ...EasyStoreAuthenticator) http.Handler {
+	return newEasyStoreService(store, logger, authenticators)
+}
+
+// NewEasyStoreTokenAuthenticator - factory for an authenticator of bearer tokens, each identifies a principal
+func NewEasyStoreTokenAuthenticator(tokens map[string]string) EasyStoreAuthenticator {
+	return newTokenAuthenticator(tokens)
+}
+
+// NewEasyStoreBasicAuthenticator - factory for an authenticator of basic auth users and their passwords,
+// the user is the principal
+func NewEasyStoreBasicAuthenticator(users map[string]string) EasyStoreAuthenticator {
+	return newBasicAuthenticator(users)
+}
+
+// NewEasyStoreCertificateAuthenticator - factory for an authenticator of client certificates, the subject
+// common name is the principal. The server must verify client certificates (see tls.Config ClientAuth)
+func NewEasyStoreCertificateAuthenticator() EasyStoreAuthenticator {
+	return newCertificateAuthenticator()
+}
+
+// NewEasyStorePrincipal - factory for an EasyStore that makes every request on behalf of the principal,
+// any principal in the context of a request is replaced
+func NewEasyStorePrincipal(store EasyStore, principal string) EasyStore {
+	return newEasyStorePrincipal(store, principal)
+}
+
 // NewEasyStoreObject - factory for our easystore object
 func NewEasyStoreObject(namespace string, id string) EasyStoreObject {
 	return newEasyStoreObject(namespace, id)
@@ -228,6 +468,12 @@ func NewEasyStoreBlob(name string, mimeType string, payload []byte) EasyStoreBlo
 	return newEasyStoreBlob(name, mimeType, payload)
 }
 
+// NewEasyStoreBlobFromReader - factory for our easystore blob object with a streamed payload.
+// The reader is consumed (and closed if it is an io.Closer) when the blob is stored
+func NewEasyStoreBlobFromReader(name string, mimeType string, reader io.Reader) EasyStoreBlob {
+	return newEasyStoreBlobFromReader(name, mimeType, reader)
+}
+
 // NewEasyStoreMetadata - factory for our easystore blob object
 func NewEasyStoreMetadata(mimeType string, payload []byte) EasyStoreMetadata {
 	return newEasyStoreMetadata(mimeType, payload)
@@ -244,6 +490,44 @@ func DefaultEasyStoreSerializer() EasyStoreSerializer {
 	return newEasyStoreSerializer()
 }
 
+// WithActor - a context for changes made on behalf of the actor, it is recorded in the events
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext - the actor the changes are made on behalf of (blank if there is none)
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// WithPrincipal - a context for requests made by the principal, it is checked against the access policy
+func WithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext - the principal making the request (blank if there is none)
+func PrincipalFromContext(ctx context.Context) string {
+	principal, _ := ctx.Value(principalKey{}).(string)
+	return principal
+}
+
+// EventDetail - the detail of a storage event
+func EventDetail(event EasyStoreEvent) (EasyStoreEventDetail, error) {
+	var detail EasyStoreEventDetail
+	err := json.Unmarshal(event.Detail, &detail)
+	if err != nil {
+		return detail, fmt.Errorf("%q: %w", err.Error(), ErrDeserialize)
+	}
+	return detail, nil
+}
+
+// the context key for the actor
+type actorKey struct{}
+
+// the context key for the principal
+type principalKey struct{}
+
 //
 // end of file
 //